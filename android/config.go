@@ -883,6 +883,36 @@ func (c *config) UseRemoteBuild() bool {
 	return c.UseGoma() || c.UseRBE()
 }
 
+// SboxIsolate returns true if sbox should hide all files from sandboxed commands except their
+// declared inputs and tools, so that missing dependencies are caught locally.
+func (c *config) SboxIsolate() bool {
+	return c.IsEnvTrue("SBOX_ISOLATE")
+}
+
+// SboxCacheDir returns the directory of the local cache that sbox stores the outputs of sandboxed
+// commands in, or an empty string if the outputs should not be cached.
+func (c *config) SboxCacheDir() string {
+	return c.Getenv("SBOX_CACHE_DIR")
+}
+
+// ReportPolicyViolations returns true if the neverallow and visibility violations should be
+// written to the policy violations report instead of failing the build.
+func (c *config) ReportPolicyViolations() bool {
+	return c.IsEnvTrue("SOONG_REPORT_POLICY_VIOLATIONS")
+}
+
+// ExplainVisibilityQuery returns the qualified names of the two modules that soong_build should
+// explain the visibility of instead of building, e.g. "//foo:libfoo //bar:libbar".
+func (c *config) ExplainVisibilityQuery() string {
+	return c.Getenv("SOONG_EXPLAIN_VISIBILITY")
+}
+
+// DependencyPathsQuery returns the names of the two modules that soong_build should print the
+// dependency paths between instead of building, e.g. "libfoo libbar".
+func (c *config) DependencyPathsQuery() string {
+	return c.Getenv("SOONG_QUERY_DEPENDENCY_PATHS")
+}
+
 func (c *config) RunErrorProne() bool {
 	return c.IsEnvTrue("RUN_ERROR_PRONE")
 }
@@ -1016,6 +1046,18 @@ func (c *deviceConfig) WithDexpreopt() bool {
 	return c.config.productVariables.WithDexpreopt
 }
 
+// PrebuiltSelectionFile returns the path of the product's file that selects the source or the
+// prebuilt module for a list of modules, or an empty string if the product has none.
+func (c *config) PrebuiltSelectionFile() string {
+	return String(c.productVariables.PrebuiltSelectionFile)
+}
+
+// ProductPackages returns the modules listed in the ProductPackages key of soong.variables, the
+// roots of the set of modules that are installed in the product.
+func (c *config) ProductPackages() []string {
+	return c.productVariables.ProductPackages
+}
+
 func (c *config) FrameworksBaseDirExists(ctx PathContext) bool {
 	return ExistentPathForSource(ctx, "frameworks", "base", "Android.bp").Valid()
 }
@@ -1311,6 +1353,12 @@ func (c *config) EnforceSystemCertificate() bool {
 	return Bool(c.productVariables.EnforceSystemCertificate)
 }
 
+// EnforceLicenseKindExceptions returns true if using a license kind with the "by_exception_only"
+// condition outside of the directories allowed by AddLicenseKindExceptions is an error.
+func (c *config) EnforceLicenseKindExceptions() bool {
+	return Bool(c.productVariables.EnforceLicenseKindExceptions)
+}
+
 func (c *config) EnforceSystemCertificateAllowList() []string {
 	return c.productVariables.EnforceSystemCertificateAllowList
 }
@@ -120,8 +120,26 @@ var (
 
 	// Used for processes that need significant RAM to ensure there are not too many running in parallel.
 	highmemPool = blueprint.NewBuiltinPool("highmem_pool")
+
+	// Used for rules that are known to need a lot of RAM when run locally.  soong_ui sizes each of
+	// these pools from the peak memory used by the rules in it in previous builds, see
+	// ui/build/highmem_pools.go.
+	HighmemLinkPool     = blueprint.NewBuiltinPool("highmem_link_pool")
+	HighmemR8Pool       = blueprint.NewBuiltinPool("highmem_r8_pool")
+	HighmemMetalavaPool = blueprint.NewBuiltinPool("highmem_metalava_pool")
+	HighmemKotlinPool   = blueprint.NewBuiltinPool("highmem_kotlin_pool")
 )
 
+// isHighmemPool returns true if pool restricts the number of rules that run in parallel based on the
+// RAM of the local machine.
+func isHighmemPool(pool blueprint.Pool) bool {
+	switch pool {
+	case highmemPool, HighmemLinkPool, HighmemR8Pool, HighmemMetalavaPool, HighmemKotlinPool:
+		return true
+	}
+	return false
+}
+
 func init() {
 	pctx.Import("github.com/google/blueprint/bootstrap")
 
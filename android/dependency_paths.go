@@ -0,0 +1,302 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Dependency path queries.
+//
+// When SOONG_QUERY_DEPENDENCY_PATHS is set to the names of two modules, e.g. "libfoo libbar",
+// soong_build prints the shortest dependency path from any variant of the first module to any
+// variant of the second module, and all of the paths without cycles, instead of building.  Each
+// edge of the paths is annotated with the type of its dependency tag and each module with its
+// variations, which answers questions like why a library is in an APEX or why a jar is on the
+// bootclasspath.  The query runs on the final module graph, after all mutators ran.
+
+// The maximum number of paths without cycles that are printed.
+const maxDependencyPaths = 100
+
+// The module graph that blueprint's PrintJSONGraph writes.
+type jsonModuleGraphName struct {
+	Name       string
+	Variations map[string]string
+}
+
+type jsonModuleGraphDep struct {
+	jsonModuleGraphName
+	Tag string
+}
+
+type jsonModuleGraphModule struct {
+	jsonModuleGraphName
+	Deps []jsonModuleGraphDep
+}
+
+// dependencyGraphNode is a variant of a module in the dependency graph.
+type dependencyGraphNode struct {
+	name      string
+	variation string
+	deps      []dependencyGraphEdge
+}
+
+// dependencyGraphEdge is a dependency of a variant of a module on another one.
+type dependencyGraphEdge struct {
+	to      *dependencyGraphNode
+	tagType string
+}
+
+func (n *dependencyGraphNode) String() string {
+	if n.variation == "" {
+		return n.name
+	}
+	return n.name + " (" + n.variation + ")"
+}
+
+// variationString returns the non-empty variations sorted by mutator, e.g.
+// "arch:android_arm64_armv8-a, link:shared".
+func variationString(variations map[string]string) string {
+	var ret []string
+	for _, mutator := range SortedStringKeys(variations) {
+		if variations[mutator] != "" {
+			ret = append(ret, mutator+":"+variations[mutator])
+		}
+	}
+	return strings.Join(ret, ", ")
+}
+
+// tagType returns the type of a dependency tag from its description in the JSON module graph,
+// e.g. "cc.libraryDependencyTag" for "cc.libraryDependencyTag {Kind:1 ...}".
+func tagType(tag string) string {
+	return strings.SplitN(tag, " ", 2)[0]
+}
+
+// newDependencyGraph returns the variants of each module of the JSON module graph.
+func newDependencyGraph(modules []jsonModuleGraphModule) map[string][]*dependencyGraphNode {
+	nodes := make(map[string]*dependencyGraphNode)
+	key := func(n jsonModuleGraphName) string {
+		var variations []string
+		for _, mutator := range SortedStringKeys(n.Variations) {
+			variations = append(variations, mutator+"="+n.Variations[mutator])
+		}
+		return n.Name + "\x00" + strings.Join(variations, "\x00")
+	}
+	node := func(n jsonModuleGraphName) *dependencyGraphNode {
+		k := key(n)
+		if nodes[k] == nil {
+			nodes[k] = &dependencyGraphNode{name: n.Name, variation: variationString(n.Variations)}
+		}
+		return nodes[k]
+	}
+
+	graph := make(map[string][]*dependencyGraphNode)
+	for _, m := range modules {
+		n := node(m.jsonModuleGraphName)
+		graph[m.Name] = append(graph[m.Name], n)
+		for _, dep := range m.Deps {
+			n.deps = append(n.deps, dependencyGraphEdge{to: node(dep.jsonModuleGraphName), tagType: tagType(dep.Tag)})
+		}
+	}
+	return graph
+}
+
+// dependencyPath is a path in the dependency graph, the edges lead from the module to the last
+// module of the path.
+type dependencyPath struct {
+	from  *dependencyGraphNode
+	edges []dependencyGraphEdge
+}
+
+func (p dependencyPath) String() string {
+	sb := &strings.Builder{}
+	sb.WriteString(p.from.String())
+	for _, e := range p.edges {
+		fmt.Fprintf(sb, "\n    -> [%s] %s", e.tagType, e.to)
+	}
+	return sb.String()
+}
+
+// shortestDependencyPath returns the shortest path from any of the from nodes to any of the to
+// nodes, found by a breadth first search.
+func shortestDependencyPath(from, to []*dependencyGraphNode) (dependencyPath, bool) {
+	type step struct {
+		prev *dependencyGraphNode
+		edge dependencyGraphEdge
+	}
+	isTarget := make(map[*dependencyGraphNode]bool)
+	for _, n := range to {
+		isTarget[n] = true
+	}
+
+	steps := make(map[*dependencyGraphNode]*step)
+	queue := append([]*dependencyGraphNode(nil), from...)
+	for _, n := range from {
+		steps[n] = nil
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if isTarget[n] && steps[n] != nil {
+			var edges []dependencyGraphEdge
+			for s := steps[n]; s != nil; s = steps[s.prev] {
+				edges = append([]dependencyGraphEdge{s.edge}, edges...)
+				n = s.prev
+			}
+			return dependencyPath{from: n, edges: edges}, true
+		}
+		for _, e := range n.deps {
+			if _, seen := steps[e.to]; !seen {
+				steps[e.to] = &step{prev: n, edge: e}
+				queue = append(queue, e.to)
+			}
+		}
+	}
+	return dependencyPath{}, false
+}
+
+// allDependencyPaths returns up to limit paths without cycles from any of the from nodes to any
+// of the to nodes, and whether there are more.
+func allDependencyPaths(from, to []*dependencyGraphNode, limit int) ([]dependencyPath, bool) {
+	isTarget := make(map[*dependencyGraphNode]bool)
+	for _, n := range to {
+		isTarget[n] = true
+	}
+
+	// Only search the nodes that lead to a target, found by a breadth first search of the reversed
+	// dependencies of the nodes that can be reached from the from nodes.
+	reverseDeps := make(map[*dependencyGraphNode][]*dependencyGraphNode)
+	seen := make(map[*dependencyGraphNode]bool)
+	queue := append([]*dependencyGraphNode(nil), from...)
+	for _, n := range from {
+		seen[n] = true
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, e := range n.deps {
+			reverseDeps[e.to] = append(reverseDeps[e.to], n)
+			if !seen[e.to] {
+				seen[e.to] = true
+				queue = append(queue, e.to)
+			}
+		}
+	}
+	reaches := make(map[*dependencyGraphNode]bool)
+	for _, n := range to {
+		if seen[n] && !reaches[n] {
+			reaches[n] = true
+			queue = append(queue, n)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, r := range reverseDeps[n] {
+			if !reaches[r] {
+				reaches[r] = true
+				queue = append(queue, r)
+			}
+		}
+	}
+
+	var paths []dependencyPath
+	truncated := false
+	onPath := make(map[*dependencyGraphNode]bool)
+	var edges []dependencyGraphEdge
+	var visit func(start, n *dependencyGraphNode)
+	visit = func(start, n *dependencyGraphNode) {
+		if truncated {
+			return
+		}
+		if isTarget[n] && len(edges) > 0 {
+			if len(paths) == limit {
+				truncated = true
+				return
+			}
+			paths = append(paths, dependencyPath{from: start, edges: append([]dependencyGraphEdge(nil), edges...)})
+			return
+		}
+		onPath[n] = true
+		for _, e := range n.deps {
+			if !onPath[e.to] && reaches[e.to] {
+				edges = append(edges, e)
+				visit(start, e.to)
+				edges = edges[:len(edges)-1]
+			}
+		}
+		onPath[n] = false
+	}
+	for _, n := range from {
+		visit(n, n)
+	}
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(paths[i].edges) < len(paths[j].edges)
+	})
+	return paths, truncated
+}
+
+// QueryDependencyPaths returns the description of the dependency paths between the modules in
+// SOONG_QUERY_DEPENDENCY_PATHS in the module graph of the context.
+func QueryDependencyPaths(config Config, ctx *Context) (string, error) {
+	query := strings.Fields(config.DependencyPathsQuery())
+	if len(query) != 2 {
+		return "", fmt.Errorf("SOONG_QUERY_DEPENDENCY_PATHS must be set to two modules, e.g. %q, got %q",
+			"libfoo libbar", config.DependencyPathsQuery())
+	}
+
+	buf := &bytes.Buffer{}
+	ctx.Context.PrintJSONGraph(buf)
+	var modules []jsonModuleGraphModule
+	if err := json.Unmarshal(buf.Bytes(), &modules); err != nil {
+		return "", fmt.Errorf("failed to parse the module graph: %s", err)
+	}
+	graph := newDependencyGraph(modules)
+
+	for _, name := range query {
+		if len(graph[name]) == 0 {
+			return "", fmt.Errorf("SOONG_QUERY_DEPENDENCY_PATHS: unknown module %q", name)
+		}
+	}
+
+	return describeDependencyPaths(graph[query[0]], graph[query[1]]), nil
+}
+
+func describeDependencyPaths(from, to []*dependencyGraphNode) string {
+	sb := &strings.Builder{}
+
+	shortest, ok := shortestDependencyPath(from, to)
+	if !ok {
+		fmt.Fprintf(sb, "%s does not depend on %s\n", from[0].name, to[0].name)
+		return sb.String()
+	}
+	fmt.Fprintf(sb, "Shortest dependency path from %s to %s:\n  %s\n", from[0].name, to[0].name, shortest)
+
+	paths, truncated := allDependencyPaths(from, to, maxDependencyPaths)
+	if truncated {
+		fmt.Fprintf(sb, "\nThe first %d dependency paths:\n", len(paths))
+	} else {
+		fmt.Fprintf(sb, "\nAll %d dependency paths:\n", len(paths))
+	}
+	for i, p := range paths {
+		fmt.Fprintf(sb, "%d. %s\n", i+1, p)
+	}
+	return sb.String()
+}
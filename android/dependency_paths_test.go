@@ -0,0 +1,107 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+
+	"github.com/google/blueprint"
+)
+
+type dependencyPathsTestDepTag struct {
+	blueprint.BaseDependencyTag
+}
+
+type dependencyPathsTestModule struct {
+	ModuleBase
+	properties struct {
+		Deps []string
+	}
+}
+
+func dependencyPathsTestModuleFactory() Module {
+	m := &dependencyPathsTestModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidModule(m)
+	return m
+}
+
+func (m *dependencyPathsTestModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddDependency(ctx.Module(), dependencyPathsTestDepTag{}, m.properties.Deps...)
+}
+
+func (m *dependencyPathsTestModule) GenerateAndroidBuildActions(ModuleContext) {}
+
+func TestQueryDependencyPaths(t *testing.T) {
+	bp := `
+		test_module {
+			name: "a",
+			deps: ["b", "c"],
+		}
+
+		test_module {
+			name: "b",
+			deps: ["d"],
+		}
+
+		test_module {
+			name: "c",
+			deps: ["b"],
+		}
+
+		test_module {
+			name: "d",
+		}
+	`
+
+	run := func(query string) (string, error) {
+		result := GroupFixturePreparers(
+			FixtureRegisterWithContext(func(ctx RegistrationContext) {
+				ctx.RegisterModuleType("test_module", dependencyPathsTestModuleFactory)
+			}),
+			FixtureMergeEnv(map[string]string{"SOONG_QUERY_DEPENDENCY_PATHS": query}),
+			FixtureWithRootAndroidBp(bp),
+		).RunTest(t)
+		return QueryDependencyPaths(result.Config, result.TestContext.Context)
+	}
+
+	got, err := run("a d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertStringEquals(t, "dependency paths from a to d", `Shortest dependency path from a to d:
+  a
+    -> [android.dependencyPathsTestDepTag] b
+    -> [android.dependencyPathsTestDepTag] d
+
+All 2 dependency paths:
+1. a
+    -> [android.dependencyPathsTestDepTag] b
+    -> [android.dependencyPathsTestDepTag] d
+2. a
+    -> [android.dependencyPathsTestDepTag] c
+    -> [android.dependencyPathsTestDepTag] b
+    -> [android.dependencyPathsTestDepTag] d
+`, got)
+
+	got, err = run("d a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertStringEquals(t, "dependency paths from d to a", "d does not depend on a\n", got)
+
+	_, err = run("a e")
+	AssertErrorMessageEquals(t, "unknown module", `SOONG_QUERY_DEPENDENCY_PATHS: unknown module "e"`, err)
+}
@@ -43,3 +43,36 @@ func IsInstallDepNeeded(tag blueprint.DependencyTag) bool {
 	}
 	return false
 }
+
+// Dependency tags can implement this interface and return true from PropagatesLicenseConditions to
+// annotate that the child is statically linked into or packaged with the parent, so the conditions
+// of the licenses of the child apply to the parent.
+type LicenseConditionsPropagatingDependencyTag interface {
+	// If PropagatesLicenseConditions returns true then the license conditions of the child apply
+	// to the parent.
+	PropagatesLicenseConditions() bool
+}
+
+// Dependency tags can embed this struct to annotate that the license conditions of the child
+// apply to the parent.
+type AlwaysPropagateLicenseConditionsTag struct{}
+
+func (AlwaysPropagateLicenseConditionsTag) PropagatesLicenseConditions() bool {
+	return true
+}
+
+var _ LicenseConditionsPropagatingDependencyTag = AlwaysPropagateLicenseConditionsTag{}
+
+// IsLicenseConditionsPropagatingDepTag returns true if the dependency tag implements the
+// LicenseConditionsPropagatingDependencyTag interface and PropagatesLicenseConditions returns
+// true, or if it is a PackagingItem that is packaged, meaning that the license conditions of the
+// child apply to the parent.
+func IsLicenseConditionsPropagatingDepTag(tag blueprint.DependencyTag) bool {
+	if p, ok := tag.(LicenseConditionsPropagatingDependencyTag); ok {
+		return p.PropagatesLicenseConditions()
+	}
+	if p, ok := tag.(PackagingItem); ok {
+		return p.IsPackagingItem()
+	}
+	return false
+}
@@ -53,6 +53,9 @@ type licenseModule struct {
 	DefaultableModuleBase
 
 	properties licenseProperties
+
+	// licenseTexts are the paths to the texts of the license, used by the sbom singleton.
+	licenseTexts Paths
 }
 
 func (m *licenseModule) DepsMutator(ctx BottomUpMutatorContext) {
@@ -60,7 +63,7 @@ func (m *licenseModule) DepsMutator(ctx BottomUpMutatorContext) {
 }
 
 func (m *licenseModule) GenerateAndroidBuildActions(ctx ModuleContext) {
-	// Nothing to do.
+	m.licenseTexts = PathsForModuleSrc(ctx, m.properties.License_text)
 }
 
 func LicenseFactory() Module {
@@ -0,0 +1,250 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+// License condition checks.
+//
+// license_kind modules declare the conditions of their licenses, e.g. "notice", "reciprocal",
+// "restricted" or "by_exception_only".  The license conditions of a module are the conditions of
+// its applicable licenses, plus the license conditions of the dependencies that are statically
+// linked into it or packaged with it, i.e. whose dependency tags implement
+// LicenseConditionsPropagatingDependencyTag or PackagingItem.
+//
+// A module violates the license conditions if:
+// - it is installed in a proprietary partition (vendor or odm) and statically links or packages
+//   code under a license with the "restricted" condition.
+// - it has a license with the "by_exception_only" condition, directly or through a dependency,
+//   and it is not in one of the directories allowed for the license kind by
+//   AddLicenseKindExceptions.
+//
+// The directories that use the license kinds with the "by_exception_only" condition in
+// build/soong/licenses haven't been added to the exceptions yet, so the second check is only
+// enforced in products that set EnforceLicenseKindExceptions.
+
+const (
+	licenseConditionRestricted      = "restricted"
+	licenseConditionByExceptionOnly = "by_exception_only"
+)
+
+// Registers the function that propagates license conditions along dependencies and checks them.
+//
+// This goes after the licenses dependency checker so that the license and license_kind
+// dependencies are known to be valid.
+func RegisterLicenseConditionsChecker(ctx RegisterMutatorsContext) {
+	ctx.BottomUp("licenseConditionsChecker", licenseConditionsChecker).Parallel()
+}
+
+// licenseKindExceptions maps the license kinds with the "by_exception_only" condition to the
+// directories whose modules are allowed to use them.
+var licenseKindExceptions = map[string][]string{}
+
+// AddLicenseKindExceptions allows modules in the given directories, and the directories below
+// them, to use licenses of a license kind with the "by_exception_only" condition.
+func AddLicenseKindExceptions(licenseKind string, paths ...string) {
+	licenseKindExceptions[licenseKind] = append(licenseKindExceptions[licenseKind], cleanPaths(paths)...)
+}
+
+var licenseKindExceptionsKey = NewOnceKey("licenseKindExceptions")
+
+func getLicenseKindExceptions(config Config) map[string][]string {
+	return config.Once(licenseKindExceptionsKey, func() interface{} {
+		// No test exceptions were set by setTestLicenseKindExceptions, use the global exceptions
+		return licenseKindExceptions
+	}).(map[string][]string)
+}
+
+// Overrides the default license kind exceptions for the supplied config.
+//
+// For testing only.
+func setTestLicenseKindExceptions(config Config, exceptions map[string][]string) {
+	config.Once(licenseKindExceptionsKey, func() interface{} { return exceptions })
+}
+
+// Prepares for a test by setting license kind exceptions and enabling the license conditions
+// checker, with the license kind exceptions enforced.
+//
+// If the supplied exceptions are nil then the default exceptions are used.
+func PrepareForTestWithLicenseConditionsChecker(exceptions map[string][]string) FixturePreparer {
+	return GroupFixturePreparers(
+		FixtureModifyConfig(func(config Config) {
+			if exceptions != nil {
+				setTestLicenseKindExceptions(config, exceptions)
+			}
+		}),
+		FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+			variables.EnforceLicenseKindExceptions = boolPtr(true)
+		}),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.PostDepsMutators(RegisterLicenseConditionsChecker)
+		}),
+	)
+}
+
+// licenseConditionSource is a license with a condition that applies to a module.
+type licenseConditionSource struct {
+	// The name of the license module.
+	license string
+
+	// The name of the license_kind module that has the condition.
+	licenseKind string
+
+	// The names of the modules on the dependency path through which the license applies to the
+	// module, starting with the direct dependency.  Empty if the license applies to the module
+	// directly.
+	path []string
+}
+
+func (s licenseConditionSource) String() string {
+	ret := "license " + s.license + " (license_kind " + s.licenseKind + ")"
+	if len(s.path) > 0 {
+		ret += " of " + s.path[len(s.path)-1] + " via " + strings.Join(s.path, " -> ")
+	}
+	return ret
+}
+
+// licenseConditionsInfo is provided by every module with the license conditions that apply to it.
+type licenseConditionsInfo struct {
+	// conditions maps each license condition to the licenses with the condition that apply to the
+	// module, only the first path through which each license applies is recorded.
+	conditions map[string][]licenseConditionSource
+}
+
+func (info *licenseConditionsInfo) add(condition string, source licenseConditionSource) {
+	for _, existing := range info.conditions[condition] {
+		if existing.license == source.license && existing.licenseKind == source.licenseKind {
+			return
+		}
+	}
+	if info.conditions == nil {
+		info.conditions = make(map[string][]licenseConditionSource)
+	}
+	info.conditions[condition] = append(info.conditions[condition], source)
+}
+
+var licenseConditionsProvider = blueprint.NewMutatorProvider(licenseConditionsInfo{}, "licenseConditionsChecker")
+
+// licenseConditionsChecker is a bottom up mutator, so the license conditions of the dependencies
+// of a module, and of its license modules, have been computed before it runs on the module.
+func licenseConditionsChecker(ctx BottomUpMutatorContext) {
+	m, ok := ctx.Module().(Module)
+	if !ok {
+		return
+	}
+
+	info := licenseConditionsInfo{}
+
+	if _, ok := m.(*licenseModule); ok {
+		license := ctx.ModuleName()
+		ctx.VisitDirectDepsWithTag(licenseKindTag, func(dep Module) {
+			if lk, ok := dep.(*licenseKindModule); ok {
+				for _, condition := range lk.properties.Conditions {
+					info.add(condition, licenseConditionSource{
+						license:     license,
+						licenseKind: ctx.OtherModuleName(dep),
+					})
+				}
+			}
+		})
+		ctx.SetProvider(licenseConditionsProvider, info)
+		return
+	}
+
+	ctx.VisitDirectDeps(func(dep Module) {
+		tag := ctx.OtherModuleDependencyTag(dep)
+		if tag == licensesTag {
+			depInfo := ctx.OtherModuleProvider(dep, licenseConditionsProvider).(licenseConditionsInfo)
+			for _, condition := range SortedStringKeys(depInfo.conditions) {
+				for _, source := range depInfo.conditions[condition] {
+					info.add(condition, source)
+				}
+			}
+		} else if IsLicenseConditionsPropagatingDepTag(tag) {
+			depInfo := ctx.OtherModuleProvider(dep, licenseConditionsProvider).(licenseConditionsInfo)
+			depName := ctx.OtherModuleName(dep)
+			for _, condition := range SortedStringKeys(depInfo.conditions) {
+				for _, source := range depInfo.conditions[condition] {
+					source.path = append([]string{depName}, source.path...)
+					info.add(condition, source)
+				}
+			}
+		}
+	})
+
+	ctx.SetProvider(licenseConditionsProvider, info)
+
+	if !m.Enabled() {
+		return
+	}
+
+	if partition := proprietaryPartition(m); partition != "" {
+		for _, source := range info.conditions[licenseConditionRestricted] {
+			if len(source.path) == 0 {
+				// Modules can be under a restricted license themselves, they just can't pass on the
+				// restrictions to proprietary code.
+				continue
+			}
+			ctx.ModuleErrorf("violates license condition %q: a module in the %s partition must not statically link or package code under %s",
+				licenseConditionRestricted, partition, source)
+		}
+	}
+
+	if !ctx.Config().EnforceLicenseKindExceptions() {
+		return
+	}
+
+	exceptions := getLicenseKindExceptions(ctx.Config())
+	dir := ctx.ModuleDir() + "/"
+	for _, source := range info.conditions[licenseConditionByExceptionOnly] {
+		allowed := exceptions[source.licenseKind]
+		if len(allowed) == 0 {
+			ctx.ModuleErrorf("violates license condition %q: %s is not allowed in any directory",
+				licenseConditionByExceptionOnly, source)
+		} else if !HasAnyPrefix(dir, allowed) {
+			ctx.ModuleErrorf("violates license condition %q: %s is only allowed in %s",
+				licenseConditionByExceptionOnly, source, formatLicenseKindExceptions(allowed))
+		}
+	}
+}
+
+// proprietaryPartition returns the name of the proprietary partition the module is installed in,
+// or an empty string if it isn't installed in one.
+func proprietaryPartition(m Module) string {
+	if m.base().SocSpecific() {
+		return "vendor"
+	} else if m.base().DeviceSpecific() {
+		return "odm"
+	} else if v, ok := m.(vendorVariantModule); ok && v.InVendor() {
+		return "vendor"
+	}
+	return ""
+}
+
+// vendorVariantModule is implemented by modules that have image variants for the vendor partition.
+type vendorVariantModule interface {
+	InVendor() bool
+}
+
+func formatLicenseKindExceptions(paths []string) string {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	return "dir:" + strings.Join(sorted, "*, dir:") + "*"
+}
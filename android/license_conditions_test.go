@@ -0,0 +1,339 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/blueprint"
+)
+
+var licenseConditionsLicenses = `
+	license_kind {
+		name: "gpl_kind",
+		conditions: ["restricted"],
+	}
+
+	license_kind {
+		name: "notice_kind",
+		conditions: ["notice"],
+	}
+
+	license_kind {
+		name: "exception_kind",
+		conditions: ["by_exception_only", "notice"],
+	}
+
+	license {
+		name: "gpl_license",
+		license_kinds: ["gpl_kind"],
+	}
+
+	license {
+		name: "notice_license",
+		license_kinds: ["notice_kind"],
+	}
+
+	license {
+		name: "exception_license",
+		license_kinds: ["exception_kind"],
+	}
+`
+
+var licenseConditionsTests = []struct {
+	name           string
+	exceptions     map[string][]string
+	fs             MockFS
+	expectedErrors []string
+}{
+	{
+		name: "restricted static lib in system module",
+		fs: map[string][]byte{
+			"top/Blueprints": []byte(`
+				mock_library {
+					name: "libgpl",
+					licenses: ["gpl_license"],
+				}
+
+				mock_library {
+					name: "libsystem",
+					licenses: ["notice_license"],
+					static_libs: ["libgpl"],
+				}`),
+		},
+	},
+	{
+		name: "restricted module in vendor",
+		fs: map[string][]byte{
+			"top/Blueprints": []byte(`
+				mock_library {
+					name: "libgpl",
+					licenses: ["gpl_license"],
+					vendor: true,
+				}`),
+		},
+	},
+	{
+		name: "restricted shared lib in vendor module",
+		fs: map[string][]byte{
+			"top/Blueprints": []byte(`
+				mock_library {
+					name: "libgpl",
+					licenses: ["gpl_license"],
+				}
+
+				mock_library {
+					name: "libvendor",
+					licenses: ["notice_license"],
+					shared_libs: ["libgpl"],
+					vendor: true,
+				}`),
+		},
+	},
+	{
+		name: "restricted static lib in vendor module",
+		fs: map[string][]byte{
+			"top/Blueprints": []byte(`
+				mock_library {
+					name: "libgpl",
+					licenses: ["gpl_license"],
+				}
+
+				mock_library {
+					name: "libintermediate",
+					licenses: ["notice_license"],
+					static_libs: ["libgpl"],
+				}
+
+				mock_library {
+					name: "libvendor",
+					licenses: ["notice_license"],
+					static_libs: ["libintermediate"],
+					vendor: true,
+				}
+
+				mock_library {
+					name: "libodm",
+					licenses: ["notice_license"],
+					static_libs: ["libgpl"],
+					device_specific: true,
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libvendor".*: violates license condition "restricted": a module in the vendor partition must not statically link or package code under license gpl_license \(license_kind gpl_kind\) of libgpl via libintermediate -> libgpl`,
+			`module "libodm".*: violates license condition "restricted": a module in the odm partition must not statically link or package code under license gpl_license \(license_kind gpl_kind\) of libgpl via libgpl`,
+		},
+	},
+	{
+		name: "by_exception_only license without exceptions",
+		fs: map[string][]byte{
+			"top/Blueprints": []byte(`
+				mock_library {
+					name: "libexception",
+					licenses: ["exception_license"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libexception".*: violates license condition "by_exception_only": license exception_license \(license_kind exception_kind\) is not allowed in any directory`,
+		},
+	},
+	{
+		name: "by_exception_only license with exceptions",
+		exceptions: map[string][]string{
+			"exception_kind": {"allowed/", "other_allowed/"},
+		},
+		fs: map[string][]byte{
+			"allowed/Blueprints": []byte(`
+				mock_library {
+					name: "libexception",
+					licenses: ["exception_license"],
+				}
+
+				mock_library {
+					name: "liballowed",
+					licenses: ["notice_license"],
+					static_libs: ["libexception"],
+				}`),
+			"notallowed/Blueprints": []byte(`
+				mock_library {
+					name: "libnotallowed",
+					licenses: ["notice_license"],
+					static_libs: ["liballowed"],
+				}
+
+				mock_library {
+					name: "libshared",
+					licenses: ["notice_license"],
+					shared_libs: ["libexception"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libnotallowed".*: violates license condition "by_exception_only": license exception_license \(license_kind exception_kind\) of libexception via liballowed -> libexception is only allowed in dir:allowed/\*, dir:other_allowed/\*`,
+		},
+	},
+}
+
+func TestLicenseConditions(t *testing.T) {
+	for _, test := range licenseConditionsTests {
+		t.Run(test.name, func(t *testing.T) {
+			GroupFixturePreparers(
+				PrepareForTestWithLicenses,
+				PrepareForTestWithLicenseConditionsChecker(test.exceptions),
+				FixtureRegisterWithContext(func(ctx RegistrationContext) {
+					ctx.RegisterModuleType("mock_library", newMockLicenseConditionsLibraryModule)
+				}),
+				FixtureWithRootAndroidBp(licenseConditionsLicenses),
+				test.fs.AddToFixture(),
+			).
+				ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern(test.expectedErrors)).
+				RunTest(t)
+		})
+	}
+}
+
+// The modules in external/foo use license kinds from build/soong/licenses with the
+// "by_exception_only" condition.
+var licenseConditionsLicenseKindsBp = `
+	license {
+		name: "foo_unknown_license",
+		license_kinds: ["legacy_unknown"],
+	}
+
+	license {
+		name: "foo_agpl_license",
+		license_kinds: ["SPDX-license-identifier-AGPL"],
+	}
+
+	license {
+		name: "foo_apache_license",
+		license_kinds: ["SPDX-license-identifier-Apache-2.0"],
+	}
+
+	mock_library {
+		name: "libfoo_unknown",
+		licenses: ["foo_unknown_license"],
+	}
+
+	mock_library {
+		name: "libfoo_agpl",
+		licenses: ["foo_agpl_license"],
+	}
+
+	mock_library {
+		name: "libfoo",
+		licenses: ["foo_apache_license"],
+		static_libs: ["libfoo_unknown"],
+	}
+
+	mock_library {
+		name: "libfoo_notice",
+		licenses: ["foo_apache_license"],
+	}
+`
+
+func TestLicenseConditionsWithLicenseKinds(t *testing.T) {
+	licensesBp, err := ioutil.ReadFile("../licenses/Android.bp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		enforce        bool
+		exceptions     map[string][]string
+		expectedErrors []string
+	}{
+		{
+			name:    "not enforced",
+			enforce: false,
+		},
+		{
+			name:    "enforced without exceptions",
+			enforce: true,
+			expectedErrors: []string{
+				`module "libfoo_unknown".*: violates license condition "by_exception_only": license foo_unknown_license \(license_kind legacy_unknown\) is not allowed in any directory`,
+				`module "libfoo_agpl".*: violates license condition "by_exception_only": license foo_agpl_license \(license_kind SPDX-license-identifier-AGPL\) is not allowed in any directory`,
+				`module "libfoo".*: violates license condition "by_exception_only": license foo_unknown_license \(license_kind legacy_unknown\) of libfoo_unknown via libfoo_unknown is not allowed in any directory`,
+			},
+		},
+		{
+			name:    "enforced with exceptions",
+			enforce: true,
+			exceptions: map[string][]string{
+				"legacy_unknown":               {"external/foo/"},
+				"SPDX-license-identifier-AGPL": {"external/foo/"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			GroupFixturePreparers(
+				PrepareForTestWithLicenses,
+				PrepareForTestWithPackageModule,
+				PrepareForTestWithLicenseConditionsChecker(test.exceptions),
+				FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+					variables.EnforceLicenseKindExceptions = boolPtr(test.enforce)
+				}),
+				FixtureRegisterWithContext(func(ctx RegistrationContext) {
+					ctx.RegisterModuleType("mock_library", newMockLicenseConditionsLibraryModule)
+				}),
+				FixtureWithRootAndroidBp(""),
+				MockFS{
+					"build/soong/licenses/Blueprints": licensesBp,
+					"build/soong/licenses/LICENSE":    []byte("license text"),
+					"external/foo/Blueprints":         []byte(licenseConditionsLicenseKindsBp),
+				}.AddToFixture(),
+			).
+				ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern(test.expectedErrors)).
+				RunTest(t)
+		})
+	}
+}
+
+type mockLicenseConditionsLibraryProperties struct {
+	Static_libs []string
+	Shared_libs []string
+}
+
+type mockLicenseConditionsLibraryModule struct {
+	ModuleBase
+	properties mockLicenseConditionsLibraryProperties
+}
+
+func newMockLicenseConditionsLibraryModule() Module {
+	m := &mockLicenseConditionsLibraryModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidArchModule(m, DeviceSupported, MultilibCommon)
+	return m
+}
+
+type mockStaticLibDependencyTag struct {
+	blueprint.BaseDependencyTag
+	AlwaysPropagateLicenseConditionsTag
+}
+
+type mockSharedLibDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+func (m *mockLicenseConditionsLibraryModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddVariationDependencies(nil, mockStaticLibDependencyTag{}, m.properties.Static_libs...)
+	ctx.AddVariationDependencies(nil, mockSharedLibDependencyTag{}, m.properties.Shared_libs...)
+}
+
+func (m *mockLicenseConditionsLibraryModule) GenerateAndroidBuildActions(ModuleContext) {
+}
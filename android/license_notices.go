@@ -0,0 +1,254 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// License notices.
+//
+// The notices for a set of installed files are generated from the license modules that apply to
+// them instead of from NOTICE files: every file is attributed the copyright notices and the
+// license_text of its effective licenses.  The license_notices tool deduplicates identical license
+// texts, and writes the notices in the text, HTML and XML formats with an index of the files that
+// each license text applies to.
+//
+// The license_notices singleton generates the notices for each partition from the files that Soong
+// installs into it, build them with `m license_notices`.  Packaging modules, e.g.
+// android_filesystem, generate the notices for the files that they package with
+// BuildLicenseNoticesForPackagingSpecs.
+
+func init() {
+	RegisterLicenseNoticesBuildComponents(InitRegistrationContext)
+}
+
+func RegisterLicenseNoticesBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("license_notices", licenseNoticesSingletonFactory)
+}
+
+// licenseNoticesInput is the JSON description of the installed files that is read by the
+// license_notices tool.  It must be kept in sync with cmd/license_notices.
+type licenseNoticesInput struct {
+	Title    string
+	Files    []licenseNoticesFile
+	Licenses []licenseNoticesLicense
+}
+
+type licenseNoticesFile struct {
+	// Path is the path of the file on the device.
+	Path string
+
+	// Licenses are the names of the license modules that apply to the file.
+	Licenses []string
+}
+
+type licenseNoticesLicense struct {
+	Name            string
+	PackageName     string   `json:",omitempty"`
+	CopyrightNotice string   `json:",omitempty"`
+	TextFiles       []string `json:",omitempty"`
+}
+
+// LicenseNoticeOutputs are the notices generated from the license metadata of a set of files.
+type LicenseNoticeOutputs struct {
+	Txt    OutputPath
+	HtmlGz OutputPath
+	XmlGz  OutputPath
+}
+
+// Paths returns all of the notice files.
+func (o LicenseNoticeOutputs) Paths() Paths {
+	return Paths{o.Txt, o.HtmlGz, o.XmlGz}
+}
+
+// buildLicenseNotices writes the notices for the files into outDir using the given license
+// modules, which must include the licenses of all of the files.
+func buildLicenseNotices(ctx BuilderContext, ruleName, title string, files []licenseNoticesFile,
+	licenses map[string]*licenseModule, outDir OutputPath) LicenseNoticeOutputs {
+
+	input := licenseNoticesInput{
+		Title: title,
+		Files: files,
+	}
+
+	usedLicenses := make(map[string]bool)
+	for _, f := range files {
+		for _, l := range f.Licenses {
+			usedLicenses[l] = true
+		}
+	}
+
+	var deps Paths
+	for _, name := range SortedStringKeys(usedLicenses) {
+		l, ok := licenses[name]
+		if !ok {
+			continue
+		}
+		input.Licenses = append(input.Licenses, licenseNoticesLicense{
+			Name:            name,
+			PackageName:     String(l.properties.Package_name),
+			CopyrightNotice: String(l.properties.Copyright_notice),
+			TextFiles:       l.licenseTexts.Strings(),
+		})
+		deps = append(deps, l.licenseTexts...)
+	}
+
+	data, err := json.MarshalIndent(input, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	inputFile := outDir.Join(ctx, "license_notices_input.json")
+	WriteFileRule(ctx, inputFile, string(data))
+
+	outputs := LicenseNoticeOutputs{
+		Txt:    outDir.Join(ctx, "NOTICE.txt"),
+		HtmlGz: outDir.Join(ctx, "NOTICE.html.gz"),
+		XmlGz:  outDir.Join(ctx, "NOTICE.xml.gz"),
+	}
+
+	rule := NewRuleBuilder(pctx, ctx)
+	rule.Command().BuiltTool("license_notices").
+		FlagWithInput("-i ", inputFile).
+		FlagWithOutput("-txt ", outputs.Txt).
+		FlagWithOutput("-html ", outputs.HtmlGz).
+		FlagWithOutput("-xml ", outputs.XmlGz).
+		Implicits(FirstUniquePaths(deps))
+	rule.Build(ruleName, title)
+
+	return outputs
+}
+
+// BuildLicenseNoticesForPackagingSpecs generates the notices for the files of the PackagingSpecs,
+// e.g. the ones returned by PackageModule.GatherPackagingSpecs, from the license modules that the
+// module depends on transitively.  installDir is the directory on the device that the paths of the
+// PackagingSpecs relative to the root of the package are relative to.
+func BuildLicenseNoticesForPackagingSpecs(ctx ModuleContext, specs map[string]PackagingSpec,
+	installDir string) LicenseNoticeOutputs {
+
+	licenses := make(map[string]*licenseModule)
+	ctx.WalkDeps(func(child, parent Module) bool {
+		if l, ok := child.(*licenseModule); ok {
+			licenses[ctx.OtherModuleName(l)] = l
+			return false
+		}
+		return true
+	})
+
+	var files []licenseNoticesFile
+	for _, rel := range SortedStringKeys(specs) {
+		spec := specs[rel]
+		if spec.symlinkTarget != "" || spec.srcPath == nil {
+			continue
+		}
+		files = append(files, licenseNoticesFile{
+			Path:     filepath.Join("/", installDir, rel),
+			Licenses: spec.effectiveLicenses,
+		})
+	}
+
+	return buildLicenseNotices(ctx, "license_notices", "Notices for files contained in "+ctx.ModuleName(),
+		files, licenses, PathForModuleOut(ctx, "license_notices").OutputPath)
+}
+
+func licenseNoticesSingletonFactory() Singleton {
+	return &licenseNoticesSingleton{}
+}
+
+type licenseNoticesSingleton struct {
+	// partitionNotices maps the name of each partition to its notices.
+	partitionNotices map[string]LicenseNoticeOutputs
+}
+
+func (s *licenseNoticesSingleton) GenerateBuildActions(ctx SingletonContext) {
+	productOut := PathForOutput(ctx, "target", "product", ctx.Config().DeviceName()).String()
+
+	partitionFiles := make(map[string][]licenseNoticesFile)
+	licenses := make(map[string]*licenseModule)
+
+	ctx.VisitAllModules(func(module Module) {
+		if l, ok := module.(*licenseModule); ok {
+			licenses[ctx.ModuleName(module)] = l
+			return
+		}
+
+		// Use the same modules as the sbom singleton.
+		if !isSbomInstalled(module) {
+			return
+		}
+
+		for _, spec := range module.base().PackagingSpecs() {
+			if spec.symlinkTarget != "" || spec.srcPath == nil {
+				continue
+			}
+			rel, isRel := MaybeRel(ctx, productOut, spec.installPath.String())
+			if !isRel {
+				continue
+			}
+			rel = filepath.ToSlash(rel)
+			i := strings.IndexByte(rel, '/')
+			if i < 0 {
+				continue
+			}
+			partition := rel[:i]
+			partitionFiles[partition] = append(partitionFiles[partition], licenseNoticesFile{
+				Path:     "/" + rel,
+				Licenses: spec.effectiveLicenses,
+			})
+		}
+	})
+
+	s.partitionNotices = make(map[string]LicenseNoticeOutputs)
+	var outputs Paths
+	for _, partition := range SortedStringKeys(partitionFiles) {
+		files := partitionFiles[partition]
+		// The same file can be installed by more than one module variant.
+		files = firstUniqueLicenseNoticesFiles(files)
+		notices := buildLicenseNotices(ctx, "license_notices_"+partition,
+			"Notices for files contained in the "+partition+" partition",
+			files, licenses, PathForOutput(ctx, "license_notices", partition))
+		s.partitionNotices[partition] = notices
+		outputs = append(outputs, notices.Paths()...)
+	}
+
+	ctx.Phony("license_notices", outputs...)
+}
+
+// firstUniqueLicenseNoticesFiles returns the files sorted by path, keeping only the first of the
+// files with the same path.
+func firstUniqueLicenseNoticesFiles(files []licenseNoticesFile) []licenseNoticesFile {
+	byPath := make(map[string]licenseNoticesFile)
+	for _, f := range files {
+		if _, exists := byPath[f.Path]; !exists {
+			byPath[f.Path] = f
+		}
+	}
+	ret := make([]licenseNoticesFile, 0, len(byPath))
+	for _, path := range SortedStringKeys(byPath) {
+		ret = append(ret, byPath[path])
+	}
+	return ret
+}
+
+func (s *licenseNoticesSingleton) MakeVars(ctx MakeVarsContext) {
+	for _, partition := range SortedStringKeys(s.partitionNotices) {
+		for _, path := range s.partitionNotices[partition].Paths() {
+			ctx.DistForGoalWithFilename("license_notices", path, partition+"_"+path.Base())
+		}
+	}
+}
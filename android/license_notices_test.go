@@ -0,0 +1,131 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLicenseNotices(t *testing.T) {
+	bp := `
+		license_kind {
+			name: "notice_kind",
+			conditions: ["notice"],
+		}
+
+		license {
+			name: "foo_license",
+			license_kinds: ["notice_kind"],
+			copyright_notice: "Copyright (C) Foo",
+			license_text: ["LICENSE"],
+		}
+
+		license {
+			name: "bar_license",
+			license_kinds: ["notice_kind"],
+			package_name: "Bar",
+			license_text: ["bar/LICENSE"],
+		}
+
+		test_module {
+			name: "foo",
+			host_supported: true,
+			licenses: ["foo_license"],
+		}
+
+		test_module {
+			name: "bar",
+			vendor: true,
+			licenses: ["foo_license", "bar_license"],
+		}
+
+		test_module {
+			name: "baz",
+			vendor: true,
+		}
+	`
+
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithLicenses,
+		FixtureRegisterWithContext(RegisterLicenseNoticesBuildComponents),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("test_module", sbomTestModuleFactory)
+		}),
+		FixtureAddTextFile("LICENSE", "license text"),
+		FixtureAddTextFile("bar/LICENSE", "bar license text"),
+		FixtureWithRootAndroidBp(bp),
+	).RunTest(t)
+
+	notices := result.SingletonForTests("license_notices")
+
+	input := func(partition string) licenseNoticesInput {
+		var input licenseNoticesInput
+		content := ContentFromFileRuleForTests(t, notices.Output("out/soong/license_notices/"+partition+"/license_notices_input.json"))
+		if err := json.Unmarshal([]byte(content), &input); err != nil {
+			t.Fatal(err)
+		}
+		return input
+	}
+
+	AssertDeepEquals(t, "system notices input", licenseNoticesInput{
+		Title: "Notices for files contained in the system partition",
+		Files: []licenseNoticesFile{
+			{Path: "/system/bin/foo", Licenses: []string{"foo_license"}},
+		},
+		Licenses: []licenseNoticesLicense{
+			{
+				Name:            "foo_license",
+				CopyrightNotice: "Copyright (C) Foo",
+				TextFiles:       []string{"LICENSE"},
+			},
+		},
+	}, input("system"))
+
+	AssertDeepEquals(t, "vendor notices input", licenseNoticesInput{
+		Title: "Notices for files contained in the vendor partition",
+		Files: []licenseNoticesFile{
+			{Path: "/vendor/bin/bar", Licenses: []string{"bar_license", "foo_license"}},
+			{Path: "/vendor/bin/baz"},
+		},
+		Licenses: []licenseNoticesLicense{
+			{
+				Name:        "bar_license",
+				PackageName: "Bar",
+				TextFiles:   []string{"bar/LICENSE"},
+			},
+			{
+				Name:            "foo_license",
+				CopyrightNotice: "Copyright (C) Foo",
+				TextFiles:       []string{"LICENSE"},
+			},
+		},
+	}, input("vendor"))
+
+	rule := notices.Rule("license_notices_vendor")
+	AssertPathRelativeToTopEquals(t, "html notice", "out/soong/license_notices/vendor/NOTICE.html.gz", rule.Output)
+	AssertPathsRelativeToTopEquals(t, "text and xml notices",
+		[]string{
+			"out/soong/license_notices/vendor/NOTICE.txt",
+			"out/soong/license_notices/vendor/NOTICE.xml.gz",
+		}, rule.ImplicitOutputs.Paths())
+	AssertPathsRelativeToTopEquals(t, "notices implicits",
+		[]string{
+			"out/soong/license_notices/vendor/license_notices_input.json",
+			"LICENSE",
+			"bar/LICENSE",
+		}, rule.Implicits)
+}
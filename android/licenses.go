@@ -284,6 +284,7 @@ func exemptFromRequiredApplicableLicensesProperty(module Module) bool {
 	case "*android.licenseModule": // is a license, doesn't need one
 	case "*android.licenseKindModule": // is a license, doesn't need one
 	case "*android.NamespaceModule": // just partitions things, doesn't add anything
+	case "*android.neverallowRulesModule": // just declares rules, doesn't add anything
 	case "*android.soongConfigModuleTypeModule": // creates aliases for modules with licenses
 	case "*android.soongConfigModuleTypeImport": // creates aliases for modules with licenses
 	case "*android.soongConfigStringVariableDummyModule": // used for creating aliases
@@ -2623,10 +2623,12 @@ func (m *moduleContext) PackageFile(installPath InstallPath, name string, srcPat
 
 func (m *moduleContext) packageFile(fullInstallPath InstallPath, srcPath Path, executable bool) PackagingSpec {
 	spec := PackagingSpec{
-		relPathInPackage: Rel(m, fullInstallPath.PartitionDir(), fullInstallPath.String()),
-		srcPath:          srcPath,
-		symlinkTarget:    "",
-		executable:       executable,
+		relPathInPackage:  Rel(m, fullInstallPath.PartitionDir(), fullInstallPath.String()),
+		srcPath:           srcPath,
+		symlinkTarget:     "",
+		executable:        executable,
+		installPath:       fullInstallPath,
+		effectiveLicenses: m.module.base().commonProperties.Effective_licenses,
 	}
 	m.packagingSpecs = append(m.packagingSpecs, spec)
 	return spec
@@ -2705,6 +2707,7 @@ func (m *moduleContext) InstallSymlink(installPath InstallPath, name string, src
 		srcPath:          nil,
 		symlinkTarget:    relPath,
 		executable:       false,
+		installPath:      fullInstallPath,
 	})
 
 	return fullInstallPath
@@ -2735,6 +2738,7 @@ func (m *moduleContext) InstallAbsoluteSymlink(installPath InstallPath, name str
 		srcPath:          nil,
 		symlinkTarget:    absPath,
 		executable:       false,
+		installPath:      fullInstallPath,
 	})
 
 	return fullInstallPath
@@ -185,6 +185,10 @@ var preArch = []RegisterMutatorFunc{
 	// This must come after the defaults mutators to ensure that any visibility supplied
 	// in a defaults module has been successfully applied before the rules are gathered.
 	RegisterVisibilityRuleGatherer,
+
+	// Gather the neverallow rules declared in neverallow_rules modules for use by the neverallow
+	// mutator.
+	RegisterNeverallowRulesGatherer,
 }
 
 func registerArchMutator(ctx RegisterMutatorsContext) {
@@ -202,6 +206,7 @@ var postDeps = []RegisterMutatorFunc{
 	RegisterPrebuiltsPostDepsMutators,
 	RegisterVisibilityRuleEnforcer,
 	RegisterLicensesDependencyChecker,
+	RegisterLicenseConditionsChecker,
 	registerNeverallowMutator,
 	RegisterOverridePostDepsMutators,
 }
@@ -256,6 +256,10 @@ func (r *NameResolver) ModuleFromName(name string, namespace blueprint.Namespace
 	for _, candidate := range r.getNamespacesToSearchForModule(namespace) {
 		group, found = candidate.moduleContainer.ModuleFromName(name, nil)
 		if found {
+			if ns, ok := namespace.(*Namespace); ok && ns.visibleNamespaces != nil {
+				// Remember where the name was found for the namespace checks.
+				ns.resolvedNames.Store(name, candidate)
+			}
 			return group, true
 		}
 	}
@@ -352,6 +356,9 @@ type Namespace struct {
 	importedNamespaceNames []string
 	// all namespaces that should be searched when a module in this namespace declares a dependency
 	visibleNamespaces []*Namespace
+	// map from the names that were resolved from this namespace to the namespace they were found in,
+	// see namespace_check.go
+	resolvedNames sync.Map // if generics were supported, this would be sync.Map[string]*Namespace
 
 	id string
 
@@ -0,0 +1,269 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Checks of the soong_namespace import graph.
+//
+// The NameResolver remembers where each name that is referenced from a namespace was found, and
+// the soong_namespaces singleton uses that to warn about:
+// - cycles of namespace imports
+// - imports that no name was resolved through
+// - names that were found in the namespace itself or an import, shadowing modules with the same
+//   name in other imports
+// - names that were found in the root namespace, although modules with the same name are defined
+//   in namespaces that are not imported, which usually means that an import is missing
+//
+// The warnings are written to out/soong/soong_namespace_warnings.txt, and the namespace graph with
+// the modules of each namespace to out/soong/soong_namespaces.json.  Both are built by
+// `m soong_namespaces`.
+
+func init() {
+	RegisterNamespaceCheckBuildComponents(InitRegistrationContext)
+}
+
+func RegisterNamespaceCheckBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("soong_namespaces", namespaceCheckSingletonFactory)
+}
+
+// namespaceInfo is the description of a namespace in soong_namespaces.json.
+type namespaceInfo struct {
+	Path           string
+	Imports        []string `json:",omitempty"`
+	ExportedToMake bool
+	Modules        []string
+}
+
+func namespaceCheckSingletonFactory() Singleton {
+	return &namespaceCheckSingleton{}
+}
+
+type namespaceCheckSingleton struct {
+	graph    WritablePath
+	warnings WritablePath
+}
+
+func (s *namespaceCheckSingleton) GenerateBuildActions(ctx SingletonContext) {
+	// Every soong_namespace module refers to the NameResolver.
+	var resolver *NameResolver
+	ctx.VisitAllModules(func(m Module) {
+		if n, ok := m.(*NamespaceModule); ok && resolver == nil {
+			resolver = n.resolver
+		}
+	})
+	if resolver == nil {
+		// There are no namespaces besides the root namespace.
+		return
+	}
+
+	modules := make(map[*Namespace][]string)
+	ctx.VisitAllModules(func(m Module) {
+		if _, ok := m.(*NamespaceModule); ok {
+			return
+		}
+		if _, ok := m.(NamelessModule); ok {
+			return
+		}
+		if ns := resolver.findNamespace(ctx.ModuleDir(m)); ns != nil {
+			modules[ns] = append(modules[ns], ctx.ModuleName(m))
+		}
+	})
+
+	var graph []namespaceInfo
+	for _, ns := range resolver.sortedNamespaces.sortedItems() {
+		graph = append(graph, namespaceInfo{
+			Path:           ns.Path,
+			Imports:        ns.importedNamespaceNames,
+			ExportedToMake: ns.exportToKati,
+			Modules:        SortedUniqueStrings(modules[ns]),
+		})
+	}
+
+	data, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal the namespace graph: %s", err)
+		return
+	}
+	s.graph = PathForOutput(ctx, "soong_namespaces.json")
+	WriteFileRule(ctx, s.graph, string(data))
+
+	s.warnings = PathForOutput(ctx, "soong_namespace_warnings.txt")
+	WriteFileRule(ctx, s.warnings, strings.Join(resolver.checkNamespaces(), "\n"))
+
+	ctx.Phony("soong_namespaces", s.graph, s.warnings)
+}
+
+func (s *namespaceCheckSingleton) MakeVars(ctx MakeVarsContext) {
+	if s.graph != nil {
+		ctx.DistForGoal("soong_namespaces", s.graph, s.warnings)
+	}
+}
+
+// checkNamespaces returns the sorted warnings about the namespace imports and the names that were
+// resolved through them.
+func (r *NameResolver) checkNamespaces() []string {
+	warnings := r.namespaceImportCycles()
+
+	for _, ns := range r.sortedNamespaces.sortedItems() {
+		if ns == r.rootNamespace || ns.visibleNamespaces == nil {
+			continue
+		}
+
+		usedNamespaces := make(map[*Namespace]bool)
+		ns.resolvedNames.Range(func(key, value interface{}) bool {
+			name, found := key.(string), value.(*Namespace)
+			usedNamespaces[found] = true
+			if warning := r.checkResolvedName(ns, name, found); warning != "" {
+				warnings = append(warnings, warning)
+			}
+			return true
+		})
+
+		// The namespace itself is searched first and the root namespace last, the imports are in
+		// between.
+		for _, imported := range ns.visibleNamespaces[1 : len(ns.visibleNamespaces)-1] {
+			if !usedNamespaces[imported] {
+				warnings = append(warnings, fmt.Sprintf("namespace %q imports %q but does not use any of its modules",
+					ns.Path, imported.Path))
+			}
+		}
+	}
+
+	sort.Strings(warnings)
+	return warnings
+}
+
+// checkResolvedName returns a warning if the name that was found in a namespace when it was
+// referenced from another namespace is ambiguous, or an empty string.
+func (r *NameResolver) checkResolvedName(ns *Namespace, name string, found *Namespace) string {
+	if found == r.rootNamespace {
+		// The root namespace is searched last, so the name is not defined in any visible namespace.
+		var elsewhere []string
+		for _, other := range r.sortedNamespaces.sortedItems() {
+			if other == r.rootNamespace || namespaceInList(other, ns.visibleNamespaces) {
+				continue
+			}
+			if _, ok := other.moduleContainer.ModuleFromName(name, nil); ok {
+				elsewhere = append(elsewhere, other.Path)
+			}
+		}
+		if len(elsewhere) > 0 {
+			return fmt.Sprintf("%q in namespace %q resolves to %s in the root namespace, "+
+				"but is also defined in the namespaces %q, which are not imported",
+				name, ns.Path, fullyQualifiedModuleName(found, name), elsewhere)
+		}
+		return ""
+	}
+
+	// Modules in the root namespace are intentionally overridden by the ones in namespaces, only
+	// report the modules with the same name in the namespace itself and its imports.
+	var shadowed []string
+	for _, other := range ns.visibleNamespaces {
+		if other == found || other == r.rootNamespace {
+			continue
+		}
+		if _, ok := other.moduleContainer.ModuleFromName(name, nil); ok {
+			shadowed = append(shadowed, fullyQualifiedModuleName(other, name))
+		}
+	}
+	if len(shadowed) > 0 {
+		return fmt.Sprintf("%q in namespace %q resolves to %s, shadowing %s",
+			name, ns.Path, fullyQualifiedModuleName(found, name), strings.Join(shadowed, ", "))
+	}
+	return ""
+}
+
+// namespaceImportCycles returns a warning for each cycle of namespace imports found by a depth
+// first search of the imports.
+func (r *NameResolver) namespaceImportCycles() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*Namespace]int)
+	var stack []*Namespace
+	seen := make(map[string]bool)
+	var warnings []string
+
+	var visit func(ns *Namespace)
+	visit = func(ns *Namespace) {
+		state[ns] = visiting
+		stack = append(stack, ns)
+		for _, name := range ns.importedNamespaceNames {
+			imported, ok := r.namespaceAt(name)
+			if !ok {
+				// Reported by FindNamespaceImports.
+				continue
+			}
+			switch state[imported] {
+			case unvisited:
+				visit(imported)
+			case visiting:
+				var cycle []string
+				for i := len(stack) - 1; i >= 0; i-- {
+					cycle = append([]string{stack[i].Path}, cycle...)
+					if stack[i] == imported {
+						break
+					}
+				}
+				// Start the cycle with the first path so that it is only reported once.
+				first := 0
+				for i, path := range cycle {
+					if path < cycle[first] {
+						first = i
+					}
+				}
+				cycle = append(append(append([]string(nil), cycle[first:]...), cycle[:first]...), cycle[first])
+
+				description := strings.Join(cycle, " -> ")
+				if !seen[description] {
+					seen[description] = true
+					warnings = append(warnings, "namespace import cycle: "+description)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[ns] = visited
+	}
+
+	for _, ns := range r.sortedNamespaces.sortedItems() {
+		if state[ns] == unvisited {
+			visit(ns)
+		}
+	}
+	return warnings
+}
+
+func namespaceInList(ns *Namespace, list []*Namespace) bool {
+	for _, x := range list {
+		if x == ns {
+			return true
+		}
+	}
+	return false
+}
+
+// fullyQualifiedModuleName returns the reference to a module in a namespace, e.g.
+// "//namespace_path:module_name".
+func fullyQualifiedModuleName(ns *Namespace, name string) string {
+	return "//" + ns.Path + ":" + name
+}
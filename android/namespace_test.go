@@ -15,9 +15,11 @@
 package android
 
 import (
+	"encoding/json"
 	"errors"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/google/blueprint"
@@ -621,6 +623,98 @@ func TestRename(t *testing.T) {
 	// setupTest will report any errors
 }
 
+func TestNamespaceChecks(t *testing.T) {
+	ctx := setupTest(t,
+		map[string]string{
+			".": `
+			test_module {
+				name: "c",
+			}
+			`,
+			"dir1": `
+			soong_namespace {
+				imports: ["dir2", "dir3", "dir4"],
+			}
+			test_module {
+				name: "a",
+				deps: ["b", "c"],
+			}
+			`,
+			"dir2": `
+			soong_namespace {
+				imports: ["dir3"],
+			}
+			test_module {
+				name: "b",
+				deps: ["x"],
+			}
+			test_module {
+				name: "y",
+			}
+			`,
+			"dir3": `
+			soong_namespace {
+				imports: ["dir2"],
+			}
+			test_module {
+				name: "b",
+				deps: ["y"],
+			}
+			test_module {
+				name: "x",
+			}
+			`,
+			"dir4": `
+			soong_namespace {
+			}
+			test_module {
+				name: "z",
+			}
+			`,
+			"dir5": `
+			soong_namespace {
+			}
+			test_module {
+				name: "c",
+			}
+			`,
+		},
+	)
+
+	AssertDeepEquals(t, "warnings", []string{
+		`"b" in namespace "dir1" resolves to //dir2:b, shadowing //dir3:b`,
+		`"c" in namespace "dir1" resolves to //.:c in the root namespace, but is also defined in the namespaces ["dir5"], which are not imported`,
+		`namespace "dir1" imports "dir4" but does not use any of its modules`,
+		`namespace import cycle: dir2 -> dir3 -> dir2`,
+	}, ctx.NameResolver.checkNamespaces())
+
+	singleton := ctx.SingletonForTests("soong_namespaces")
+	AssertStringEquals(t, "warnings file", strings.Join(ctx.NameResolver.checkNamespaces(), "\n"),
+		ContentFromFileRuleForTests(t, singleton.Output("soong_namespace_warnings.txt")))
+
+	var graph []namespaceInfo
+	if err := json.Unmarshal([]byte(ContentFromFileRuleForTests(t, singleton.Output("soong_namespaces.json"))), &graph); err != nil {
+		t.Fatal(err)
+	}
+	namespaces := make(map[string]namespaceInfo)
+	for _, ns := range graph {
+		namespaces[ns.Path] = ns
+	}
+	AssertDeepEquals(t, "dir1", namespaceInfo{
+		Path:           "dir1",
+		Imports:        []string{"dir2", "dir3", "dir4"},
+		ExportedToMake: true,
+		Modules:        []string{"a"},
+	}, namespaces["dir1"])
+	AssertDeepEquals(t, "dir2", namespaceInfo{
+		Path:           "dir2",
+		Imports:        []string{"dir3"},
+		ExportedToMake: true,
+		Modules:        []string{"b", "y"},
+	}, namespaces["dir2"])
+	AssertDeepEquals(t, "root", []string{"c"}, namespaces["."].Modules)
+}
+
 // some utils to support the tests
 
 func mockFiles(bps map[string]string) (files map[string][]byte) {
@@ -638,6 +732,7 @@ func setupTestFromFiles(t *testing.T, bps MockFS) (ctx *TestContext, errs []erro
 			ctx.RegisterModuleType("test_module", newTestModule)
 			ctx.RegisterModuleType("soong_namespace", NamespaceFactory)
 			ctx.Context.RegisterModuleType("blueprint_test_module", newBlueprintTestModule)
+			ctx.RegisterSingletonType("soong_namespaces", namespaceCheckSingletonFactory)
 			ctx.PreArchMutators(RegisterNamespaceMutator)
 			ctx.PreDepsMutators(func(ctx RegisterMutatorsContext) {
 				ctx.BottomUp("rename", renameMutator)
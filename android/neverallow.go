@@ -15,6 +15,7 @@
 package android
 
 import (
+	"fmt"
 	"path/filepath"
 	"reflect"
 	"regexp"
@@ -41,6 +42,12 @@ import (
 // - - if the property is a list, any of the values in the list being matches
 //     counts as a match
 // - it has none of the "Without" properties matched (same rules as above)
+//
+// Rules can also be declared in Android.bp files with neverallow_rules modules, see
+// neverallow_rules.go.
+//
+// If SOONG_REPORT_POLICY_VIOLATIONS is set the violations are written to a report instead of
+// failing the build, see policy_violations.go.
 
 func registerNeverallowMutator(ctx RegisterMutatorsContext) {
 	ctx.BottomUp("neverallow", neverallowMutator).Parallel()
@@ -211,7 +218,12 @@ func neverallowMutator(ctx BottomUpMutatorContext) {
 
 	osClass := ctx.Module().Target().Os.Class
 
-	for _, r := range neverallowRules(ctx.Config()) {
+	rules := neverallowRules(ctx.Config())
+	if declared := getDeclaredNeverallowRules(ctx.Config()).forDir(ctx.ModuleDir()); len(declared) > 0 {
+		rules = append(append([]Rule(nil), rules...), declared...)
+	}
+
+	for _, r := range rules {
 		n := r.(*rule)
 		if !n.appliesToPath(dir) {
 			continue
@@ -237,6 +249,17 @@ func neverallowMutator(ctx BottomUpMutatorContext) {
 			continue
 		}
 
+		if ctx.Config().ReportPolicyViolations() {
+			getPolicyViolations(ctx.Config()).add(policyViolation{
+				Kind:                    "neverallow",
+				Rule:                    n.String(),
+				Module:                  createQualifiedModuleName(ctx).String(),
+				Values:                  n.matchingPropertyValues(properties),
+				SuggestedAllowlistEntry: n.suggestedAllowlistEntry(ctx.ModuleDir()),
+			})
+			continue
+		}
+
 		ctx.ModuleErrorf("violates " + n.String())
 	}
 }
@@ -369,6 +392,9 @@ type rule struct {
 	unlessProps []ruleProperty
 
 	onlyBootclasspathJar bool
+
+	// The neverallow_rules module that declared the rule, empty for the rules added in Go.
+	source qualifiedModuleName
 }
 
 // Create a new NeverAllow rule.
@@ -481,6 +507,9 @@ func (r *rule) String() string {
 	if len(r.reason) != 0 {
 		s += " which is restricted because " + r.reason
 	}
+	if r.source.name != "" {
+		s += " (declared by " + r.source.String() + ")"
+	}
 	return s
 }
 
@@ -538,6 +567,46 @@ func (r *rule) appliesToProperties(properties []interface{}) bool {
 	return includeProps && !excludeProps
 }
 
+// matchingPropertyValues returns the values of the properties of the module that match the
+// properties of the rule, e.g. "sdk_version=current".
+func (r *rule) matchingPropertyValues(properties []interface{}) []string {
+	var values []string
+	for _, prop := range r.props {
+		names := make([]string, len(prop.fields))
+		for i, field := range prop.fields {
+			names[i] = proptools.PropertyNameForField(field)
+		}
+		name := strings.Join(names, ".")
+
+		for _, propertyStruct := range properties {
+			matchValue(propertyValue(propertyStruct, prop.fields), func(value string) bool {
+				if prop.matcher.Test(value) {
+					values = append(values, name+"="+value)
+				}
+				// Keep going to collect all of the matching values of a list.
+				return false
+			})
+		}
+	}
+	return values
+}
+
+// suggestedAllowlistEntry returns the exception that would stop the rule from applying to the
+// modules in the directory, or an empty string if the rule always applies to the directory.
+func (r *rule) suggestedAllowlistEntry(moduleDir string) string {
+	if r.source.name == "" {
+		return fmt.Sprintf("NotIn(%q)", moduleDir)
+	}
+
+	// The not_in directories of the declared rules are relative to the neverallow_rules module,
+	// and can't exclude its own directory.
+	rel, err := filepath.Rel(r.source.pkg, moduleDir)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return fmt.Sprintf("not_in: [%q]", rel)
+}
+
 func StartsWith(prefix string) ValueMatcher {
 	return &startsWithMatcher{prefix}
 }
@@ -592,13 +661,7 @@ func hasAllProperties(properties []interface{}, props []ruleProperty) bool {
 
 func hasProperty(properties []interface{}, prop ruleProperty) bool {
 	for _, propertyStruct := range properties {
-		propertiesValue := reflect.ValueOf(propertyStruct).Elem()
-		for _, v := range prop.fields {
-			if !propertiesValue.IsValid() {
-				break
-			}
-			propertiesValue = propertiesValue.FieldByName(v)
-		}
+		propertiesValue := propertyValue(propertyStruct, prop.fields)
 		if !propertiesValue.IsValid() {
 			continue
 		}
@@ -614,6 +677,19 @@ func hasProperty(properties []interface{}, prop ruleProperty) bool {
 	return false
 }
 
+// propertyValue returns the value of the nested field of the property struct, or an invalid value
+// if the struct doesn't have the field.
+func propertyValue(propertyStruct interface{}, fields []string) reflect.Value {
+	propertiesValue := reflect.ValueOf(propertyStruct).Elem()
+	for _, v := range fields {
+		if !propertiesValue.IsValid() {
+			break
+		}
+		propertiesValue = propertiesValue.FieldByName(v)
+	}
+	return propertiesValue
+}
+
 func matchValue(value reflect.Value, check func(string) bool) bool {
 	if !value.IsValid() {
 		return false
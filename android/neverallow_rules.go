@@ -0,0 +1,331 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Neverallow rules declared in Android.bp files.
+//
+// A neverallow_rules module declares neverallow rules that are checked by the neverallow mutator
+// together with the rules added in Go with AddNeverAllowRules.  The rules can only restrict the
+// modules in the directory of the neverallow_rules module and its subdirectories, so that every
+// tree can have its own policies, and the in and not_in directories of the rules are relative to
+// the directory of the neverallow_rules module.
+//
+// e.g.
+//
+//    neverallow_rules {
+//        name: "vendor_policy",
+//        rules: [
+//            {
+//                not_in: ["legacy"],
+//                module_type: ["cc_library", "cc_library_shared"],
+//                with: [{
+//                    property: "include_dirs",
+//                    starts_with: "vendor/internal",
+//                }],
+//                because: "vendor/internal headers must be exported by a library",
+//            },
+//        ],
+//    }
+
+func init() {
+	RegisterNeverallowRulesBuildComponents(InitRegistrationContext)
+}
+
+// Register the neverallow_rules module type.
+func RegisterNeverallowRulesBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterModuleType("neverallow_rules", NeverallowRulesFactory)
+}
+
+// The neverallow_rules module type and the mutator that gathers the rules.
+var PrepareForTestWithNeverallowRulesModule = GroupFixturePreparers(
+	FixtureRegisterWithContext(RegisterNeverallowRulesBuildComponents),
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.PreArchMutators(RegisterNeverallowRulesGatherer)
+	}),
+)
+
+// Registers the function that gathers the rules of the neverallow_rules modules.
+func RegisterNeverallowRulesGatherer(ctx RegisterMutatorsContext) {
+	ctx.BottomUp("neverallowRulesGatherer", neverallowRulesGatherer).Parallel()
+}
+
+type neverallowRulesProperties struct {
+	// The rules that apply to the modules in this directory and its subdirectories.
+	Rules []neverallowRuleProperties
+}
+
+type neverallowRuleProperties struct {
+	// Directories, relative to this directory, that the rule applies to.  Defaults to this
+	// directory.
+	In []string
+
+	// Subdirectories, relative to this directory, that the rule does not apply to.
+	Not_in []string
+
+	// Module types that the rule applies to.  Defaults to all module types.
+	Module_type []string
+
+	// Module types that the rule does not apply to.
+	Not_module_type []string
+
+	// The rule only applies to modules whose properties match all of these.
+	With []neverallowPropertyProperties
+
+	// The rule does not apply to modules whose properties match any of these.
+	Without []neverallowPropertyProperties
+
+	// The rule only applies to modules that depend directly on any of these modules.
+	In_direct_deps []string
+
+	// The OS classes of the variants that the rule applies to, "device" or "host".  Defaults to
+	// all variants.
+	With_os_class []string
+
+	// The reason for the rule, which is included in the error message.
+	Because *string
+}
+
+// A property matcher of a neverallow rule, exactly one of value, starts_with, regexp and
+// not_in_list must be set.
+type neverallowPropertyProperties struct {
+	// The name of the property, with the names of nested properties separated by '.', e.g.
+	// "vndk.enabled".  If the property is a list then it matches if any value in the list matches.
+	Property *string
+
+	// Matches the value, "*" matches any value.  An unset property has the value "".
+	Value *string
+
+	// Matches values that start with the prefix.
+	Starts_with *string
+
+	// Matches values that match the regular expression.
+	Regexp *string
+
+	// Matches values that are not in the list.
+	Not_in_list []string
+}
+
+type neverallowRulesModule struct {
+	ModuleBase
+
+	properties neverallowRulesProperties
+}
+
+func (m *neverallowRulesModule) GenerateAndroidBuildActions(ModuleContext) {
+	// Nothing to do.
+}
+
+// neverallow_rules declares neverallow rules for the modules in its directory and subdirectories.
+func NeverallowRulesFactory() Module {
+	module := &neverallowRulesModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+// rules returns the neverallow rules declared by the module, reporting errors for the invalid
+// properties.
+func (m *neverallowRulesModule) rules(ctx BottomUpMutatorContext) []Rule {
+	dir := ctx.ModuleDir()
+	source := createQualifiedModuleName(ctx)
+
+	var rules []Rule
+	for i, p := range m.properties.Rules {
+		errorf := func(format string, args ...interface{}) {
+			ctx.PropertyErrorf("rules", "rule %d: %s", i, fmt.Sprintf(format, args...))
+		}
+
+		r := NeverAllow().(*rule)
+		r.source = source
+
+		in := neverallowRulesPaths(dir, p.In, "in", errorf)
+		if len(p.In) == 0 {
+			in = []string{dir}
+		}
+		// The root directory contains every module.
+		if !InList(".", in) {
+			r.In(in...)
+		}
+
+		notIn := neverallowRulesPaths(dir, p.Not_in, "not_in", errorf)
+		for _, path := range notIn {
+			if path == dir {
+				errorf("not_in: %q excludes the whole directory", path)
+			}
+		}
+		r.NotIn(notIn...)
+
+		r.ModuleType(p.Module_type...)
+		r.NotModuleType(p.Not_module_type...)
+
+		for j, with := range p.With {
+			if property, matcher := neverallowPropertyMatcher(with, fmt.Sprintf("with[%d]", j), errorf); matcher != nil {
+				r.WithMatcher(property, matcher)
+			}
+		}
+		for j, without := range p.Without {
+			if property, matcher := neverallowPropertyMatcher(without, fmt.Sprintf("without[%d]", j), errorf); matcher != nil {
+				r.WithoutMatcher(property, matcher)
+			}
+		}
+
+		r.InDirectDeps(p.In_direct_deps...)
+
+		for _, class := range p.With_os_class {
+			switch class {
+			case Device.String():
+				r.WithOsClass(Device)
+			case Host.String():
+				r.WithOsClass(Host)
+			default:
+				errorf("with_os_class: unknown OS class %q, expected %q or %q", class, Device, Host)
+			}
+		}
+
+		r.Because(String(p.Because))
+
+		rules = append(rules, r)
+	}
+
+	return rules
+}
+
+// neverallowRulesPaths returns the paths relative to the directory of a neverallow_rules module
+// as paths relative to the root of the source tree, reporting errors for the ones that are not
+// in the directory.
+func neverallowRulesPaths(dir string, paths []string, property string,
+	errorf func(format string, args ...interface{})) []string {
+
+	var ret []string
+	for _, path := range paths {
+		if filepath.IsAbs(path) {
+			errorf("%s: %q must be relative to the directory of the module", property, path)
+			continue
+		}
+		rel := filepath.Clean(path)
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			errorf("%s: %q is outside of the directory of the module", property, path)
+			continue
+		}
+		ret = append(ret, filepath.Join(dir, rel))
+	}
+	return ret
+}
+
+// neverallowPropertyMatcher returns the property name and the matcher of a with or without
+// property matcher, or a nil matcher if it is invalid.
+func neverallowPropertyMatcher(p neverallowPropertyProperties, property string,
+	errorf func(format string, args ...interface{})) (string, ValueMatcher) {
+
+	name := String(p.Property)
+	if name == "" {
+		errorf("%s: property must be set", property)
+		return "", nil
+	}
+
+	var matchers []ValueMatcher
+	if p.Value != nil {
+		matchers = append(matchers, selectMatcher(*p.Value))
+	}
+	if p.Starts_with != nil {
+		matchers = append(matchers, StartsWith(*p.Starts_with))
+	}
+	if p.Regexp != nil {
+		re, err := regexp.Compile(*p.Regexp)
+		if err != nil {
+			errorf("%s: invalid regexp %q: %s", property, *p.Regexp, err)
+			return "", nil
+		}
+		matchers = append(matchers, &regexMatcher{re})
+	}
+	if p.Not_in_list != nil {
+		matchers = append(matchers, NotInList(p.Not_in_list))
+	}
+
+	if len(matchers) != 1 {
+		errorf("%s: exactly one of value, starts_with, regexp and not_in_list must be set for property %q",
+			property, name)
+		return "", nil
+	}
+	return name, matchers[0]
+}
+
+var declaredNeverallowRulesKey = NewOnceKey("declaredNeverallowRules")
+
+// declaredNeverallowRules are the rules of the neverallow_rules modules, keyed by the directory of
+// the module and then by the name of the module.
+type declaredNeverallowRules struct {
+	mutex sync.Mutex
+	rules map[string]map[string][]Rule
+}
+
+func getDeclaredNeverallowRules(config Config) *declaredNeverallowRules {
+	return config.Once(declaredNeverallowRulesKey, func() interface{} {
+		return &declaredNeverallowRules{rules: make(map[string]map[string][]Rule)}
+	}).(*declaredNeverallowRules)
+}
+
+func (d *declaredNeverallowRules) add(dir, name string, rules []Rule) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.rules[dir] == nil {
+		d.rules[dir] = make(map[string][]Rule)
+	}
+	d.rules[dir][name] = rules
+}
+
+// forDir returns the rules declared in the directory and its parent directories, which are the
+// only ones that can apply to the modules in the directory.
+func (d *declaredNeverallowRules) forDir(dir string) []Rule {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if len(d.rules) == 0 {
+		return nil
+	}
+
+	var ret []Rule
+	for {
+		byName := d.rules[dir]
+		for _, name := range SortedStringKeys(byName) {
+			ret = append(ret, byName[name]...)
+		}
+		if dir == "." {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	return ret
+}
+
+func neverallowRulesGatherer(ctx BottomUpMutatorContext) {
+	m, ok := ctx.Module().(*neverallowRulesModule)
+	if !ok {
+		return
+	}
+
+	rules := m.rules(ctx)
+	if ctx.Failed() {
+		return
+	}
+	getDeclaredNeverallowRules(ctx.Config()).add(ctx.ModuleDir(), ctx.ModuleName(), rules)
+}
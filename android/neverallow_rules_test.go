@@ -0,0 +1,246 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+var neverallowRulesTests = []struct {
+	// The name of the test.
+	name string
+
+	// Contents to add to the virtual filesystem used by the tests.
+	fs MockFS
+
+	// The expected error patterns. If empty then no errors are expected.
+	expectedErrors []string
+}{
+	{
+		name: "rules apply to the subtree of the module",
+		fs: map[string][]byte{
+			"vendor/foo/Android.bp": []byte(`
+				neverallow_rules {
+					name: "policy",
+					rules: [{
+						not_module_type: ["java_library"],
+						with: [{
+							property: "vendor_available",
+							value: "true",
+						}],
+						because: "vendor libraries are not available to other partitions",
+					}],
+				}
+
+				cc_library {
+					name: "libfoo",
+					vendor_available: true,
+				}`),
+			"vendor/foo/bar/Android.bp": []byte(`
+				cc_library {
+					name: "libbar",
+					vendor_available: true,
+				}
+
+				java_library {
+					name: "libjava",
+				}`),
+			"vendor/other/Android.bp": []byte(`
+				cc_library {
+					name: "libother",
+					vendor_available: true,
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libfoo": violates neverallow dir:vendor/foo/\* -type:java_library Vendor_available=true which is restricted because vendor libraries are not available to other partitions \(declared by //vendor/foo:policy\)`,
+			`module "libbar": violates neverallow dir:vendor/foo/\*`,
+		},
+	},
+	{
+		name: "in and not_in are relative to the module",
+		fs: map[string][]byte{
+			"vendor/Android.bp": []byte(`
+				neverallow_rules {
+					name: "policy",
+					rules: [{
+						in: ["foo"],
+						not_in: ["foo/legacy"],
+						with: [{
+							property: "include_dirs",
+							starts_with: "vendor/internal",
+						}],
+					}],
+				}`),
+			"vendor/foo/Android.bp": []byte(`
+				cc_library {
+					name: "libfoo",
+					include_dirs: ["vendor/internal/include"],
+				}`),
+			"vendor/foo/legacy/Android.bp": []byte(`
+				cc_library {
+					name: "liblegacy",
+					include_dirs: ["vendor/internal/include"],
+				}`),
+			"vendor/bar/Android.bp": []byte(`
+				cc_library {
+					name: "libbar",
+					include_dirs: ["vendor/internal/include"],
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libfoo": violates neverallow dir:vendor/foo/\* -dir:vendor/foo/legacy/\* Include_dirs.starts-with\(vendor/internal\)`,
+		},
+	},
+	{
+		name: "rules in the root directory apply to every module",
+		fs: map[string][]byte{
+			"Android.bp": []byte(`
+				neverallow_rules {
+					name: "policy",
+					rules: [{
+						without: [{
+							property: "sdk_version",
+							regexp: "^[0-9]+$",
+						}],
+						module_type: ["cc_library"],
+					}],
+				}`),
+			"a/Android.bp": []byte(`
+				cc_library {
+					name: "liba",
+					sdk_version: "current",
+				}
+
+				cc_library {
+					name: "libb",
+					sdk_version: "29",
+				}
+
+				java_library {
+					name: "libjava",
+				}`),
+		},
+		expectedErrors: []string{
+			`module "liba": violates neverallow type:cc_library -Sdk_version.regexp\(\^\[0-9\]\+\$\) \(declared by //.:policy\)`,
+		},
+	},
+	{
+		name: "not_in_list, in_direct_deps and with_os_class",
+		fs: map[string][]byte{
+			"top/Android.bp": []byte(`
+				neverallow_rules {
+					name: "deps_policy",
+					rules: [
+						{
+							in_direct_deps: ["libforbidden"],
+							with: [{
+								property: "vndk.extends",
+								not_in_list: ["libextended"],
+							}],
+						},
+						{
+							in_direct_deps: ["libforbidden"],
+							with_os_class: ["host"],
+						},
+					],
+				}
+
+				cc_library {
+					name: "libforbidden",
+				}
+
+				cc_library {
+					name: "libuser",
+					static_libs: ["libforbidden"],
+				}
+
+				cc_library {
+					name: "libextends",
+					static_libs: ["libforbidden"],
+					vndk: {
+						extends: "libextended",
+					},
+				}`),
+		},
+		expectedErrors: []string{
+			`module "libuser": violates neverallow dir:top/\* Vndk.Extends.not-in-list\(libextended\) deps:libforbidden`,
+		},
+	},
+	{
+		name: "invalid rules",
+		fs: map[string][]byte{
+			"top/Android.bp": []byte(`
+				neverallow_rules {
+					name: "policy",
+					rules: [
+						{
+							in: ["../other"],
+						},
+						{
+							not_in: ["."],
+						},
+						{
+							with: [{
+								property: "sdk_version",
+								value: "current",
+								starts_with: "c",
+							}],
+						},
+						{
+							without: [{
+								value: "current",
+							}],
+						},
+						{
+							with: [{
+								property: "sdk_version",
+								regexp: "(",
+							}],
+						},
+						{
+							with_os_class: ["linux"],
+						},
+					],
+				}
+
+				cc_library {
+					name: "libfoo",
+				}`),
+		},
+		expectedErrors: []string{
+			`module "policy": rules: rule 0: in: "../other" is outside of the directory of the module`,
+			`module "policy": rules: rule 1: not_in: "top" excludes the whole directory`,
+			`module "policy": rules: rule 2: with\[0\]: exactly one of value, starts_with, regexp and not_in_list must be set for property "sdk_version"`,
+			`module "policy": rules: rule 3: without\[0\]: property must be set`,
+			`module "policy": rules: rule 4: with\[0\]: invalid regexp "\("`,
+			`module "policy": rules: rule 5: with_os_class: unknown OS class "linux", expected "device" or "host"`,
+		},
+	},
+}
+
+func TestNeverallowRules(t *testing.T) {
+	for _, test := range neverallowRulesTests {
+		t.Run(test.name, func(t *testing.T) {
+			GroupFixturePreparers(
+				prepareForNeverAllowTest,
+				PrepareForTestWithNeverallowRules([]Rule{}),
+				PrepareForTestWithNeverallowRulesModule,
+				test.fs.AddToFixture(),
+			).
+				ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern(test.expectedErrors)).
+				RunTest(t)
+		})
+	}
+}
@@ -247,6 +247,13 @@ func (p PackageContext) AndroidRemoteStaticRule(name string, supports RemoteRule
 
 	return p.PackageContext.RuleFunc(name, func(config interface{}) (blueprint.RuleParams, error) {
 		ctx := &configErrorWrapper{p, config.(Config), nil}
+		params := params
+		if isHighmemPool(params.Pool) &&
+			(ctx.Config().UseGoma() && supports.Goma || ctx.Config().UseRBE() && supports.RBE) {
+			// The rule runs remotely, don't restrict it based on the RAM of the local machine.
+			params.Pool = nil
+		}
+
 		if ctx.Config().UseGoma() && !supports.Goma {
 			// When USE_GOMA=true is set and the rule is not supported by goma, restrict jobs to the
 			// local parallelism value
@@ -1353,15 +1353,7 @@ func pathForModuleOut(ctx ModuleOutPathContext) OutputPath {
 func PathForVndkRefAbiDump(ctx ModuleInstallPathContext, version, fileName string,
 	isNdk, isLlndkOrVndk, isGzip bool) OptionalPath {
 
-	arches := ctx.DeviceConfig().Arches()
-	if len(arches) == 0 {
-		panic("device build with no primary arch")
-	}
-	currentArch := ctx.Arch()
-	archNameAndVariant := currentArch.ArchType.String()
-	if currentArch.ArchVariant != "" {
-		archNameAndVariant += "_" + currentArch.ArchVariant
-	}
+	archNameAndVariant := refAbiDumpArchDir(ctx)
 
 	var dirName string
 	if isNdk {
@@ -1386,6 +1378,28 @@ func PathForVndkRefAbiDump(ctx ModuleInstallPathContext, version, fileName strin
 		fileName+ext)
 }
 
+// PathForModuleRefAbiDump returns a Path representing the reference abi dump for the given
+// module in dir, a directory relative to the root of the source tree that contains a
+// subdirectory for each architecture.  The path is uncompressed and may not exist.
+func PathForModuleRefAbiDump(ctx ModuleInstallPathContext, dir, fileName string) SourcePath {
+	return PathForSource(ctx, dir, refAbiDumpArchDir(ctx), fileName+".lsdump")
+}
+
+// refAbiDumpArchDir returns the name of the directory of the reference abi dumps for the
+// architecture of the module, e.g. "arm64_armv8-a".
+func refAbiDumpArchDir(ctx ModuleInstallPathContext) string {
+	arches := ctx.DeviceConfig().Arches()
+	if len(arches) == 0 {
+		panic("device build with no primary arch")
+	}
+	currentArch := ctx.Arch()
+	archNameAndVariant := currentArch.ArchType.String()
+	if currentArch.ArchVariant != "" {
+		archNameAndVariant += "_" + currentArch.ArchVariant
+	}
+	return archNameAndVariant
+}
+
 // PathForModuleOut returns a Path representing the paths... under the module's
 // output directory.
 func PathForModuleOut(ctx ModuleOutPathContext, paths ...string) ModuleOutPath {
@@ -1723,6 +1737,9 @@ func modulePartition(ctx ModuleInstallPathContext, os OsType) string {
 		if ctx.InstallInSanitizerDir() {
 			partition = "data/asan/" + partition
 		}
+	} else if ctx.InstallInSanitizerDir() {
+		// Host modules are only installed to the sanitizer directory for msan.
+		partition = "msan"
 	}
 	return partition
 }
@@ -226,6 +226,19 @@ func TestPathForModuleInstall(t *testing.T) {
 			out:          "host/linux-x86/bin/my_test",
 			partitionDir: "host/linux-x86",
 		},
+		{
+			name: "sanitized host library",
+			ctx: &testModuleInstallPathContext{
+				baseModuleContext: baseModuleContext{
+					os:     hostTarget.Os,
+					target: hostTarget,
+				},
+				inSanitizerDir: true,
+			},
+			in:           []string{"lib64", "libfoo.so"},
+			out:          "host/linux-x86/msan/lib64/libfoo.so",
+			partitionDir: "host/linux-x86/msan",
+		},
 
 		{
 			name: "system binary",
@@ -0,0 +1,114 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Policy violations report.
+//
+// When SOONG_REPORT_POLICY_VIOLATIONS is set the neverallow and visibility mutators record the
+// modules that violate their rules instead of reporting errors, and the policy_violations
+// singleton writes all of them to out/soong/policy_violations.json, which is built by
+// `m policy_violations`.  This gives the full set of modules affected by a new or tightened rule
+// in a single build rather than one error at a time.
+
+func init() {
+	RegisterPolicyViolationsBuildComponents(InitRegistrationContext)
+}
+
+func RegisterPolicyViolationsBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("policy_violations", policyViolationsSingletonFactory)
+}
+
+// policyViolation is an entry of the policy violations report.
+type policyViolation struct {
+	// The kind of the rule, "neverallow" or "visibility".
+	Kind string
+
+	// The description of the rule that is violated.
+	Rule string
+
+	// The qualified name of the module that violates the rule, e.g. //foo:bar.
+	Module string
+
+	// The values of the properties of the module that match a neverallow rule, e.g.
+	// "sdk_version=current", or the visibility of the dependency.
+	Values []string `json:",omitempty"`
+
+	// The exception that would allow the module, e.g. a directory to add to the NotIn paths of a
+	// neverallow rule or to the visibility of the dependency.
+	SuggestedAllowlistEntry string `json:",omitempty"`
+}
+
+func (v policyViolation) reportKey() string {
+	return strings.Join([]string{v.Module, v.Kind, v.Rule, strings.Join(v.Values, " ")}, "\x00")
+}
+
+var policyViolationsKey = NewOnceKey("policyViolations")
+
+type policyViolations struct {
+	reportEntries
+}
+
+func getPolicyViolations(config Config) *policyViolations {
+	return config.Once(policyViolationsKey, func() interface{} {
+		return &policyViolations{}
+	}).(*policyViolations)
+}
+
+// sorted returns the violations sorted by module and rule, without the duplicates reported by the
+// variants of a module.
+func (p *policyViolations) sorted() []policyViolation {
+	entries := p.reportEntries.sorted()
+	ret := make([]policyViolation, len(entries))
+	for i, entry := range entries {
+		ret[i] = entry.(policyViolation)
+	}
+	return ret
+}
+
+func policyViolationsSingletonFactory() Singleton {
+	return &policyViolationsSingleton{}
+}
+
+type policyViolationsSingleton struct {
+	output WritablePath
+}
+
+func (s *policyViolationsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	if !ctx.Config().ReportPolicyViolations() {
+		return
+	}
+
+	data, err := json.MarshalIndent(getPolicyViolations(ctx.Config()).sorted(), "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal the policy violations: %s", err)
+		return
+	}
+
+	s.output = PathForOutput(ctx, "policy_violations.json")
+	WriteFileRule(ctx, s.output, string(data))
+
+	ctx.Phony("policy_violations", s.output)
+}
+
+func (s *policyViolationsSingleton) MakeVars(ctx MakeVarsContext) {
+	if s.output != nil {
+		ctx.DistForGoal("policy_violations", s.output)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+var policyViolationsFs = MockFS{
+	"vendor/Android.bp": []byte(`
+		neverallow_rules {
+			name: "policy",
+			rules: [{
+				with: [{
+					property: "include_dirs",
+					starts_with: "vendor/internal",
+				}],
+			}],
+		}`),
+	"vendor/foo/Android.bp": []byte(`
+		cc_library {
+			name: "libfoo",
+			include_dirs: [
+				"vendor/internal/a",
+				"vendor/internal/b",
+				"vendor/public",
+			],
+			static_libs: ["libprivate"],
+		}`),
+	"other/Android.bp": []byte(`
+		cc_library {
+			name: "libother",
+			vendor_available: true,
+			static_libs: ["libprivate"],
+		}`),
+	"private/Android.bp": []byte(`
+		cc_library {
+			name: "libprivate",
+			visibility: ["//other"],
+		}`),
+}
+
+var prepareForPolicyViolationsTest = GroupFixturePreparers(
+	prepareForNeverAllowTest,
+	PrepareForTestWithVisibility,
+	PrepareForTestWithNeverallowRulesModule,
+	PrepareForTestWithNeverallowRules([]Rule{
+		NeverAllow().
+			NotIn("vendor").
+			With("vendor_available", "true"),
+	}),
+	FixtureRegisterWithContext(RegisterPolicyViolationsBuildComponents),
+	policyViolationsFs.AddToFixture(),
+)
+
+func TestPolicyViolationsReport(t *testing.T) {
+	result := GroupFixturePreparers(
+		prepareForPolicyViolationsTest,
+		FixtureMergeEnv(map[string]string{
+			"SOONG_REPORT_POLICY_VIOLATIONS": "true",
+		}),
+	).RunTest(t)
+
+	report := result.SingletonForTests("policy_violations").Output("policy_violations.json")
+
+	var violations []policyViolation
+	if err := json.Unmarshal([]byte(ContentFromFileRuleForTests(t, report)), &violations); err != nil {
+		t.Fatal(err)
+	}
+
+	AssertDeepEquals(t, "violations", []policyViolation{
+		{
+			Kind:                    "neverallow",
+			Rule:                    "neverallow -dir:vendor/* Vendor_available=true",
+			Module:                  "//other:libother",
+			Values:                  []string{"vendor_available=true"},
+			SuggestedAllowlistEntry: `NotIn("other")`,
+		},
+		{
+			Kind:                    "neverallow",
+			Rule:                    "neverallow dir:vendor/* Include_dirs.starts-with(vendor/internal) (declared by //vendor:policy)",
+			Module:                  "//vendor/foo:libfoo",
+			Values:                  []string{"include_dirs=vendor/internal/a", "include_dirs=vendor/internal/b"},
+			SuggestedAllowlistEntry: `not_in: ["foo"]`,
+		},
+		{
+			Kind:                    "visibility",
+			Rule:                    "visibility of //private:libprivate",
+			Module:                  "//vendor/foo:libfoo",
+			Values:                  []string{"//other"},
+			SuggestedAllowlistEntry: "//vendor/foo",
+		},
+	}, violations)
+}
+
+func TestPolicyViolationsWithoutReport(t *testing.T) {
+	// The build stops after the visibility enforcer, so the neverallow violations are not reported.
+	prepareForPolicyViolationsTest.
+		ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern([]string{
+			`module "libfoo": depends on //private:libprivate which is not visible to this module`,
+		})).
+		RunTest(t)
+}
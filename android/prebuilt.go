@@ -73,6 +73,9 @@ type Prebuilt struct {
 
 	srcsSupplier     PrebuiltSrcsSupplier
 	srcsPropertyName string
+
+	// Set if the prefer property was set by a Soong config variable.
+	preferFromSoongConfig bool
 }
 
 // RemoveOptionalPrebuiltPrefix returns the result of removing the "prebuilt_" prefix from the
@@ -296,15 +299,19 @@ func PrebuiltSelectModuleMutator(ctx TopDownMutatorContext) {
 			panic(fmt.Errorf("prebuilt module did not have InitPrebuiltModule called on it"))
 		}
 		if !p.properties.SourceExists {
-			p.properties.UsePrebuilt = p.usePrebuilt(ctx, nil, m)
+			usePrebuilt, reason := p.usePrebuilt(ctx, nil, m)
+			p.properties.UsePrebuilt = usePrebuilt
+			recordPrebuiltSelection(ctx, m, false, usePrebuilt, reason)
 		}
 	} else if s, ok := ctx.Module().(Module); ok {
 		ctx.VisitDirectDepsWithTag(PrebuiltDepTag, func(m Module) {
 			p := m.(PrebuiltInterface).Prebuilt()
-			if p.usePrebuilt(ctx, s, m) {
+			usePrebuilt, reason := p.usePrebuilt(ctx, s, m)
+			if usePrebuilt {
 				p.properties.UsePrebuilt = true
 				s.ReplacedByPrebuilt()
 			}
+			recordPrebuiltSelection(ctx, m, true, usePrebuilt, reason)
 		})
 	}
 }
@@ -332,26 +339,49 @@ func PrebuiltPostDepsMutator(ctx BottomUpMutatorContext) {
 	}
 }
 
-// usePrebuilt returns true if a prebuilt should be used instead of the source module.  The prebuilt
-// will be used if it is marked "prefer" or if the source module is disabled.
-func (p *Prebuilt) usePrebuilt(ctx TopDownMutatorContext, source Module, prebuilt Module) bool {
+// usePrebuilt returns true if a prebuilt should be used instead of the source module, and the
+// reason for the decision.  The prebuilt will be used if the product's prebuilt selection file
+// selects it, or if it is marked "prefer" and the file does not select the source, or if the source
+// module is missing or disabled.
+func (p *Prebuilt) usePrebuilt(ctx TopDownMutatorContext, source Module, prebuilt Module) (bool, string) {
+	selected, inSelectionFile := getPrebuiltSelectionOverrides(ctx).selection(prebuilt.base().BaseModuleName())
+
 	if p.srcsSupplier != nil && len(p.srcsSupplier(ctx, prebuilt)) == 0 {
-		return false
+		return false, prebuiltReasonNoSrcs
 	}
 
 	// Skip prebuilt modules under unexported namespaces so that we won't
 	// end up shadowing non-prebuilt module when prebuilt module under same
 	// name happens to have a `Prefer` property set to true.
 	if ctx.Config().KatiEnabled() && !prebuilt.ExportedToMake() {
-		return false
+		return false, prebuiltReasonNotExportedToMake
+	}
+
+	if source == nil {
+		return true, prebuiltReasonSourceMissing
+	}
+
+	if inSelectionFile {
+		if !selected && !source.Enabled() {
+			return true, prebuiltReasonSourceDisabled
+		}
+		return selected, prebuiltReasonSelectionFile
 	}
 
-	// TODO: use p.Properties.Name and ctx.ModuleDir to override preference
 	if Bool(p.properties.Prefer) {
-		return true
+		if p.preferFromSoongConfig {
+			return true, prebuiltReasonSoongConfigPrefer
+		}
+		return true, prebuiltReasonPrefer
 	}
 
-	return source == nil || !source.Enabled()
+	if !source.Enabled() {
+		return true, prebuiltReasonSourceDisabled
+	}
+	if p.preferFromSoongConfig {
+		return false, prebuiltReasonSoongConfigPrefer
+	}
+	return false, prebuiltReasonNotPreferred
 }
 
 func (p *Prebuilt) SourceExists() bool {
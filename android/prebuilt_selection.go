@@ -0,0 +1,293 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint"
+)
+
+// Prebuilt selection report and overrides.
+//
+// PrebuiltSelectModuleMutator records, for every prebuilt module and the source module with the
+// same name, whether the source or the prebuilt is used and why, and the prebuilt_selection
+// singleton writes the records to out/soong/prebuilt_selection.json, which is built by
+// `m prebuilt_selection`.
+//
+// A product can set the PrebuiltSelectionFile key of soong.variables to the path of a JSON file
+// that forces modules to use the source or the prebuilt regardless of the prefer properties of the
+// prebuilts, e.g.
+//
+//    {
+//        "Source": ["libfoo"],
+//        "Prebuilt": ["libbar", "services-platform-compat-config"]
+//    }
+
+func init() {
+	RegisterPrebuiltSelectionBuildComponents(InitRegistrationContext)
+}
+
+func RegisterPrebuiltSelectionBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("prebuilt_selection", prebuiltSelectionSingletonFactory)
+}
+
+// The reasons for using the source or the prebuilt module.
+const (
+	// The prebuilt has "prefer: true".
+	prebuiltReasonPrefer = "prefer"
+	// The prefer property of the prebuilt was set by a Soong config variable.
+	prebuiltReasonSoongConfigPrefer = "soong_config_prefer"
+	// The module is listed in the product's prebuilt selection file.
+	prebuiltReasonSelectionFile = "selection_file"
+	// There is no source module with the same name.
+	prebuiltReasonSourceMissing = "source_missing"
+	// The source module is disabled.
+	prebuiltReasonSourceDisabled = "source_disabled"
+	// The prebuilt has no source files.
+	prebuiltReasonNoSrcs = "no_prebuilt_srcs"
+	// The prebuilt is in a namespace that is not exported to Make.
+	prebuiltReasonNotExportedToMake = "not_exported_to_make"
+	// The prebuilt is not preferred.
+	prebuiltReasonNotPreferred = "not_preferred"
+)
+
+// prebuiltSelection is an entry of the prebuilt selection report.
+type prebuiltSelection struct {
+	// The name of the source module.
+	Name string
+
+	// The name of the prebuilt module, e.g. prebuilt_foo.
+	Prebuilt string
+
+	// Whether a source module with the same name exists.
+	SourceExists bool
+
+	// The module that is used, "source" or "prebuilt".
+	Selected string
+
+	// The reason why the module is used.
+	Reason string
+}
+
+func (s prebuiltSelection) reportKey() string {
+	return strings.Join([]string{s.Name, s.Prebuilt, s.Selected, s.Reason}, "\x00")
+}
+
+var prebuiltSelectionsKey = NewOnceKey("prebuiltSelections")
+
+type prebuiltSelections struct {
+	reportEntries
+}
+
+func getPrebuiltSelections(config Config) *prebuiltSelections {
+	return config.Once(prebuiltSelectionsKey, func() interface{} {
+		return &prebuiltSelections{}
+	}).(*prebuiltSelections)
+}
+
+// sorted returns the selections sorted by name, without the duplicates recorded by the variants of
+// a module.
+func (p *prebuiltSelections) sorted() []prebuiltSelection {
+	entries := p.reportEntries.sorted()
+	ret := make([]prebuiltSelection, len(entries))
+	for i, entry := range entries {
+		ret[i] = entry.(prebuiltSelection)
+	}
+	return ret
+}
+
+// recordPrebuiltSelection records whether the source or the prebuilt module is used.
+func recordPrebuiltSelection(ctx BaseModuleContext, prebuilt Module, sourceExists, usePrebuilt bool, reason string) {
+	selected := "source"
+	if usePrebuilt {
+		selected = "prebuilt"
+	}
+	getPrebuiltSelections(ctx.Config()).add(prebuiltSelection{
+		Name:         prebuilt.base().BaseModuleName(),
+		Prebuilt:     ctx.OtherModuleName(prebuilt),
+		SourceExists: sourceExists,
+		Selected:     selected,
+		Reason:       reason,
+	})
+}
+
+// prebuiltSelectionFile is the contents of the product's prebuilt selection file.
+type prebuiltSelectionFile struct {
+	// The modules that use the source module.
+	Source []string
+
+	// The modules that use the prebuilt module.
+	Prebuilt []string
+}
+
+type prebuiltSelectionOverrides struct {
+	file prebuiltSelectionFile
+	err  error
+
+	mutex sync.Mutex
+	used  map[string]bool
+}
+
+var prebuiltSelectionOverridesKey = NewOnceKey("prebuiltSelectionOverrides")
+
+// getPrebuiltSelectionOverrides returns the contents of the product's prebuilt selection file.
+// Errors reading the file are reported by the prebuilt_selection singleton.
+func getPrebuiltSelectionOverrides(ctx PathContext) *prebuiltSelectionOverrides {
+	path := ctx.Config().PrebuiltSelectionFile()
+	if path == "" {
+		return nil
+	}
+	ctx.AddNinjaFileDeps(path)
+
+	return ctx.Config().Once(prebuiltSelectionOverridesKey, func() interface{} {
+		overrides := &prebuiltSelectionOverrides{used: make(map[string]bool)}
+		overrides.file, overrides.err = readPrebuiltSelectionFile(ctx.Config(), path)
+		return overrides
+	}).(*prebuiltSelectionOverrides)
+}
+
+func readPrebuiltSelectionFile(config Config, path string) (prebuiltSelectionFile, error) {
+	var file prebuiltSelectionFile
+
+	f, err := config.fs.Open(path)
+	if err != nil {
+		return file, err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return file, err
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return file, fmt.Errorf("%s: %s", path, err)
+	}
+	if both := FirstUniqueStrings(intersectStrings(file.Source, file.Prebuilt)); len(both) > 0 {
+		return file, fmt.Errorf("%s: modules %q are listed in both Source and Prebuilt", path, both)
+	}
+	return file, nil
+}
+
+func intersectStrings(a, b []string) []string {
+	var ret []string
+	for _, s := range a {
+		if InList(s, b) {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// selection returns whether the file forces the module with the name to use the prebuilt, and
+// whether it lists the module at all.
+func (o *prebuiltSelectionOverrides) selection(name string) (usePrebuilt bool, ok bool) {
+	if o == nil || o.err != nil {
+		return false, false
+	}
+	if InList(name, o.file.Prebuilt) {
+		usePrebuilt, ok = true, true
+	} else if InList(name, o.file.Source) {
+		usePrebuilt, ok = false, true
+	}
+	if ok {
+		o.mutex.Lock()
+		o.used[name] = true
+		o.mutex.Unlock()
+	}
+	return usePrebuilt, ok
+}
+
+// unused returns the modules listed in the file that are not a prebuilt module or the source
+// module of a prebuilt.
+func (o *prebuiltSelectionOverrides) unused() []string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	var ret []string
+	for _, name := range append(append([]string(nil), o.file.Source...), o.file.Prebuilt...) {
+		if !o.used[name] {
+			ret = append(ret, name)
+		}
+	}
+	return SortedUniqueStrings(ret)
+}
+
+// markPreferFromSoongConfig remembers that the prefer property of a prebuilt module was set by the
+// properties that a Soong config variable applied to the module.
+func markPreferFromSoongConfig(module blueprint.Module, props interface{}) {
+	prebuilt, ok := module.(PrebuiltInterface)
+	if !ok || prebuilt.Prebuilt() == nil {
+		return
+	}
+	v := reflect.ValueOf(props)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	if prefer := v.Elem().FieldByName("Prefer"); prefer.IsValid() && prefer.Kind() == reflect.Ptr && !prefer.IsNil() {
+		prebuilt.Prebuilt().preferFromSoongConfig = true
+	}
+}
+
+func prebuiltSelectionSingletonFactory() Singleton {
+	return &prebuiltSelectionSingleton{}
+}
+
+type prebuiltSelectionSingleton struct {
+	output WritablePath
+}
+
+// prebuiltSelectionReport is the contents of prebuilt_selection.json.
+type prebuiltSelectionReport struct {
+	Selections []prebuiltSelection
+
+	// The modules listed in the product's prebuilt selection file that have no prebuilt.
+	UnusedOverrides []string `json:",omitempty"`
+}
+
+func (s *prebuiltSelectionSingleton) GenerateBuildActions(ctx SingletonContext) {
+	report := prebuiltSelectionReport{
+		Selections: getPrebuiltSelections(ctx.Config()).sorted(),
+	}
+
+	if overrides := getPrebuiltSelectionOverrides(ctx); overrides != nil {
+		if overrides.err != nil {
+			ctx.Errorf("failed to read the prebuilt selection file: %s", overrides.err)
+			return
+		}
+		report.UnusedOverrides = overrides.unused()
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal the prebuilt selections: %s", err)
+		return
+	}
+
+	s.output = PathForOutput(ctx, "prebuilt_selection.json")
+	WriteFileRule(ctx, s.output, string(data))
+
+	ctx.Phony("prebuilt_selection", s.output)
+}
+
+func (s *prebuiltSelectionSingleton) MakeVars(ctx MakeVarsContext) {
+	if s.output != nil {
+		ctx.DistForGoal("prebuilt_selection", s.output)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+)
+
+func TestPrebuiltSelection(t *testing.T) {
+	bp := `
+		source {
+			name: "bar",
+		}
+
+		prebuilt {
+			name: "bar",
+			prefer: true,
+			srcs: ["prebuilt_file"],
+		}
+
+		prebuilt {
+			name: "baz",
+			srcs: ["prebuilt_file"],
+		}
+
+		source {
+			name: "qux",
+		}
+
+		prebuilt {
+			name: "qux",
+			srcs: ["prebuilt_file"],
+		}
+
+		source {
+			name: "quux",
+		}
+
+		prebuilt {
+			name: "quux",
+			srcs: ["prebuilt_file"],
+		}
+
+		source {
+			name: "corge",
+		}
+
+		prebuilt {
+			name: "corge",
+			prefer: true,
+			srcs: ["prebuilt_file"],
+		}
+
+		soong_config_module_type {
+			name: "acme_prebuilt",
+			module_type: "prebuilt",
+			config_namespace: "acme",
+			bool_variables: ["use_prebuilts"],
+			properties: ["prefer"],
+		}
+
+		source {
+			name: "grault",
+		}
+
+		acme_prebuilt {
+			name: "grault",
+			srcs: ["prebuilt_file"],
+			soong_config_variables: {
+				use_prebuilts: {
+					prefer: true,
+				},
+			},
+		}
+	`
+
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithPrebuilts,
+		FixtureRegisterWithContext(registerTestPrebuiltModules),
+		FixtureRegisterWithContext(RegisterPrebuiltSelectionBuildComponents),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("soong_config_module_type", soongConfigModuleTypeFactory)
+		}),
+		FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+			variables.PrebuiltSelectionFile = proptools.StringPtr("vendor/prebuilt_selection.json")
+			variables.VendorVars = map[string]map[string]string{
+				"acme": {"use_prebuilts": "true"},
+			}
+		}),
+		FixtureAddTextFile("prebuilt_file", ""),
+		FixtureAddTextFile("source_file", ""),
+		FixtureAddTextFile("vendor/prebuilt_selection.json", `{
+			"Source": ["corge"],
+			"Prebuilt": ["quux", "missing"]
+		}`),
+		FixtureWithRootAndroidBp(bp),
+	).RunTest(t)
+
+	AssertBoolEquals(t, "quux uses the prebuilt", true,
+		result.ModuleForTests("prebuilt_quux", "android_common").Module().(*prebuiltModule).Prebuilt().UsePrebuilt())
+	AssertBoolEquals(t, "corge uses the source", false,
+		result.ModuleForTests("prebuilt_corge", "android_common").Module().(*prebuiltModule).Prebuilt().UsePrebuilt())
+
+	var report prebuiltSelectionReport
+	content := ContentFromFileRuleForTests(t, result.SingletonForTests("prebuilt_selection").Output("out/soong/prebuilt_selection.json"))
+	if err := json.Unmarshal([]byte(content), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	AssertDeepEquals(t, "prebuilt selection report", prebuiltSelectionReport{
+		Selections: []prebuiltSelection{
+			{Name: "bar", Prebuilt: "prebuilt_bar", SourceExists: true, Selected: "prebuilt", Reason: "prefer"},
+			{Name: "baz", Prebuilt: "baz", Selected: "prebuilt", Reason: "source_missing"},
+			{Name: "corge", Prebuilt: "prebuilt_corge", SourceExists: true, Selected: "source", Reason: "selection_file"},
+			{Name: "grault", Prebuilt: "prebuilt_grault", SourceExists: true, Selected: "prebuilt", Reason: "soong_config_prefer"},
+			{Name: "quux", Prebuilt: "prebuilt_quux", SourceExists: true, Selected: "prebuilt", Reason: "selection_file"},
+			{Name: "qux", Prebuilt: "prebuilt_qux", SourceExists: true, Selected: "source", Reason: "not_preferred"},
+		},
+		UnusedOverrides: []string{"missing"},
+	}, report)
+}
+
+func TestPrebuiltSelectionFileErrors(t *testing.T) {
+	GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithPrebuilts,
+		FixtureRegisterWithContext(registerTestPrebuiltModules),
+		FixtureRegisterWithContext(RegisterPrebuiltSelectionBuildComponents),
+		FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+			variables.PrebuiltSelectionFile = proptools.StringPtr("vendor/prebuilt_selection.json")
+		}),
+		FixtureAddTextFile("vendor/prebuilt_selection.json", `{
+			"Source": ["foo"],
+			"Prebuilt": ["foo"]
+		}`),
+		FixtureWithRootAndroidBp(`
+			source {
+				name: "foo",
+			}`),
+	).ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern([]string{
+		`failed to read the prebuilt selection file: vendor/prebuilt_selection.json: modules \["foo"\] are listed in both Source and Prebuilt`,
+	})).RunTest(t)
+}
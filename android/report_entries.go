@@ -0,0 +1,60 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"sort"
+	"sync"
+)
+
+// reportEntry is an entry of a report that is written by a singleton, e.g. the prebuilt selection
+// or the policy violations report.
+type reportEntry interface {
+	// reportKey returns the string that the entries are sorted by.  Entries with the same key are
+	// duplicates.
+	reportKey() string
+}
+
+// reportEntries collects the entries of a report from mutators that run in parallel.
+type reportEntries struct {
+	mutex   sync.Mutex
+	entries []reportEntry
+}
+
+func (r *reportEntries) add(entry reportEntry) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// sorted returns the entries sorted by key, without the duplicates recorded by the variants of a
+// module.
+func (r *reportEntries) sorted() []reportEntry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entries := append([]reportEntry(nil), r.entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].reportKey() < entries[j].reportKey()
+	})
+
+	ret := make([]reportEntry, 0, len(entries))
+	for i, entry := range entries {
+		if i == 0 || entry.reportKey() != entries[i-1].reportKey() {
+			ret = append(ret, entry)
+		}
+	}
+	return ret
+}
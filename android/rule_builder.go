@@ -48,7 +48,7 @@ type RuleBuilder struct {
 	temporariesSet   map[WritablePath]bool
 	restat           bool
 	sbox             bool
-	highmem          bool
+	highmemPool      blueprint.Pool
 	remoteable       RemoteRuleSupports
 	rbeParams        *remoteexec.REParams
 	outDir           WritablePath
@@ -113,7 +113,13 @@ func (r *RuleBuilder) Restat() *RuleBuilder {
 // HighMem marks the rule as a high memory rule, which will limit how many run in parallel with other high memory
 // rules.
 func (r *RuleBuilder) HighMem() *RuleBuilder {
-	r.highmem = true
+	return r.HighMemPool(highmemPool)
+}
+
+// HighMemPool marks the rule as a high memory rule like HighMem, but only limits how many run in parallel with
+// other rules in the given pool, for example HighmemMetalavaPool.
+func (r *RuleBuilder) HighMemPool(pool blueprint.Pool) *RuleBuilder {
+	r.highmemPool = pool
 	return r
 }
 
@@ -589,6 +595,23 @@ func (r *RuleBuilder) Build(name string, desc string) {
 			command.Chdir = proto.Bool(true)
 		}
 
+		// When sbox isolates the command from the filesystem or caches its outputs it needs to know
+		// which files outside the sbox directory the command reads.  Inputs and tools that were
+		// copied into the sbox directory don't need to be listed.
+		if r.ctx.Config().SboxIsolate() || r.ctx.Config().SboxCacheDir() != "" {
+			var isolatedInputs Paths
+			if !r.sboxTools {
+				isolatedInputs = append(isolatedInputs, tools...)
+			}
+			if !r.sboxInputs {
+				isolatedInputs = append(isolatedInputs, inputs...)
+				for _, rspFile := range rspFiles {
+					isolatedInputs = append(isolatedInputs, rspFile.file)
+				}
+			}
+			command.IsolatedInputs = FirstUniqueStrings(isolatedInputs.Strings())
+		}
+
 		// Add copy rules to the manifest to copy each output file from the sbox directory.
 		// to the output directory after running the commands.
 		sboxOutputs := make([]string, len(outputs))
@@ -636,6 +659,13 @@ func (r *RuleBuilder) Build(name string, desc string) {
 		sboxCmd.BuiltTool("sbox").
 			Flag("--sandbox-path").Text(shared.TempDirForOutDir(PathForOutput(r.ctx).String())).
 			Flag("--manifest").Input(r.sboxManifestPath)
+		if r.ctx.Config().SboxIsolate() {
+			sboxCmd.Flag("--isolate")
+		}
+		if cacheDir := r.ctx.Config().SboxCacheDir(); cacheDir != "" {
+			sboxCmd.Flag("--cache-dir").Text(cacheDir).
+				Flag("--out-dir").Text(PathForOutput(r.ctx).String())
+		}
 
 		// Replace the command string, and add the sbox tool and manifest textproto to the
 		// dependencies of the final sbox rule.
@@ -711,8 +741,8 @@ func (r *RuleBuilder) Build(name string, desc string) {
 	} else if r.ctx.Config().UseRBE() && r.remoteable.RBE {
 		// When USE_RBE=true is set and the rule is supported by RBE, use the remotePool.
 		pool = remotePool
-	} else if r.highmem {
-		pool = highmemPool
+	} else if r.highmemPool != nil {
+		pool = r.highmemPool
 	} else if r.ctx.Config().UseRemoteBuild() {
 		pool = localPool
 	}
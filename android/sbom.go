@@ -0,0 +1,267 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The sbom singleton generates an SPDX software bill of materials for the product from the
+// license graph.  It walks the device modules that Soong installs, and writes a JSON description
+// of their installed files and resolved licenses.  The files don't exist yet during analysis, so
+// the sbom tool computes their checksums in a build rule and writes the SPDX document in the
+// tag-value and JSON formats.  Build it with `m sbom`.
+//
+// When the ProductPackages key of soong.variables is set, the document lists the modules that are
+// installed in the product: the modules named in ProductPackages, and the modules they require or
+// whose files they install, transitively.  Make doesn't set ProductPackages, so by default the
+// document lists every device module that Soong installs in the product out directory.
+
+func init() {
+	RegisterSbomBuildComponents(InitRegistrationContext)
+}
+
+func RegisterSbomBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("sbom", sbomSingletonFactory)
+}
+
+func sbomSingletonFactory() Singleton {
+	return &sbomSingleton{}
+}
+
+type sbomSingleton struct {
+	tagValueOutput WritablePath
+	jsonOutput     WritablePath
+}
+
+// sbomInput is the JSON description of the product that is read by the sbom tool.  It must be
+// kept in sync with cmd/sbom.
+type sbomInput struct {
+	Product  string
+	Modules  []sbomModule
+	Licenses []sbomLicense
+}
+
+type sbomModule struct {
+	Name        string
+	Variant     string
+	Dir         string
+	PackageName string `json:",omitempty"`
+
+	// Licenses are the names of the license modules that apply to the module.
+	Licenses []string `json:",omitempty"`
+
+	Files []sbomFile
+}
+
+type sbomFile struct {
+	// Path is the path of the file on the device.
+	Path string
+
+	// Src is the path to the built file that is installed, it is empty for symlinks.
+	Src string `json:",omitempty"`
+
+	SymlinkTarget string `json:",omitempty"`
+}
+
+type sbomLicense struct {
+	Name            string
+	Kinds           []string `json:",omitempty"`
+	Conditions      []string `json:",omitempty"`
+	CopyrightNotice string   `json:",omitempty"`
+	PackageName     string   `json:",omitempty"`
+	TextFiles       []string `json:",omitempty"`
+}
+
+// isSbomInstalled returns true if the module is a device module whose files are installed on the
+// device, using the same conditions as moduleContext.skipInstall except for the ones that only
+// apply when Make installs the module.
+func isSbomInstalled(module Module) bool {
+	base := module.base()
+	return module.Enabled() &&
+		base.Os().Class == Device &&
+		!base.IsSkipInstall() &&
+		!base.IsHideFromMake() &&
+		base.ExportedToMake()
+}
+
+// sbomProductModules returns the modules that are installed in the product, in the order they
+// are visited: the device variants of the modules named in productPackages, and the modules they
+// require or whose files they install, transitively.  All the installed device modules are
+// returned when productPackages is empty.
+func sbomProductModules(ctx SingletonContext, productPackages []string) []Module {
+	var candidates []Module
+	modulesByName := make(map[string][]Module)
+	owners := make(map[string]Module)
+	ctx.VisitAllModules(func(module Module) {
+		if !isSbomInstalled(module) {
+			return
+		}
+		candidates = append(candidates, module)
+		name := ctx.ModuleName(module)
+		modulesByName[name] = append(modulesByName[name], module)
+		for _, spec := range module.base().PackagingSpecs() {
+			owners[spec.installPath.String()] = module
+		}
+	})
+	if len(productPackages) == 0 {
+		return candidates
+	}
+
+	installed := make(map[Module]bool)
+	queue := append([]string(nil), productPackages...)
+	var install func(module Module)
+	install = func(module Module) {
+		if installed[module] {
+			return
+		}
+		installed[module] = true
+		queue = append(queue, module.base().RequiredModuleNames()...)
+		queue = append(queue, module.base().TargetRequiredModuleNames()...)
+		for _, spec := range module.base().TransitivePackagingSpecs() {
+			if owner, ok := owners[spec.installPath.String()]; ok {
+				install(owner)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		// ProductPackages entries can select the 32 or 64 bit variant with a :32 or :64 suffix,
+		// all the device variants of the module are listed.
+		if i := strings.IndexByte(name, ':'); i != -1 {
+			name = name[:i]
+		}
+		for _, module := range modulesByName[name] {
+			install(module)
+		}
+	}
+
+	var modules []Module
+	for _, module := range candidates {
+		if installed[module] {
+			modules = append(modules, module)
+		}
+	}
+	return modules
+}
+
+func (s *sbomSingleton) GenerateBuildActions(ctx SingletonContext) {
+	productOut := PathForOutput(ctx, "target", "product", ctx.Config().DeviceName()).String()
+
+	input := sbomInput{
+		Product: ctx.Config().DeviceName(),
+	}
+
+	var deps Paths
+	usedLicenses := make(map[string]bool)
+	licenses := make(map[string]*licenseModule)
+
+	ctx.VisitAllModules(func(module Module) {
+		if l, ok := module.(*licenseModule); ok {
+			licenses[ctx.ModuleName(module)] = l
+		}
+	})
+
+	for _, module := range sbomProductModules(ctx, ctx.Config().ProductPackages()) {
+		var files []sbomFile
+		for _, spec := range module.base().PackagingSpecs() {
+			rel, isRel := MaybeRel(ctx, productOut, spec.installPath.String())
+			if !isRel {
+				continue
+			}
+			file := sbomFile{
+				Path:          "/" + filepath.ToSlash(rel),
+				SymlinkTarget: spec.symlinkTarget,
+			}
+			if spec.srcPath != nil && spec.symlinkTarget == "" {
+				file.Src = spec.srcPath.String()
+				deps = append(deps, spec.srcPath)
+			}
+			files = append(files, file)
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		props := &module.base().commonProperties
+		for _, l := range props.Effective_licenses {
+			usedLicenses[l] = true
+		}
+		input.Modules = append(input.Modules, sbomModule{
+			Name:        ctx.ModuleName(module),
+			Variant:     ctx.ModuleSubDir(module),
+			Dir:         ctx.ModuleDir(module),
+			PackageName: String(props.Effective_package_name),
+			Licenses:    props.Effective_licenses,
+			Files:       files,
+		})
+	}
+
+	for _, name := range SortedStringKeys(usedLicenses) {
+		l, ok := licenses[name]
+		if !ok {
+			continue
+		}
+		props := &l.base().commonProperties
+		input.Licenses = append(input.Licenses, sbomLicense{
+			Name:            name,
+			Kinds:           props.Effective_license_kinds,
+			Conditions:      props.Effective_license_conditions,
+			CopyrightNotice: String(l.properties.Copyright_notice),
+			PackageName:     String(l.properties.Package_name),
+			TextFiles:       l.licenseTexts.Strings(),
+		})
+		deps = append(deps, l.licenseTexts...)
+	}
+
+	sort.SliceStable(input.Modules, func(i, j int) bool {
+		if input.Modules[i].Name != input.Modules[j].Name {
+			return input.Modules[i].Name < input.Modules[j].Name
+		}
+		return input.Modules[i].Variant < input.Modules[j].Variant
+	})
+
+	data, err := json.MarshalIndent(input, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to write sbom input: %s", err)
+		return
+	}
+
+	inputFile := PathForOutput(ctx, "sbom", "sbom_input.json")
+	WriteFileRule(ctx, inputFile, string(data))
+
+	s.tagValueOutput = PathForOutput(ctx, "sbom", input.Product+".spdx")
+	s.jsonOutput = PathForOutput(ctx, "sbom", input.Product+".spdx.json")
+
+	rule := NewRuleBuilder(pctx, ctx)
+	rule.Command().BuiltTool("sbom").
+		FlagWithInput("-i ", inputFile).
+		FlagWithOutput("-o ", s.tagValueOutput).
+		FlagWithOutput("-json ", s.jsonOutput).
+		Implicits(FirstUniquePaths(deps))
+	rule.Build("sbom", "sbom "+input.Product)
+
+	ctx.Phony("sbom", s.tagValueOutput, s.jsonOutput)
+}
+
+func (s *sbomSingleton) MakeVars(ctx MakeVarsContext) {
+	if s.tagValueOutput != nil {
+		ctx.DistForGoal("sbom", s.tagValueOutput, s.jsonOutput)
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type sbomTestModule struct {
+	ModuleBase
+}
+
+func (m *sbomTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	outputFile := PathForModuleOut(ctx, ctx.ModuleName())
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: outputFile,
+	})
+	installPath := ctx.InstallFile(PathForModuleInstall(ctx, "bin"), ctx.ModuleName(), outputFile)
+	ctx.InstallSymlink(PathForModuleInstall(ctx, "bin"), ctx.ModuleName()+"_link", installPath)
+}
+
+func sbomTestModuleFactory() Module {
+	module := &sbomTestModule{}
+	InitAndroidArchModule(module, HostAndDeviceSupported, MultilibCommon)
+	return module
+}
+
+func TestSbom(t *testing.T) {
+	bp := `
+		license_kind {
+			name: "notice_kind",
+			conditions: ["notice"],
+		}
+
+		license {
+			name: "foo_license",
+			license_kinds: ["notice_kind"],
+			copyright_notice: "Copyright (C) The Android Open Source Project",
+			license_text: ["LICENSE"],
+			package_name: "Foo",
+		}
+
+		license {
+			name: "unused_license",
+			license_kinds: ["notice_kind"],
+		}
+
+		test_module {
+			name: "foo",
+			host_supported: true,
+			licenses: ["foo_license"],
+			required: ["baz"],
+		}
+
+		test_module {
+			name: "bar",
+			enabled: false,
+		}
+
+		test_module {
+			name: "baz",
+		}
+
+		test_module {
+			name: "qux",
+			licenses: ["unused_license"],
+		}
+	`
+
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithLicenses,
+		FixtureRegisterWithContext(RegisterSbomBuildComponents),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("test_module", sbomTestModuleFactory)
+		}),
+		FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+			variables.ProductPackages = []string{"foo", "bar"}
+		}),
+		FixtureAddTextFile("LICENSE", "license text"),
+		FixtureWithRootAndroidBp(bp),
+	).RunTest(t)
+
+	sbom := result.SingletonForTests("sbom")
+
+	var input sbomInput
+	content := ContentFromFileRuleForTests(t, sbom.Output("sbom/sbom_input.json"))
+	if err := json.Unmarshal([]byte(content), &input); err != nil {
+		t.Fatal(err)
+	}
+
+	AssertDeepEquals(t, "sbom input", sbomInput{
+		Product: "test_device",
+		Modules: []sbomModule{
+			{
+				Name:    "baz",
+				Variant: "android_common",
+				Dir:     ".",
+				Files: []sbomFile{
+					{
+						Path: "/system/bin/baz",
+						Src:  "out/soong/.intermediates/baz/android_common/baz",
+					},
+					{
+						Path:          "/system/bin/baz_link",
+						SymlinkTarget: "baz",
+					},
+				},
+			},
+			{
+				Name:        "foo",
+				Variant:     "android_common",
+				Dir:         ".",
+				PackageName: "Foo",
+				Licenses:    []string{"foo_license"},
+				Files: []sbomFile{
+					{
+						Path: "/system/bin/foo",
+						Src:  "out/soong/.intermediates/foo/android_common/foo",
+					},
+					{
+						Path:          "/system/bin/foo_link",
+						SymlinkTarget: "foo",
+					},
+				},
+			},
+		},
+		Licenses: []sbomLicense{
+			{
+				Name:            "foo_license",
+				Kinds:           []string{"notice_kind"},
+				Conditions:      []string{"notice"},
+				CopyrightNotice: "Copyright (C) The Android Open Source Project",
+				PackageName:     "Foo",
+				TextFiles:       []string{"LICENSE"},
+			},
+		},
+	}, input)
+
+	rule := sbom.Rule("sbom")
+	AssertPathRelativeToTopEquals(t, "sbom output", "out/soong/sbom/test_device.spdx", rule.Output)
+	AssertPathsRelativeToTopEquals(t, "sbom json output",
+		[]string{"out/soong/sbom/test_device.spdx.json"}, rule.ImplicitOutputs.Paths())
+	AssertDeepEquals(t, "sbom implicits",
+		[]string{
+			"LICENSE",
+			"out/soong/.intermediates/baz/android_common/baz",
+			"out/soong/.intermediates/foo/android_common/foo",
+			"out/soong/sbom/sbom_input.json",
+		},
+		SortedUniqueStrings(PathsRelativeToTop(rule.Implicits)))
+}
+
+func TestSbomWithoutProductPackages(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		FixtureRegisterWithContext(RegisterSbomBuildComponents),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("test_module", sbomTestModuleFactory)
+		}),
+		FixtureWithRootAndroidBp(`
+			test_module {
+				name: "foo",
+			}
+
+			test_module {
+				name: "bar",
+			}
+
+			test_module {
+				name: "baz",
+				enabled: false,
+			}
+		`),
+	).RunTest(t)
+
+	// Without ProductPackages all the installed device modules are listed.
+	var input sbomInput
+	content := ContentFromFileRuleForTests(t, result.SingletonForTests("sbom").Output("sbom/sbom_input.json"))
+	if err := json.Unmarshal([]byte(content), &input); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, module := range input.Modules {
+		names = append(names, module.Name)
+	}
+	AssertArrayString(t, "sbom modules", []string{"bar", "foo"}, names)
+}
@@ -35,6 +35,8 @@ func init() {
 	RegisterModuleType("soong_config_module_type", soongConfigModuleTypeFactory)
 	RegisterModuleType("soong_config_string_variable", soongConfigStringVariableDummyFactory)
 	RegisterModuleType("soong_config_bool_variable", soongConfigBoolVariableDummyFactory)
+	RegisterModuleType("soong_config_int_variable", soongConfigIntVariableDummyFactory)
+	RegisterModuleType("soong_config_list_variable", soongConfigListVariableDummyFactory)
 }
 
 type soongConfigModuleTypeImport struct {
@@ -55,12 +57,18 @@ type soongConfigModuleTypeImportProperties struct {
 // specified in `conditions_default` will only be used under the following conditions:
 //   bool variable: the variable is unspecified or not set to a true value
 //   value variable: the variable is unspecified
+//   int variable: the variable is unspecified or empty
+//   list variable: the variable is unspecified or empty
 //   string variable: the variable is unspecified or the variable is set to a string unused in the
 //                    given module. For example, string variable `test` takes values: "a" and "b",
 //                    if the module contains a property `a` and `conditions_default`, when test=b,
 //                    the properties under `conditions_default` will be used. To specify that no
 //                    properties should be amended for `b`, you can set `b: {},`.
 //
+// Setting a string variable to a value that is not one of its values, an int variable to a value
+// that is not an integer in its range, or an element of a list variable to a value that is not one
+// of its values is an error.
+//
 // For example, an Android.bp file could have:
 //
 //     soong_config_module_type_import {
@@ -196,12 +204,18 @@ type soongConfigModuleTypeModule struct {
 // specified in `conditions_default` will only be used under the following conditions:
 //   bool variable: the variable is unspecified or not set to a true value
 //   value variable: the variable is unspecified
+//   int variable: the variable is unspecified or empty
+//   list variable: the variable is unspecified or empty
 //   string variable: the variable is unspecified or the variable is set to a string unused in the
 //                    given module. For example, string variable `test` takes values: "a" and "b",
 //                    if the module contains a property `a` and `conditions_default`, when test=b,
 //                    the properties under `conditions_default` will be used. To specify that no
 //                    properties should be amended for `b`, you can set `b: {},`.
 //
+// Setting a string variable to a value that is not one of its values, an int variable to a value
+// that is not an integer in its range, or an element of a list variable to a value that is not one
+// of its values is an error.
+//
 // For example, an Android.bp file could have:
 //
 //     soong_config_module_type {
@@ -299,6 +313,18 @@ type soongConfigBoolVariableDummyModule struct {
 	properties soongconfig.VariableProperties
 }
 
+type soongConfigIntVariableDummyModule struct {
+	ModuleBase
+	properties    soongconfig.VariableProperties
+	intProperties soongconfig.IntVariableProperties
+}
+
+type soongConfigListVariableDummyModule struct {
+	ModuleBase
+	properties     soongconfig.VariableProperties
+	listProperties soongconfig.ListVariableProperties
+}
+
 // soong_config_string_variable defines a variable and a set of possible string values for use
 // in a soong_config_module_type definition.
 func soongConfigStringVariableDummyFactory() Module {
@@ -317,6 +343,47 @@ func soongConfigBoolVariableDummyFactory() Module {
 	return module
 }
 
+// soong_config_int_variable defines an integer variable, optionally with the range of its values,
+// for use in a soong_config_module_type definition.  In a module, the %d in the properties of the
+// variable is replaced with the value, int properties are set to the value, and the properties in
+// the at_least and less_than blocks are applied when the value is compared successfully with the
+// value of the block, e.g.
+//
+//     soong_config_int_variable {
+//         name: "api_level",
+//         min: 28,
+//     }
+//
+//     acme_cc_defaults {
+//         name: "acme_defaults",
+//         soong_config_variables: {
+//             api_level: {
+//                 cflags: ["-DAPI_LEVEL=%d"],
+//                 at_least: [{
+//                     value: 30,
+//                     cflags: ["-DHAS_R_APIS"],
+//                 }],
+//             },
+//         },
+//     }
+func soongConfigIntVariableDummyFactory() Module {
+	module := &soongConfigIntVariableDummyModule{}
+	module.AddProperties(&module.properties, &module.intProperties)
+	initAndroidModuleBase(module)
+	return module
+}
+
+// soong_config_list_variable defines a variable containing a space separated list, optionally with
+// the set of possible values of its elements, for use in a soong_config_module_type definition.
+// In a module, each entry of a list property of the variable that contains %s is replaced with an
+// entry for each element of the list.
+func soongConfigListVariableDummyFactory() Module {
+	module := &soongConfigListVariableDummyModule{}
+	module.AddProperties(&module.properties, &module.listProperties)
+	initAndroidModuleBase(module)
+	return module
+}
+
 func (m *soongConfigStringVariableDummyModule) Name() string {
 	return m.properties.Name
 }
@@ -329,6 +396,18 @@ func (m *soongConfigBoolVariableDummyModule) Name() string {
 func (*soongConfigBoolVariableDummyModule) Nameless()                                     {}
 func (*soongConfigBoolVariableDummyModule) GenerateAndroidBuildActions(ctx ModuleContext) {}
 
+func (m *soongConfigIntVariableDummyModule) Name() string {
+	return m.properties.Name
+}
+func (*soongConfigIntVariableDummyModule) Nameless()                                     {}
+func (*soongConfigIntVariableDummyModule) GenerateAndroidBuildActions(ctx ModuleContext) {}
+
+func (m *soongConfigListVariableDummyModule) Name() string {
+	return m.properties.Name
+}
+func (*soongConfigListVariableDummyModule) Nameless()                                     {}
+func (*soongConfigListVariableDummyModule) GenerateAndroidBuildActions(ctx ModuleContext) {}
+
 func importModuleTypes(ctx LoadHookContext, from string, moduleTypes ...string) {
 	from = filepath.Clean(from)
 	if filepath.Ext(from) != ".bp" {
@@ -432,6 +511,7 @@ func soongConfigModuleFactory(factory blueprint.ModuleFactory,
 				}
 				for _, ps := range newProps {
 					ctx.AppendProperties(ps)
+					markPreferFromSoongConfig(module, ps)
 				}
 			})
 
@@ -40,7 +40,8 @@ type soongConfigTestModule struct {
 }
 
 type soongConfigTestModuleProperties struct {
-	Cflags []string
+	Cflags   []string
+	Max_size *int64
 }
 
 func soongConfigTestModuleFactory() Module {
@@ -313,6 +314,143 @@ func TestSoongConfigModule(t *testing.T) {
 	})
 }
 
+func TestSoongConfigModuleIntAndListVariables(t *testing.T) {
+	bp := `
+		soong_config_module_type {
+			name: "acme_test",
+			module_type: "test",
+			config_namespace: "acme",
+			variables: ["api_level", "features"],
+			int_variables: ["size"],
+			list_variables: ["extra"],
+			properties: ["cflags", "max_size"],
+		}
+
+		soong_config_int_variable {
+			name: "api_level",
+			min: 28,
+			max: 31,
+		}
+
+		soong_config_list_variable {
+			name: "features",
+			values: ["camera", "nfc", "wifi"],
+		}
+
+		acme_test {
+			name: "foo",
+			cflags: ["-DGENERIC"],
+			soong_config_variables: {
+				api_level: {
+					cflags: ["-DAPI_LEVEL=%d"],
+					at_least: [{
+						value: 30,
+						cflags: ["-DHAS_R_APIS"],
+					}],
+					less_than: [{
+						value: 30,
+						cflags: ["-DLEGACY"],
+					}],
+					conditions_default: {
+						cflags: ["-DAPI_LEVEL_DEFAULT"],
+					},
+				},
+				features: {
+					cflags: ["-DHAS_%s"],
+				},
+				size: {
+					max_size: 0,
+					conditions_default: {
+						max_size: 64,
+					},
+				},
+				extra: {
+					cflags: ["%s"],
+				},
+			},
+		}
+	`
+
+	testCases := []struct {
+		name             string
+		vars             map[string]string
+		expectedFlags    []string
+		expectedMaxSize  int
+		expectedErrorsRE []string
+	}{
+		{
+			name: "set",
+			vars: map[string]string{
+				"api_level": "30",
+				"features":  "camera wifi",
+				"size":      "128",
+				"extra":     "-DEXTRA1 -DEXTRA2",
+			},
+			expectedFlags:   []string{"-DGENERIC", "-DEXTRA1", "-DEXTRA2", "-DAPI_LEVEL=30", "-DHAS_R_APIS", "-DHAS_camera", "-DHAS_wifi"},
+			expectedMaxSize: 128,
+		},
+		{
+			name:            "less_than",
+			vars:            map[string]string{"api_level": "29"},
+			expectedFlags:   []string{"-DGENERIC", "-DAPI_LEVEL=29", "-DLEGACY"},
+			expectedMaxSize: 64,
+		},
+		{
+			name:            "conditions_default",
+			vars:            map[string]string{},
+			expectedFlags:   []string{"-DGENERIC", "-DAPI_LEVEL_DEFAULT"},
+			expectedMaxSize: 64,
+		},
+		{
+			name: "api_level_out_of_range",
+			vars: map[string]string{"api_level": "27"},
+			expectedErrorsRE: []string{
+				`soong_config_variables.api_level: value 27 is smaller than the minimum 28`,
+			},
+		},
+		{
+			name: "size_not_an_integer",
+			vars: map[string]string{"size": "large"},
+			expectedErrorsRE: []string{
+				`soong_config_variables.size: value "large" is not an integer`,
+			},
+		},
+		{
+			name: "unknown_feature",
+			vars: map[string]string{"features": "camera bluetooth"},
+			expectedErrorsRE: []string{
+				`soong_config_variables.features: element "bluetooth" is not one of \["camera" "nfc" "wifi"\]`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := GroupFixturePreparers(
+				FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+					variables.VendorVars = map[string]map[string]string{"acme": tc.vars}
+				}),
+				FixtureRegisterWithContext(func(ctx RegistrationContext) {
+					ctx.RegisterModuleType("soong_config_module_type", soongConfigModuleTypeFactory)
+					ctx.RegisterModuleType("soong_config_int_variable", soongConfigIntVariableDummyFactory)
+					ctx.RegisterModuleType("soong_config_list_variable", soongConfigListVariableDummyFactory)
+					ctx.RegisterModuleType("test", soongConfigTestModuleFactory)
+				}),
+				FixtureWithRootAndroidBp(bp),
+			).ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern(tc.expectedErrorsRE)).
+				RunTest(t)
+
+			if len(tc.expectedErrorsRE) > 0 {
+				return
+			}
+
+			foo := result.ModuleForTests("foo", "").Module().(*soongConfigTestModule)
+			AssertDeepEquals(t, "foo cflags", tc.expectedFlags, foo.props.Cflags)
+			AssertIntEquals(t, "foo max_size", tc.expectedMaxSize, int(*foo.props.Max_size))
+		})
+	}
+}
+
 func testConfigWithVendorVars(buildDir, bp string, fs map[string][]byte, vendorVars map[string]map[string]string) Config {
 	config := TestConfig(buildDir, nil, bp, fs)
 
@@ -19,6 +19,7 @@ import (
 	"io"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/google/blueprint"
@@ -88,6 +89,10 @@ func processImportModuleDef(v *SoongConfigDefinition, def *parser.Module) (errs
 		return processStringVariableDef(v, def)
 	case "soong_config_bool_variable":
 		return processBoolVariableDef(v, def)
+	case "soong_config_int_variable":
+		return processIntVariableDef(v, def)
+	case "soong_config_list_variable":
+		return processListVariableDef(v, def)
 	default:
 		// Unknown module types will be handled when the file is parsed as a normal
 		// Android.bp file.
@@ -118,6 +123,15 @@ type ModuleTypeProperties struct {
 	// inserted into the properties with %s substitution.
 	Value_variables []string
 
+	// the list of integer SOONG_CONFIG variables that this module type will read. The value will be
+	// inserted into the properties with %d substitution, and int properties will be set to it.
+	Int_variables []string
+
+	// the list of SOONG_CONFIG variables containing space separated lists that this module type
+	// will read. Each entry of a list property that contains %s will be replaced with an entry for
+	// each element of the list.
+	List_variables []string
+
 	// the list of properties that this module type will extend.
 	Properties []string
 }
@@ -164,6 +178,19 @@ type StringVariableProperties struct {
 	Values []string
 }
 
+type IntVariableProperties struct {
+	// the smallest value that the variable can be set to.
+	Min *int64
+
+	// the largest value that the variable can be set to.
+	Max *int64
+}
+
+type ListVariableProperties struct {
+	// the values that the elements of the list can have.  Defaults to any value.
+	Values []string
+}
+
 func processStringVariableDef(v *SoongConfigDefinition, def *parser.Module) (errs []error) {
 	stringProps := &StringVariableProperties{}
 
@@ -203,6 +230,44 @@ func processBoolVariableDef(v *SoongConfigDefinition, def *parser.Module) (errs
 	return nil
 }
 
+func processIntVariableDef(v *SoongConfigDefinition, def *parser.Module) (errs []error) {
+	intProps := &IntVariableProperties{}
+
+	base, errs := processVariableDef(def, intProps)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if intProps.Min != nil && intProps.Max != nil && *intProps.Min > *intProps.Max {
+		return []error{fmt.Errorf("soong_config_int_variable: min %d is larger than max %d",
+			*intProps.Min, *intProps.Max)}
+	}
+
+	v.variables[base.variable] = &intVariable{
+		baseVariable: base,
+		min:          intProps.Min,
+		max:          intProps.Max,
+	}
+
+	return nil
+}
+
+func processListVariableDef(v *SoongConfigDefinition, def *parser.Module) (errs []error) {
+	listProps := &ListVariableProperties{}
+
+	base, errs := processVariableDef(def, listProps)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	v.variables[base.variable] = &listVariable{
+		baseVariable: base,
+		values:       listProps.Values,
+	}
+
+	return nil
+}
+
 func processVariableDef(def *parser.Module,
 	extraProps ...interface{}) (cond baseVariable, errs []error) {
 
@@ -463,6 +528,30 @@ func newModuleType(props *ModuleTypeProperties) (*ModuleType, []error) {
 		})
 	}
 
+	for _, name := range props.Int_variables {
+		if err := checkVariableName(name); err != nil {
+			return nil, []error{fmt.Errorf("int_variables %s", err)}
+		}
+
+		mt.Variables = append(mt.Variables, &intVariable{
+			baseVariable: baseVariable{
+				variable: name,
+			},
+		})
+	}
+
+	for _, name := range props.List_variables {
+		if err := checkVariableName(name); err != nil {
+			return nil, []error{fmt.Errorf("list_variables %s", err)}
+		}
+
+		mt.Variables = append(mt.Variables, &listVariable{
+			baseVariable: baseVariable{
+				variable: name,
+			},
+		})
+	}
+
 	return mt, nil
 }
 
@@ -532,7 +621,14 @@ func (s *stringVariable) initializeProperties(v reflect.Value, typ reflect.Type)
 
 // Extracts an interface from values containing the properties to apply based on config.
 // If config does not match a value with a non-nil property set, the default value will be returned.
+// Returns an error if config sets the variable to a value that is not one of the values of the
+// variable.
 func (s *stringVariable) PropertiesToApply(config SoongConfig, values reflect.Value) (interface{}, error) {
+	if configValue := config.String(s.variable); configValue != "" &&
+		!inList(CanonicalizeToProperty(configValue), s.values) {
+		return nil, fmt.Errorf("soong_config_variables.%s: value %q is not one of %q",
+			s.variable, configValue, s.values)
+	}
 	for j, v := range s.values {
 		f := values.Field(j)
 		if config.String(s.variable) == v && !f.Elem().IsNil() {
@@ -570,15 +666,16 @@ func (b boolVariable) initializeProperties(v reflect.Value, typ reflect.Type) {
 }
 
 // initializePropertiesWithDefault, initialize with zero value,  v to contain a field for each field
-// in typ, with an additional field for defaults of type typ. This should be used to initialize
-// boolVariable, valueVariable, or any future implementations of soongConfigVariable which support
-// one variable and a default.
-func initializePropertiesWithDefault(v reflect.Value, typ reflect.Type) {
+// in typ, followed by extraFields, with an additional field for defaults of type typ. This should be
+// used to initialize boolVariable, valueVariable, or any future implementations of
+// soongConfigVariable which support one variable and a default.
+func initializePropertiesWithDefault(v reflect.Value, typ reflect.Type, extraFields ...reflect.StructField) {
 	sTyp := typ.Elem()
 	var fields []reflect.StructField
 	for i := 0; i < sTyp.NumField(); i++ {
 		fields = append(fields, sTyp.Field(i))
 	}
+	fields = append(fields, extraFields...)
 
 	// create conditions_default field
 	nestedFieldName := proptools.FieldNameForProperty(conditionsDefault)
@@ -705,6 +802,12 @@ func (s *valueVariable) PropertiesToApply(config SoongConfig, values reflect.Val
 }
 
 func printfIntoProperty(propertyValue reflect.Value, configValue string) error {
+	return printfVariableIntoProperty(propertyValue, "value", "%s", configValue)
+}
+
+// printfVariableIntoProperty substitutes the value of a variable into a string property that
+// contains the verb.
+func printfVariableIntoProperty(propertyValue reflect.Value, kind, verb string, configValue interface{}) error {
 	s := propertyValue.String()
 
 	count := strings.Count(s, "%")
@@ -713,11 +816,11 @@ func printfIntoProperty(propertyValue reflect.Value, configValue string) error {
 	}
 
 	if count > 1 {
-		return fmt.Errorf("value variable properties only support a single '%%'")
+		return fmt.Errorf("%s variable properties only support a single '%%'", kind)
 	}
 
-	if !strings.Contains(s, "%s") {
-		return fmt.Errorf("unsupported %% in value variable property")
+	if !strings.Contains(s, verb) {
+		return fmt.Errorf("unsupported %% in %s variable property", kind)
 	}
 
 	propertyValue.Set(reflect.ValueOf(fmt.Sprintf(s, configValue)))
@@ -725,6 +828,251 @@ func printfIntoProperty(propertyValue reflect.Value, configValue string) error {
 	return nil
 }
 
+// Struct to allow conditions set based on an integer variable, supporting %d substitution, setting
+// int properties to the value, and comparisons of the value in at_least and less_than blocks.
+type intVariable struct {
+	baseVariable
+	min, max *int64
+}
+
+const (
+	atLeast  = "at_least"
+	lessThan = "less_than"
+)
+
+func (s *intVariable) variableValuesType() reflect.Type {
+	return emptyInterfaceType
+}
+
+// initializeProperties initializes a property to zero value of typ with additional at_least and
+// less_than lists of comparison blocks, and a conditions default field.
+func (s *intVariable) initializeProperties(v reflect.Value, typ reflect.Type) {
+	// A comparison block is the value to compare with followed by the affectable properties.
+	fields := []reflect.StructField{{
+		Name: "Value",
+		Type: reflect.TypeOf((*int64)(nil)),
+	}}
+	for i := 0; i < typ.Elem().NumField(); i++ {
+		fields = append(fields, typ.Elem().Field(i))
+	}
+	comparisonType := reflect.SliceOf(reflect.StructOf(fields))
+
+	initializePropertiesWithDefault(v, typ,
+		reflect.StructField{Name: proptools.FieldNameForProperty(atLeast), Type: comparisonType},
+		reflect.StructField{Name: proptools.FieldNameForProperty(lessThan), Type: comparisonType})
+}
+
+// value returns the value of the variable and whether it was set, or an error if it was set to a
+// value that is not an integer in the range of the variable.
+func (s *intVariable) value(config SoongConfig) (int64, bool, error) {
+	configValue := config.String(s.variable)
+	if configValue == "" {
+		return 0, false, nil
+	}
+	value, err := strconv.ParseInt(configValue, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("soong_config_variables.%s: value %q is not an integer", s.variable, configValue)
+	}
+	if s.min != nil && value < *s.min {
+		return 0, false, fmt.Errorf("soong_config_variables.%s: value %d is smaller than the minimum %d", s.variable, value, *s.min)
+	}
+	if s.max != nil && value > *s.max {
+		return 0, false, fmt.Errorf("soong_config_variables.%s: value %d is larger than the maximum %d", s.variable, value, *s.max)
+	}
+	return value, true, nil
+}
+
+// PropertiesToApply returns an interface{} value based on initializeProperties to be applied to
+// the module. If the variable was not set, conditions_default interface will be returned;
+// otherwise, the interface in values, with %d substituted and int properties set to the value,
+// extended with the at_least and less_than blocks that match the value, in order, will be
+// returned.
+func (s *intVariable) PropertiesToApply(config SoongConfig, values reflect.Value) (interface{}, error) {
+	value, set, err := s.value(config)
+	if err != nil {
+		return nil, err
+	}
+	// If this variable was not referenced in the module, there are no properties to apply.
+	if !values.IsValid() || values.Elem().IsZero() {
+		return nil, nil
+	}
+	if !set {
+		return conditionsDefaultField(values.Elem().Elem()).Interface(), nil
+	}
+
+	v := values.Elem().Elem()
+	values = removeDefault(values)
+	if err := s.substitute(values.Elem(), value, true); err != nil {
+		return nil, err
+	}
+
+	applyComparisons := func(property string, matches func(int64) bool) error {
+		comparisons := v.FieldByName(proptools.FieldNameForProperty(property))
+		for i := 0; i < comparisons.Len(); i++ {
+			comparison := comparisons.Index(i)
+			than := comparison.Field(0)
+			if than.IsNil() {
+				return fmt.Errorf("soong_config_variables.%s.%s[%d]: value must be set", s.variable, property, i)
+			}
+			if !matches(than.Elem().Int()) {
+				continue
+			}
+			props := reflect.New(values.Type().Elem())
+			for j := 0; j < props.Elem().NumField(); j++ {
+				props.Elem().Field(j).Set(comparison.Field(j + 1))
+			}
+			// Int properties of comparison blocks are constants, only strings are substituted.
+			if err := s.substitute(props.Elem(), value, false); err != nil {
+				return err
+			}
+			if err := proptools.AppendProperties(values.Interface(), props.Interface(), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := applyComparisons(atLeast, func(than int64) bool { return value >= than }); err != nil {
+		return nil, err
+	}
+	if err := applyComparisons(lessThan, func(than int64) bool { return value < than }); err != nil {
+		return nil, err
+	}
+
+	return values.Interface(), nil
+}
+
+// substitute substitutes the value into the %d of the string properties of propStruct, and sets
+// its int properties to the value if setInts is true.
+func (s *intVariable) substitute(propStruct reflect.Value, value int64, setInts bool) error {
+	for i := 0; i < propStruct.NumField(); i++ {
+		field := propStruct.Field(i)
+		kind := field.Kind()
+		if kind == reflect.Ptr {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+			kind = field.Kind()
+		}
+		var err error
+		switch kind {
+		case reflect.String:
+			err = printfVariableIntoProperty(field, "int", "%d", value)
+		case reflect.Slice:
+			for j := 0; j < field.Len() && err == nil; j++ {
+				err = printfVariableIntoProperty(field.Index(j), "int", "%d", value)
+			}
+		case reflect.Int64:
+			if setInts {
+				field.SetInt(value)
+			}
+		case reflect.Bool:
+			// Nothing to do
+		default:
+			err = fmt.Errorf("unsupported property type %q", kind)
+		}
+		if err != nil {
+			return fmt.Errorf("soong_config_variables.%s.%s: %s", s.variable, propStruct.Type().Field(i).Name, err)
+		}
+	}
+	return nil
+}
+
+// Struct to allow conditions set based on a variable containing a space separated list, supporting
+// expansion of list property entries for each element of the list.
+type listVariable struct {
+	baseVariable
+	values []string
+}
+
+func (s *listVariable) variableValuesType() reflect.Type {
+	return emptyInterfaceType
+}
+
+// initializeProperties initializes a property to zero value of typ with an additional conditions
+// default field.
+func (s *listVariable) initializeProperties(v reflect.Value, typ reflect.Type) {
+	initializePropertiesWithDefault(v, typ)
+}
+
+// PropertiesToApply returns an interface{} value based on initializeProperties to be applied to
+// the module. If the variable was not set or is empty, conditions_default interface will be
+// returned; otherwise, the interface in values, without conditions_default, will be returned with
+// the entries of list properties that contain %s replaced with an entry for each element of the
+// list, and the space separated list substituted into the string properties.
+func (s *listVariable) PropertiesToApply(config SoongConfig, values reflect.Value) (interface{}, error) {
+	list := strings.Fields(config.String(s.variable))
+	if len(s.values) > 0 {
+		for _, element := range list {
+			if !inList(element, s.values) {
+				return nil, fmt.Errorf("soong_config_variables.%s: element %q is not one of %q", s.variable, element, s.values)
+			}
+		}
+	}
+	// If this variable was not referenced in the module, there are no properties to apply.
+	if !values.IsValid() || values.Elem().IsZero() {
+		return nil, nil
+	}
+	if len(list) == 0 {
+		return conditionsDefaultField(values.Elem().Elem()).Interface(), nil
+	}
+
+	values = removeDefault(values)
+	propStruct := values.Elem()
+	for i := 0; i < propStruct.NumField(); i++ {
+		field := propStruct.Field(i)
+		kind := field.Kind()
+		if kind == reflect.Ptr {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+			kind = field.Kind()
+		}
+		var err error
+		switch kind {
+		case reflect.String:
+			err = printfVariableIntoProperty(field, "list", "%s", strings.Join(list, " "))
+		case reflect.Slice:
+			var expanded []string
+			for j := 0; j < field.Len() && err == nil; j++ {
+				entry := field.Index(j).String()
+				if !strings.Contains(entry, "%") {
+					expanded = append(expanded, entry)
+					continue
+				}
+				for _, element := range list {
+					e := reflect.New(field.Type().Elem()).Elem()
+					e.SetString(entry)
+					if err = printfVariableIntoProperty(e, "list", "%s", element); err != nil {
+						break
+					}
+					expanded = append(expanded, e.String())
+				}
+			}
+			field.Set(reflect.ValueOf(expanded))
+		case reflect.Bool:
+			// Nothing to do
+		default:
+			err = fmt.Errorf("unsupported property type %q", kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("soong_config_variables.%s.%s: %s", s.variable, propStruct.Type().Field(i).Name, err)
+		}
+	}
+
+	return values.Interface(), nil
+}
+
+func inList(s string, list []string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
 func CanonicalizeToProperty(v string) string {
 	return strings.Map(func(r rune) rune {
 		switch {
@@ -364,3 +364,206 @@ func Test_PropertiesToApply(t *testing.T) {
 		}
 	}
 }
+
+type intProperties struct {
+	A *string
+	B []string
+	C *int64
+}
+
+type intVarComparison struct {
+	Value *int64
+	A     *string
+	B     []string
+	C     *int64
+}
+
+type intVarProps struct {
+	A                  *string
+	B                  []string
+	C                  *int64
+	At_least           []intVarComparison
+	Less_than          []intVarComparison
+	Conditions_default *intProperties
+}
+
+func Test_intVariablePropertiesToApply(t *testing.T) {
+	newProps := func() reflect.Value {
+		return reflect.ValueOf(&struct{ Int_var interface{} }{
+			Int_var: &intVarProps{
+				A: proptools.StringPtr("size=%d"),
+				B: []string{"-DSIZE=%d"},
+				C: proptools.Int64Ptr(0),
+				At_least: []intVarComparison{
+					{Value: proptools.Int64Ptr(10), B: []string{"-DLARGE=%d"}},
+					{Value: proptools.Int64Ptr(20), C: proptools.Int64Ptr(20)},
+				},
+				Less_than: []intVarComparison{
+					{Value: proptools.Int64Ptr(10), B: []string{"-DSMALL"}},
+				},
+				Conditions_default: &intProperties{
+					B: []string{"-DSIZE=DEFAULT"},
+				},
+			},
+		}).Elem().Field(0)
+	}
+
+	v := &intVariable{
+		baseVariable: baseVariable{variable: "int_var"},
+		min:          proptools.Int64Ptr(1),
+		max:          proptools.Int64Ptr(100),
+	}
+
+	testCases := []struct {
+		name      string
+		config    SoongConfig
+		wantProps interface{}
+		wantErr   string
+	}{
+		{
+			name:   "unset",
+			config: Config(map[string]string{}),
+			wantProps: &intProperties{
+				B: []string{"-DSIZE=DEFAULT"},
+			},
+		},
+		{
+			name:   "less_than",
+			config: Config(map[string]string{"int_var": "5"}),
+			wantProps: &intProperties{
+				A: proptools.StringPtr("size=5"),
+				B: []string{"-DSIZE=5", "-DSMALL"},
+				C: proptools.Int64Ptr(5),
+			},
+		},
+		{
+			name:   "at_least",
+			config: Config(map[string]string{"int_var": "42"}),
+			wantProps: &intProperties{
+				A: proptools.StringPtr("size=42"),
+				B: []string{"-DSIZE=42", "-DLARGE=42"},
+				C: proptools.Int64Ptr(20),
+			},
+		},
+		{
+			name:    "not_an_integer",
+			config:  Config(map[string]string{"int_var": "large"}),
+			wantErr: `soong_config_variables.int_var: value "large" is not an integer`,
+		},
+		{
+			name:    "out_of_range",
+			config:  Config(map[string]string{"int_var": "101"}),
+			wantErr: `soong_config_variables.int_var: value 101 is larger than the maximum 100`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotProps, err := v.PropertiesToApply(tc.config, newProps())
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("expected error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error in PropertiesToApply: %s", err)
+			}
+			if !reflect.DeepEqual(gotProps, tc.wantProps) {
+				t.Errorf("Expected %#v, got %#v", tc.wantProps, gotProps)
+			}
+		})
+	}
+}
+
+type listProperties struct {
+	A *string
+	B []string
+}
+
+type listVarProps struct {
+	A                  *string
+	B                  []string
+	Conditions_default *listProperties
+}
+
+func Test_listVariablePropertiesToApply(t *testing.T) {
+	newProps := func() reflect.Value {
+		return reflect.ValueOf(&struct{ List_var interface{} }{
+			List_var: &listVarProps{
+				A: proptools.StringPtr("features: %s"),
+				B: []string{"-DGENERIC", "-DFEATURE_%s"},
+				Conditions_default: &listProperties{
+					B: []string{"-DNO_FEATURES"},
+				},
+			},
+		}).Elem().Field(0)
+	}
+
+	v := &listVariable{
+		baseVariable: baseVariable{variable: "list_var"},
+		values:       []string{"a", "b", "c"},
+	}
+
+	testCases := []struct {
+		name      string
+		config    SoongConfig
+		wantProps interface{}
+		wantErr   string
+	}{
+		{
+			name:   "empty",
+			config: Config(map[string]string{"list_var": ""}),
+			wantProps: &listProperties{
+				B: []string{"-DNO_FEATURES"},
+			},
+		},
+		{
+			name:   "list",
+			config: Config(map[string]string{"list_var": "a  c"}),
+			wantProps: &listProperties{
+				A: proptools.StringPtr("features: a c"),
+				B: []string{"-DGENERIC", "-DFEATURE_a", "-DFEATURE_c"},
+			},
+		},
+		{
+			name:    "unknown_element",
+			config:  Config(map[string]string{"list_var": "a d"}),
+			wantErr: `soong_config_variables.list_var: element "d" is not one of ["a" "b" "c"]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotProps, err := v.PropertiesToApply(tc.config, newProps())
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Errorf("expected error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error in PropertiesToApply: %s", err)
+			}
+			if !reflect.DeepEqual(gotProps, tc.wantProps) {
+				t.Errorf("Expected %#v, got %#v", tc.wantProps, gotProps)
+			}
+		})
+	}
+}
+
+func Test_stringVariableValidation(t *testing.T) {
+	v := &stringVariable{
+		baseVariable: baseVariable{variable: "board"},
+		values:       []string{"soc_a", "soc_b"},
+	}
+	values := reflect.ValueOf(struct {
+		Soc_a, Soc_b, Conditions_default interface{}
+	}{})
+
+	_, err := v.PropertiesToApply(Config(map[string]string{"board": "soc_c"}), values)
+	want := `soong_config_variables.board: value "soc_c" is not one of ["soc_a" "soc_b"]`
+	if err == nil || err.Error() != want {
+		t.Errorf("expected error %q, got %v", want, err)
+	}
+}
@@ -74,6 +74,18 @@ var PrepareForTestWithOverrides = FixtureRegisterWithContext(func(ctx Registrati
 	ctx.PostDepsMutators(RegisterOverridePostDepsMutators)
 })
 
+// Test fixture preparer that registers the license and license_kind module types and the mutators
+// that apply licenses to modules.
+var PrepareForTestWithLicenses = GroupFixturePreparers(
+	FixtureRegisterWithContext(RegisterLicenseKindBuildComponents),
+	FixtureRegisterWithContext(RegisterLicenseBuildComponents),
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.PreArchMutators(RegisterLicensesPackageMapper)
+		ctx.PreArchMutators(RegisterLicensesPropertyGatherer)
+		ctx.PostDepsMutators(RegisterLicensesDependencyChecker)
+	}),
+)
+
 // Test fixture preparer that will register most java build components.
 //
 // Singletons and mutators should only be added here if they are needed for a majority of java
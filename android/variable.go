@@ -349,6 +349,8 @@ type productVariables struct {
 	EnforceSystemCertificate          *bool    `json:",omitempty"`
 	EnforceSystemCertificateAllowList []string `json:",omitempty"`
 
+	EnforceLicenseKindExceptions *bool `json:",omitempty"`
+
 	ProductHiddenAPIStubs       []string `json:",omitempty"`
 	ProductHiddenAPIStubsSystem []string `json:",omitempty"`
 	ProductHiddenAPIStubsTest   []string `json:",omitempty"`
@@ -378,6 +380,10 @@ type productVariables struct {
 
 	PrebuiltHiddenApiDir *string `json:",omitempty"`
 
+	PrebuiltSelectionFile *string `json:",omitempty"`
+
+	ProductPackages []string `json:",omitempty"`
+
 	ShippingApiLevel *string `json:",omitempty"`
 
 	BuildBrokenEnforceSyspropOwner     bool `json:",omitempty"`
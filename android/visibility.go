@@ -44,7 +44,8 @@ import (
 //   the same package then it is automatically visible. Otherwise, for each dep it first extracts
 //   its visibilityRule from the config map. If one could not be found then it assumes that it is
 //   publicly visible. Otherwise, it calls the visibility rule to check that the module can see
-//   the dependency. If it cannot then an error is reported.
+//   the dependency. If it cannot then an error is reported, or the violation is recorded if
+//   SOONG_REPORT_POLICY_VIOLATIONS is set, see policy_violations.go.
 //
 // TODO(b/130631145) - Make visibility work properly with prebuilts.
 // TODO(b/130796911) - Make visibility work properly with defaults.
@@ -242,6 +243,10 @@ func visibilityRuleChecker(ctx BottomUpMutatorContext) {
 				checkRules(ctx, qualified.pkg, p.getName(), visibility)
 			}
 		}
+
+		if explainVisibilityEnabled(ctx.Config()) {
+			recordVisibilityProperties(ctx, m)
+		}
 	}
 }
 
@@ -321,6 +326,10 @@ func visibilityRuleGatherer(ctx BottomUpMutatorContext) {
 			}
 		}
 	}
+
+	if explainVisibilityEnabled(ctx.Config()) {
+		recordVisibilityDefaults(ctx, m)
+	}
 }
 
 func parseRules(ctx BaseModuleContext, currentPkg, property string, visibility []string) compositeRule {
@@ -456,6 +465,20 @@ func visibilityRuleEnforcer(ctx TopDownMutatorContext) {
 
 		rule := effectiveVisibilityRules(ctx.Config(), depQualified)
 		if !rule.matches(qualified) {
+			if ctx.Config().ReportPolicyViolations() {
+				getPolicyViolations(ctx.Config()).add(policyViolation{
+					Kind:                    "visibility",
+					Rule:                    "visibility of " + depQualified.String(),
+					Module:                  qualified.String(),
+					Values:                  rule.Strings(),
+					SuggestedAllowlistEntry: "//" + ctx.ModuleDir(),
+				})
+				return
+			}
+			if explainVisibilityEnabled(ctx.Config()) {
+				// The violation is not an error when explaining visibility, see visibility_explain.go.
+				return
+			}
 			ctx.ModuleErrorf("depends on %s which is not visible to this module\nYou may need to add %q to its visibility", depQualified, "//"+ctx.ModuleDir())
 		}
 	})
@@ -494,16 +517,23 @@ func createQualifiedModuleName(ctx BaseModuleContext) qualifiedModuleName {
 }
 
 func packageDefaultVisibility(config Config, moduleId qualifiedModuleName) compositeRule {
+	rule, _ := findPackageDefaultVisibility(config, moduleId)
+	return rule
+}
+
+// findPackageDefaultVisibility returns the default_visibility of the closest package that sets it
+// and the id of the package.
+func findPackageDefaultVisibility(config Config, moduleId qualifiedModuleName) (compositeRule, qualifiedModuleName) {
 	moduleToVisibilityRule := moduleToVisibilityRuleMap(config)
 	packageQualifiedId := moduleId.getContainingPackageId()
 	for {
 		value, ok := moduleToVisibilityRule.Load(packageQualifiedId)
 		if ok {
-			return value.(compositeRule)
+			return value.(compositeRule), packageQualifiedId
 		}
 
 		if packageQualifiedId.isRootPackage() {
-			return nil
+			return nil, packageQualifiedId
 		}
 
 		packageQualifiedId = packageQualifiedId.getContainingPackageId()
@@ -0,0 +1,272 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Explains the visibility of a module to another module.
+//
+// When SOONG_EXPLAIN_VISIBILITY is set to the qualified names of two modules, e.g.
+// "//foo:libfoo //bar:libbar", soong_build prints whether the first module can depend on the
+// second one instead of building.  The explanation lists the rules that make up the visibility of
+// the second module in the order that they are applied, where each one came from, i.e. the
+// visibility property of the module, of one of its defaults modules or the default_visibility of
+// a package, the rules that were discarded by //visibility:override and the rules that match the
+// first module.
+//
+// The visibility properties are flattened by the defaults mutator, so the properties of each
+// module are recorded by the visibility rule checker before the defaults are applied, and the
+// defaults modules that were applied are recorded by the visibility rule gatherer.  Visibility
+// violations are not reported in this mode so that rejected dependencies can be explained.
+
+var visibilitySourcesMapKey = NewOnceKey("visibilitySourcesMap")
+
+// visibilitySources are the properties that the effective visibility of a module is computed from.
+type visibilitySources struct {
+	// The name of the property that controls the visibility of the module.
+	primaryProperty string
+
+	// The visibility properties of the module before the defaults were applied, by name.
+	properties map[string][]string
+
+	// The defaults modules of the module, in the order that they were applied.
+	defaults []qualifiedModuleName
+}
+
+// The map from qualifiedModuleName to *visibilitySources.
+func moduleToVisibilitySourcesMap(config Config) *sync.Map {
+	return config.Once(visibilitySourcesMapKey, func() interface{} {
+		return &sync.Map{}
+	}).(*sync.Map)
+}
+
+func explainVisibilityEnabled(config Config) bool {
+	return config.ExplainVisibilityQuery() != ""
+}
+
+// recordVisibilityProperties records the visibility properties of the module before the defaults
+// are applied.
+func recordVisibilityProperties(ctx BaseModuleContext, m Module) {
+	sources := &visibilitySources{properties: make(map[string][]string)}
+	if p := m.base().primaryVisibilityProperty; p != nil {
+		sources.primaryProperty = p.getName()
+	}
+	for _, p := range m.visibilityProperties() {
+		sources.properties[p.getName()] = p.getStrings()
+	}
+	moduleToVisibilitySourcesMap(ctx.Config()).Store(m.qualifiedModuleId(ctx), sources)
+}
+
+// recordVisibilityDefaults records the defaults modules that were applied to the module, in the
+// same order as the defaults mutator.
+func recordVisibilityDefaults(ctx BottomUpMutatorContext, m Module) {
+	id := m.qualifiedModuleId(ctx)
+	value, ok := moduleToVisibilitySourcesMap(ctx.Config()).Load(id)
+	if !ok {
+		// The module was created after the visibility properties were checked, so defaults could not
+		// be applied to it and its properties are the ones it was created with.
+		recordVisibilityProperties(ctx, m)
+		return
+	}
+	sources := value.(*visibilitySources)
+
+	seen := make(map[Module]bool)
+	ctx.WalkDeps(func(child, parent Module) bool {
+		if ctx.OtherModuleDependencyTag(child) != DefaultsDepTag {
+			return false
+		}
+		defaults, ok := child.(Defaults)
+		if !ok || seen[child] {
+			return false
+		}
+		seen[child] = true
+		sources.defaults = append(sources.defaults,
+			qualifiedModuleName{ctx.OtherModuleDir(child), ctx.OtherModuleName(child)})
+		return len(defaults.defaults().Defaults) > 0
+	})
+}
+
+// explainedRule is a visibility rule and the property that it came from.
+type explainedRule struct {
+	expression string
+	source     string
+}
+
+// ExplainVisibility returns the explanation of the visibility of the second module of the
+// SOONG_EXPLAIN_VISIBILITY query to the first one.
+func ExplainVisibility(config Config) (string, error) {
+	query := strings.Fields(config.ExplainVisibilityQuery())
+	if len(query) != 2 {
+		return "", fmt.Errorf("SOONG_EXPLAIN_VISIBILITY must be set to two modules, e.g. %q, got %q",
+			"//foo:libfoo //bar:libbar", config.ExplainVisibilityQuery())
+	}
+
+	var modules [2]qualifiedModuleName
+	for i, q := range query {
+		module, err := parseQualifiedModuleName(q)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := moduleToVisibilitySourcesMap(config).Load(module); !ok {
+			return "", fmt.Errorf("SOONG_EXPLAIN_VISIBILITY: unknown module %s", module)
+		}
+		modules[i] = module
+	}
+
+	return explainVisibility(config, modules[0], modules[1]), nil
+}
+
+// parseQualifiedModuleName parses a qualified module name, e.g. //foo:bar.
+func parseQualifiedModuleName(s string) (qualifiedModuleName, error) {
+	i := strings.LastIndex(s, ":")
+	if !strings.HasPrefix(s, "//") || i == -1 || i == len(s)-1 {
+		return qualifiedModuleName{}, fmt.Errorf("invalid module %q, expected //<package>:<name>", s)
+	}
+	pkg := s[2:i]
+	if pkg == "" {
+		// The directory of the modules in the root directory is ".".
+		pkg = "."
+	}
+	return qualifiedModuleName{pkg: pkg, name: s[i+1:]}, nil
+}
+
+func explainVisibility(config Config, module, dep qualifiedModuleName) string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "Visibility of %s to %s:\n", dep, module)
+
+	explainRules := func(rules []explainedRule) {
+		for _, r := range rules {
+			fmt.Fprintf(sb, "  %q from %s", r.expression, r.source)
+			if rule := explainVisibilityRule(dep.pkg, r.expression); rule != nil {
+				if rule.matches(module) {
+					fmt.Fprintf(sb, ": matches %s", module)
+				} else {
+					fmt.Fprint(sb, ": does not match")
+				}
+			}
+			fmt.Fprintln(sb)
+		}
+	}
+
+	if _, ok := moduleToVisibilityRuleMap(config).Load(dep); ok {
+		value, _ := moduleToVisibilitySourcesMap(config).Load(dep)
+		sources := value.(*visibilitySources)
+
+		// Each defaults module is prepended to the properties, so the rules of the last one come
+		// first.
+		var rules []explainedRule
+		for i := len(sources.defaults) - 1; i >= 0; i-- {
+			d := sources.defaults[i]
+			var visibility []string
+			if value, ok := moduleToVisibilitySourcesMap(config).Load(d); ok {
+				visibility = value.(*visibilitySources).properties["visibility"]
+			}
+			for _, v := range visibility {
+				rules = append(rules, explainedRule{v, fmt.Sprintf("the visibility of defaults %s", d)})
+			}
+		}
+		for _, v := range sources.properties[sources.primaryProperty] {
+			rules = append(rules, explainedRule{v, fmt.Sprintf("the %s of %s", sources.primaryProperty, dep)})
+		}
+
+		// //visibility:override discards all of the rules before it.
+		for i := len(rules) - 1; i >= 0; i-- {
+			if rules[i].expression == "//visibility:override" {
+				for _, r := range rules[:i] {
+					fmt.Fprintf(sb, "  %q from %s: discarded by %q from %s\n",
+						r.expression, r.source, rules[i].expression, rules[i].source)
+				}
+				rules = rules[i:]
+				break
+			}
+		}
+		explainRules(rules)
+	} else if rule, pkg := findPackageDefaultVisibility(config, dep); rule != nil {
+		fmt.Fprintf(sb, "  The %s of %s is not set, using the default_visibility of package %s\n",
+			primaryVisibilityPropertyName(config, dep), dep, pkg)
+		var rules []explainedRule
+		for _, r := range rule.Strings() {
+			rules = append(rules, explainedRule{r, fmt.Sprintf("the default_visibility of package %s", pkg)})
+		}
+		explainRules(rules)
+	} else {
+		fmt.Fprintf(sb, "  The %s of %s and the default_visibility of its packages are not set, "+
+			"using %q\n", primaryVisibilityPropertyName(config, dep), dep, defaultVisibility.Strings()[0])
+	}
+
+	switch {
+	case module.pkg == dep.pkg:
+		fmt.Fprintf(sb, "%s can see %s because they are in the same package.\n", module, dep)
+	case effectiveVisibilityRules(config, dep).matches(module):
+		fmt.Fprintf(sb, "%s can see %s.\n", module, dep)
+	default:
+		fmt.Fprintf(sb, "%s cannot see %s, you may need to add %q to its visibility.\n",
+			module, dep, "//"+module.pkg)
+	}
+
+	return sb.String()
+}
+
+// primaryVisibilityPropertyName returns the name of the property that controls the visibility of
+// the module.
+func primaryVisibilityPropertyName(config Config, q qualifiedModuleName) string {
+	if value, ok := moduleToVisibilitySourcesMap(config).Load(q); ok {
+		if name := value.(*visibilitySources).primaryProperty; name != "" {
+			return name
+		}
+	}
+	return "visibility"
+}
+
+// explainVisibilityRule returns the visibility rule of a valid rule expression, or nil for
+// //visibility:override and invalid expressions, which have already been reported by the
+// visibility rule checker.
+func explainVisibilityRule(currentPkg, expression string) visibilityRule {
+	matches := visibilityRuleRegexp.FindStringSubmatch(expression)
+	if expression == "" || matches == nil {
+		return nil
+	}
+
+	pkg := matches[1]
+	name := matches[2]
+	if pkg == "" {
+		pkg = currentPkg
+	}
+	if name == "" {
+		name = "__pkg__"
+	}
+
+	if pkg == "visibility" {
+		switch name {
+		case "public":
+			return publicRule{}
+		case "private":
+			return privateRule{}
+		}
+		return nil
+	}
+
+	switch name {
+	case "__pkg__":
+		return packageRule{pkg}
+	case "__subpackages__":
+		return subpackagesRule{pkg}
+	}
+	return nil
+}
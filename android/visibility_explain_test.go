@@ -0,0 +1,144 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+func TestExplainVisibility(t *testing.T) {
+	fs := MockFS{
+		"lib/Android.bp": []byte(`
+			mock_defaults {
+				name: "inner_defaults",
+				visibility: ["//c"],
+			}
+
+			mock_defaults {
+				name: "outer_defaults",
+				defaults: ["inner_defaults"],
+				visibility: ["//a:__subpackages__"],
+			}
+
+			mock_library {
+				name: "libbar",
+				defaults: ["outer_defaults"],
+				visibility: [":__pkg__"],
+			}
+
+			mock_library {
+				name: "liboverride",
+				defaults: ["outer_defaults"],
+				visibility: ["//visibility:override", "//b"],
+			}`),
+		"pkg/Android.bp": []byte(`
+			package {
+				default_visibility: ["//b:__subpackages__"],
+			}
+
+			mock_library {
+				name: "libpkg",
+			}`),
+		"a/x/Android.bp": []byte(`
+			mock_library {
+				name: "libfoo",
+				// liboverride is not visible, which is not an error when explaining visibility.
+				deps: ["libbar", "liboverride"],
+			}`),
+		"b/Android.bp": []byte(`
+			mock_library {
+				name: "libb",
+			}`),
+		"b/y/Android.bp": []byte(`
+			mock_library {
+				name: "liby",
+			}`),
+	}
+
+	result := GroupFixturePreparers(
+		PrepareForTestWithArchMutator,
+		PrepareForTestWithDefaults,
+		PrepareForTestWithPackageModule,
+		PrepareForTestWithVisibility,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("mock_library", newMockLibraryModule)
+			ctx.RegisterModuleType("mock_defaults", defaultsFactory)
+		}),
+		FixtureMergeEnv(map[string]string{
+			"SOONG_EXPLAIN_VISIBILITY": "//a/x:libfoo //lib:libbar",
+		}),
+		fs.AddToFixture(),
+	).RunTest(t)
+
+	explanation, err := ExplainVisibility(result.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertStringEquals(t, "defaults", `Visibility of //lib:libbar to //a/x:libfoo:
+  "//c" from the visibility of defaults //lib:inner_defaults: does not match
+  "//a:__subpackages__" from the visibility of defaults //lib:outer_defaults: matches //a/x:libfoo
+  ":__pkg__" from the visibility of //lib:libbar: does not match
+//a/x:libfoo can see //lib:libbar.
+`, explanation)
+
+	explain := func(module, dep string) string {
+		t.Helper()
+		moduleName, err := parseQualifiedModuleName(module)
+		if err != nil {
+			t.Fatal(err)
+		}
+		depName, err := parseQualifiedModuleName(dep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return explainVisibility(result.Config, moduleName, depName)
+	}
+
+	AssertStringEquals(t, "override", `Visibility of //lib:liboverride to //a/x:libfoo:
+  "//c" from the visibility of defaults //lib:inner_defaults: discarded by "//visibility:override" from the visibility of //lib:liboverride
+  "//a:__subpackages__" from the visibility of defaults //lib:outer_defaults: discarded by "//visibility:override" from the visibility of //lib:liboverride
+  "//visibility:override" from the visibility of //lib:liboverride
+  "//b" from the visibility of //lib:liboverride: does not match
+//a/x:libfoo cannot see //lib:liboverride, you may need to add "//a/x" to its visibility.
+`, explain("//a/x:libfoo", "//lib:liboverride"))
+
+	AssertStringEquals(t, "same package", `Visibility of //lib:liboverride to //lib:libbar:
+  "//c" from the visibility of defaults //lib:inner_defaults: discarded by "//visibility:override" from the visibility of //lib:liboverride
+  "//a:__subpackages__" from the visibility of defaults //lib:outer_defaults: discarded by "//visibility:override" from the visibility of //lib:liboverride
+  "//visibility:override" from the visibility of //lib:liboverride
+  "//b" from the visibility of //lib:liboverride: does not match
+//lib:libbar can see //lib:liboverride because they are in the same package.
+`, explain("//lib:libbar", "//lib:liboverride"))
+
+	AssertStringEquals(t, "package default_visibility", `Visibility of //pkg:libpkg to //b/y:liby:
+  The visibility of //pkg:libpkg is not set, using the default_visibility of package //pkg
+  "//b:__subpackages__" from the default_visibility of package //pkg: matches //b/y:liby
+//b/y:liby can see //pkg:libpkg.
+`, explain("//b/y:liby", "//pkg:libpkg"))
+
+	AssertStringEquals(t, "public", `Visibility of //b:libb to //a/x:libfoo:
+  The visibility of //b:libb and the default_visibility of its packages are not set, using "//visibility:public"
+//a/x:libfoo can see //b:libb.
+`, explain("//a/x:libfoo", "//b:libb"))
+}
+
+func TestExplainVisibilityInvalidQuery(t *testing.T) {
+	for _, query := range []string{"//a:libfoo", "libfoo //b:libbar", "//a:libfoo //b:"} {
+		config := TestConfig("out", map[string]string{"SOONG_EXPLAIN_VISIBILITY": query}, "", nil)
+		if _, err := ExplainVisibility(config); err == nil {
+			t.Errorf("expected an error for %q", query)
+		}
+	}
+}
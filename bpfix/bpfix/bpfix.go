@@ -18,9 +18,11 @@ package bpfix
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"path/filepath"
 	"strings"
 
@@ -153,6 +155,12 @@ func (r FixRequest) AddBase() (result FixRequest) {
 	return result
 }
 
+func (r FixRequest) AddSteps(steps ...FixStep) (result FixRequest) {
+	result.steps = append([]FixStep(nil), r.steps...)
+	result.steps = append(result.steps, steps...)
+	return result
+}
+
 func (r FixRequest) AddMatchingExtensions(pattern string) (result FixRequest) {
 	result.steps = append([]FixStep(nil), r.steps...)
 	for _, extension := range fixStepsExtensions {
@@ -734,6 +742,106 @@ func removeEmptyLibDependencies(f *Fixer) error {
 	return nil
 }
 
+// UnusedCcDeps is an entry of the report of unused cc dependencies that Soong writes to
+// out/soong/unused_cc_deps.json, the libraries of a module that contributed nothing to its build
+// by property, e.g. "shared_libs".
+type UnusedCcDeps struct {
+	Module    string
+	Blueprint string
+	Unused    map[string][]string
+}
+
+// ReadUnusedCcDeps reads a report of unused cc dependencies.
+func ReadUnusedCcDeps(filename string) ([]UnusedCcDeps, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var report []UnusedCcDeps
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return report, nil
+}
+
+// RemoveUnusedCcDeps returns a fix step that removes the unused cc dependencies in the report
+// from the modules of the file, including the arch and target specific values of the properties.
+// The report lists the Android.bp files relative to the root of the source tree.
+func RemoveUnusedCcDeps(report []UnusedCcDeps) FixStep {
+	return FixStep{
+		Name: "removeUnusedCcDeps",
+		Fix: func(f *Fixer) error {
+			return removeUnusedCcDeps(f, report)
+		},
+	}
+}
+
+func removeUnusedCcDeps(f *Fixer, report []UnusedCcDeps) error {
+	filename := filepath.Clean(f.tree.Name)
+	unused := make(map[string]map[string][]string)
+	for _, entry := range report {
+		blueprint := filepath.Clean(entry.Blueprint)
+		if filename == blueprint || strings.HasSuffix(filename, "/"+blueprint) {
+			unused[entry.Module] = entry.Unused
+		}
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+
+	for _, def := range f.tree.Defs {
+		mod, ok := def.(*parser.Module)
+		if !ok {
+			continue
+		}
+		name, ok := getLiteralStringPropertyValue(mod, "name")
+		if !ok || unused[name] == nil {
+			continue
+		}
+		for field, libs := range unused[name] {
+			if err := removeListPropertyValues(mod, &mod.Properties, field, libs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeListPropertyValues removes the values from the list property with the name in the
+// properties and in their nested maps, and removes the list properties that become empty.
+func removeListPropertyValues(mod *parser.Module, props *[]*parser.Property, field string, values []string) error {
+	newProps := make([]*parser.Property, 0, len(*props))
+	for _, prop := range *props {
+		switch value := prop.Value.(type) {
+		case *parser.Map:
+			if err := removeListPropertyValues(mod, &value.Properties, field, values); err != nil {
+				return err
+			}
+		case *parser.List:
+			if prop.Name != field {
+				break
+			}
+			newValues := []parser.Expression{}
+			for _, v := range value.Values {
+				stringValue, ok := v.(*parser.String)
+				if !ok {
+					return fmt.Errorf("Expecting string for %s.%s fields", mod.Type, field)
+				}
+				if !inList(stringValue.Value, values) {
+					newValues = append(newValues, stringValue)
+				}
+			}
+			if len(newValues) == 0 && len(value.Values) != 0 {
+				continue
+			}
+			value.Values = newValues
+		}
+		newProps = append(newProps, prop)
+	}
+	*props = newProps
+	return nil
+}
+
 // Removes hidl_interface 'types' which are no longer needed
 func removeHidlInterfaceTypes(f *Fixer) error {
 	for _, def := range f.tree.Defs {
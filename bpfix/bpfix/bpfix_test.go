@@ -904,6 +904,120 @@ func TestRemoveEmptyLibDependencies(t *testing.T) {
 	}
 }
 
+func TestRemoveUnusedCcDeps(t *testing.T) {
+	tests := []struct {
+		name   string
+		report []UnusedCcDeps
+		in     string
+		out    string
+	}{
+		{
+			name: "remove unused libs",
+			report: []UnusedCcDeps{
+				{
+					Module:    "foo",
+					Blueprint: "<testcase>",
+					Unused: map[string][]string{
+						"shared_libs": {"libunused"},
+						"static_libs": {"libunused_static"},
+					},
+				},
+			},
+			in: `
+				cc_library {
+					name: "foo",
+					shared_libs: ["libunused"],
+					static_libs: [
+						"libused",
+						"libunused_static",
+					],
+				}
+
+				cc_library {
+					name: "bar",
+					shared_libs: ["libunused"],
+				}
+			`,
+			out: `
+				cc_library {
+					name: "foo",
+
+					static_libs: [
+						"libused",
+
+					],
+				}
+
+				cc_library {
+					name: "bar",
+					shared_libs: ["libunused"],
+				}
+			`,
+		},
+		{
+			name: "remove arch specific header lib",
+			report: []UnusedCcDeps{
+				{
+					Module:    "foo",
+					Blueprint: "<testcase>",
+					Unused:    map[string][]string{"header_libs": {"libunused_headers"}},
+				},
+			},
+			in: `
+				cc_binary {
+					name: "foo",
+					arch: {
+						arm: {
+							cflags: ["-DARM"],
+							header_libs: ["libunused_headers"],
+						},
+					},
+					header_libs: ["libused_headers"],
+				}
+			`,
+			out: `
+				cc_binary {
+					name: "foo",
+					arch: {
+						arm: {
+							cflags: ["-DARM"],
+
+						},
+					},
+					header_libs: ["libused_headers"],
+				}
+			`,
+		},
+		{
+			name: "other file",
+			report: []UnusedCcDeps{
+				{
+					Module:    "foo",
+					Blueprint: "other/Android.bp",
+					Unused:    map[string][]string{"shared_libs": {"libunused"}},
+				},
+			},
+			in: `
+				cc_library {
+					name: "foo",
+					shared_libs: ["libunused"],
+				}
+			`,
+			out: `
+				cc_library {
+					name: "foo",
+					shared_libs: ["libunused"],
+				}
+			`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runPass(t, test.in, test.out, RemoveUnusedCcDeps(test.report).Fix)
+		})
+	}
+}
+
 func TestRemoveHidlInterfaceTypes(t *testing.T) {
 	tests := []struct {
 		name string
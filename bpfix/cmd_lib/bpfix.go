@@ -38,6 +38,11 @@ var (
 	list   = flag.Bool("l", false, "list files whose formatting differs from bpfmt's")
 	write  = flag.Bool("w", false, "write result to (source) file instead of stdout")
 	doDiff = flag.Bool("d", false, "display diffs instead of rewriting files")
+
+	// only remove the unused cc dependencies listed in the report that soong writes to
+	// out/soong/unused_cc_deps.json when CHECK_UNUSED_CC_DEPS=true is set
+	removeUnusedCcDeps = flag.String("remove_unused_cc_deps", "",
+		"remove the unused cc dependencies listed in the report instead of applying the fixes")
 )
 
 var (
@@ -132,6 +137,14 @@ func Run() {
 	flag.Parse()
 
 	fixRequest := bpfix.NewFixRequest().AddAll()
+	if *removeUnusedCcDeps != "" {
+		unused, err := bpfix.ReadUnusedCcDeps(*removeUnusedCcDeps)
+		if err != nil {
+			report(err)
+			return
+		}
+		fixRequest = bpfix.NewFixRequest().AddSteps(bpfix.RemoveUnusedCcDeps(unused))
+	}
 
 	if flag.NArg() == 0 {
 		if *write {
@@ -49,6 +49,17 @@ var (
 		},
 		"ccCmd", "cFlags")
 
+	// Rule to invoke gcc like cc that keeps a copy of the .d depfile in $headerDeps for the unused
+	// dependency analysis, as ninja deletes the depfile after reading it.
+	ccKeepHeaderDeps = pctx.AndroidRemoteStaticRule("ccKeepHeaderDeps", android.RemoteRuleSupports{Goma: true, RBE: true},
+		blueprint.RuleParams{
+			Depfile:     "${out}.d",
+			Deps:        blueprint.DepsGCC,
+			Command:     "$relPwd ${config.CcWrapper}$ccCmd -c $cFlags -MD -MF ${out}.d -o $out $in && cp ${out}.d $headerDeps",
+			CommandDeps: []string{"$ccCmd"},
+		},
+		"ccCmd", "cFlags", "headerDeps")
+
 	// Rule to invoke gcc with given command and flags, but no dependencies.
 	ccNoDeps = pctx.AndroidStaticRule("ccNoDeps",
 		blueprint.RuleParams{
@@ -68,6 +79,7 @@ var (
 			RspfileContent: "${in}",
 			// clang -Wl,--out-implib doesn't update its output file if it hasn't changed.
 			Restat: true,
+			Pool:   android.HighmemLinkPool,
 		},
 		&remoteexec.REParams{
 			Labels:          map[string]string{"type": "link", "tool": "clang"},
@@ -188,22 +200,58 @@ var (
 		},
 		"clangBin", "format")
 
-	// Rule for invoking clang-tidy (a clang-based linter).
+	// Remote execution parameters of the clang-tidy rules.
+	clangTidyREParams = &remoteexec.REParams{
+		Labels:       map[string]string{"type": "lint", "tool": "clang-tidy", "lang": "cpp"},
+		ExecStrategy: "${config.REClangTidyExecStrategy}",
+		Inputs:       []string{"$in"},
+		// OutputFile here is $in for remote-execution since its possible that
+		// clang-tidy modifies the given input file itself and $out refers to the
+		// ".tidy" file generated for ninja-dependency reasons. $tidyFixes is the
+		// file the fix-its are exported to.
+		OutputFiles: []string{"$in", "$tidyFixes"},
+		Platform:    map[string]string{remoteexec.PoolKey: "${config.REClangTidyPool}"},
+	}
+
+	// Rule for invoking clang-tidy (a clang-based linter).  The fix-its are exported to
+	// $tidyFixes, which clang-tidy only writes when there are any.
 	clangTidy, clangTidyRE = pctx.RemoteStaticRules("clangTidy",
 		blueprint.RuleParams{
-			Command:     "rm -f $out && $reTemplate${config.ClangBin}/clang-tidy $tidyFlags $in -- $cFlags && touch $out",
+			Command: "rm -f $out $tidyFixes && " +
+				"$reTemplate${config.ClangBin}/clang-tidy $tidyFlags -export-fixes=$tidyFixes $in -- $cFlags && " +
+				"touch $out $tidyFixes",
 			CommandDeps: []string{"${config.ClangBin}/clang-tidy"},
+		}, clangTidyREParams, []string{"cFlags", "tidyFlags", "tidyFixes"}, []string{})
+
+	// Rule for invoking clang-tidy for modules with a tidy baseline, which writes the findings to
+	// $out instead of printing them so that they can be checked against the baseline.
+	clangTidyFindings, clangTidyFindingsRE = pctx.RemoteStaticRules("clangTidyFindings",
+		blueprint.RuleParams{
+			Command: "rm -f $out $tidyFixes && " +
+				"($reTemplate${config.ClangBin}/clang-tidy $tidyFlags -export-fixes=$tidyFixes $in -- $cFlags > $out || " +
+				"(cat $out && rm -f $out && false)) && " +
+				"touch $tidyFixes",
+			CommandDeps: []string{"${config.ClangBin}/clang-tidy"},
+		}, clangTidyREParams, []string{"cFlags", "tidyFlags", "tidyFixes"}, []string{})
+
+	_ = pctx.SourcePathVariable("tidyBaselinePath", "build/soong/scripts/tidy_baseline.sh")
+
+	// Rule for checking the clang-tidy findings of a module against its baseline.
+	tidyBaselineCheck = pctx.AndroidStaticRule("tidyBaselineCheck",
+		blueprint.RuleParams{
+			Command:     "$tidyBaselinePath $baseline $findings $in && touch $out",
+			CommandDeps: []string{"$tidyBaselinePath"},
 		},
-		&remoteexec.REParams{
-			Labels:       map[string]string{"type": "lint", "tool": "clang-tidy", "lang": "cpp"},
-			ExecStrategy: "${config.REClangTidyExecStrategy}",
-			Inputs:       []string{"$in"},
-			// OutputFile here is $in for remote-execution since its possible that
-			// clang-tidy modifies the given input file itself and $out refers to the
-			// ".tidy" file generated for ninja-dependency reasons.
-			OutputFiles: []string{"$in"},
-			Platform:    map[string]string{remoteexec.PoolKey: "${config.REClangTidyPool}"},
-		}, []string{"cFlags", "tidyFlags"}, []string{})
+		"baseline", "findings")
+
+	_ = pctx.SourcePathVariable("mergeTidyFixesPath", "build/soong/scripts/merge_tidy_fixes.sh")
+
+	// Rule for merging the clang-tidy fix-its of the sources of a module into one replacement file.
+	mergeTidyFixes = pctx.AndroidStaticRule("mergeTidyFixes",
+		blueprint.RuleParams{
+			Command:     "$mergeTidyFixesPath $out $in",
+			CommandDeps: []string{"$mergeTidyFixesPath"},
+		})
 
 	_ = pctx.SourcePathVariable("yasmCmd", "prebuilts/misc/${config.HostPrebuiltTag}/yasm/yasm")
 
@@ -268,7 +316,7 @@ var (
 	sAbiDiff = pctx.RuleFunc("sAbiDiff",
 		func(ctx android.PackageRuleContext) blueprint.RuleParams {
 			commandStr := "($sAbiDiffer ${extraFlags} -lib ${libName} -arch ${arch} -o ${out} -new ${in} -old ${referenceDump})"
-			commandStr += "|| (echo 'error: Please update ABI references with: $$ANDROID_BUILD_TOP/development/vndk/tools/header-checker/utils/create_reference_dumps.py ${createReferenceDumpFlags} -l ${libName}'"
+			commandStr += "|| (echo 'error: Please update ABI references with: ${updateRefDumpCmd}'"
 			commandStr += " && (mkdir -p $$DIST_DIR/abidiffs && cp ${out} $$DIST_DIR/abidiffs/)"
 			commandStr += " && exit 1)"
 			return blueprint.RuleParams{
@@ -276,7 +324,7 @@ var (
 				CommandDeps: []string{"$sAbiDiffer"},
 			}
 		},
-		"extraFlags", "referenceDump", "libName", "arch", "createReferenceDumpFlags")
+		"extraFlags", "referenceDump", "libName", "arch", "updateRefDumpCmd")
 
 	// Rule to unzip a reference abi dump.
 	unzipRefSAbiDump = pctx.AndroidStaticRule("unzipRefSAbiDump",
@@ -284,6 +332,15 @@ var (
 			Command: "gunzip -c $in > $out",
 		})
 
+	// Rule to replace a reference abi dump that is checked in next to a module with a linked
+	// sAbi dump file, compressed if $gzip is true.
+	updateRefSAbiDump = pctx.AndroidStaticRule("updateRefSAbiDump",
+		blueprint.RuleParams{
+			Command: "rm -f ${refDump} ${refDump}.gz && mkdir -p $$(dirname ${refDump}) && " +
+				"(if ${gzip}; then gzip -c $in > ${refDump}.gz; else cp -f $in ${refDump}; fi) && touch $out",
+		},
+		"refDump", "gzip")
+
 	// Rule to zip files.
 	zip = pctx.AndroidStaticRule("zip",
 		blueprint.RuleParams{
@@ -369,6 +426,7 @@ type builderFlags struct {
 
 	// True if these extra features are enabled.
 	tidy         bool
+	tidyBaseline bool
 	gcovCoverage bool
 	sAbiDump     bool
 	emitXrefs    bool
@@ -386,6 +444,12 @@ type builderFlags struct {
 
 	yacc *YaccProperties
 	lex  *LexProperties
+
+	// The evidence of the unused dependency analysis, nil if it is disabled.
+	unusedDeps *unusedDepsAnalysis
+
+	// The stamp files of the checks of the exported headers of the dependencies.
+	headerChecks android.Paths
 }
 
 // StripFlags represents flags related to stripping. This is separate from builderFlags, as these
@@ -402,30 +466,36 @@ type StripFlags struct {
 
 // Objects is a collection of file paths corresponding to outputs for C++ related build statements.
 type Objects struct {
-	objFiles      android.Paths
-	tidyFiles     android.Paths
-	coverageFiles android.Paths
-	sAbiDumpFiles android.Paths
-	kytheFiles    android.Paths
+	objFiles       android.Paths
+	tidyFiles      android.Paths
+	tidyFixesFiles android.Paths
+	coverageFiles  android.Paths
+	sAbiDumpFiles  android.Paths
+	kytheFiles     android.Paths
+	headerDepFiles android.Paths
 }
 
 func (a Objects) Copy() Objects {
 	return Objects{
-		objFiles:      append(android.Paths{}, a.objFiles...),
-		tidyFiles:     append(android.Paths{}, a.tidyFiles...),
-		coverageFiles: append(android.Paths{}, a.coverageFiles...),
-		sAbiDumpFiles: append(android.Paths{}, a.sAbiDumpFiles...),
-		kytheFiles:    append(android.Paths{}, a.kytheFiles...),
+		objFiles:       append(android.Paths{}, a.objFiles...),
+		tidyFiles:      append(android.Paths{}, a.tidyFiles...),
+		tidyFixesFiles: append(android.Paths{}, a.tidyFixesFiles...),
+		coverageFiles:  append(android.Paths{}, a.coverageFiles...),
+		sAbiDumpFiles:  append(android.Paths{}, a.sAbiDumpFiles...),
+		kytheFiles:     append(android.Paths{}, a.kytheFiles...),
+		headerDepFiles: append(android.Paths{}, a.headerDepFiles...),
 	}
 }
 
 func (a Objects) Append(b Objects) Objects {
 	return Objects{
-		objFiles:      append(a.objFiles, b.objFiles...),
-		tidyFiles:     append(a.tidyFiles, b.tidyFiles...),
-		coverageFiles: append(a.coverageFiles, b.coverageFiles...),
-		sAbiDumpFiles: append(a.sAbiDumpFiles, b.sAbiDumpFiles...),
-		kytheFiles:    append(a.kytheFiles, b.kytheFiles...),
+		objFiles:       append(a.objFiles, b.objFiles...),
+		tidyFiles:      append(a.tidyFiles, b.tidyFiles...),
+		tidyFixesFiles: append(a.tidyFixesFiles, b.tidyFixesFiles...),
+		coverageFiles:  append(a.coverageFiles, b.coverageFiles...),
+		sAbiDumpFiles:  append(a.sAbiDumpFiles, b.sAbiDumpFiles...),
+		kytheFiles:     append(a.kytheFiles, b.kytheFiles...),
+		headerDepFiles: append(a.headerDepFiles, b.headerDepFiles...),
 	}
 }
 
@@ -435,9 +505,10 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 
 	// Source files are one-to-one with tidy, coverage, or kythe files, if enabled.
 	objFiles := make(android.Paths, len(srcFiles))
-	var tidyFiles android.Paths
+	var tidyFiles, tidyFixesFiles android.Paths
 	if flags.tidy {
 		tidyFiles = make(android.Paths, 0, len(srcFiles))
+		tidyFixesFiles = make(android.Paths, 0, len(srcFiles))
 	}
 	var coverageFiles android.Paths
 	if flags.gcovCoverage {
@@ -447,6 +518,7 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 	if flags.emitXrefs {
 		kytheFiles = make(android.Paths, 0, len(srcFiles))
 	}
+	var headerDepFiles android.Paths
 
 	// Produce fully expanded flags for use by C tools, C compiles, C++ tools, C++ compiles, and asm compiles
 	// respectively.
@@ -587,6 +659,18 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 			coverageFiles = append(coverageFiles, gcnoFile)
 		}
 
+		args := map[string]string{
+			"cFlags": moduleFlags,
+			"ccCmd":  ccCmd,
+		}
+		if flags.unusedDeps != nil && rule == cc {
+			headerDepFile := android.ObjPathWithExt(ctx, subdir, srcFile, "headers.d")
+			implicitOutputs = append(implicitOutputs, headerDepFile)
+			headerDepFiles = append(headerDepFiles, headerDepFile)
+			rule = ccKeepHeaderDeps
+			args["headerDeps"] = headerDepFile.String()
+		}
+
 		ctx.Build(pctx, android.BuildParams{
 			Rule:            rule,
 			Description:     ccDesc + " " + srcFile.Rel(),
@@ -595,10 +679,8 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 			Input:           srcFile,
 			Implicits:       cFlagsDeps,
 			OrderOnly:       pathDeps,
-			Args: map[string]string{
-				"cFlags": moduleFlags,
-				"ccCmd":  ccCmd,
-			},
+			Validations:     flags.headerChecks,
+			Args:            args,
 		})
 
 		// Register post-process build statements (such as for tidy or kythe).
@@ -621,17 +703,23 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 		if tidy {
 			tidyFile := android.ObjPathWithExt(ctx, subdir, srcFile, "tidy")
 			tidyFiles = append(tidyFiles, tidyFile)
+			tidyFixesFile := android.ObjPathWithExt(ctx, subdir, srcFile, "fixes.yaml")
+			tidyFixesFiles = append(tidyFixesFiles, tidyFixesFile)
 
-			rule := clangTidy
+			rule, ruleRE := clangTidy, clangTidyRE
+			if flags.tidyBaseline {
+				rule, ruleRE = clangTidyFindings, clangTidyFindingsRE
+			}
 			if ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_CLANG_TIDY") {
-				rule = clangTidyRE
+				rule = ruleRE
 			}
 
 			ctx.Build(pctx, android.BuildParams{
-				Rule:        rule,
-				Description: "clang-tidy " + srcFile.Rel(),
-				Output:      tidyFile,
-				Input:       srcFile,
+				Rule:           rule,
+				Description:    "clang-tidy " + srcFile.Rel(),
+				Output:         tidyFile,
+				ImplicitOutput: tidyFixesFile,
+				Input:          srcFile,
 				// We must depend on objFile, since clang-tidy doesn't
 				// support exporting dependencies.
 				Implicit:  objFile,
@@ -640,6 +728,7 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 				Args: map[string]string{
 					"cFlags":    moduleToolingFlags,
 					"tidyFlags": flags.tidyFlags,
+					"tidyFixes": tidyFixesFile.String(),
 				},
 			})
 		}
@@ -670,18 +759,20 @@ func transformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 	}
 
 	return Objects{
-		objFiles:      objFiles,
-		tidyFiles:     tidyFiles,
-		coverageFiles: coverageFiles,
-		sAbiDumpFiles: sAbiDumpFiles,
-		kytheFiles:    kytheFiles,
+		objFiles:       objFiles,
+		tidyFiles:      tidyFiles,
+		tidyFixesFiles: tidyFixesFiles,
+		coverageFiles:  coverageFiles,
+		sAbiDumpFiles:  sAbiDumpFiles,
+		kytheFiles:     kytheFiles,
+		headerDepFiles: headerDepFiles,
 	}
 }
 
 // Generate a rule for compiling multiple .o files to a static library (.a)
 func transformObjToStaticLib(ctx android.ModuleContext,
 	objFiles android.Paths, wholeStaticLibs android.Paths,
-	flags builderFlags, outputFile android.ModuleOutPath, deps android.Paths) {
+	flags builderFlags, outputFile android.ModuleOutPath, deps android.Paths, validations android.Paths) {
 
 	arCmd := "${config.ClangBin}/llvm-ar"
 	arFlags := ""
@@ -696,6 +787,7 @@ func transformObjToStaticLib(ctx android.ModuleContext,
 			Output:      outputFile,
 			Inputs:      objFiles,
 			Implicits:   deps,
+			Validations: validations,
 			Args: map[string]string{
 				"arFlags": "crsPD" + arFlags,
 				"arCmd":   arCmd,
@@ -709,6 +801,7 @@ func transformObjToStaticLib(ctx android.ModuleContext,
 			Output:      outputFile,
 			Inputs:      append(objFiles, wholeStaticLibs...),
 			Implicits:   deps,
+			Validations: validations,
 			Args: map[string]string{
 				"arCmd":      arCmd,
 				"arObjFlags": "crsPD" + arFlags,
@@ -724,7 +817,8 @@ func transformObjToStaticLib(ctx android.ModuleContext,
 // and shared libraries, to a shared library (.so) or dynamic executable
 func transformObjToDynamicBinary(ctx android.ModuleContext,
 	objFiles, sharedLibs, staticLibs, lateStaticLibs, wholeStaticLibs, deps android.Paths,
-	crtBegin, crtEnd android.OptionalPath, groupLate bool, flags builderFlags, outputFile android.WritablePath, implicitOutputs android.WritablePaths) {
+	crtBegin, crtEnd android.OptionalPath, groupLate bool, flags builderFlags, outputFile android.WritablePath,
+	implicitOutputs android.WritablePaths, validations android.Paths) {
 
 	ldCmd := "${config.ClangBin}/clang++"
 
@@ -784,6 +878,15 @@ func transformObjToDynamicBinary(ctx android.ModuleContext,
 		"ldFlags":       flags.globalLdFlags + " " + flags.localLdFlags,
 		"crtEnd":        crtEnd.String(),
 	}
+	if flags.unusedDeps != nil {
+		// The link map lists the archive members that the linker used for the unused dependency
+		// analysis.
+		linkMap := android.PathForModuleOut(ctx, "unused_deps", outputFile.Base()+".map")
+		args["ldFlags"] += " -Wl,-Map=" + linkMap.String()
+		implicitOutputs = append(android.WritablePaths{linkMap}, implicitOutputs...)
+		flags.unusedDeps.linkMap = linkMap
+		flags.unusedDeps.output = outputFile
+	}
 	if ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_CXX_LINKS") {
 		rule = ldRE
 		args["implicitOutputs"] = strings.Join(implicitOutputs.Strings(), ",")
@@ -797,6 +900,7 @@ func transformObjToDynamicBinary(ctx android.ModuleContext,
 		ImplicitOutputs: implicitOutputs,
 		Inputs:          objFiles,
 		Implicits:       deps,
+		Validations:     validations,
 		Args:            args,
 	})
 }
@@ -863,8 +967,10 @@ func unzipRefDump(ctx android.ModuleContext, zippedRefDump android.Path, baseNam
 }
 
 // sourceAbiDiff registers a build statement to compare linked sAbi dump files (.ldump).
+// updateRefDumpCmd is the command that updates the reference dump, or empty for the reference
+// dumps in prebuilts/abi-dumps.
 func sourceAbiDiff(ctx android.ModuleContext, inputDump android.Path, referenceDump android.Path,
-	baseName, exportedHeaderFlags string, checkAllApis, isLlndk, isNdk, isVndkExt bool) android.OptionalPath {
+	baseName, exportedHeaderFlags, updateRefDumpCmd string, checkAllApis, isLlndk, isNdk, isVndkExt bool) android.OptionalPath {
 
 	outputFile := android.PathForModuleOut(ctx, baseName+".abidiff")
 	libName := strings.TrimSuffix(baseName, filepath.Ext(baseName))
@@ -896,6 +1002,11 @@ func sourceAbiDiff(ctx android.ModuleContext, inputDump android.Path, referenceD
 		extraFlags = append(extraFlags, "-allow-extensions")
 	}
 
+	if updateRefDumpCmd == "" {
+		updateRefDumpCmd = "$$ANDROID_BUILD_TOP/development/vndk/tools/header-checker/utils/create_reference_dumps.py " +
+			createReferenceDumpFlags + " -l " + libName
+	}
+
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        sAbiDiff,
 		Description: "header-abi-diff " + outputFile.Base(),
@@ -903,11 +1014,11 @@ func sourceAbiDiff(ctx android.ModuleContext, inputDump android.Path, referenceD
 		Input:       inputDump,
 		Implicit:    referenceDump,
 		Args: map[string]string{
-			"referenceDump":            referenceDump.String(),
-			"libName":                  libName,
-			"arch":                     ctx.Arch().ArchType.Name,
-			"extraFlags":               strings.Join(extraFlags, " "),
-			"createReferenceDumpFlags": createReferenceDumpFlags,
+			"referenceDump":    referenceDump.String(),
+			"libName":          libName,
+			"arch":             ctx.Arch().ArchType.Name,
+			"extraFlags":       strings.Join(extraFlags, " "),
+			"updateRefDumpCmd": updateRefDumpCmd,
 		},
 	})
 	return android.OptionalPathForPath(outputFile)
@@ -73,6 +73,9 @@ func RegisterCCBuildComponents(ctx android.RegistrationContext) {
 		ctx.TopDown("tsan_deps", sanitizerDepsMutator(tsan))
 		ctx.BottomUp("tsan", sanitizerMutator(tsan)).Parallel()
 
+		ctx.TopDown("msan_deps", sanitizerDepsMutator(Msan))
+		ctx.BottomUp("msan", sanitizerMutator(Msan)).Parallel()
+
 		ctx.TopDown("sanitize_runtime_deps", sanitizerRuntimeDepsMutator).Parallel()
 		ctx.BottomUp("sanitize_runtime", sanitizerRuntimeMutator).Parallel()
 
@@ -91,6 +94,8 @@ func RegisterCCBuildComponents(ctx android.RegistrationContext) {
 	})
 
 	ctx.RegisterSingletonType("kythe_extract_all", kytheExtractAllFactory)
+	ctx.RegisterSingletonType("tidy_fixes", tidyFixesSingletonFactory)
+	ctx.RegisterSingletonType("unused_cc_deps", unusedCcDepsSingletonFactory)
 }
 
 // Deps is a struct containing module names of dependencies, separated by the kind of dependency.
@@ -176,6 +181,13 @@ type PathDeps struct {
 	ReexportedGeneratedHeaders android.Paths
 	ReexportedDeps             android.Paths
 
+	// The stamp files of the checks of the exported headers of the direct dependencies.
+	HeaderChecks android.Paths
+
+	// Include flags exported by the STL and the system shared libraries, which every module that
+	// depends on this module gets as well.
+	StlAndSystemLibFlags []string
+
 	// Paths to crt*.o files
 	CrtBegin, CrtEnd android.OptionalPath
 
@@ -226,6 +238,9 @@ type Flags struct {
 	SAbiDump     bool // True if header abi dumps should be generated.
 	EmitXrefs    bool // If true, generate Ninja rules to generate emitXrefs input files for Kythe
 
+	// The checked-in clang-tidy findings that do not fail the build, if tidy_baseline is set.
+	TidyBaseline android.OptionalPath
+
 	// The instruction set required for clang ("arm" or "thumb").
 	RequiredInstructionSet string
 	// The target-device system path to the dynamic linker.
@@ -245,6 +260,13 @@ type Flags struct {
 
 	Yacc *YaccProperties
 	Lex  *LexProperties
+
+	// Collects the evidence of the unused dependency analysis, nil if it is disabled.
+	unusedDeps *unusedDepsAnalysis
+
+	// The stamp files of the checks of the exported headers of the dependencies, which are
+	// validations of the compiles.
+	headerChecks android.Paths
 }
 
 // Properties used to compile all C or C++ modules
@@ -688,6 +710,14 @@ func (d libraryDependencyTag) InstallDepNeeded() bool {
 
 var _ android.InstallNeededDependencyTag = libraryDependencyTag{}
 
+// PropagatesLicenseConditions returns true for static libraries, which are linked into the module
+// that depends on them.
+func (d libraryDependencyTag) PropagatesLicenseConditions() bool {
+	return d.static()
+}
+
+var _ android.LicenseConditionsPropagatingDependencyTag = libraryDependencyTag{}
+
 // dependencyTag is used for tagging miscellaneous dependency types that don't fit into
 // libraryDependencyTag.  Each tag object is created globally and reused for multiple
 // dependencies (although since the object contains no references, assigning a tag to a
@@ -821,6 +851,10 @@ type Module struct {
 	makeLinkType string
 	// Kythe (source file indexer) paths for this compilation module
 	kytheFiles android.Paths
+	// The fix-its that clang-tidy exported for the sources of this compilation module
+	tidyFixesFiles android.Paths
+	// The unused dependency analysis of this module, nil if it is disabled
+	unusedDeps *unusedDepsAnalysis
 
 	// For apex variants, this is set as apex.min_sdk_version
 	apexSdkVersion android.ApiLevel
@@ -1686,8 +1720,10 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 	}
 
 	flags := Flags{
-		Toolchain: c.toolchain(ctx),
-		EmitXrefs: ctx.Config().EmitXrefRules(),
+		Toolchain:    c.toolchain(ctx),
+		EmitXrefs:    ctx.Config().EmitXrefRules(),
+		unusedDeps:   c.unusedDeps,
+		headerChecks: android.FirstUniquePaths(deps.HeaderChecks),
 	}
 	if c.compiler != nil {
 		flags = c.compiler.compilerFlags(ctx, flags, deps)
@@ -1754,6 +1790,13 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 			return
 		}
 		c.kytheFiles = objs.kytheFiles
+		c.tidyFixesFiles = objs.tidyFixesFiles
+		if c.unusedDeps != nil {
+			c.unusedDeps.headerDepFiles = append(c.unusedDeps.headerDepFiles, objs.headerDepFiles...)
+		}
+		if flags.TidyBaseline.Valid() {
+			objs.tidyFiles = checkTidyBaseline(ctx, objs.tidyFiles, flags.TidyBaseline.Path())
+		}
 	}
 
 	if c.linker != nil {
@@ -1763,6 +1806,10 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 		}
 		c.outputFile = android.OptionalPathForPath(outputFile)
 
+		if c.unusedDeps != nil {
+			c.unusedDeps.analyze(ctx)
+		}
+
 		// If a lib is directly included in any of the APEXes or is not available to the
 		// platform (which is often the case when the stub is provided as a prebuilt),
 		// unhide the stubs variant having the latest version gets visible to make. In
@@ -2482,6 +2529,8 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 	var directStaticDeps []StaticLibraryInfo
 	var directSharedDeps []SharedLibraryInfo
 
+	c.unusedDeps = newUnusedDepsAnalysis(ctx, c)
+
 	reexportExporter := func(exporter FlagExporterInfo) {
 		depPaths.ReexportedDirs = append(depPaths.ReexportedDirs, exporter.IncludeDirs...)
 		depPaths.ReexportedSystemDirs = append(depPaths.ReexportedSystemDirs, exporter.SystemIncludeDirs...)
@@ -2578,6 +2627,9 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 				staticAnalogue := ctx.OtherModuleProvider(dep, StaticLibraryInfoProvider).(StaticLibraryInfo)
 				objs := staticAnalogue.ReuseObjects
 				depPaths.Objs = depPaths.Objs.Append(objs)
+				if c.unusedDeps != nil {
+					c.unusedDeps.headerDepFiles = append(c.unusedDeps.headerDepFiles, objs.headerDepFiles...)
+				}
 				depExporterInfo := ctx.OtherModuleProvider(dep, FlagExporterInfoProvider).(FlagExporterInfo)
 				reexportExporter(depExporterInfo)
 			}
@@ -2802,11 +2854,22 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 				*depPtr = append(*depPtr, dep.Path())
 			}
 
+			if c.unusedDeps != nil && !libDepTag.reexportFlags {
+				c.unusedDeps.addCandidate(depName, libDepTag, depExporterInfo, linkFile)
+			}
+
 			depPaths.IncludeDirs = append(depPaths.IncludeDirs, depExporterInfo.IncludeDirs...)
 			depPaths.SystemIncludeDirs = append(depPaths.SystemIncludeDirs, depExporterInfo.SystemIncludeDirs...)
 			depPaths.GeneratedDeps = append(depPaths.GeneratedDeps, depExporterInfo.Deps...)
+			depPaths.HeaderChecks = append(depPaths.HeaderChecks, depExporterInfo.HeaderChecks...)
 			depPaths.Flags = append(depPaths.Flags, depExporterInfo.Flags...)
 
+			if (c.stl != nil && depName == c.stl.Properties.SelectedStl) ||
+				inList(depName, c.Properties.AndroidMkSystemSharedLibs) {
+				depPaths.StlAndSystemLibFlags = append(depPaths.StlAndSystemLibFlags,
+					exportedIncludeFlags(depExporterInfo)...)
+			}
+
 			if libDepTag.reexportFlags {
 				reexportExporter(depExporterInfo)
 				// Add these re-exported flags to help header-abi-dumper to infer the abi exported by a library.
@@ -2819,6 +2882,9 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 			}
 
 			makeLibName := MakeLibName(ctx, c, ccDep, depName) + libDepTag.makeSuffix
+			if d, ok := ccDep.(*Module); ok && libDepTag.shared() && d.sanitize.msanInSanitizerDir() {
+				makeLibName += ".msan"
+			}
 			switch {
 			case libDepTag.header():
 				c.Properties.AndroidMkHeaderLibs = append(
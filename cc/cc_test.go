@@ -4171,6 +4171,103 @@ func TestSanitizeMemtagHeapWithSanitizeDeviceDiag(t *testing.T) {
 	checkHasMemtagNote(t, ctx.ModuleForTests("sync_binary_true_diag", variant), Sync)
 }
 
+func TestSanitizeMemory(t *testing.T) {
+	bp := `
+		cc_binary {
+			name: "bin_with_msan",
+			host_supported: true,
+			srcs: ["foo.c"],
+			shared_libs: ["libshared"],
+			static_libs: ["libstatic"],
+			sanitize: {
+				memory: true,
+			},
+		}
+
+		cc_binary {
+			name: "bin_no_msan",
+			host_supported: true,
+			srcs: ["foo.c"],
+			shared_libs: ["libshared"],
+		}
+
+		cc_test {
+			name: "test_with_msan",
+			host_supported: true,
+			srcs: ["foo.c"],
+			shared_libs: ["libshared"],
+			gtest: false,
+			sanitize: {
+				memory: true,
+			},
+		}
+
+		cc_library_shared {
+			name: "libshared",
+			host_supported: true,
+			srcs: ["foo.c"],
+		}
+
+		cc_library_static {
+			name: "libstatic",
+			host_supported: true,
+			srcs: ["foo.c"],
+		}
+	`
+
+	result := prepareForCcTest.RunTestWithBp(t, bp)
+	ctx := result.TestContext
+
+	const hostVariant = "linux_glibc_x86_64"
+	withMsan := result.ModuleForTests("bin_with_msan", hostVariant)
+	android.AssertStringDoesContain(t, "bin_with_msan cflags", withMsan.Rule("cc").Args["cFlags"], "-fsanitize=memory")
+	link := withMsan.Rule("ld")
+	android.AssertStringDoesContain(t, "bin_with_msan ldflags", link.Args["ldFlags"], "-fsanitize=memory")
+	android.AssertStringDoesContain(t, "bin_with_msan rpath", link.Args["ldFlags"],
+		`-Wl,-rpath,\$$ORIGIN/../msan/lib64 -Wl,-rpath,\$$ORIGIN/../lib64 `+
+			`-Wl,-rpath,\$$ORIGIN/msan/lib64 -Wl,-rpath,\$$ORIGIN/lib64`)
+	android.AssertStringDoesNotContain(t, "bin_no_msan rpath",
+		result.ModuleForTests("bin_no_msan", hostVariant).Rule("ld").Args["ldFlags"], "msan/lib64")
+
+	// The runpaths of tests point to the library directories from the test directories, each of
+	// them is preceded by the msan directory next to it.
+	testLink := result.ModuleForTests("test_with_msan", hostVariant).Rule("ld")
+	android.AssertStringDoesContain(t, "test_with_msan rpath", testLink.Args["ldFlags"],
+		`-Wl,-rpath,\$$ORIGIN/../../msan/lib64 -Wl,-rpath,\$$ORIGIN/../../lib64 `+
+			`-Wl,-rpath,\$$ORIGIN/../../../msan/lib64 -Wl,-rpath,\$$ORIGIN/../../../lib64 `)
+
+	linksVariant := func(m android.TestingModule, variant string) bool {
+		for _, implicit := range m.Rule("ld").Implicits.Strings() {
+			if strings.Contains(implicit, "/"+variant+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	android.AssertBoolEquals(t, "bin_with_msan links the msan variant of libshared", true,
+		linksVariant(withMsan, hostVariant+"_shared_msan"))
+	android.AssertBoolEquals(t, "bin_with_msan links the msan variant of libstatic", true,
+		linksVariant(withMsan, hostVariant+"_static_msan"))
+	android.AssertBoolEquals(t, "bin_no_msan links the uninstrumented variant of libshared", true,
+		linksVariant(result.ModuleForTests("bin_no_msan", hostVariant), hostVariant+"_shared"))
+	android.AssertBoolEquals(t, "test_with_msan links the msan variant of libshared", true,
+		linksVariant(result.ModuleForTests("test_with_msan", hostVariant), hostVariant+"_shared_msan"))
+
+	libsharedMsan := result.ModuleForTests("libshared", hostVariant+"_shared_msan").Module().(*Module)
+	android.AssertBoolEquals(t, "msan variant of libshared is installed to the msan directory", true,
+		libsharedMsan.InstallInSanitizerDir())
+	entries := android.AndroidMkEntriesForTest(t, ctx, libsharedMsan)[0]
+	android.AssertStringEquals(t, "msan variant of libshared make name suffix", ".msan", entries.SubName)
+	android.AssertStringListContains(t, "bin_with_msan make shared libs",
+		android.AndroidMkEntriesForTest(t, ctx, withMsan.Module())[0].EntryMap["LOCAL_SHARED_LIBRARIES"],
+		"libshared.msan")
+
+	android.AssertStringDoesNotContain(t, "32-bit bin_with_msan cflags",
+		result.ModuleForTests("bin_with_msan", "linux_glibc_x86").Rule("cc").Args["cFlags"], "-fsanitize=memory")
+	android.AssertStringDoesNotContain(t, "device bin_with_msan cflags",
+		result.ModuleForTests("bin_with_msan", "android_arm64_armv8-a").Rule("cc").Args["cFlags"], "-fsanitize=memory")
+}
+
 func TestIncludeDirsExporting(t *testing.T) {
 
 	// Trim spaces from the beginning, end and immediately after any newline characters. Leaves
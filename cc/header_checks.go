@@ -0,0 +1,212 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/pathtools"
+
+	"android/soong/android"
+)
+
+// Exported header checks.
+//
+// When `header_checks: { enabled: true }` is set on a library, or CHECK_EXPORTED_HEADERS=true is
+// set, each header in its export_include_dirs and export_system_include_dirs is compiled on its own
+// with only the flags and include directories that the library exports, as seen by a module that
+// depends on it.  A header that relies on the includes of the sources that include it, or on
+// headers of libraries that the library does not reexport, fails to compile.  The headers of
+// libraries that only have C sources are checked as C headers, the others as C++ headers, unless
+// header_checks.language is set.
+//
+// The checks are validations of the output of the library and of the compiles of the modules
+// that depend on it, so they run whenever the library or a module that includes its headers is
+// built, including for header libraries that have no output of their own, but they don't delay
+// the modules that depend on it.
+
+var (
+	// Rule for checking that an exported header compiles on its own.
+	headerCheck = pctx.AndroidStaticRule("headerCheck",
+		blueprint.RuleParams{
+			Depfile:     "${out}.d",
+			Deps:        blueprint.DepsGCC,
+			Command:     "$relPwd ${config.ClangBin}/$ccCmd -fsyntax-only -x $language $cFlags -MD -MF ${out}.d -MT $out $in && touch $out",
+			CommandDeps: []string{"${config.ClangBin}/clang", "${config.ClangBin}/clang++"},
+		},
+		"ccCmd", "language", "cFlags")
+
+	// The extensions of the exported files that are checked.  Files that are meant to be
+	// included textually, like .inc and .inl files, are not.
+	headerCheckExts = []string{".h", ".hh", ".hpp", ".hxx", ".h++"}
+)
+
+// headerChecksEnabled returns true if the exported headers of the library are checked.  They are
+// only checked for one variant of a library that is both static and shared.
+func (library *libraryDecorator) headerChecksEnabled(ctx ModuleContext) bool {
+	if library.buildStubs() {
+		return false
+	}
+	if !library.static() && !library.header() && library.buildStatic() {
+		return false
+	}
+	return BoolDefault(library.Properties.Header_checks.Enabled,
+		ctx.Config().IsEnvTrue("CHECK_EXPORTED_HEADERS"))
+}
+
+// planHeaderChecks finds the exported headers to check and the stamp files of their checks.
+func (library *libraryDecorator) planHeaderChecks(ctx ModuleContext) {
+	if !library.headerChecksEnabled(ctx) {
+		return
+	}
+
+	dirs := append(library.flagExporter.exportedIncludes(ctx),
+		android.PathsForModuleSrc(ctx, library.flagExporter.Properties.Export_system_include_dirs)...)
+	var headers android.Paths
+	for _, dir := range android.FirstUniquePaths(dirs) {
+		glob, err := ctx.GlobWithDeps(dir.String()+"/**/*", nil)
+		if err != nil {
+			ctx.ModuleErrorf("glob failed: %#v", err)
+			return
+		}
+		for _, file := range glob {
+			if !inList(filepath.Ext(file), headerCheckExts) {
+				continue
+			}
+			rel, _ := android.MaybeRel(ctx, ctx.ModuleDir(), file)
+			if library.headerCheckExcluded(ctx, rel) {
+				continue
+			}
+			headers = append(headers, android.PathForSource(ctx, file))
+		}
+	}
+
+	library.checkedHeaders = android.FirstUniquePaths(headers)
+	for _, header := range library.checkedHeaders {
+		rel, isRel := android.MaybeRel(ctx, ctx.ModuleDir(), header.String())
+		if !isRel {
+			rel = header.String()
+		}
+		library.headerCheckStamps = append(library.headerCheckStamps,
+			android.PathForModuleOut(ctx, "header_checks", rel+".check"))
+	}
+	library.flagExporter.headerChecks = library.headerCheckStamps.Paths()
+}
+
+// headerChecksLanguage returns the language the exported headers are checked as, "c" or "c++".
+func (library *libraryDecorator) headerChecksLanguage(ctx ModuleContext) string {
+	if language := library.Properties.Header_checks.Language; language != nil {
+		if *language != "c" && *language != "c++" {
+			ctx.PropertyErrorf("header_checks.language", "must be \"c\" or \"c++\", not %q", *language)
+		}
+		return *language
+	}
+	for _, ext := range []string{".cpp", ".cc", ".cxx", ".mm"} {
+		if library.baseCompiler.hasSrcExt(ext) {
+			return "c++"
+		}
+	}
+	if library.baseCompiler.hasSrcExt(".c") {
+		return "c"
+	}
+	return "c++"
+}
+
+// headerCheckExcluded returns true if the header, relative to the module directory, matches
+// header_checks.exclude.
+func (library *libraryDecorator) headerCheckExcluded(ctx ModuleContext, header string) bool {
+	for _, pattern := range library.Properties.Header_checks.Exclude {
+		match, err := pathtools.Match(pattern, header)
+		if err != nil {
+			ctx.PropertyErrorf("header_checks.exclude", "invalid pattern %q: %s", pattern, err)
+			return false
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// buildHeaderChecks builds the checks of the exported headers, once the flags and include
+// directories that the library exports are known.
+func (library *libraryDecorator) buildHeaderChecks(ctx ModuleContext, flags Flags, deps PathDeps) {
+	if len(library.checkedHeaders) == 0 {
+		return
+	}
+
+	// The headers are compiled with the flags of a module that depends on the library: the
+	// toolchain flags, the flags of the STL, the include directories of the STL and the system
+	// libraries, and what the library exports.
+	var stlFlags LocalOrGlobalFlags
+	if stl := ctx.Module().(*Module).stl; stl != nil {
+		stlFlags = stl.flags(ctx, Flags{Toolchain: flags.Toolchain}).Local
+	}
+
+	// C headers are checked with the flags of the C sources, and C++ headers with the flags of the
+	// C++ sources, like in transformSourceToObj.
+	language := library.headerChecksLanguage(ctx)
+	ccCmd, languageFlags, stlLanguageFlags := "clang++", flags.Global.CppFlags, stlFlags.CppFlags
+	if language == "c" {
+		ccCmd, languageFlags, stlLanguageFlags = "clang", flags.Global.ConlyFlags, stlFlags.ConlyFlags
+	}
+
+	var cFlags []string
+	cFlags = append(cFlags, flags.Global.CommonFlags...)
+	cFlags = append(cFlags, flags.Global.CFlags...)
+	cFlags = append(cFlags, languageFlags...)
+	cFlags = append(cFlags, stlFlags.CommonFlags...)
+	cFlags = append(cFlags, stlFlags.CFlags...)
+	cFlags = append(cFlags, stlLanguageFlags...)
+	cFlags = append(cFlags, deps.StlAndSystemLibFlags...)
+	cFlags = append(cFlags, exportedIncludeFlags(FlagExporterInfo{
+		IncludeDirs:       android.FirstUniquePaths(library.flagExporter.dirs),
+		SystemIncludeDirs: android.FirstUniquePaths(library.flagExporter.systemDirs),
+		Flags:             library.flagExporter.flags,
+	})...)
+	cFlags = append(cFlags, flags.SystemIncludeFlags...)
+	cFlags = append(cFlags, "${config.NoOverrideClangGlobalCflags}")
+	ctx.Variable(pctx, "headerCheckCflags", strings.Join(cFlags, " "))
+
+	for i, header := range library.checkedHeaders {
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        headerCheck,
+			Description: "check header " + header.Rel(),
+			Output:      library.headerCheckStamps[i],
+			Input:       header,
+			OrderOnly:   library.flagExporter.deps,
+			Args: map[string]string{
+				"ccCmd":    ccCmd,
+				"language": language + "-header",
+				"cFlags":   "$headerCheckCflags",
+			},
+		})
+	}
+}
+
+// exportedIncludeFlags returns the compiler flags for the include directories and the flags that
+// a module exports.
+func exportedIncludeFlags(exporter FlagExporterInfo) []string {
+	var ret []string
+	for _, dir := range exporter.IncludeDirs {
+		ret = append(ret, "-I"+dir.String())
+	}
+	for _, dir := range exporter.SystemIncludeDirs {
+		ret = append(ret, "-isystem "+dir.String())
+	}
+	return append(ret, exporter.Flags...)
+}
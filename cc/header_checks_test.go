@@ -0,0 +1,212 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestHeaderChecks(t *testing.T) {
+	bp := `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.cpp"],
+			export_include_dirs: ["include"],
+			export_system_include_dirs: ["system_include"],
+			header_checks: {
+				enabled: true,
+				exclude: ["include/internal/*.h"],
+			},
+		}
+
+		cc_library_static {
+			name: "libbar",
+			srcs: ["bar.cpp"],
+			export_include_dirs: ["include"],
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeMockFs(android.MockFS{
+			"include/foo.h":             nil,
+			"include/foo.inc":           nil,
+			"include/internal/detail.h": nil,
+			"system_include/sys/foo.h":  nil,
+		}),
+	).RunTestWithBp(t, bp)
+
+	const staticVariant = "android_arm64_armv8-a_static"
+	const out = "out/soong/.intermediates/libfoo/" + staticVariant
+	libfoo := result.ModuleForTests("libfoo", staticVariant)
+
+	stamps := []string{
+		out + "/header_checks/include/foo.h.check",
+		out + "/header_checks/system_include/sys/foo.h.check",
+	}
+	android.AssertPathsRelativeToTopEquals(t, "libfoo archive validations", stamps,
+		libfoo.Output(out+"/libfoo.a").Validations)
+
+	check := libfoo.Output(out + "/header_checks/include/foo.h.check")
+	android.AssertBoolEquals(t, "libfoo header check rule", true, check.Rule == headerCheck)
+	android.AssertStringEquals(t, "libfoo header check input", "include/foo.h", check.Input.String())
+
+	android.AssertStringEquals(t, "libfoo header check compiler", "clang++", check.Args["ccCmd"])
+	android.AssertStringEquals(t, "libfoo header check language", "c++-header", check.Args["language"])
+	android.AssertStringEquals(t, "libfoo header check flags", "$headerCheckCflags", check.Args["cFlags"])
+	cFlags := libfoo.VariablesForTestsRelativeToTop()["headerCheckCflags"]
+	android.AssertStringDoesContain(t, "libfoo exported include dir", cFlags, "-Iinclude")
+	android.AssertStringDoesContain(t, "libfoo exported system include dir", cFlags, "-isystem system_include")
+	android.AssertStringDoesNotContain(t, "libfoo local include dir", cFlags, "-I.")
+
+	android.AssertBoolEquals(t, "excluded header is not checked", true,
+		libfoo.MaybeOutput(out+"/header_checks/include/internal/detail.h.check").Rule == nil)
+	android.AssertBoolEquals(t, "textual header is not checked", true,
+		libfoo.MaybeOutput(out+"/header_checks/include/foo.inc.check").Rule == nil)
+
+	const sharedVariant = "android_arm64_armv8-a_shared"
+	libfooShared := result.ModuleForTests("libfoo", sharedVariant)
+	android.AssertBoolEquals(t, "shared variant of libfoo is not checked", true,
+		libfooShared.MaybeRule("headerCheck").Rule == nil)
+
+	libbar := result.ModuleForTests("libbar", staticVariant)
+	android.AssertBoolEquals(t, "libbar is not checked", true, libbar.MaybeRule("headerCheck").Rule == nil)
+}
+
+func TestHeaderChecksLanguage(t *testing.T) {
+	bp := `
+		cc_library_static {
+			name: "libc",
+			srcs: ["c.c"],
+			export_include_dirs: ["c"],
+			header_checks: {
+				enabled: true,
+			},
+		}
+
+		cc_library_static {
+			name: "libmixed",
+			srcs: ["c.c", "cpp.cpp"],
+			export_include_dirs: ["mixed"],
+			header_checks: {
+				enabled: true,
+			},
+		}
+
+		cc_library_headers {
+			name: "libc_headers",
+			export_include_dirs: ["c_headers"],
+			header_checks: {
+				enabled: true,
+				language: "c",
+			},
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeMockFs(android.MockFS{
+			"c/c.h":             nil,
+			"mixed/mixed.h":     nil,
+			"c_headers/c_hdr.h": nil,
+		}),
+	).RunTestWithBp(t, bp)
+
+	const staticVariant = "android_arm64_armv8-a_static"
+	const headersVariant = "android_arm64_armv8-a"
+
+	testCases := []struct {
+		module, variant, stamp string
+		language, ccCmd        string
+	}{
+		{"libc", staticVariant, "header_checks/c/c.h.check", "c-header", "clang"},
+		{"libmixed", staticVariant, "header_checks/mixed/mixed.h.check", "c++-header", "clang++"},
+		{"libc_headers", headersVariant, "header_checks/c_headers/c_hdr.h.check", "c-header", "clang"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.module, func(t *testing.T) {
+			module := result.ModuleForTests(tc.module, tc.variant)
+			check := module.Output("out/soong/.intermediates/" + tc.module + "/" + tc.variant + "/" + tc.stamp)
+			android.AssertStringEquals(t, "header check language", tc.language, check.Args["language"])
+			android.AssertStringEquals(t, "header check compiler", tc.ccCmd, check.Args["ccCmd"])
+
+			cFlags := module.VariablesForTestsRelativeToTop()["headerCheckCflags"]
+			if tc.language == "c-header" {
+				android.AssertStringDoesContain(t, "C flags", cFlags, "${config.CommonGlobalConlyflags}")
+				android.AssertStringDoesNotContain(t, "C++ flags", cFlags, "${config.CommonClangGlobalCppflags}")
+			} else {
+				android.AssertStringDoesContain(t, "C++ flags", cFlags, "${config.CommonClangGlobalCppflags}")
+				android.AssertStringDoesNotContain(t, "C flags", cFlags, "${config.CommonGlobalConlyflags}")
+			}
+		})
+	}
+}
+
+func TestHeaderChecksInvalidLanguage(t *testing.T) {
+	android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeMockFs(android.MockFS{
+			"include/foo.h": nil,
+		}),
+	).
+		ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`header_checks.language: must be "c" or "c\+\+", not "objc"`)).
+		RunTestWithBp(t, `
+			cc_library_static {
+				name: "libfoo",
+				srcs: ["foo.c"],
+				export_include_dirs: ["include"],
+				header_checks: {
+					enabled: true,
+					language: "objc",
+				},
+			}
+		`)
+}
+
+func TestHeaderChecksHeaderLibrary(t *testing.T) {
+	bp := `
+		cc_library_headers {
+			name: "libfoo_headers",
+			export_include_dirs: ["include"],
+			header_checks: {
+				enabled: true,
+			},
+		}
+
+		cc_library_static {
+			name: "libbar",
+			srcs: ["bar.cpp"],
+			header_libs: ["libfoo_headers"],
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeMockFs(android.MockFS{
+			"include/foo.h": nil,
+		}),
+	).RunTestWithBp(t, bp)
+
+	const stamp = "out/soong/.intermediates/libfoo_headers/android_arm64_armv8-a/header_checks/include/foo.h.check"
+	result.ModuleForTests("libfoo_headers", "android_arm64_armv8-a").Output(stamp)
+
+	// Nothing depends on the output of a header library, the checks of its headers run when a
+	// module that includes them is built.
+	compile := result.ModuleForTests("libbar", "android_arm64_armv8-a_static").Rule("cc")
+	android.AssertPathsRelativeToTopEquals(t, "libbar compile validations", []string{stamp}, compile.Validations)
+}
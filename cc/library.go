@@ -109,6 +109,32 @@ type LibraryProperties struct {
 		// Run checks on all APIs (in addition to the ones referred by
 		// one of exported ELF symbols.)
 		Check_all_apis *bool
+
+		// Directory, relative to the module directory, of the checked in reference ABI dumps of
+		// this library, with a subdirectory for each image (platform, vendor or product) and
+		// architecture, e.g. <ref_dump_dir>/platform/arm64_armv8-a/libfoo.so.lsdump.  Setting it
+		// enables the ABI checks and the library is checked against these dumps instead of the
+		// ones in prebuilts/abi-dumps.  Only the platform variant of the core image is checked,
+		// not the APEX variants.  `m <module>-update-abi-dump` regenerates the dumps.
+		Ref_dump_dir *string
+	}
+
+	// Properties for the exported header checks
+	Header_checks struct {
+		// Compile each header in export_include_dirs and export_system_include_dirs on its own
+		// with only the flags and include directories that the library exports, to check that
+		// the headers include everything they use.  Defaults to true if
+		// CHECK_EXPORTED_HEADERS=true is set.
+		Enabled *bool
+
+		// List of exported headers that are not checked, e.g. headers that are only meant to be
+		// included by other headers.  Paths are relative to the module directory and support
+		// globs.
+		Exclude []string
+
+		// The language the headers are checked as, "c" or "c++".  Defaults to "c" if the
+		// library only has C sources, and to "c++" otherwise.
+		Language *string
 	}
 
 	// Order symbols in .bss section by their sizes.  Only useful for shared libraries.
@@ -339,6 +365,9 @@ type flagExporter struct {
 	flags      []string      // Exported raw flags.
 	deps       android.Paths
 	headers    android.Paths
+
+	// The stamp files of the checks of the exported headers.
+	headerChecks android.Paths
 }
 
 // exportedIncludes returns the effective include paths for this module and
@@ -408,6 +437,7 @@ func (f *flagExporter) setProvider(ctx android.ModuleContext) {
 		Flags:             f.flags,
 		Deps:              f.deps,
 		GeneratedHeaders:  f.headers,
+		HeaderChecks:      f.headerChecks,
 	})
 }
 
@@ -456,6 +486,10 @@ type libraryDecorator struct {
 	// Location of the file that should be copied to dist dir when requested
 	distFile android.Path
 
+	// The exported headers that are checked, and the stamp files of their checks
+	checkedHeaders    android.Paths
+	headerCheckStamps android.WritablePaths
+
 	versionScriptPath android.OptionalPath
 
 	postInstallCmds []string
@@ -748,7 +782,8 @@ func (library *libraryDecorator) compilerFlags(ctx ModuleContext, flags Flags, d
 }
 
 func (library *libraryDecorator) headerAbiCheckerEnabled() bool {
-	return Bool(library.Properties.Header_abi_checker.Enabled)
+	return BoolDefault(library.Properties.Header_abi_checker.Enabled,
+		library.Properties.Header_abi_checker.Ref_dump_dir != nil)
 }
 
 func (library *libraryDecorator) headerAbiCheckerExplicitlyDisabled() bool {
@@ -1087,7 +1122,8 @@ func (library *libraryDecorator) linkStatic(ctx ModuleContext,
 		}
 	}
 
-	transformObjToStaticLib(ctx, library.objects.objFiles, deps.WholeStaticLibsFromPrebuilts, builderFlags, outputFile, objs.tidyFiles)
+	transformObjToStaticLib(ctx, library.objects.objFiles, deps.WholeStaticLibsFromPrebuilts, builderFlags, outputFile,
+		objs.tidyFiles, library.headerCheckStamps.Paths())
 
 	library.coverageOutputFile = transformCoverageFilesToZip(ctx, library.objects, ctx.ModuleName())
 
@@ -1220,9 +1256,12 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 
 	if Bool(library.Properties.Sort_bss_symbols_by_size) && !library.buildStubs() {
 		unsortedOutputFile := android.PathForModuleOut(ctx, "unsorted", fileName)
+		// Only the final link is analyzed for unused dependencies.
+		unsortedFlags := builderFlags
+		unsortedFlags.unusedDeps = nil
 		transformObjToDynamicBinary(ctx, objs.objFiles, sharedLibs,
 			deps.StaticLibs, deps.LateStaticLibs, deps.WholeStaticLibs,
-			linkerDeps, deps.CrtBegin, deps.CrtEnd, false, builderFlags, unsortedOutputFile, implicitOutputs)
+			linkerDeps, deps.CrtBegin, deps.CrtEnd, false, unsortedFlags, unsortedOutputFile, implicitOutputs, nil)
 
 		symbolOrderingFile := android.PathForModuleOut(ctx, "unsorted", fileName+".symbol_order")
 		symbolOrderingFlag := library.baseLinker.sortBssSymbolsBySize(ctx, unsortedOutputFile, symbolOrderingFile, builderFlags)
@@ -1232,7 +1271,8 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 
 	transformObjToDynamicBinary(ctx, objs.objFiles, sharedLibs,
 		deps.StaticLibs, deps.LateStaticLibs, deps.WholeStaticLibs,
-		linkerDeps, deps.CrtBegin, deps.CrtEnd, false, builderFlags, outputFile, implicitOutputs)
+		linkerDeps, deps.CrtBegin, deps.CrtEnd, false, builderFlags, outputFile, implicitOutputs,
+		library.headerCheckStamps.Paths())
 
 	objs.coverageFiles = append(objs.coverageFiles, deps.StaticLibObjs.coverageFiles...)
 	objs.coverageFiles = append(objs.coverageFiles, deps.WholeStaticLibObjs.coverageFiles...)
@@ -1322,6 +1362,56 @@ func getRefAbiDumpFile(ctx ModuleContext, vndkVersion, fileName string) android.
 	return nil
 }
 
+// refAbiDumpImageDir returns the subdirectory of ref_dump_dir for the image of the library.  Each
+// image has its own reference ABI dumps, as the variants are built with different flags.
+func refAbiDumpImageDir(ctx ModuleContext) string {
+	if ctx.inVendor() {
+		return "vendor"
+	} else if ctx.inProduct() {
+		return "product"
+	}
+	return "platform"
+}
+
+// moduleRefAbiDumpFile returns the reference ABI dump of the library in refDumpDir, a directory
+// relative to the module directory, or nil if there is none yet.  It also adds the rule that
+// replaces the reference ABI dump with the ABI dump of the library to the
+// <module>-update-abi-dump phony target.
+func moduleRefAbiDumpFile(ctx ModuleContext, refDumpDir, fileName string, sAbiDump android.Path) android.Path {
+	refAbiDump := android.PathForModuleRefAbiDump(ctx,
+		filepath.Join(ctx.ModuleDir(), refDumpDir, refAbiDumpImageDir(ctx)), fileName)
+	refAbiDumpTextFile := android.ExistentPathForSource(ctx, refAbiDump.String())
+	refAbiDumpGzipFile := android.ExistentPathForSource(ctx, refAbiDump.String()+".gz")
+	if refAbiDumpTextFile.Valid() && refAbiDumpGzipFile.Valid() {
+		ctx.PropertyErrorf("header_abi_checker.ref_dump_dir",
+			"Two reference ABI dump files are found: %q and %q. Please delete the stale one.",
+			refAbiDumpTextFile, refAbiDumpGzipFile)
+		return nil
+	}
+
+	// Keep the reference ABI dump compressed if it is.
+	updateTimestamp := android.PathForModuleOut(ctx, fileName+"_update_ref.timestamp")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        updateRefSAbiDump,
+		Description: "update reference ABI dump " + refAbiDump.Rel(),
+		Output:      updateTimestamp,
+		Input:       sAbiDump,
+		Args: map[string]string{
+			"refDump": refAbiDump.String(),
+			"gzip":    strconv.FormatBool(refAbiDumpGzipFile.Valid()),
+		},
+	})
+	ctx.Phony(ctx.ModuleName()+"-update-abi-dump", updateTimestamp)
+
+	if refAbiDumpTextFile.Valid() {
+		return refAbiDumpTextFile.Path()
+	}
+	if refAbiDumpGzipFile.Valid() {
+		return unzipRefDump(ctx, refAbiDumpGzipFile.Path(), fileName)
+	}
+	return nil
+}
+
 func (library *libraryDecorator) linkSAbiDumpFiles(ctx ModuleContext, objs Objects, fileName string, soFile android.Path) {
 	if library.sabi.shouldCreateSourceAbiDump() {
 		var vndkVersion string
@@ -1350,10 +1440,21 @@ func (library *libraryDecorator) linkSAbiDumpFiles(ctx ModuleContext, objs Objec
 
 		addLsdumpPath(classifySourceAbiDump(ctx) + ":" + library.sAbiOutputFile.String())
 
-		refAbiDumpFile := getRefAbiDumpFile(ctx, vndkVersion, fileName)
+		var refAbiDumpFile android.Path
+		updateRefDumpCmd := ""
+		if refDumpDir := library.Properties.Header_abi_checker.Ref_dump_dir; refDumpDir != nil {
+			// The APEX variants share the reference ABI dumps of the platform variant, only one of
+			// them is checked and updates the dumps.
+			if ctx.Provider(android.ApexInfoProvider).(android.ApexInfo).IsForPlatform() {
+				refAbiDumpFile = moduleRefAbiDumpFile(ctx, *refDumpDir, fileName, library.sAbiOutputFile.Path())
+				updateRefDumpCmd = "m " + ctx.ModuleName() + "-update-abi-dump"
+			}
+		} else {
+			refAbiDumpFile = getRefAbiDumpFile(ctx, vndkVersion, fileName)
+		}
 		if refAbiDumpFile != nil {
 			library.sAbiDiff = sourceAbiDiff(ctx, library.sAbiOutputFile.Path(),
-				refAbiDumpFile, fileName, exportedHeaderFlags,
+				refAbiDumpFile, fileName, exportedHeaderFlags, updateRefDumpCmd,
 				Bool(library.Properties.Header_abi_checker.Check_all_apis),
 				ctx.IsLlndk(), ctx.isNdk(ctx.Config()), ctx.IsVndkExt())
 		}
@@ -1419,6 +1520,10 @@ func (library *libraryDecorator) link(ctx ModuleContext,
 		}
 	}
 
+	// The exported header checks are validations of the output of the library, but they are
+	// built once all the exported flags are known.
+	library.planHeaderChecks(ctx)
+
 	// Linking this library consists of linking `deps.Objs` (.o files in dependencies
 	// of this library), together with `objs` (.o files created by compiling this
 	// library).
@@ -1494,6 +1599,8 @@ func (library *libraryDecorator) link(ctx ModuleContext,
 	// Add stub-related flags if this library is a stub library.
 	library.exportVersioningMacroIfNeeded(ctx)
 
+	library.buildHeaderChecks(ctx, flags, deps)
+
 	// Propagate a Provider containing information about exported flags, deps, and include paths.
 	library.flagExporter.setProvider(ctx)
 
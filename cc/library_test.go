@@ -240,3 +240,86 @@ func TestStubsVersions_ParseError(t *testing.T) {
 
 	testCcError(t, `"libfoo" .*: versions: "X" could not be parsed as an integer and is not a recognized codename`, bp)
 }
+
+func TestLibraryRefAbiDumpDir(t *testing.T) {
+	bp := `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.cpp"],
+			export_include_dirs: ["include"],
+			vendor_available: true,
+			product_available: true,
+			header_abi_checker: {
+				ref_dump_dir: "abi-dumps",
+			},
+		}
+
+		cc_library_shared {
+			name: "libbar",
+			srcs: ["bar.cpp"],
+			header_abi_checker: {
+				ref_dump_dir: "bar-abi-dumps",
+			},
+		}
+
+		cc_library_shared {
+			name: "libbaz",
+			srcs: ["baz.cpp"],
+			header_abi_checker: {
+				ref_dump_dir: "baz-abi-dumps",
+			},
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeMockFs(android.MockFS{
+			"abi-dumps/platform/arm64_armv8-a/libfoo.so.lsdump":        nil,
+			"abi-dumps/vendor/arm64_armv8-a/libfoo.so.lsdump":          nil,
+			"bar-abi-dumps/platform/arm64_armv8-a/libbar.so.lsdump.gz": nil,
+		}),
+	).RunTestWithBp(t, bp)
+
+	const variant = "android_arm64_armv8-a_shared"
+
+	libfoo := result.ModuleForTests("libfoo", variant)
+	diff := libfoo.Output("out/soong/.intermediates/libfoo/" + variant + "/libfoo.so.abidiff")
+	android.AssertStringEquals(t, "libfoo reference dump", "abi-dumps/platform/arm64_armv8-a/libfoo.so.lsdump",
+		diff.Args["referenceDump"])
+	android.AssertStringEquals(t, "libfoo update command", "m libfoo-update-abi-dump", diff.Args["updateRefDumpCmd"])
+	update := libfoo.Output("out/soong/.intermediates/libfoo/" + variant + "/libfoo.so_update_ref.timestamp")
+	android.AssertStringEquals(t, "libfoo updated dump", "abi-dumps/platform/arm64_armv8-a/libfoo.so.lsdump", update.Args["refDump"])
+	android.AssertStringEquals(t, "libfoo updated dump is compressed", "false", update.Args["gzip"])
+	android.AssertPathRelativeToTopEquals(t, "libfoo update input",
+		"out/soong/.intermediates/libfoo/"+variant+"/libfoo.so.lsdump", update.Input)
+
+	// The vendor and product variants are built with different flags and have their own reference
+	// dumps.
+	for _, tc := range []struct{ variant, refDump string }{
+		{vendorVariant, "abi-dumps/vendor/arm64_armv8-a/libfoo.so.lsdump"},
+		{productVariant, "abi-dumps/product/arm64_armv8-a/libfoo.so.lsdump"},
+	} {
+		libfooImage := result.ModuleForTests("libfoo", tc.variant)
+		update := libfooImage.Output("out/soong/.intermediates/libfoo/" + tc.variant + "/libfoo.so_update_ref.timestamp")
+		android.AssertStringEquals(t, "libfoo "+tc.variant+" updated dump", tc.refDump, update.Args["refDump"])
+	}
+	diff = result.ModuleForTests("libfoo", vendorVariant).
+		Output("out/soong/.intermediates/libfoo/" + vendorVariant + "/libfoo.so.abidiff")
+	android.AssertStringEquals(t, "libfoo vendor reference dump", "abi-dumps/vendor/arm64_armv8-a/libfoo.so.lsdump",
+		diff.Args["referenceDump"])
+	android.AssertBoolEquals(t, "libfoo product variant has no reference dump", true,
+		result.ModuleForTests("libfoo", productVariant).
+			MaybeOutput("out/soong/.intermediates/libfoo/"+productVariant+"/libfoo.so.abidiff").Rule == nil)
+
+	libbar := result.ModuleForTests("libbar", variant)
+	diff = libbar.Output("out/soong/.intermediates/libbar/" + variant + "/libbar.so.abidiff")
+	android.AssertStringEquals(t, "libbar reference dump",
+		"out/soong/.intermediates/libbar/"+variant+"/libbar.so_ref.lsdump", diff.Args["referenceDump"])
+	update = libbar.Output("out/soong/.intermediates/libbar/" + variant + "/libbar.so_update_ref.timestamp")
+	android.AssertStringEquals(t, "libbar updated dump is compressed", "true", update.Args["gzip"])
+
+	libbaz := result.ModuleForTests("libbaz", variant)
+	android.AssertBoolEquals(t, "libbaz has no reference dump", true,
+		libbaz.MaybeOutput("out/soong/.intermediates/libbaz/"+variant+"/libbaz.so.abidiff").Rule == nil)
+	libbaz.Output("out/soong/.intermediates/libbaz/" + variant + "/libbaz.so_update_ref.timestamp")
+}
@@ -271,6 +271,10 @@ type FlagExporterInfo struct {
 	Flags             []string      // Exported raw flags.
 	Deps              android.Paths
 	GeneratedHeaders  android.Paths
+
+	// The stamp files of the checks of the exported headers, validations of the compiles of the
+	// modules that depend on the library.
+	HeaderChecks android.Paths
 }
 
 var FlagExporterInfoProvider = blueprint.NewProvider(FlagExporterInfo{})
@@ -487,7 +487,13 @@ func (linker *baseLinker) linkerFlags(ctx ModuleContext, flags Flags) Flags {
 		}
 
 		if !ctx.static() {
+			msan := linker.sanitize.isSanitizerEnabled(Msan)
 			for _, rpath := range linker.dynamicProperties.RunPaths {
+				// Find the instrumented variants of the shared libraries in the msan directory
+				// before the uninstrumented ones.
+				if msanRpath := msanRunPath(rpath); msan && msanRpath != "" {
+					flags.Global.LdFlags = append(flags.Global.LdFlags, "-Wl,-rpath,"+rpathPrefix+msanRpath)
+				}
 				flags.Global.LdFlags = append(flags.Global.LdFlags, "-Wl,-rpath,"+rpathPrefix+rpath)
 			}
 		}
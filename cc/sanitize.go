@@ -16,6 +16,7 @@ package cc
 
 import (
 	"fmt"
+	"path"
 	"sort"
 	"strings"
 	"sync"
@@ -69,6 +70,8 @@ var (
 		"-fno-sanitize-recover=integer,undefined"}
 	hwasanGlobalOptions = []string{"heap_history_size=1023", "stack_history_size=512",
 		"export_memory_stats=0", "max_malloc_fill_size=0"}
+
+	msanCflags = []string{"-fno-omit-frame-pointer", "-fsanitize-memory-track-origins"}
 )
 
 type SanitizerType int
@@ -85,6 +88,7 @@ const (
 	Asan SanitizerType = iota + 1
 	Hwasan
 	tsan
+	Msan
 	intOverflow
 	cfi
 	scs
@@ -101,6 +105,8 @@ func (t SanitizerType) variationName() string {
 		return "hwasan"
 	case tsan:
 		return "tsan"
+	case Msan:
+		return "msan"
 	case intOverflow:
 		return "intOverflow"
 	case cfi:
@@ -127,6 +133,8 @@ func (t SanitizerType) name() string {
 		return "memtag_heap"
 	case tsan:
 		return "thread"
+	case Msan:
+		return "memory"
 	case intOverflow:
 		return "integer_overflow"
 	case cfi:
@@ -148,6 +156,8 @@ func (*Module) SanitizerSupported(t SanitizerType) bool {
 		return true
 	case tsan:
 		return true
+	case Msan:
+		return true
 	case intOverflow:
 		return true
 	case cfi:
@@ -173,6 +183,10 @@ type SanitizeUserProps struct {
 	Address   *bool `android:"arch_variant"`
 	Thread    *bool `android:"arch_variant"`
 	Hwaddress *bool `android:"arch_variant"`
+	// MemorySanitizer, only supported on 64-bit Linux hosts.  All the code in the process must
+	// be instrumented, so every static and shared library that a module sanitized for memory
+	// depends on, including libc++, gets an instrumented variant.
+	Memory *bool `android:"arch_variant"`
 
 	// local sanitizers
 	Undefined        *bool    `android:"arch_variant"`
@@ -191,7 +205,7 @@ type SanitizeUserProps struct {
 
 	// Sanitizers to run in the diagnostic mode (as opposed to the release mode).
 	// Replaces abort() on error with a human-readable error message.
-	// Address and Thread sanitizers always run in diagnostic mode.
+	// Address, Thread and Memory sanitizers always run in diagnostic mode.
 	Diag struct {
 		Undefined        *bool    `android:"arch_variant"`
 		Cfi              *bool    `android:"arch_variant"`
@@ -306,6 +320,10 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 			s.Thread = boolPtr(true)
 		}
 
+		if found, globalSanitizers = removeFromList("memory", globalSanitizers); found && s.Memory == nil {
+			s.Memory = boolPtr(true)
+		}
+
 		if found, globalSanitizers = removeFromList("fuzzer", globalSanitizers); found && s.Fuzzer == nil {
 			s.Fuzzer = boolPtr(true)
 		}
@@ -419,6 +437,11 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 		s.Memtag_heap = nil
 	}
 
+	// MSan is only implemented for glibc Linux hosts.
+	if ctx.Os() != android.Linux {
+		s.Memory = nil
+	}
+
 	// Also disable CFI if ASAN is enabled.
 	if Bool(s.Address) || Bool(s.Hwaddress) {
 		s.Cfi = boolPtr(false)
@@ -458,6 +481,7 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 		s.Address = nil
 		s.Fuzzer = nil
 		s.Thread = nil
+		s.Memory = nil
 	}
 
 	if Bool(s.All_undefined) {
@@ -465,23 +489,31 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 	}
 
 	if !ctx.toolchain().Is64Bit() {
-		// TSAN and SafeStack are not supported on 32-bit architectures
+		// TSAN, MSAN and SafeStack are not supported on 32-bit architectures
 		s.Thread = nil
+		s.Memory = nil
 		s.Safestack = nil
 		// TODO(ccross): error for compile_multilib = "32"?
 	}
 
 	if ctx.Os() != android.Windows && (Bool(s.All_undefined) || Bool(s.Undefined) || Bool(s.Address) || Bool(s.Thread) ||
-		Bool(s.Fuzzer) || Bool(s.Safestack) || Bool(s.Cfi) || Bool(s.Integer_overflow) || len(s.Misc_undefined) > 0 ||
+		Bool(s.Memory) || Bool(s.Fuzzer) || Bool(s.Safestack) || Bool(s.Cfi) || Bool(s.Integer_overflow) || len(s.Misc_undefined) > 0 ||
 		Bool(s.Scudo) || Bool(s.Hwaddress) || Bool(s.Scs) || Bool(s.Memtag_heap)) {
 		sanitize.Properties.SanitizerEnabled = true
 	}
 
-	// Disable Scudo if ASan or TSan is enabled, or if it's disabled globally.
-	if Bool(s.Address) || Bool(s.Thread) || Bool(s.Hwaddress) || ctx.Config().DisableScudo() {
+	// Disable Scudo if ASan, TSan or MSan is enabled, or if it's disabled globally.
+	if Bool(s.Address) || Bool(s.Thread) || Bool(s.Memory) || Bool(s.Hwaddress) || ctx.Config().DisableScudo() {
 		s.Scudo = nil
 	}
 
+	// MSan can't be combined with ASan or TSan, it takes precedence over a global
+	// SANITIZE_HOST=address or thread.
+	if Bool(s.Memory) {
+		s.Address = nil
+		s.Thread = nil
+	}
+
 	if Bool(s.Hwaddress) {
 		s.Address = nil
 		s.Thread = nil
@@ -584,6 +616,11 @@ func (sanitize *sanitize) flags(ctx ModuleContext, flags Flags) Flags {
 		}
 	}
 
+	if Bool(sanitize.Properties.Sanitize.Memory) {
+		flags.Local.CFlags = append(flags.Local.CFlags, msanCflags...)
+		flags.Local.LdFlags = append(flags.Local.LdFlags, "-Wl,--no-as-needed")
+	}
+
 	if Bool(sanitize.Properties.Sanitize.Hwaddress) {
 		flags.Local.CFlags = append(flags.Local.CFlags, hwasanCflags...)
 		if Bool(sanitize.Properties.Sanitize.Writeonly) {
@@ -734,12 +771,36 @@ func (sanitize *sanitize) AndroidMkEntries(ctx AndroidMkContext, entries *androi
 			entries.SubName += ".scs"
 		}
 	}
+	// The msan variant of a shared library that is installed to the msan directory is exported
+	// to make next to the uninstrumented variant.
+	if entries.Class == "SHARED_LIBRARIES" && sanitize.msanInSanitizerDir() {
+		entries.SubName += ".msan"
+	}
 }
 
 func (sanitize *sanitize) inSanitizerDir() bool {
 	return sanitize.Properties.InSanitizerDir
 }
 
+// msanRunPath returns the runpath of the msan directory that holds the instrumented variants of
+// the shared libraries found in rpath, e.g. "../../msan/lib64" for "../../lib64", or an empty
+// string if rpath isn't a library directory.  The msan variants of host shared libraries that are
+// only dependencies of msan modules are installed to out/host/<os>/msan/lib[64].
+func msanRunPath(rpath string) string {
+	dir, libDir := path.Split(rpath)
+	if libDir != "lib" && libDir != "lib64" {
+		return ""
+	}
+	return dir + "msan/" + libDir
+}
+
+// msanInSanitizerDir returns true for the msan variant of a shared library that is installed to
+// the msan directory, because it is only a dependency of modules sanitized for msan.
+// Could be called as a nil receiver.
+func (sanitize *sanitize) msanInSanitizerDir() bool {
+	return sanitize != nil && sanitize.isSanitizerEnabled(Msan) && sanitize.inSanitizerDir()
+}
+
 // getSanitizerBoolPtr returns the SanitizerTypes associated bool pointer from SanitizeProperties.
 func (sanitize *sanitize) getSanitizerBoolPtr(t SanitizerType) *bool {
 	switch t {
@@ -749,6 +810,8 @@ func (sanitize *sanitize) getSanitizerBoolPtr(t SanitizerType) *bool {
 		return sanitize.Properties.Sanitize.Hwaddress
 	case tsan:
 		return sanitize.Properties.Sanitize.Thread
+	case Msan:
+		return sanitize.Properties.Sanitize.Memory
 	case intOverflow:
 		return sanitize.Properties.Sanitize.Integer_overflow
 	case cfi:
@@ -769,6 +832,7 @@ func (sanitize *sanitize) isUnsanitizedVariant() bool {
 	return !sanitize.isSanitizerEnabled(Asan) &&
 		!sanitize.isSanitizerEnabled(Hwasan) &&
 		!sanitize.isSanitizerEnabled(tsan) &&
+		!sanitize.isSanitizerEnabled(Msan) &&
 		!sanitize.isSanitizerEnabled(cfi) &&
 		!sanitize.isSanitizerEnabled(scs) &&
 		!sanitize.isSanitizerEnabled(memtag_heap) &&
@@ -780,6 +844,7 @@ func (sanitize *sanitize) isVariantOnProductionDevice() bool {
 	return !sanitize.isSanitizerEnabled(Asan) &&
 		!sanitize.isSanitizerEnabled(Hwasan) &&
 		!sanitize.isSanitizerEnabled(tsan) &&
+		!sanitize.isSanitizerEnabled(Msan) &&
 		!sanitize.isSanitizerEnabled(Fuzzer)
 }
 
@@ -791,6 +856,8 @@ func (sanitize *sanitize) SetSanitizer(t SanitizerType, b bool) {
 		sanitize.Properties.Sanitize.Hwaddress = boolPtr(b)
 	case tsan:
 		sanitize.Properties.Sanitize.Thread = boolPtr(b)
+	case Msan:
+		sanitize.Properties.Sanitize.Memory = boolPtr(b)
 	case intOverflow:
 		sanitize.Properties.Sanitize.Integer_overflow = boolPtr(b)
 	case cfi:
@@ -1046,6 +1113,10 @@ func sanitizerRuntimeMutator(mctx android.BottomUpMutatorContext) {
 			sanitizers = append(sanitizers, "thread")
 		}
 
+		if Bool(c.sanitize.Properties.Sanitize.Memory) {
+			sanitizers = append(sanitizers, "memory")
+		}
+
 		if Bool(c.sanitize.Properties.Sanitize.Safestack) {
 			sanitizers = append(sanitizers, "safe-stack")
 		}
@@ -1253,11 +1324,11 @@ func sanitizerMutator(t SanitizerType) func(android.BottomUpMutatorContext) {
 				modules[0].(PlatformSanitizeable).SetSanitizer(t, true)
 			} else if c.IsSanitizerEnabled(t) || c.SanitizeDep() {
 				isSanitizerEnabled := c.IsSanitizerEnabled(t)
-				if c.StaticallyLinked() || c.Header() || t == Asan || t == Fuzzer {
+				if c.StaticallyLinked() || c.Header() || t == Asan || t == Fuzzer || t == Msan {
 					// Static and header libs are split into non-sanitized and sanitized variants.
-					// Shared libs are not split. However, for asan and fuzzer, we split even for shared
-					// libs because a library sanitized for asan/fuzzer can't be linked from a library
-					// that isn't sanitized for asan/fuzzer.
+					// Shared libs are not split. However, for asan, fuzzer and msan, we split even for
+					// shared libs because a library sanitized for asan/fuzzer/msan can't be linked from
+					// a library that isn't sanitized for asan/fuzzer/msan.
 					//
 					// Note for defaultVariation: since we don't split for shared libs but for static/header
 					// libs, it is possible for the sanitized variant of a static/header lib to depend
@@ -1285,8 +1356,12 @@ func sanitizerMutator(t SanitizerType) func(android.BottomUpMutatorContext) {
 
 					// For cfi/scs/hwasan, we can export both sanitized and un-sanitized variants
 					// to Make, because the sanitized version has a different suffix in name.
+					// The same goes for the msan variant of a shared lib that is only a dependency
+					// of msan modules, which is installed to the msan directory.
 					// For other types of sanitizers, suppress the variation that is disabled.
-					if t != cfi && t != scs && t != Hwasan {
+					if t == Msan && !isSanitizerEnabled && !c.StaticallyLinked() && !c.Header() {
+						modules[1].(PlatformSanitizeable).SetInSanitizerDir()
+					} else if t != cfi && t != scs && t != Hwasan {
 						if isSanitizerEnabled {
 							modules[0].(PlatformSanitizeable).SetPreventInstall()
 							modules[0].(PlatformSanitizeable).SetHideFromMake()
@@ -15,6 +15,7 @@
 package cc
 
 import (
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -36,6 +37,13 @@ type TidyProperties struct {
 
 	// Checks that should be treated as errors.
 	Tidy_checks_as_errors []string
+
+	// Path to a checked-in file with the clang-tidy findings of the module that are tolerated,
+	// one per line without line and column numbers, e.g.
+	// "path/to/foo.cpp: warning: message [check-name]".  When set, every finding that is not in
+	// the baseline fails the build instead of the checks in tidy_checks_as_errors.  The findings
+	// of a variant of the module are written to tidy_findings.txt in its intermediates directory.
+	Tidy_baseline *string `android:"path"`
 }
 
 type tidyFeature struct {
@@ -84,6 +92,12 @@ func (tidy *tidyFeature) flags(ctx ModuleContext, flags Flags) Flags {
 
 	flags.Tidy = true
 
+	// The baselines do not contain the findings of the checks that WITH_TIDY=1 may enable
+	// globally, so the findings are only checked against the baseline without it.
+	if tidy.Properties.Tidy_baseline != nil && !ctx.Config().IsEnvTrue("WITH_TIDY") {
+		flags.TidyBaseline = android.OptionalPathForModuleSrc(ctx, tidy.Properties.Tidy_baseline)
+	}
+
 	// Add global WITH_TIDY_FLAGS and local tidy_flags.
 	withTidyFlags := ctx.Config().Getenv("WITH_TIDY_FLAGS")
 	if len(withTidyFlags) > 0 {
@@ -179,9 +193,68 @@ func (tidy *tidyFeature) flags(ctx ModuleContext, flags Flags) Flags {
 		if !inserted {
 			flags.TidyFlags = append(flags.TidyFlags, "-warnings-as-errors=-*")
 		}
-	} else if len(tidy.Properties.Tidy_checks_as_errors) > 0 {
+	} else if len(tidy.Properties.Tidy_checks_as_errors) > 0 && !flags.TidyBaseline.Valid() {
 		tidyChecksAsErrors := "-warnings-as-errors=" + strings.Join(esc(ctx, "tidy_checks_as_errors", tidy.Properties.Tidy_checks_as_errors), ",")
 		flags.TidyFlags = append(flags.TidyFlags, tidyChecksAsErrors)
 	}
 	return flags
 }
+
+// checkTidyBaseline checks the clang-tidy findings of the module against its baseline, and returns
+// the stamp file of the check to replace the tidy files that the module depends on.
+func checkTidyBaseline(ctx android.ModuleContext, tidyFiles android.Paths, baseline android.Path) android.Paths {
+	if len(tidyFiles) == 0 {
+		return tidyFiles
+	}
+
+	stamp := android.PathForModuleOut(ctx, "tidy_baseline.stamp")
+	findings := android.PathForModuleOut(ctx, "tidy_findings.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:           tidyBaselineCheck,
+		Description:    "clang-tidy baseline check",
+		Output:         stamp,
+		ImplicitOutput: findings,
+		Inputs:         tidyFiles,
+		Implicit:       baseline,
+		Args: map[string]string{
+			"baseline": baseline.String(),
+			"findings": findings.String(),
+		},
+	})
+	return android.Paths{stamp}
+}
+
+func tidyFixesSingletonFactory() android.Singleton {
+	return &tidyFixesSingleton{}
+}
+
+// tidyFixesSingleton merges the fix-its that clang-tidy exported for all variants of a module into
+// out/soong/tidy_fixes/<module dir>/<module name>.yaml, which is built by `m tidy_fixes`.
+// clang-apply-replacements applies all the replacement files in a directory and its
+// subdirectories, e.g. `clang-apply-replacements out/soong/tidy_fixes/path/to/project`.
+type tidyFixesSingleton struct{}
+
+func (s *tidyFixesSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	fixes := make(map[string]android.Paths)
+	ctx.VisitAllModules(func(module android.Module) {
+		if ccModule, ok := module.(*Module); ok && len(ccModule.tidyFixesFiles) > 0 {
+			path := filepath.Join(ctx.ModuleDir(module), ctx.ModuleName(module)+".yaml")
+			fixes[path] = append(fixes[path], ccModule.tidyFixesFiles...)
+		}
+	})
+
+	var outputs android.Paths
+	for _, path := range android.SortedStringKeys(fixes) {
+		output := android.PathForOutput(ctx, "tidy_fixes", path)
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        mergeTidyFixes,
+			Description: "merge clang-tidy fixes " + path,
+			Output:      output,
+			Inputs:      fixes[path],
+		})
+		outputs = append(outputs, output)
+	}
+	if len(outputs) > 0 {
+		ctx.Phony("tidy_fixes", outputs...)
+	}
+}
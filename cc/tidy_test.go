@@ -0,0 +1,74 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestTidyBaselineAndFixes(t *testing.T) {
+	bp := `
+		cc_library_static {
+			name: "libfoo",
+			srcs: ["foo.cpp"],
+			tidy: true,
+			tidy_checks_as_errors: ["misc-unused-parameters"],
+			tidy_baseline: "tidy_baseline.txt",
+		}
+
+		cc_library_static {
+			name: "libbar",
+			srcs: ["bar.cpp"],
+			tidy: true,
+			tidy_checks_as_errors: ["misc-unused-parameters"],
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureAddTextFile("tidy_baseline.txt", ""),
+	).RunTestWithBp(t, bp)
+
+	const variant = "android_arm64_armv8-a_static"
+	libfoo := result.ModuleForTests("libfoo", variant)
+	libbar := result.ModuleForTests("libbar", variant)
+
+	fooTidy := libfoo.Output("out/soong/.intermediates/libfoo/" + variant + "/obj/foo.tidy")
+	android.AssertBoolEquals(t, "libfoo writes the clang-tidy findings", true, fooTidy.Rule == clangTidyFindings)
+	android.AssertStringDoesNotContain(t, "libfoo tidy flags", fooTidy.Args["tidyFlags"], "-warnings-as-errors")
+	android.AssertStringEquals(t, "libfoo exported fixes",
+		"out/soong/.intermediates/libfoo/"+variant+"/obj/foo.fixes.yaml", fooTidy.Args["tidyFixes"])
+
+	check := libfoo.Rule("tidyBaselineCheck")
+	android.AssertPathsRelativeToTopEquals(t, "libfoo baseline check inputs",
+		[]string{"out/soong/.intermediates/libfoo/" + variant + "/obj/foo.tidy"}, check.Inputs)
+	android.AssertStringEquals(t, "libfoo baseline", "tidy_baseline.txt", check.Args["baseline"])
+	android.AssertPathsRelativeToTopEquals(t, "libfoo archive depends on the baseline check",
+		[]string{"out/soong/.intermediates/libfoo/" + variant + "/tidy_baseline.stamp"},
+		libfoo.Output("out/soong/.intermediates/libfoo/"+variant+"/libfoo.a").Implicits)
+
+	barTidy := libbar.Output("out/soong/.intermediates/libbar/" + variant + "/obj/bar.tidy")
+	android.AssertBoolEquals(t, "libbar prints the clang-tidy findings", true, barTidy.Rule == clangTidy)
+	android.AssertStringDoesContain(t, "libbar tidy flags", barTidy.Args["tidyFlags"], "-warnings-as-errors=misc-unused-parameters")
+	android.AssertBoolEquals(t, "libbar has no baseline check", true, libbar.MaybeRule("tidyBaselineCheck").Rule == nil)
+
+	merged := result.SingletonForTests("tidy_fixes").Output("out/soong/tidy_fixes/libbar.yaml")
+	android.AssertStringListContains(t, "libbar merged fixes inputs", merged.Inputs.Strings(),
+		"out/soong/.intermediates/libbar/"+variant+"/obj/bar.fixes.yaml")
+	android.AssertBoolEquals(t, "libfoo fixes are merged", true,
+		result.SingletonForTests("tidy_fixes").MaybeOutput("out/soong/tidy_fixes/libfoo.yaml").Rule != nil)
+}
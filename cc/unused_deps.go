@@ -0,0 +1,240 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/json"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// Unused dependency analysis.
+//
+// When CHECK_UNUSED_CC_DEPS=true is set, every variant of a cc module that is compiled for Android
+// or for Linux hosts records which of the libraries listed in its shared_libs, static_libs and
+// header_libs contributed nothing to its build.  The evidence is the headers listed in the depfiles
+// of its sources, the archive members listed in the link map of its final link and the undefined
+// dynamic symbols of its output.  Libraries whose headers are reexported are not analyzed.
+//
+// The unused_cc_deps singleton merges the results of all variants into
+// out/soong/unused_cc_deps.json, which is built by `m unused_cc_deps`.  A library is only
+// reported if no variant of the module used it, and the report can be applied with
+// `bpfix -w -remove_unused_cc_deps out/soong/unused_cc_deps.json <Android.bp files>`.
+
+var (
+	_ = pctx.HostBinToolVariable("unusedCcDepsCmd", "unused_cc_deps")
+
+	// Rule for finding the unused dependencies of a variant of a module.
+	unusedCcDeps = pctx.AndroidStaticRule("unusedCcDeps",
+		blueprint.RuleParams{
+			Command:     "$unusedCcDepsCmd -llvm_nm ${config.ClangBin}/llvm-nm -o $out $in",
+			CommandDeps: []string{"$unusedCcDepsCmd", "${config.ClangBin}/llvm-nm"},
+		})
+
+	// Rule for merging the unused dependencies of all variants of all modules.
+	unusedCcDepsMerge = pctx.AndroidStaticRule("unusedCcDepsMerge",
+		blueprint.RuleParams{
+			Command:        "$unusedCcDepsCmd -merge -o $out @$out.rsp",
+			CommandDeps:    []string{"$unusedCcDepsCmd"},
+			Rspfile:        "$out.rsp",
+			RspfileContent: "$in",
+		})
+)
+
+// unusedDepsCandidate is a library listed in shared_libs, static_libs or header_libs, in the
+// format that unused_cc_deps reads.
+type unusedDepsCandidate struct {
+	Name     string
+	Property string
+
+	IncludeDirs      []string `json:",omitempty"`
+	GeneratedHeaders []string `json:",omitempty"`
+
+	StaticLibrary string `json:",omitempty"`
+	SharedLibrary string `json:",omitempty"`
+}
+
+// unusedDepsInput is the input of unused_cc_deps for a variant of a module.
+type unusedDepsInput struct {
+	Module    string
+	Blueprint string
+	Depfiles  []string `json:",omitempty"`
+	LinkMap   string   `json:",omitempty"`
+	Output    string   `json:",omitempty"`
+	Deps      []unusedDepsCandidate
+}
+
+// unusedDepsAnalysis collects the candidates and the evidence of the unused dependency analysis of
+// a variant of a module.
+type unusedDepsAnalysis struct {
+	// The libraries listed in shared_libs, static_libs and header_libs, keyed by property and name.
+	declared map[string]bool
+
+	candidates []unusedDepsCandidate
+	// The shared libraries of the candidates, whose symbols unused_cc_deps reads.
+	sharedLibs android.Paths
+
+	// The copies of the depfiles of the sources, including the sources of the static variant whose
+	// objects a shared variant reuses.
+	headerDepFiles android.Paths
+
+	// The link map and the output of the final link, set by transformObjToDynamicBinary.
+	linkMap android.WritablePath
+	output  android.Path
+
+	// The unused dependencies of the variant.
+	result android.Path
+}
+
+// newUnusedDepsAnalysis returns the unused dependency analysis of a variant of a module, or nil
+// if it is disabled or the variant is not analyzed.
+func newUnusedDepsAnalysis(ctx android.ModuleContext, c *Module) *unusedDepsAnalysis {
+	if !ctx.Config().IsEnvTrue("CHECK_UNUSED_CC_DEPS") {
+		return nil
+	}
+	if c.compiler == nil || c.linker == nil || c.IsStubs() || ctx.Darwin() || ctx.Windows() {
+		return nil
+	}
+
+	declared := make(map[string]bool)
+	for _, props := range c.GetProperties() {
+		if linkerProps, ok := props.(*BaseLinkerProperties); ok {
+			for _, lib := range linkerProps.Header_libs {
+				declared["header_libs\x00"+lib] = true
+			}
+			for _, lib := range linkerProps.Static_libs {
+				declared["static_libs\x00"+lib] = true
+			}
+			for _, lib := range linkerProps.Shared_libs {
+				declared["shared_libs\x00"+lib] = true
+			}
+		}
+	}
+	if len(declared) == 0 {
+		return nil
+	}
+	return &unusedDepsAnalysis{declared: declared}
+}
+
+// addCandidate adds a direct library dependency to the analysis if it is listed in the
+// shared_libs, static_libs or header_libs of the module.
+func (a *unusedDepsAnalysis) addCandidate(depName string, tag libraryDependencyTag,
+	exporter FlagExporterInfo, linkFile android.OptionalPath) {
+
+	var property string
+	switch {
+	case tag.header():
+		property = "header_libs"
+	case tag.shared():
+		property = "shared_libs"
+	case tag.static() && !tag.wholeStatic:
+		property = "static_libs"
+	default:
+		return
+	}
+
+	name := android.RemoveOptionalPrebuiltPrefix(depName)
+	if !a.declared[property+"\x00"+name] {
+		return
+	}
+
+	candidate := unusedDepsCandidate{
+		Name:             name,
+		Property:         property,
+		IncludeDirs:      append(exporter.IncludeDirs.Strings(), exporter.SystemIncludeDirs.Strings()...),
+		GeneratedHeaders: exporter.GeneratedHeaders.Strings(),
+	}
+	if linkFile.Valid() {
+		switch property {
+		case "shared_libs":
+			candidate.SharedLibrary = linkFile.String()
+			a.sharedLibs = append(a.sharedLibs, linkFile.Path())
+		case "static_libs":
+			candidate.StaticLibrary = linkFile.String()
+		}
+	}
+	a.candidates = append(a.candidates, candidate)
+}
+
+// analyze writes the input of unused_cc_deps for the variant and the rule that finds its unused
+// dependencies.
+func (a *unusedDepsAnalysis) analyze(ctx android.ModuleContext) {
+	if len(a.candidates) == 0 {
+		return
+	}
+
+	input := unusedDepsInput{
+		Module:    ctx.ModuleName(),
+		Blueprint: ctx.BlueprintsFile(),
+		Depfiles:  a.headerDepFiles.Strings(),
+		Deps:      a.candidates,
+	}
+	implicits := append(android.Paths{}, a.headerDepFiles...)
+	implicits = append(implicits, a.sharedLibs...)
+	if a.linkMap != nil {
+		input.LinkMap = a.linkMap.String()
+		input.Output = a.output.String()
+		implicits = append(implicits, a.linkMap, a.output)
+	}
+
+	data, err := json.MarshalIndent(input, "", "  ")
+	if err != nil {
+		ctx.ModuleErrorf("failed to marshal the unused dependency analysis input: %s", err)
+		return
+	}
+	inputFile := android.PathForModuleOut(ctx, "unused_deps", "input.json")
+	android.WriteFileRule(ctx, inputFile, string(data))
+
+	result := android.PathForModuleOut(ctx, "unused_deps", "unused_deps.json")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        unusedCcDeps,
+		Description: "unused dependencies " + ctx.ModuleName(),
+		Output:      result,
+		Input:       inputFile,
+		Implicits:   implicits,
+	})
+	a.result = result
+}
+
+func unusedCcDepsSingletonFactory() android.Singleton {
+	return &unusedCcDepsSingleton{}
+}
+
+// unusedCcDepsSingleton merges the unused dependencies of all variants of all modules into
+// out/soong/unused_cc_deps.json.
+type unusedCcDepsSingleton struct{}
+
+func (s *unusedCcDepsSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	var results android.Paths
+	ctx.VisitAllModules(func(module android.Module) {
+		if ccModule, ok := module.(*Module); ok && ccModule.unusedDeps != nil && ccModule.unusedDeps.result != nil {
+			results = append(results, ccModule.unusedDeps.result)
+		}
+	})
+	if len(results) == 0 {
+		return
+	}
+
+	output := android.PathForOutput(ctx, "unused_cc_deps.json")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        unusedCcDepsMerge,
+		Description: "merge unused cc dependencies",
+		Output:      output,
+		Inputs:      results,
+	})
+	ctx.Phony("unused_cc_deps", output)
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestUnusedDeps(t *testing.T) {
+	bp := `
+		cc_library_headers {
+			name: "libheaders",
+			export_include_dirs: ["include"],
+		}
+
+		cc_library_static {
+			name: "libstatic",
+			srcs: ["static.cpp"],
+		}
+
+		cc_library_shared {
+			name: "libshared",
+			srcs: ["shared.cpp"],
+		}
+
+		cc_library_shared {
+			name: "libreexported",
+			srcs: ["reexported.cpp"],
+		}
+
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.cpp"],
+			header_libs: ["libheaders"],
+			static_libs: ["libstatic"],
+			shared_libs: ["libshared", "libreexported"],
+			export_shared_lib_headers: ["libreexported"],
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureMergeEnv(map[string]string{"CHECK_UNUSED_CC_DEPS": "true"}),
+	).RunTestWithBp(t, bp)
+
+	const variant = "android_arm64_armv8-a_shared"
+	const out = "out/soong/.intermediates/libfoo/" + variant
+	libfoo := result.ModuleForTests("libfoo", variant)
+
+	compile := libfoo.Output(out + "/obj/foo.o")
+	android.AssertBoolEquals(t, "libfoo keeps the depfile", true, compile.Rule == ccKeepHeaderDeps)
+	android.AssertStringEquals(t, "libfoo depfile copy", out+"/obj/foo.headers.d", compile.Args["headerDeps"])
+
+	link := libfoo.Output(out + "/unstripped/libfoo.so")
+	android.AssertStringDoesContain(t, "libfoo link map flag", link.Args["ldFlags"],
+		"-Wl,-Map="+out+"/unused_deps/libfoo.so.map")
+
+	var input unusedDepsInput
+	content := android.ContentFromFileRuleForTests(t, libfoo.Output(out+"/unused_deps/input.json"))
+	if err := json.Unmarshal([]byte(content), &input); err != nil {
+		t.Fatal(err)
+	}
+	android.AssertStringEquals(t, "module", "libfoo", input.Module)
+	android.AssertStringEquals(t, "blueprint", "Android.bp", input.Blueprint)
+	android.AssertDeepEquals(t, "depfiles", []string{out + "/obj/foo.headers.d"}, input.Depfiles)
+	android.AssertStringEquals(t, "link map", out+"/unused_deps/libfoo.so.map", input.LinkMap)
+	android.AssertStringEquals(t, "output", out+"/unstripped/libfoo.so", input.Output)
+
+	var candidates []string
+	for _, dep := range input.Deps {
+		candidates = append(candidates, dep.Property+":"+dep.Name)
+	}
+	android.AssertDeepEquals(t, "candidates", []string{
+		"header_libs:libheaders",
+		"shared_libs:libshared",
+		"static_libs:libstatic",
+	}, android.SortedUniqueStrings(candidates))
+
+	analysis := libfoo.Rule("unusedCcDeps")
+	android.AssertStringListContains(t, "analysis reads the link map", analysis.Implicits.Strings(),
+		out+"/unused_deps/libfoo.so.map")
+
+	merged := result.SingletonForTests("unused_cc_deps").Output("out/soong/unused_cc_deps.json")
+	android.AssertStringListContains(t, "merged results", merged.Inputs.Strings(),
+		out+"/unused_deps/unused_deps.json")
+}
@@ -84,6 +84,7 @@ func flagsToBuilderFlags(in Flags) builderFlags {
 		toolchain:     in.Toolchain,
 		gcovCoverage:  in.GcovCoverage,
 		tidy:          in.Tidy,
+		tidyBaseline:  in.TidyBaseline.Valid(),
 		sAbiDump:      in.SAbiDump,
 		emitXrefs:     in.EmitXrefs,
 
@@ -98,6 +99,10 @@ func flagsToBuilderFlags(in Flags) builderFlags {
 
 		yacc: in.Yacc,
 		lex:  in.Lex,
+
+		unusedDeps: in.unusedDeps,
+
+		headerChecks: in.headerChecks,
 	}
 }
 
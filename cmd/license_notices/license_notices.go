@@ -0,0 +1,159 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// license_notices writes the notices for a set of installed files from the description of their
+// licenses that is written by Soong, see android/license_notices.go.
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+var (
+	input      = flag.String("i", "", "JSON description of the installed files written by Soong")
+	txtOutput  = flag.String("txt", "", "output text notice file")
+	htmlOutput = flag.String("html", "", "output HTML notice file, compressed if it ends in .gz")
+	xmlOutput  = flag.String("xml", "", "output XML notice file, compressed if it ends in .gz")
+)
+
+// noticesInput is the JSON description of the installed files written by
+// android/license_notices.go.
+type noticesInput struct {
+	Title    string
+	Files    []file
+	Licenses []license
+}
+
+type file struct {
+	// Path is the path of the file on the device.
+	Path string
+
+	// Licenses are the names of the license modules that apply to the file.
+	Licenses []string
+}
+
+type license struct {
+	Name            string
+	PackageName     string
+	CopyrightNotice string
+	TextFiles       []string
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: license_notices -i <input.json> [-txt <NOTICE.txt>] [-html <NOTICE.html.gz>] [-xml <NOTICE.xml.gz>]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *input == "" || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "license_notices:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	data, err := ioutil.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+	var in noticesInput
+	if err := json.Unmarshal(data, &in); err != nil {
+		return fmt.Errorf("failed to parse %q: %w", *input, err)
+	}
+
+	licenseTexts, err := readLicenseTexts(in)
+	if err != nil {
+		return err
+	}
+
+	n := newNotices(in, licenseTexts)
+
+	for _, output := range []struct {
+		path  string
+		write func(w io.Writer) error
+	}{
+		{*txtOutput, n.writeText},
+		{*htmlOutput, n.writeHTML},
+		{*xmlOutput, n.writeXML},
+	} {
+		if output.path == "" {
+			continue
+		}
+		if err := writeFile(output.path, output.write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readLicenseTexts returns the concatenated texts of each license, keyed by the name of the
+// license.
+func readLicenseTexts(in noticesInput) (map[string]string, error) {
+	ret := make(map[string]string)
+	for _, l := range in.Licenses {
+		var text []byte
+		for _, textFile := range l.TextFiles {
+			data, err := ioutil.ReadFile(textFile)
+			if err != nil {
+				return nil, err
+			}
+			if len(text) > 0 && text[len(text)-1] != '\n' {
+				text = append(text, '\n')
+			}
+			text = append(text, data...)
+		}
+		ret[l.Name] = string(text)
+	}
+	return ret, nil
+}
+
+// writeFile writes a file with the contents written by write, gzipped if the path ends in .gz.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(f)
+	var gz *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		// The header has no modification time so that the output is reproducible.
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+
+	err = write(w)
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %q: %w", path, err)
+	}
+	return f.Close()
+}
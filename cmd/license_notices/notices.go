@@ -0,0 +1,242 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+// The notices use the same formats as build/soong/scripts/generate-notice-files.py, so that they
+// can be read by the same tools.  Instead of one notice per NOTICE file, there is one notice per
+// distinct license text, and the copyright notices of all of the licenses with the text precede it.
+
+// notice is a license text and the files that it applies to.
+type notice struct {
+	// id is the MD5 of the text, which identifies the notice in the XML format.
+	id               string
+	copyrightNotices []string
+	text             string
+	files            []string
+}
+
+type notices struct {
+	title string
+
+	// notices are sorted by id.
+	notices []*notice
+
+	// files are the sorted paths of the files that have notices.
+	files []string
+
+	// fileNotices are the notices of each file.
+	fileNotices map[string][]*notice
+}
+
+// newNotices returns the notices of the files, deduplicating the licenses that have the same text.
+// Licenses that have neither a text nor a copyright notice are ignored.
+func newNotices(in noticesInput, licenseTexts map[string]string) *notices {
+	n := &notices{
+		title:       in.Title,
+		fileNotices: make(map[string][]*notice),
+	}
+
+	byText := make(map[string]*notice)
+	licenseNotices := make(map[string]*notice)
+	for _, l := range in.Licenses {
+		text := licenseTexts[l.Name]
+		if text == "" && l.CopyrightNotice == "" {
+			continue
+		}
+		nt := byText[text]
+		if nt == nil {
+			sum := md5.Sum([]byte(text))
+			nt = &notice{
+				id:   hex.EncodeToString(sum[:]),
+				text: text,
+			}
+			byText[text] = nt
+			n.notices = append(n.notices, nt)
+		}
+		if l.CopyrightNotice != "" {
+			nt.copyrightNotices = append(nt.copyrightNotices, l.CopyrightNotice)
+		}
+		licenseNotices[l.Name] = nt
+	}
+
+	for _, f := range in.Files {
+		for _, l := range f.Licenses {
+			nt := licenseNotices[l]
+			if nt == nil || containsNotice(n.fileNotices[f.Path], nt) {
+				continue
+			}
+			if len(n.fileNotices[f.Path]) == 0 {
+				n.files = append(n.files, f.Path)
+			}
+			n.fileNotices[f.Path] = append(n.fileNotices[f.Path], nt)
+			nt.files = append(nt.files, f.Path)
+		}
+	}
+
+	// Drop the notices of licenses that only apply to files that aren't installed.
+	used := n.notices[:0]
+	for _, nt := range n.notices {
+		if len(nt.files) > 0 {
+			nt.copyrightNotices = sortedUnique(nt.copyrightNotices)
+			sort.Strings(nt.files)
+			used = append(used, nt)
+		}
+	}
+	n.notices = used
+	sort.Slice(n.notices, func(i, j int) bool { return n.notices[i].id < n.notices[j].id })
+	sort.Strings(n.files)
+	for _, f := range n.files {
+		sort.Slice(n.fileNotices[f], func(i, j int) bool { return n.fileNotices[f][i].id < n.fileNotices[f][j].id })
+	}
+
+	return n
+}
+
+func containsNotice(list []*notice, nt *notice) bool {
+	for _, x := range list {
+		if x == nt {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedUnique(list []string) []string {
+	sort.Strings(list)
+	ret := list[:0]
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+// content returns the copyright notices followed by the license text.
+func (nt *notice) content() string {
+	if len(nt.copyrightNotices) == 0 {
+		return nt.text
+	}
+	content := strings.Join(nt.copyrightNotices, "\n") + "\n"
+	if nt.text != "" {
+		content += "\n" + nt.text
+	}
+	return content
+}
+
+// writeText writes the notices in the text format.
+func (n *notices) writeText(w io.Writer) error {
+	sb := &strings.Builder{}
+	fmt.Fprintln(sb, n.title)
+	for _, nt := range n.notices {
+		fmt.Fprintln(sb, "============================================================")
+		fmt.Fprintln(sb, "Notices for file(s):")
+		for _, f := range nt.files {
+			fmt.Fprintln(sb, f)
+		}
+		fmt.Fprintln(sb, "------------------------------------------------------------")
+		fmt.Fprintln(sb, nt.content())
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+const htmlCSS = `<style type="text/css">
+body { padding: 0; font-family: sans-serif; }
+.same-license { background-color: #eeeeee; border-top: 20px solid white; padding: 10px; }
+.label { font-weight: bold; }
+.file-list { margin-left: 1em; color: blue; }
+</style>
+`
+
+// writeHTML writes the notices in the HTML format, with a table of contents that links each file
+// to its notices.
+func (n *notices) writeHTML(w io.Writer) error {
+	htmlIDs := make(map[*notice]string)
+	for i, nt := range n.notices {
+		htmlIDs[nt] = fmt.Sprintf("id%d", i)
+	}
+
+	sb := &strings.Builder{}
+	fmt.Fprintln(sb, "<html><head>")
+	fmt.Fprintf(sb, "<title>%s</title>\n", html.EscapeString(n.title))
+	fmt.Fprint(sb, htmlCSS)
+	fmt.Fprintln(sb, `</head><body topmargin="0" leftmargin="0" rightmargin="0" bottommargin="0">`)
+
+	fmt.Fprintln(sb, `<div class="toc">`)
+	fmt.Fprintln(sb, "<ul>")
+	for _, f := range n.files {
+		for _, nt := range n.fileNotices[f] {
+			fmt.Fprintf(sb, "<li><a href=\"#%s\">%s</a></li>\n", htmlIDs[nt], html.EscapeString(f))
+		}
+	}
+	fmt.Fprintln(sb, "</ul>")
+	fmt.Fprintln(sb, "</div><!-- table of contents -->")
+
+	fmt.Fprintln(sb, `<table cellpadding="0" cellspacing="0" border="0">`)
+	for _, nt := range n.notices {
+		fmt.Fprintf(sb, "<tr id=\"%s\"><td class=\"same-license\">\n", htmlIDs[nt])
+		fmt.Fprintln(sb, `<div class="label">Notices for file(s):</div>`)
+		fmt.Fprintln(sb, `<div class="file-list">`)
+		for _, f := range nt.files {
+			fmt.Fprintf(sb, "%s <br/>\n", html.EscapeString(f))
+		}
+		fmt.Fprintln(sb, "</div><!-- file-list -->")
+		fmt.Fprintln(sb, `<pre class="license-text">`)
+		fmt.Fprintln(sb, html.EscapeString(nt.content()))
+		fmt.Fprintln(sb, "</pre><!-- license-text -->")
+		fmt.Fprintln(sb, "</td></tr><!-- same-license -->")
+	}
+	fmt.Fprintln(sb, "</table>")
+	fmt.Fprintln(sb, "</body></html>")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeXML writes the notices in the XML format, which indexes the files by the id of their
+// notices.
+func (n *notices) writeXML(w io.Writer) error {
+	sb := &strings.Builder{}
+	fmt.Fprintln(sb, `<?xml version="1.0" encoding="utf-8"?>`)
+	fmt.Fprintln(sb, "<licenses>")
+	for _, f := range n.files {
+		for _, nt := range n.fileNotices[f] {
+			fmt.Fprintf(sb, "<file-name contentId=\"%s\">%s</file-name>\n", nt.id, html.EscapeString(f))
+		}
+	}
+	fmt.Fprintln(sb)
+	for _, nt := range n.notices {
+		// The content is escaped even though it is in a CDATA section, the readers of the XML format
+		// expect it.
+		fmt.Fprintf(sb, "<file-content contentId=\"%s\"><![CDATA[%s]]></file-content>\n",
+			nt.id, html.EscapeString(nt.content()))
+		fmt.Fprintln(sb)
+	}
+	fmt.Fprintln(sb, "</licenses>")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,199 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testInput = noticesInput{
+	Title: "Notices for files contained in the system partition",
+	Files: []file{
+		{Path: "/system/bin/foo", Licenses: []string{"foo_license"}},
+		{Path: "/system/bin/bar", Licenses: []string{"bar_license", "mit_license"}},
+		{Path: "/system/lib64/libbaz.so", Licenses: []string{"baz_license"}},
+		{Path: "/system/etc/unlicensed"},
+	},
+	Licenses: []license{
+		{Name: "foo_license", CopyrightNotice: "Copyright (C) Foo"},
+		{Name: "bar_license", CopyrightNotice: "Copyright (C) Bar"},
+		{Name: "baz_license"},
+		{Name: "mit_license"},
+		{Name: "unused_license", CopyrightNotice: "Copyright (C) Unused"},
+	},
+}
+
+var testLicenseTexts = map[string]string{
+	"foo_license":    "Apache License\n",
+	"bar_license":    "Apache License\n",
+	"mit_license":    "MIT License\n",
+	"unused_license": "Unused License\n",
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestNewNotices(t *testing.T) {
+	n := newNotices(testInput, testLicenseTexts)
+
+	if len(n.notices) != 2 {
+		t.Fatalf("expected 2 notices, got %d", len(n.notices))
+	}
+
+	notices := map[string]*notice{}
+	for _, nt := range n.notices {
+		notices[nt.text] = nt
+	}
+
+	apache := notices["Apache License\n"]
+	if apache == nil {
+		t.Fatal("missing notice for the Apache license text")
+	}
+	if g, w := apache.id, md5Hex("Apache License\n"); g != w {
+		t.Errorf("incorrect id, want %q, got %q", w, g)
+	}
+	if g, w := strings.Join(apache.files, " "), "/system/bin/bar /system/bin/foo"; g != w {
+		t.Errorf("incorrect files, want %q, got %q", w, g)
+	}
+	if g, w := apache.content(), "Copyright (C) Bar\nCopyright (C) Foo\n\nApache License\n"; g != w {
+		t.Errorf("incorrect content, want %q, got %q", w, g)
+	}
+
+	mit := notices["MIT License\n"]
+	if mit == nil {
+		t.Fatal("missing notice for the MIT license text")
+	}
+	if g, w := strings.Join(mit.files, " "), "/system/bin/bar"; g != w {
+		t.Errorf("incorrect files, want %q, got %q", w, g)
+	}
+
+	if g, w := strings.Join(n.files, " "), "/system/bin/bar /system/bin/foo"; g != w {
+		t.Errorf("incorrect files with notices, want %q, got %q", w, g)
+	}
+	if g := len(n.fileNotices["/system/bin/bar"]); g != 2 {
+		t.Errorf("expected 2 notices for /system/bin/bar, got %d", g)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	in := noticesInput{
+		Title: "Notices",
+		Files: []file{
+			{Path: "/system/bin/foo", Licenses: []string{"foo_license"}},
+		},
+		Licenses: []license{
+			{Name: "foo_license", CopyrightNotice: "Copyright (C) Foo"},
+		},
+	}
+	n := newNotices(in, map[string]string{"foo_license": "License <text>\n"})
+
+	buf := &bytes.Buffer{}
+	if err := n.writeText(buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `Notices
+============================================================
+Notices for file(s):
+/system/bin/foo
+------------------------------------------------------------
+Copyright (C) Foo
+
+License <text>
+
+`
+	if g := buf.String(); g != want {
+		t.Errorf("incorrect text notices\nwant:\n%s\ngot:\n%s", want, g)
+	}
+
+	buf.Reset()
+	if err := n.writeXML(buf); err != nil {
+		t.Fatal(err)
+	}
+	id := md5Hex("License <text>\n")
+	wantXML := `<?xml version="1.0" encoding="utf-8"?>
+<licenses>
+<file-name contentId="` + id + `">/system/bin/foo</file-name>
+
+<file-content contentId="` + id + `"><![CDATA[Copyright (C) Foo
+
+License &lt;text&gt;
+]]></file-content>
+
+</licenses>
+`
+	if g := buf.String(); g != wantXML {
+		t.Errorf("incorrect XML notices\nwant:\n%s\ngot:\n%s", wantXML, g)
+	}
+
+	buf.Reset()
+	if err := n.writeHTML(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []string{
+		`<li><a href="#id0">/system/bin/foo</a></li>`,
+		`<tr id="id0"><td class="same-license">`,
+		"License &lt;text&gt;",
+	} {
+		if !strings.Contains(buf.String(), w) {
+			t.Errorf("HTML notices don't contain %q:\n%s", w, buf.String())
+		}
+	}
+}
+
+func TestWriteFileGzip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "license_notices_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	n := newNotices(testInput, testLicenseTexts)
+	path := filepath.Join(dir, "NOTICE.xml.gz")
+	if err := writeFile(path, n.writeXML); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &bytes.Buffer{}
+	if err := n.writeXML(want); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("incorrect gzipped contents\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/blueprint/pathtools"
+
+	"android/soong/jar"
+	"android/soong/third_party/zip"
+)
+
+// A conflict policy file tells merge_zips what to do when the same path exists with different
+// contents in more than one input zip.  Each line contains a glob, in the syntax accepted by
+// -stripFile, followed by a policy.  The first line whose glob matches the path is used, paths
+// that don't match any line are an error unless -ignore-duplicates is set.  Empty lines and lines
+// starting with # are ignored.  For example:
+//
+//	# Service loader files list one implementation per line, keep all of them.
+//	META-INF/services/* concatenate
+//	**/*.properties last
+
+type conflictPolicy string
+
+const (
+	// Take the entry from the first input zip that contains the path.
+	policyFirst conflictPolicy = "first"
+	// Take the entry from the last input zip that contains the path.
+	policyLast conflictPolicy = "last"
+	// Fail if the entries are not identical.
+	policyIdentical conflictPolicy = "identical"
+	// Concatenate the contents of the entries in the order of the input zips, separated by
+	// newlines.
+	policyConcatenate conflictPolicy = "concatenate"
+)
+
+var validConflictPolicies = []conflictPolicy{policyFirst, policyLast, policyIdentical, policyConcatenate}
+
+// maxDiffSize is the maximum size of entries that are included as a text diff in conflict reports.
+const maxDiffSize = 16 * 1024
+
+type conflictPolicyRule struct {
+	glob   string
+	policy conflictPolicy
+}
+
+// parseConflictPolicies parses a conflict policy file.
+func parseConflictPolicies(r io.Reader, name string) ([]conflictPolicyRule, error) {
+	var rules []conflictPolicyRule
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected <glob> <policy>, got %q", name, lineNum, line)
+		}
+
+		rule := conflictPolicyRule{glob: fields[0], policy: conflictPolicy(fields[1])}
+		if !isValidConflictPolicy(rule.policy) {
+			return nil, fmt.Errorf("%s:%d: unknown policy %q, expected one of %v",
+				name, lineNum, rule.policy, validConflictPolicies)
+		}
+		if _, err := pathtools.Match(rule.glob, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid glob %q: %s", name, lineNum, rule.glob, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	return rules, nil
+}
+
+func isValidConflictPolicy(policy conflictPolicy) bool {
+	for _, valid := range validConflictPolicies {
+		if policy == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// conflictPolicyFor returns the policy of the first rule whose glob matches name.
+func conflictPolicyFor(rules []conflictPolicyRule, name string) (conflictPolicy, bool) {
+	for _, rule := range rules {
+		if match, _ := pathtools.Match(rule.glob, name); match {
+			return rule.policy, true
+		}
+	}
+	return "", false
+}
+
+// conflict records all the entries with different contents that were found for a path.
+type conflict struct {
+	name    string
+	policy  conflictPolicy
+	sources []ZipEntryContents
+}
+
+// conflictSource is a source of a conflict in the JSON conflict report.
+type conflictSource struct {
+	Source string
+	Size   uint64
+	CRC32  string
+	// Diff is a line diff from the contents of the first source, if both are small text files.
+	Diff string `json:",omitempty"`
+}
+
+// conflictReport is a conflict in the JSON conflict report.
+type conflictReport struct {
+	Path    string
+	Policy  string
+	Sources []conflictSource
+}
+
+func (c *conflict) report() (conflictReport, error) {
+	ret := conflictReport{
+		Path:   c.name,
+		Policy: string(c.policy),
+	}
+
+	var first []byte
+	for i, source := range c.sources {
+		reportSource := conflictSource{
+			Source: source.String(),
+			Size:   source.Size(),
+			CRC32:  fmt.Sprintf("%08x", source.CRC32()),
+		}
+		if source.Size() <= maxDiffSize {
+			contents, err := source.Contents()
+			if err != nil {
+				return conflictReport{}, err
+			}
+			if i == 0 {
+				first = contents
+			} else if isText(first) && isText(contents) {
+				reportSource.Diff = lineDiff(first, contents)
+			}
+		}
+		ret.Sources = append(ret.Sources, reportSource)
+	}
+	return ret, nil
+}
+
+// String returns the conflict report in the format used in error messages.
+func (r conflictReport) String() string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "Duplicate path %s (policy: %s) found in:\n", r.Path, r.Policy)
+	for _, source := range r.Sources {
+		fmt.Fprintf(sb, "  %s: size %d, crc32 %s\n", source.Source, source.Size, source.CRC32)
+	}
+	for _, source := range r.Sources[1:] {
+		if source.Diff != "" {
+			fmt.Fprintf(sb, "--- %s\n+++ %s\n%s", r.Sources[0].Source, source.Source, source.Diff)
+		}
+	}
+	return sb.String()
+}
+
+// writeConflictReport writes a JSON report of the conflicts, sorted by path.
+func writeConflictReport(w io.Writer, conflicts map[string]*conflict) error {
+	names := make([]string, 0, len(conflicts))
+	for name := range conflicts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	reports := make([]conflictReport, 0, len(names))
+	for _, name := range names {
+		report, err := conflicts[name].report()
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	}
+
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// concatenateEntries returns an entry whose contents are the contents of a followed by the
+// contents of b, with a newline between them if a doesn't end in one.
+func concatenateEntries(name string, a, b ZipEntryContents) (ZipEntryContents, error) {
+	aContents, err := a.Contents()
+	if err != nil {
+		return nil, err
+	}
+	bContents, err := b.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	contents := append([]byte(nil), aContents...)
+	if len(contents) > 0 && contents[len(contents)-1] != '\n' {
+		contents = append(contents, '\n')
+	}
+	contents = append(contents, bContents...)
+
+	fh := &zip.FileHeader{
+		Name:               name,
+		Method:             zip.Deflate,
+		UncompressedSize64: uint64(len(contents)),
+	}
+	fh.SetMode(0644)
+	fh.SetModTime(jar.DefaultTime)
+	return ZipEntryFromBuffer{fh, contents}, nil
+}
+
+// readZipFile returns the uncompressed contents of a zip entry.
+func readZipFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// isText returns true if the contents look like text.
+func isText(contents []byte) bool {
+	return utf8.Valid(contents) && bytes.IndexByte(contents, 0) == -1
+}
+
+// lineDiff returns a diff of the lines of a and b, with unchanged lines prefixed by a space,
+// removed lines by - and added lines by +.
+func lineDiff(a, b []byte) string {
+	aLines := splitLines(a)
+	bLines := splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of aLines[i:] and bLines[j:].
+	lcs := make([][]int, len(aLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bLines)+1)
+	}
+	for i := len(aLines) - 1; i >= 0; i-- {
+		for j := len(bLines) - 1; j >= 0; j-- {
+			if aLines[i] == bLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	sb := &strings.Builder{}
+	i, j := 0, 0
+	for i < len(aLines) || j < len(bLines) {
+		switch {
+		case i < len(aLines) && j < len(bLines) && aLines[i] == bLines[j]:
+			fmt.Fprintf(sb, " %s\n", aLines[i])
+			i++
+			j++
+		case j == len(bLines) || (i < len(aLines) && lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(sb, "-%s\n", aLines[i])
+			i++
+		default:
+			fmt.Fprintf(sb, "+%s\n", bLines[j])
+			j++
+		}
+	}
+	return sb.String()
+}
+
+func splitLines(contents []byte) []string {
+	s := strings.TrimSuffix(string(contents), "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,207 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"android/soong/jar"
+	"android/soong/third_party/zip"
+)
+
+func TestParseConflictPolicies(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   string
+		out  []conflictPolicyRule
+		err  string
+	}{
+		{
+			name: "policies",
+			in: `
+# comment
+META-INF/services/*  concatenate
+**/*.properties last
+a first
+b identical
+`,
+			out: []conflictPolicyRule{
+				{"META-INF/services/*", policyConcatenate},
+				{"**/*.properties", policyLast},
+				{"a", policyFirst},
+				{"b", policyIdentical},
+			},
+		},
+		{
+			name: "unknown policy",
+			in:   "a first\nb newest\n",
+			err:  `policies:2: unknown policy "newest"`,
+		},
+		{
+			name: "missing policy",
+			in:   "a\n",
+			err:  `policies:1: expected <glob> <policy>`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := parseConflictPolicies(strings.NewReader(test.in), "policies")
+			if test.err != "" {
+				if err == nil {
+					t.Fatal("missing err, expected: ", test.err)
+				} else if !strings.Contains(err.Error(), test.err) {
+					t.Fatal("incorrect err, want:", test.err, "got:", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(out, test.out) {
+				t.Errorf("incorrect policies\nwant: %v\n got: %v", test.out, out)
+			}
+		})
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	a := "com.example.A\ncom.example.B\ncom.example.C\n"
+	b := "com.example.A\ncom.example.C\ncom.example.D"
+	want := " com.example.A\n-com.example.B\n com.example.C\n+com.example.D\n"
+	if got := lineDiff([]byte(a), []byte(b)); got != want {
+		t.Errorf("incorrect diff\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestConflictConcatenateAndReport(t *testing.T) {
+	services1 := testZipEntry{"META-INF/services/foo", 0644, []byte("com.example.A\n")}
+	services2 := testZipEntry{"META-INF/services/foo", 0644, []byte("com.example.B")}
+	services3 := testZipEntry{"META-INF/services/foo", 0644, []byte("com.example.C\n")}
+
+	inputZips := []InputZip{
+		&testInputZip{name: "in0", entries: []testZipEntry{services1, a}},
+		&testInputZip{name: "in1", entries: []testZipEntry{services2, a2}},
+		&testInputZip{name: "in2", entries: []testZipEntry{services3}},
+	}
+	policies := []conflictPolicyRule{
+		{"META-INF/services/*", policyConcatenate},
+		{"*", policyFirst},
+	}
+
+	out := &bytes.Buffer{}
+	writer := zip.NewWriter(out)
+	report := &bytes.Buffer{}
+	err := mergeZips(inputZips, writer, "", "", false, false, false, false, false,
+		nil, nil, nil, policies, report, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != services1.name || zr.File[1].Name != a.name {
+		t.Fatalf("incorrect zip output:\n%s", dumpZip(out.Bytes()))
+	}
+	contents, err := readZipFile(zr.File[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "com.example.A\ncom.example.B\ncom.example.C\n"; string(contents) != want {
+		t.Errorf("incorrect concatenated contents\nwant: %q\n got: %q", want, string(contents))
+	}
+
+	var reports []conflictReport
+	if err := json.Unmarshal(report.Bytes(), &reports); err != nil {
+		t.Fatal(err)
+	}
+	want := []conflictReport{
+		{
+			Path:   "META-INF/services/foo",
+			Policy: "concatenate",
+			Sources: []conflictSource{
+				{Source: "in0!META-INF/services/foo", Size: 14, CRC32: "807a5a9c"},
+				{Source: "in1!META-INF/services/foo", Size: 13, CRC32: "895e7603",
+					Diff: "-com.example.A\n+com.example.B\n"},
+				{Source: "in2!META-INF/services/foo", Size: 14, CRC32: "b24c381e",
+					Diff: "-com.example.A\n+com.example.C\n"},
+			},
+		},
+		{
+			Path:   "a",
+			Policy: "first",
+			Sources: []conflictSource{
+				{Source: "in0!a", Size: 3, CRC32: "8c736521"},
+				{Source: "in1!a", Size: 4, CRC32: "5bcb104a", Diff: "-foo\n+FOO2\n"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(reports, want) {
+		t.Errorf("incorrect report\nwant: %+v\n got: %+v", want, reports)
+	}
+}
+
+func TestConflictReportBufferedSource(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "manifest.txt")
+	if err := ioutil.WriteFile(manifest, []byte("Main-Class: Foo\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	_, manifestContents, err := jar.ManifestFileContents([]byte("Main-Class: Foo\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputManifest := testZipEntry{jar.ManifestFile, 0644, []byte("Manifest-Version: 1.0\n")}
+	inputZips := []InputZip{
+		&testInputZip{name: "in0", entries: []testZipEntry{inputManifest}},
+	}
+	policies := []conflictPolicyRule{
+		{jar.ManifestFile, policyFirst},
+	}
+
+	writer := zip.NewWriter(&bytes.Buffer{})
+	report := &bytes.Buffer{}
+	err = mergeZips(inputZips, writer, manifest, "", false, false, false, true, false,
+		nil, nil, nil, policies, report, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []conflictReport
+	if err := json.Unmarshal(report.Bytes(), &reports); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || len(reports[0].Sources) != 2 {
+		t.Fatalf("expected one conflict with two sources, got %+v", reports)
+	}
+	// The manifest passed with -m is buffered, its CRC is computed from its contents.
+	source := reports[0].Sources[0]
+	if want := fmt.Sprintf("%08x", crc32.ChecksumIEEE(manifestContents)); source.CRC32 != want {
+		t.Errorf("incorrect crc32 of %s, want %s, got %s", source.Source, want, source.CRC32)
+	}
+}
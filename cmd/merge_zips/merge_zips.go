@@ -24,6 +24,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 
@@ -33,6 +34,7 @@ import (
 
 	"android/soong/jar"
 	"android/soong/third_party/zip"
+	soongzip "android/soong/zip"
 )
 
 // Input zip: we can open it, close it, and obtain an array of entries
@@ -50,7 +52,9 @@ type ZipEntryContents interface {
 	IsDir() bool
 	CRC32() uint32
 	Size() uint64
+	Contents() ([]byte, error)
 	WriteToZip(dest string, zw *zip.Writer) error
+	RecompressToZip(dest string, r *soongzip.Recompressor) error
 }
 
 // a ZipEntryFromZip is a ZipEntryContents that pulls its content from another zip
@@ -92,6 +96,13 @@ func (ze ZipEntryFromZip) Size() uint64 {
 	return ze.size
 }
 
+func (ze ZipEntryFromZip) Contents() ([]byte, error) {
+	if err := ze.inputZip.Open(); err != nil {
+		return nil, err
+	}
+	return readZipFile(ze.inputZip.Entries()[ze.index])
+}
+
 func (ze ZipEntryFromZip) WriteToZip(dest string, zw *zip.Writer) error {
 	if err := ze.inputZip.Open(); err != nil {
 		return err
@@ -99,6 +110,13 @@ func (ze ZipEntryFromZip) WriteToZip(dest string, zw *zip.Writer) error {
 	return zw.CopyFrom(ze.inputZip.Entries()[ze.index], dest)
 }
 
+func (ze ZipEntryFromZip) RecompressToZip(dest string, r *soongzip.Recompressor) error {
+	if err := ze.inputZip.Open(); err != nil {
+		return err
+	}
+	return r.Recompress(ze.inputZip.Entries()[ze.index], dest)
+}
+
 // a ZipEntryFromBuffer is a ZipEntryContents that pulls its content from a []byte
 type ZipEntryFromBuffer struct {
 	fh      *zip.FileHeader
@@ -121,6 +139,16 @@ func (be ZipEntryFromBuffer) Size() uint64 {
 	return uint64(len(be.content))
 }
 
+func (be ZipEntryFromBuffer) Contents() ([]byte, error) {
+	return be.content, nil
+}
+
+func (be ZipEntryFromBuffer) RecompressToZip(dest string, r *soongzip.Recompressor) error {
+	return r.Write(func(zw *zip.Writer) error {
+		return be.WriteToZip(dest, zw)
+	})
+}
+
 func (be ZipEntryFromBuffer) WriteToZip(dest string, zw *zip.Writer) error {
 	w, err := zw.CreateHeader(be.fh)
 	if err != nil {
@@ -147,6 +175,12 @@ type OutputZip struct {
 	excludeDirs      []string
 	excludeFiles     []string
 	sourceByDest     map[string]ZipEntryContents
+	// destOrder is the order in which the entries were added, used to write the entries
+	// in the order of the input zips when writing them is delayed.
+	destOrder        []string
+	conflictPolicies []conflictPolicyRule
+	conflicts        map[string]*conflict
+	recompress       *soongzip.RecompressArgs
 }
 
 func NewOutputZip(outputWriter *zip.Writer, sortEntries, emulateJar, stripDirEntries, ignoreDuplicates bool) *OutputZip {
@@ -157,6 +191,7 @@ func NewOutputZip(outputWriter *zip.Writer, sortEntries, emulateJar, stripDirEnt
 		sortEntries:      sortEntries,
 		sourceByDest:     make(map[string]ZipEntryContents, 0),
 		ignoreDuplicates: ignoreDuplicates,
+		conflicts:        make(map[string]*conflict),
 	}
 }
 
@@ -171,6 +206,21 @@ func (oz *OutputZip) setExcludeFiles(excludeFiles []string) {
 	oz.excludeFiles = excludeFiles
 }
 
+func (oz *OutputZip) setConflictPolicies(conflictPolicies []conflictPolicyRule) {
+	oz.conflictPolicies = conflictPolicies
+}
+
+func (oz *OutputZip) setRecompress(recompress *soongzip.RecompressArgs) {
+	oz.recompress = recompress
+}
+
+// Returns true if writing the entries is delayed until all the input zips have been read, either
+// because they need to be rearranged, because the policy for a conflict may replace an entry or
+// because they are recompressed in parallel.
+func (oz *OutputZip) delayWrites() bool {
+	return oz.emulateJar || oz.sortEntries || len(oz.conflictPolicies) > 0 || oz.recompress != nil
+}
+
 // Adds an entry with given name whose source is given ZipEntryContents. Returns old ZipEntryContents
 // if entry with given name already exists.
 func (oz *OutputZip) addZipEntry(name string, source ZipEntryContents) (ZipEntryContents, error) {
@@ -178,8 +228,9 @@ func (oz *OutputZip) addZipEntry(name string, source ZipEntryContents) (ZipEntry
 		return existingSource, nil
 	}
 	oz.sourceByDest[name] = source
-	// Delay writing an entry if entries need to be rearranged.
-	if oz.emulateJar || oz.sortEntries {
+	oz.destOrder = append(oz.destOrder, name)
+	// Delay writing an entry if entries need to be rearranged or replaced.
+	if oz.delayWrites() {
 		return nil, nil
 	}
 	return nil, source.WriteToZip(name, oz.outputWriter)
@@ -292,17 +343,52 @@ func (oz *OutputZip) copyEntry(inputZip InputZip, index int) error {
 			entry.name, existingEntry, entry)
 	}
 
-	if oz.ignoreDuplicates ||
-		// Skip manifest and module info files that are not from the first input file
-		(oz.emulateJar && entry.name == jar.ManifestFile || entry.name == jar.ModuleInfoClass) ||
-		// Identical entries
-		(existingEntry.CRC32() == entry.CRC32() && existingEntry.Size() == entry.Size()) ||
-		// Directory entries
+	// Identical entries and directory entries don't conflict
+	if (existingEntry.CRC32() == entry.CRC32() && existingEntry.Size() == entry.Size()) ||
 		entry.IsDir() {
 		return nil
 	}
 
-	return fmt.Errorf("Duplicate path %v found in %v and %v\n", entry.name, existingEntry, inputZip.Name())
+	policy, hasPolicy := conflictPolicyFor(oz.conflictPolicies, entry.name)
+	if !hasPolicy {
+		// Skip manifest and module info files that are not from the first input file
+		if oz.emulateJar && entry.name == jar.ManifestFile || entry.name == jar.ModuleInfoClass {
+			return nil
+		}
+		if oz.ignoreDuplicates {
+			policy = policyFirst
+		} else {
+			policy = policyIdentical
+		}
+	}
+
+	c := oz.conflicts[entry.name]
+	if c == nil {
+		c = &conflict{name: entry.name, policy: policy, sources: []ZipEntryContents{existingEntry}}
+		oz.conflicts[entry.name] = c
+	}
+	c.sources = append(c.sources, entry)
+
+	switch policy {
+	case policyFirst:
+		return nil
+	case policyLast:
+		oz.sourceByDest[entry.name] = entry
+		return nil
+	case policyConcatenate:
+		concatenated, err := concatenateEntries(entry.name, existingEntry, entry)
+		if err != nil {
+			return err
+		}
+		oz.sourceByDest[entry.name] = concatenated
+		return nil
+	}
+
+	report, err := c.report()
+	if err != nil {
+		return err
+	}
+	return errors.New(report.String())
 }
 
 func (oz *OutputZip) entriesArray() []string {
@@ -328,6 +414,17 @@ func (oz *OutputZip) alphanumericSorted() []string {
 }
 
 func (oz *OutputZip) writeEntries(entries []string) error {
+	if oz.recompress != nil {
+		return soongzip.Recompress(oz.outputWriter, *oz.recompress, func(r *soongzip.Recompressor) error {
+			for _, entry := range entries {
+				if err := oz.sourceByDest[entry].RecompressToZip(entry, r); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
 	for _, entry := range entries {
 		source, _ := oz.sourceByDest[entry]
 		if err := source.WriteToZip(entry, oz.outputWriter); err != nil {
@@ -533,11 +630,23 @@ func (miz *ManagedInputZip) Entries() []*zip.File {
 // Actual processing.
 func mergeZips(inputZips []InputZip, writer *zip.Writer, manifest, pyMain string,
 	sortEntries, emulateJar, emulatePar, stripDirEntries, ignoreDuplicates bool,
-	excludeFiles, excludeDirs []string, zipsToNotStrip map[string]bool) error {
+	excludeFiles, excludeDirs []string, zipsToNotStrip map[string]bool,
+	conflictPolicies []conflictPolicyRule, conflictReport io.Writer,
+	recompress *soongzip.RecompressArgs) (err error) {
 
 	out := NewOutputZip(writer, sortEntries, emulateJar, stripDirEntries, ignoreDuplicates)
 	out.setExcludeFiles(excludeFiles)
 	out.setExcludeDirs(excludeDirs)
+	out.setConflictPolicies(conflictPolicies)
+	out.setRecompress(recompress)
+	if conflictReport != nil {
+		// Write the report even if a conflict failed the merge.
+		defer func() {
+			if reportErr := writeConflictReport(conflictReport, out.conflicts); err == nil {
+				err = reportErr
+			}
+		}()
+	}
 	if manifest != "" {
 		if err := out.addManifest(manifest); err != nil {
 			return err
@@ -575,8 +684,8 @@ func mergeZips(inputZips []InputZip, writer *zip.Writer, manifest, pyMain string
 				}
 			}
 		}
-		// Unless we need to rearrange the entries, the input zip can now be closed.
-		if !(emulateJar || sortEntries) {
+		// Unless we need to rearrange or replace the entries, the input zip can now be closed.
+		if !out.delayWrites() {
 			if err := inputZip.Close(); err != nil {
 				return err
 			}
@@ -587,6 +696,8 @@ func mergeZips(inputZips []InputZip, writer *zip.Writer, manifest, pyMain string
 		return out.writeEntries(out.jarSorted())
 	} else if sortEntries {
 		return out.writeEntries(out.alphanumericSorted())
+	} else if out.delayWrites() {
+		return out.writeEntries(out.destOrder)
 	}
 	return nil
 }
@@ -616,17 +727,22 @@ func (s zipsToNotStripSet) Set(path string) error {
 }
 
 var (
-	sortEntries      = flag.Bool("s", false, "sort entries (defaults to the order from the input zip files)")
-	emulateJar       = flag.Bool("j", false, "sort zip entries using jar ordering (META-INF first)")
-	emulatePar       = flag.Bool("p", false, "merge zip entries based on par format")
-	excludeDirs      fileList
-	excludeFiles     fileList
-	zipsToNotStrip   = make(zipsToNotStripSet)
-	stripDirEntries  = flag.Bool("D", false, "strip directory entries from the output zip file")
-	manifest         = flag.String("m", "", "manifest file to insert in jar")
-	pyMain           = flag.String("pm", "", "__main__.py file to insert in par")
-	prefix           = flag.String("prefix", "", "A file to prefix to the zip file")
-	ignoreDuplicates = flag.Bool("ignore-duplicates", false, "take each entry from the first zip it exists in and don't warn")
+	sortEntries        = flag.Bool("s", false, "sort entries (defaults to the order from the input zip files)")
+	emulateJar         = flag.Bool("j", false, "sort zip entries using jar ordering (META-INF first)")
+	emulatePar         = flag.Bool("p", false, "merge zip entries based on par format")
+	excludeDirs        fileList
+	excludeFiles       fileList
+	zipsToNotStrip     = make(zipsToNotStripSet)
+	stripDirEntries    = flag.Bool("D", false, "strip directory entries from the output zip file")
+	manifest           = flag.String("m", "", "manifest file to insert in jar")
+	pyMain             = flag.String("pm", "", "__main__.py file to insert in par")
+	prefix             = flag.String("prefix", "", "A file to prefix to the zip file")
+	ignoreDuplicates   = flag.Bool("ignore-duplicates", false, "take each entry from the first zip it exists in and don't warn")
+	conflictPolicyFile = flag.String("conflict-policy", "", "file of <glob> <first|last|identical|concatenate> lines that resolve duplicate paths with different contents")
+	conflictReportFile = flag.String("conflict-report", "", "file to write a JSON report of the duplicate paths with different contents to")
+	compLevel          = flag.Int("L", -1, "recompress entries at this deflate compression level (0-9), -1 copies entries with their existing compression")
+	storeCompressed    = flag.Bool("store-compressed", false, "when recompressing, store entries with extensions of already compressed formats (.png, .jar, .so, .apk, ...) and deflate the rest")
+	parallelJobs       = flag.Int("parallel", runtime.NumCPU(), "number of parallel threads to use when recompressing")
 )
 
 func init() {
@@ -746,15 +862,56 @@ func main() {
 		log.Fatal(errors.New("must specify -p when specifying a Python __main__.py via -pm"))
 	}
 
+	var conflictPolicies []conflictPolicyRule
+	if *conflictPolicyFile != "" {
+		f, err := os.Open(*conflictPolicyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		conflictPolicies, err = parseConflictPolicies(f, *conflictPolicyFile)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var conflictReportWriter io.Writer
+	if *conflictReportFile != "" {
+		f, err := os.Create(*conflictReportFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		conflictReportWriter = f
+	}
+
+	maxOpenZips := 1000
+
+	var recompress *soongzip.RecompressArgs
+	if *compLevel >= 0 {
+		recompress = &soongzip.RecompressArgs{
+			CompressionLevel: *compLevel,
+			StoreCompressed:  *storeCompressed,
+			NumParallelJobs:  *parallelJobs,
+		}
+		// Recompressed entries are read from the input zips in the background after they have
+		// been queued, so none of the input zips can be closed early.
+		if len(inputs) > maxOpenZips {
+			maxOpenZips = len(inputs)
+		}
+	} else if *storeCompressed {
+		log.Fatal(errors.New("must specify -L when specifying -store-compressed"))
+	}
+
 	// do merge
-	inputZipsManager := NewInputZipsManager(len(inputs), 1000)
+	inputZipsManager := NewInputZipsManager(len(inputs), maxOpenZips)
 	inputZips := make([]InputZip, len(inputs))
 	for i, input := range inputs {
 		inputZips[i] = inputZipsManager.Manage(&FileInputZip{name: input})
 	}
 	err = mergeZips(inputZips, writer, *manifest, *pyMain, *sortEntries, *emulateJar, *emulatePar,
 		*stripDirEntries, *ignoreDuplicates, []string(excludeFiles), []string(excludeDirs),
-		map[string]bool(zipsToNotStrip))
+		map[string]bool(zipsToNotStrip), conflictPolicies, conflictReportWriter, recompress)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -24,6 +24,7 @@ import (
 
 	"android/soong/jar"
 	"android/soong/third_party/zip"
+	soongzip "android/soong/zip"
 )
 
 type testZipEntry struct {
@@ -43,6 +44,7 @@ var (
 	ba    = testZipEntry{"b/a", 0755, []byte("foob")}
 	bc    = testZipEntry{"b/c", 0755, []byte("bar")}
 	bd    = testZipEntry{"b/d", 0700, []byte("baz")}
+	bd2   = testZipEntry{"b/d", 0700, []byte("baz2")}
 	be    = testZipEntry{"b/e", 0700, []byte("")}
 
 	metainfDir     = testZipEntry{jar.MetaDir, os.ModeDir | 0755, nil}
@@ -95,6 +97,7 @@ func TestMergeZips(t *testing.T) {
 		ignoreDuplicates bool
 		stripDirEntries  bool
 		zipsToNotStrip   map[string]bool
+		conflictPolicies []conflictPolicyRule
 
 		out []testZipEntry
 		err string
@@ -128,6 +131,49 @@ func TestMergeZips(t *testing.T) {
 			},
 			out: []testZipEntry{a},
 		},
+		{
+			name: "conflict policy first",
+			in: [][]testZipEntry{
+				{a, bDir, bd},
+				{a2, bd2},
+				{a3},
+			},
+			out: []testZipEntry{a, bDir, bd},
+
+			conflictPolicies: []conflictPolicyRule{{"a", policyFirst}, {"b/*", policyFirst}},
+		},
+		{
+			name: "conflict policy last",
+			in: [][]testZipEntry{
+				{a, bDir, bd},
+				{a2, bc},
+				{a3},
+			},
+			out: []testZipEntry{a3, bDir, bd, bc},
+
+			conflictPolicies: []conflictPolicyRule{{"a", policyLast}},
+		},
+		{
+			name: "conflict policy identical",
+			in: [][]testZipEntry{
+				{a, bd},
+				{a2, bd2},
+			},
+			err: "duplicate path b/d (policy: identical)",
+
+			ignoreDuplicates: true,
+			conflictPolicies: []conflictPolicyRule{{"b/*", policyIdentical}},
+		},
+		{
+			name: "conflict policy unmatched",
+			in: [][]testZipEntry{
+				{a, bd},
+				{a2, bd2},
+			},
+			err: "duplicate path b/d (policy: identical)",
+
+			conflictPolicies: []conflictPolicyRule{{"a", policyFirst}},
+		},
 		{
 			name: "sort",
 			in: [][]testZipEntry{
@@ -252,7 +298,7 @@ func TestMergeZips(t *testing.T) {
 
 			err := mergeZips(inputZips, writer, "", "",
 				test.sort, test.jar, false, test.stripDirEntries, test.ignoreDuplicates,
-				test.stripFiles, test.stripDirs, test.zipsToNotStrip)
+				test.stripFiles, test.stripDirs, test.zipsToNotStrip, test.conflictPolicies, nil, nil)
 
 			closeErr := writer.Close()
 			if closeErr != nil {
@@ -277,6 +323,61 @@ func TestMergeZips(t *testing.T) {
 	}
 }
 
+func TestMergeZipsRecompress(t *testing.T) {
+	compressible := bytes.Repeat([]byte("compressible "), 100)
+	txt := testZipEntry{"a.txt", 0644, compressible}
+	png := testZipEntry{"b.png", 0644, compressible}
+
+	inputZips := []InputZip{
+		&testInputZip{name: "in0", entries: []testZipEntry{txt, bDir}},
+		&testInputZip{name: "in1", entries: []testZipEntry{png, bDir, bc}},
+	}
+
+	out := &bytes.Buffer{}
+	writer := zip.NewWriter(out)
+	recompress := &soongzip.RecompressArgs{CompressionLevel: 9, StoreCompressed: true, NumParallelJobs: 2}
+	err := mergeZips(inputZips, writer, "", "", false, false, false, false, false,
+		nil, nil, nil, nil, nil, recompress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		entry  testZipEntry
+		method uint16
+	}{
+		{txt, zip.Deflate},
+		{bDir, zip.Store},
+		{png, zip.Store},
+		// Deflating bc doesn't make it smaller.
+		{bc, zip.Store},
+	}
+	if len(zr.File) != len(want) {
+		t.Fatalf("incorrect zip output:\n%s", dumpZip(out.Bytes()))
+	}
+	for i, f := range zr.File {
+		if f.Name != want[i].entry.name || f.Method != want[i].method {
+			t.Errorf("want %s with method %d, got %s with method %d",
+				want[i].entry.name, want[i].method, f.Name, f.Method)
+		}
+		contents, err := readZipFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(contents, want[i].entry.data) {
+			t.Errorf("%s: incorrect contents", f.Name)
+		}
+	}
+}
+
 func testZipEntriesToBuf(entries []testZipEntry) []byte {
 	b := &bytes.Buffer{}
 	zw := zip.NewWriter(b)
@@ -0,0 +1,248 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// sbom writes an SPDX software bill of materials for a product from the description of its
+// installed files and licenses that is written by the sbom singleton in Soong.
+
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	input      = flag.String("i", "", "JSON description of the product written by Soong")
+	output     = flag.String("o", "", "output SPDX tag-value file")
+	jsonOutput = flag.String("json", "", "output SPDX JSON file")
+	para       = flag.Int("para", runtime.NumCPU(), "number of files to hash in parallel")
+)
+
+// product is the JSON description of the product written by the sbom singleton in
+// android/sbom.go.
+type product struct {
+	Product  string
+	Modules  []module
+	Licenses []license
+}
+
+type module struct {
+	Name        string
+	Variant     string
+	Dir         string
+	PackageName string
+	Licenses    []string
+	Files       []file
+}
+
+type file struct {
+	// Path is the path of the file on the device.
+	Path string
+
+	// Src is the path to the built file that is installed, it is empty for symlinks.
+	Src string
+
+	SymlinkTarget string
+}
+
+type license struct {
+	Name            string
+	Kinds           []string
+	Conditions      []string
+	CopyrightNotice string
+	PackageName     string
+	TextFiles       []string
+}
+
+// checksums are the hex encoded hashes of the contents of a file.
+type checksums struct {
+	sha1   string
+	sha256 string
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: sbom -i <input.json> [-o <output.spdx>] [-json <output.spdx.json>]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *input == "" || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "sbom:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	data, err := ioutil.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+	var p product
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("failed to parse %q: %w", *input, err)
+	}
+
+	fileChecksums, err := hashFiles(p, *para)
+	if err != nil {
+		return err
+	}
+	licenseTexts, err := readLicenseTexts(p)
+	if err != nil {
+		return err
+	}
+
+	created, err := creationTime()
+	if err != nil {
+		return err
+	}
+
+	doc := newDocument(p, fileChecksums, licenseTexts, created)
+
+	if *output != "" {
+		if err := writeFile(*output, doc.writeTagValue); err != nil {
+			return err
+		}
+	}
+	if *jsonOutput != "" {
+		if err := writeFile(*jsonOutput, doc.writeJSON); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// creationTime returns the time the document is created, which is $SOURCE_DATE_EPOCH if it is set
+// so that the output can be reproducible.
+func creationTime() (time.Time, error) {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", epoch, err)
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return time.Now().UTC(), nil
+}
+
+// hashFiles returns the checksums of all the files of all the modules, keyed by the path on the
+// device.  The checksum of a symlink is the checksum of the path it points to, not of the contents
+// of its target, which may be installed by another module or not be installed at all.
+func hashFiles(p product, para int) (map[string]checksums, error) {
+	ret := make(map[string]checksums)
+	var mutex sync.Mutex
+	var firstErr error
+
+	ch := make(chan file)
+	var wg sync.WaitGroup
+	for i := 0; i < para; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range ch {
+				sums, err := hashFile(f)
+				mutex.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				ret[f.Path] = sums
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, m := range p.Modules {
+		for _, f := range m.Files {
+			ch <- f
+		}
+	}
+	close(ch)
+	wg.Wait()
+
+	return ret, firstErr
+}
+
+func hashFile(f file) (checksums, error) {
+	h1 := sha1.New()
+	h256 := sha256.New()
+	w := io.MultiWriter(h1, h256)
+
+	if f.Src == "" {
+		io.WriteString(w, f.SymlinkTarget)
+	} else {
+		r, err := os.Open(f.Src)
+		if err != nil {
+			return checksums{}, err
+		}
+		defer r.Close()
+		if _, err := io.Copy(w, r); err != nil {
+			return checksums{}, fmt.Errorf("failed to hash %q: %w", f.Src, err)
+		}
+	}
+
+	return checksums{
+		sha1:   hex.EncodeToString(h1.Sum(nil)),
+		sha256: hex.EncodeToString(h256.Sum(nil)),
+	}, nil
+}
+
+// readLicenseTexts returns the concatenated texts of each license, keyed by the name of the
+// license.
+func readLicenseTexts(p product) (map[string]string, error) {
+	ret := make(map[string]string)
+	for _, l := range p.Licenses {
+		var text []byte
+		for _, textFile := range l.TextFiles {
+			data, err := ioutil.ReadFile(textFile)
+			if err != nil {
+				return nil, err
+			}
+			if len(text) > 0 && text[len(text)-1] != '\n' {
+				text = append(text, '\n')
+			}
+			text = append(text, data...)
+		}
+		ret[l.Name] = string(text)
+	}
+	return ret, nil
+}
+
+// writeFile writes a file with the contents written by write.
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %q: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,347 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The document follows version 2.2 of the SPDX specification.  Each module variant is a package
+// that contains its installed files, and each license module is a license reference whose
+// extracted text is the license text.  The packages and files are concluded to be under all of
+// the licenses of the module.
+
+const (
+	spdxVersion     = "SPDX-2.2"
+	dataLicense     = "CC0-1.0"
+	documentID      = "SPDXRef-DOCUMENT"
+	noAssertion     = "NOASSERTION"
+	creator         = "Tool: soong-sbom"
+	namespacePrefix = "https://spdx.org/spdxdocs/android-"
+)
+
+type document struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      creationInfo       `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Files             []spdxFile         `json:"files"`
+	ExtractedLicenses []extractedLicense `json:"hasExtractedLicensingInfos,omitempty"`
+	Relationships     []relationship     `json:"relationships"`
+}
+
+type creationInfo struct {
+	Creators []string `json:"creators"`
+	Created  string   `json:"created"`
+}
+
+type spdxPackage struct {
+	Name                 string           `json:"name"`
+	SPDXID               string           `json:"SPDXID"`
+	DownloadLocation     string           `json:"downloadLocation"`
+	FilesAnalyzed        bool             `json:"filesAnalyzed"`
+	VerificationCode     verificationCode `json:"packageVerificationCode"`
+	LicenseConcluded     string           `json:"licenseConcluded"`
+	LicenseInfoFromFiles []string         `json:"licenseInfoFromFiles"`
+	LicenseDeclared      string           `json:"licenseDeclared"`
+	CopyrightText        string           `json:"copyrightText"`
+	Comment              string           `json:"comment,omitempty"`
+
+	// files are the indexes in document.Files of the files in the package.
+	files []int
+}
+
+type verificationCode struct {
+	Value string `json:"packageVerificationCodeValue"`
+}
+
+type spdxFile struct {
+	FileName           string     `json:"fileName"`
+	SPDXID             string     `json:"SPDXID"`
+	Checksums          []checksum `json:"checksums"`
+	LicenseConcluded   string     `json:"licenseConcluded"`
+	LicenseInfoInFiles []string   `json:"licenseInfoInFiles"`
+	CopyrightText      string     `json:"copyrightText"`
+	Comment            string     `json:"comment,omitempty"`
+}
+
+type checksum struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"checksumValue"`
+}
+
+type extractedLicense struct {
+	LicenseID     string `json:"licenseId"`
+	ExtractedText string `json:"extractedText"`
+	Name          string `json:"name"`
+	Comment       string `json:"comment,omitempty"`
+}
+
+type relationship struct {
+	Element string `json:"spdxElementId"`
+	Type    string `json:"relationshipType"`
+	Related string `json:"relatedSpdxElement"`
+}
+
+// invalidIDCharsRe matches the characters that are not allowed in SPDX identifiers.
+var invalidIDCharsRe = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// idAllocator returns unique SPDX identifiers.
+type idAllocator map[string]bool
+
+func (ids idAllocator) id(prefix, name string) string {
+	base := prefix + strings.Trim(invalidIDCharsRe.ReplaceAllString(name, "-"), "-")
+	id := base
+	for i := 2; ids[id]; i++ {
+		id = base + "-" + strconv.Itoa(i)
+	}
+	ids[id] = true
+	return id
+}
+
+// newDocument returns the SPDX document for the product.
+func newDocument(p product, fileChecksums map[string]checksums, licenseTexts map[string]string,
+	created time.Time) *document {
+
+	doc := &document{
+		SPDXVersion: spdxVersion,
+		DataLicense: dataLicense,
+		SPDXID:      documentID,
+		Name:        p.Product,
+		CreationInfo: creationInfo{
+			Creators: []string{creator},
+			Created:  created.UTC().Format(time.RFC3339),
+		},
+	}
+
+	ids := idAllocator{documentID: true}
+
+	licenseRefs := make(map[string]string)
+	copyrightNotices := make(map[string]string)
+	for _, l := range p.Licenses {
+		ref := ids.id("LicenseRef-", l.Name)
+		licenseRefs[l.Name] = ref
+		copyrightNotices[l.Name] = l.CopyrightNotice
+
+		text := licenseTexts[l.Name]
+		if text == "" {
+			text = noAssertion
+		}
+		var comment []string
+		if len(l.Kinds) > 0 {
+			comment = append(comment, "Kinds: "+strings.Join(l.Kinds, ", "))
+		}
+		if len(l.Conditions) > 0 {
+			comment = append(comment, "Conditions: "+strings.Join(l.Conditions, ", "))
+		}
+		if l.PackageName != "" {
+			comment = append(comment, "Package: "+l.PackageName)
+		}
+		doc.ExtractedLicenses = append(doc.ExtractedLicenses, extractedLicense{
+			LicenseID:     ref,
+			ExtractedText: text,
+			Name:          l.Name,
+			Comment:       strings.Join(comment, "\n"),
+		})
+	}
+
+	namespaceHash := sha256.New()
+	for _, m := range p.Modules {
+		var refs, notices []string
+		for _, l := range m.Licenses {
+			if ref, ok := licenseRefs[l]; ok {
+				refs = append(refs, ref)
+				if notice := copyrightNotices[l]; notice != "" {
+					notices = append(notices, notice)
+				}
+			}
+		}
+		licenseExpression := noAssertion
+		licenseInfo := []string{noAssertion}
+		if len(refs) > 0 {
+			licenseExpression = strings.Join(refs, " AND ")
+			licenseInfo = refs
+		}
+		copyright := noAssertion
+		if len(notices) > 0 {
+			copyright = strings.Join(notices, "\n")
+		}
+
+		comment := fmt.Sprintf("Module %s, variant %s, defined in %s", m.Name, m.Variant, m.Dir)
+		if m.PackageName != "" {
+			comment += ", package " + m.PackageName
+		}
+
+		pkg := spdxPackage{
+			Name:                 m.Name,
+			SPDXID:               ids.id("SPDXRef-Package-", m.Name),
+			DownloadLocation:     noAssertion,
+			FilesAnalyzed:        true,
+			LicenseConcluded:     licenseExpression,
+			LicenseInfoFromFiles: licenseInfo,
+			LicenseDeclared:      licenseExpression,
+			CopyrightText:        copyright,
+			Comment:              comment,
+		}
+		doc.Relationships = append(doc.Relationships, relationship{documentID, "DESCRIBES", pkg.SPDXID})
+
+		var fileSha1s []string
+		for _, f := range m.Files {
+			sums := fileChecksums[f.Path]
+			fileSha1s = append(fileSha1s, sums.sha1)
+			fmt.Fprintf(namespaceHash, "%s %s\n", f.Path, sums.sha256)
+
+			spdxFile := spdxFile{
+				FileName: "." + f.Path,
+				SPDXID:   ids.id("SPDXRef-File-", f.Path),
+				Checksums: []checksum{
+					{"SHA1", sums.sha1},
+					{"SHA256", sums.sha256},
+				},
+				LicenseConcluded:   licenseExpression,
+				LicenseInfoInFiles: licenseInfo,
+				CopyrightText:      copyright,
+			}
+			if f.SymlinkTarget != "" {
+				spdxFile.Comment = "Symlink to " + f.SymlinkTarget
+			}
+			pkg.files = append(pkg.files, len(doc.Files))
+			doc.Files = append(doc.Files, spdxFile)
+			doc.Relationships = append(doc.Relationships, relationship{pkg.SPDXID, "CONTAINS", spdxFile.SPDXID})
+		}
+		pkg.VerificationCode.Value = packageVerificationCode(fileSha1s)
+
+		doc.Packages = append(doc.Packages, pkg)
+	}
+
+	doc.DocumentNamespace = namespacePrefix + p.Product + "-" + hex.EncodeToString(namespaceHash.Sum(nil))
+
+	return doc
+}
+
+// packageVerificationCode returns the SPDX package verification code, the SHA1 of the sorted and
+// concatenated SHA1s of the files in the package.
+func packageVerificationCode(fileSha1s []string) string {
+	sorted := append([]string(nil), fileSha1s...)
+	sort.Strings(sorted)
+	h := sha1.New()
+	io.WriteString(h, strings.Join(sorted, ""))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeJSON writes the document in the SPDX JSON format.
+func (doc *document) writeJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// textValue returns a value for a tag that may span multiple lines.
+func textValue(s string) string {
+	if s == noAssertion {
+		return s
+	}
+	return "<text>" + s + "</text>"
+}
+
+// writeTagValue writes the document in the SPDX tag-value format.
+func (doc *document) writeTagValue(w io.Writer) error {
+	sb := &strings.Builder{}
+	tag := func(name, value string) {
+		fmt.Fprintf(sb, "%s: %s\n", name, value)
+	}
+
+	tag("SPDXVersion", doc.SPDXVersion)
+	tag("DataLicense", doc.DataLicense)
+	tag("SPDXID", doc.SPDXID)
+	tag("DocumentName", doc.Name)
+	tag("DocumentNamespace", doc.DocumentNamespace)
+	for _, c := range doc.CreationInfo.Creators {
+		tag("Creator", c)
+	}
+	tag("Created", doc.CreationInfo.Created)
+
+	for _, pkg := range doc.Packages {
+		sb.WriteString("\n")
+		tag("PackageName", pkg.Name)
+		tag("SPDXID", pkg.SPDXID)
+		tag("PackageDownloadLocation", pkg.DownloadLocation)
+		tag("FilesAnalyzed", strconv.FormatBool(pkg.FilesAnalyzed))
+		tag("PackageVerificationCode", pkg.VerificationCode.Value)
+		tag("PackageLicenseConcluded", pkg.LicenseConcluded)
+		for _, l := range pkg.LicenseInfoFromFiles {
+			tag("PackageLicenseInfoFromFiles", l)
+		}
+		tag("PackageLicenseDeclared", pkg.LicenseDeclared)
+		tag("PackageCopyrightText", textValue(pkg.CopyrightText))
+		if pkg.Comment != "" {
+			tag("PackageComment", textValue(pkg.Comment))
+		}
+
+		for _, i := range pkg.files {
+			f := doc.Files[i]
+			sb.WriteString("\n")
+			tag("FileName", f.FileName)
+			tag("SPDXID", f.SPDXID)
+			for _, c := range f.Checksums {
+				tag("FileChecksum", c.Algorithm+": "+c.Value)
+			}
+			tag("LicenseConcluded", f.LicenseConcluded)
+			for _, l := range f.LicenseInfoInFiles {
+				tag("LicenseInfoInFile", l)
+			}
+			tag("FileCopyrightText", textValue(f.CopyrightText))
+			if f.Comment != "" {
+				tag("FileComment", textValue(f.Comment))
+			}
+		}
+	}
+
+	if len(doc.Relationships) > 0 {
+		sb.WriteString("\n")
+		for _, r := range doc.Relationships {
+			tag("Relationship", r.Element+" "+r.Type+" "+r.Related)
+		}
+	}
+
+	for _, l := range doc.ExtractedLicenses {
+		sb.WriteString("\n")
+		tag("LicenseID", l.LicenseID)
+		tag("ExtractedText", textValue(l.ExtractedText))
+		tag("LicenseName", l.Name)
+		if l.Comment != "" {
+			tag("LicenseComment", textValue(l.Comment))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
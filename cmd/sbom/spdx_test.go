@@ -0,0 +1,270 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testProduct = product{
+	Product: "test_device",
+	Modules: []module{
+		{
+			Name:        "foo",
+			Variant:     "android_arm64_armv8-a",
+			Dir:         "external/foo",
+			PackageName: "Foo",
+			Licenses:    []string{"external_foo_license"},
+			Files: []file{
+				{Path: "/system/bin/foo", Src: "foo"},
+				{Path: "/system/bin/foo_link", SymlinkTarget: "foo"},
+			},
+		},
+		{
+			Name:    "bar",
+			Variant: "android_arm64_armv8-a",
+			Dir:     "bar",
+			Files: []file{
+				{Path: "/vendor/lib64/bar.so", Src: "bar.so"},
+			},
+		},
+	},
+	Licenses: []license{
+		{
+			Name:            "external_foo_license",
+			Kinds:           []string{"SPDX-license-identifier-MIT"},
+			Conditions:      []string{"notice"},
+			CopyrightNotice: "Copyright (C) Foo",
+			PackageName:     "Foo",
+			TextFiles:       []string{"LICENSE"},
+		},
+	},
+}
+
+func TestHashFilesAndLicenseTexts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sbom_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := testProduct
+	p.Modules = []module{testProduct.Modules[0]}
+	p.Modules[0].Files = []file{
+		{Path: "/system/bin/foo", Src: filepath.Join(dir, "foo")},
+		{Path: "/system/bin/foo_link", SymlinkTarget: "foo"},
+	}
+	p.Licenses = []license{testProduct.Licenses[0]}
+	p.Licenses[0].TextFiles = []string{filepath.Join(dir, "LICENSE1"), filepath.Join(dir, "LICENSE2")}
+
+	for name, contents := range map[string]string{"foo": "foo", "LICENSE1": "MIT", "LICENSE2": "more\n"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sums, err := hashFiles(p, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSums := map[string]checksums{
+		// Both have the contents "foo", the symlink is hashed by its target.
+		"/system/bin/foo": {
+			sha1:   "0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33",
+			sha256: "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+		},
+		"/system/bin/foo_link": {
+			sha1:   "0beec7b5ea3f0fdbc95d0dd47f3c5bc275da8a33",
+			sha256: "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+		},
+	}
+	if !reflect.DeepEqual(sums, wantSums) {
+		t.Errorf("incorrect checksums\nwant: %v\n got: %v", wantSums, sums)
+	}
+
+	texts, err := readLicenseTexts(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := texts["external_foo_license"], "MIT\nmore\n"; g != w {
+		t.Errorf("incorrect license text, want %q, got %q", w, g)
+	}
+
+	p.Modules[0].Files[0].Src = filepath.Join(dir, "missing")
+	if _, err := hashFiles(p, 2); err == nil {
+		t.Error("expected error hashing missing file")
+	}
+}
+
+func testDocument() *document {
+	sums := map[string]checksums{
+		"/system/bin/foo":      {"sha1foo", "sha256foo"},
+		"/system/bin/foo_link": {"sha1link", "sha256link"},
+		"/vendor/lib64/bar.so": {"sha1bar", "sha256bar"},
+	}
+	texts := map[string]string{
+		"external_foo_license": "MIT License\n",
+	}
+	return newDocument(testProduct, sums, texts, time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+}
+
+func TestTagValue(t *testing.T) {
+	doc := testDocument()
+	buf := &bytes.Buffer{}
+	if err := doc.writeTagValue(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.ReplaceAll(`SPDXVersion: SPDX-2.2
+DataLicense: CC0-1.0
+SPDXID: SPDXRef-DOCUMENT
+DocumentName: test_device
+DocumentNamespace: NAMESPACE
+Creator: Tool: soong-sbom
+Created: 2021-06-01T12:00:00Z
+
+PackageName: foo
+SPDXID: SPDXRef-Package-foo
+PackageDownloadLocation: NOASSERTION
+FilesAnalyzed: true
+PackageVerificationCode: FOO_VERIFICATION
+PackageLicenseConcluded: LicenseRef-external-foo-license
+PackageLicenseInfoFromFiles: LicenseRef-external-foo-license
+PackageLicenseDeclared: LicenseRef-external-foo-license
+PackageCopyrightText: <text>Copyright (C) Foo</text>
+PackageComment: <text>Module foo, variant android_arm64_armv8-a, defined in external/foo, package Foo</text>
+
+FileName: ./system/bin/foo
+SPDXID: SPDXRef-File-system-bin-foo
+FileChecksum: SHA1: sha1foo
+FileChecksum: SHA256: sha256foo
+LicenseConcluded: LicenseRef-external-foo-license
+LicenseInfoInFile: LicenseRef-external-foo-license
+FileCopyrightText: <text>Copyright (C) Foo</text>
+
+FileName: ./system/bin/foo_link
+SPDXID: SPDXRef-File-system-bin-foo-link
+FileChecksum: SHA1: sha1link
+FileChecksum: SHA256: sha256link
+LicenseConcluded: LicenseRef-external-foo-license
+LicenseInfoInFile: LicenseRef-external-foo-license
+FileCopyrightText: <text>Copyright (C) Foo</text>
+FileComment: <text>Symlink to foo</text>
+
+PackageName: bar
+SPDXID: SPDXRef-Package-bar
+PackageDownloadLocation: NOASSERTION
+FilesAnalyzed: true
+PackageVerificationCode: BAR_VERIFICATION
+PackageLicenseConcluded: NOASSERTION
+PackageLicenseInfoFromFiles: NOASSERTION
+PackageLicenseDeclared: NOASSERTION
+PackageCopyrightText: NOASSERTION
+PackageComment: <text>Module bar, variant android_arm64_armv8-a, defined in bar</text>
+
+FileName: ./vendor/lib64/bar.so
+SPDXID: SPDXRef-File-vendor-lib64-bar.so
+FileChecksum: SHA1: sha1bar
+FileChecksum: SHA256: sha256bar
+LicenseConcluded: NOASSERTION
+LicenseInfoInFile: NOASSERTION
+FileCopyrightText: NOASSERTION
+
+Relationship: SPDXRef-DOCUMENT DESCRIBES SPDXRef-Package-foo
+Relationship: SPDXRef-Package-foo CONTAINS SPDXRef-File-system-bin-foo
+Relationship: SPDXRef-Package-foo CONTAINS SPDXRef-File-system-bin-foo-link
+Relationship: SPDXRef-DOCUMENT DESCRIBES SPDXRef-Package-bar
+Relationship: SPDXRef-Package-bar CONTAINS SPDXRef-File-vendor-lib64-bar.so
+
+LicenseID: LicenseRef-external-foo-license
+ExtractedText: <text>MIT License
+</text>
+LicenseName: external_foo_license
+LicenseComment: <text>Kinds: SPDX-license-identifier-MIT
+Conditions: notice
+Package: Foo</text>
+`, "NAMESPACE", doc.DocumentNamespace)
+	want = strings.ReplaceAll(want, "FOO_VERIFICATION", packageVerificationCode([]string{"sha1foo", "sha1link"}))
+	want = strings.ReplaceAll(want, "BAR_VERIFICATION", packageVerificationCode([]string{"sha1bar"}))
+
+	if g := buf.String(); g != want {
+		t.Errorf("incorrect tag-value document\nwant:\n%s\ngot:\n%s", want, g)
+	}
+
+	if !strings.HasPrefix(doc.DocumentNamespace, namespacePrefix+"test_device-") {
+		t.Errorf("incorrect document namespace %q", doc.DocumentNamespace)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	doc := testDocument()
+	buf := &bytes.Buffer{}
+	if err := doc.writeJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"spdxVersion", "dataLicense", "SPDXID", "name", "documentNamespace",
+		"creationInfo", "packages", "files", "hasExtractedLicensingInfos", "relationships"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("missing %q in JSON document", key)
+		}
+	}
+
+	files := got["files"].([]interface{})
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(files))
+	}
+	wantFile := map[string]interface{}{
+		"fileName": "./system/bin/foo",
+		"SPDXID":   "SPDXRef-File-system-bin-foo",
+		"checksums": []interface{}{
+			map[string]interface{}{"algorithm": "SHA1", "checksumValue": "sha1foo"},
+			map[string]interface{}{"algorithm": "SHA256", "checksumValue": "sha256foo"},
+		},
+		"licenseConcluded":   "LicenseRef-external-foo-license",
+		"licenseInfoInFiles": []interface{}{"LicenseRef-external-foo-license"},
+		"copyrightText":      "Copyright (C) Foo",
+	}
+	if !reflect.DeepEqual(files[0], wantFile) {
+		t.Errorf("incorrect file\nwant: %v\n got: %v", wantFile, files[0])
+	}
+}
+
+func TestIDAllocator(t *testing.T) {
+	ids := idAllocator{}
+	for _, test := range []struct{ name, want string }{
+		{"foo", "SPDXRef-Package-foo"},
+		{"foo", "SPDXRef-Package-foo-2"},
+		{"lib_foo@1.0", "SPDXRef-Package-lib-foo-1.0"},
+		{"foo", "SPDXRef-Package-foo-3"},
+	} {
+		if g := ids.id("SPDXRef-Package-", test.name); g != test.want {
+			t.Errorf("incorrect id for %q, want %q, got %q", test.name, test.want, g)
+		}
+	}
+}
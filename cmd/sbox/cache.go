@@ -0,0 +1,367 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"android/soong/cmd/sbox/sbox_proto"
+	"android/soong/makedeps"
+	"android/soong/response"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// With --cache-dir sbox keeps a local content-addressed cache of the outputs of the manifests it
+// runs.  The key of an entry is the hash of the manifest and of the contents of every file that the
+// manifest declares as an input: the copy_before files, the rsp files and the files they list, and
+// the isolated_inputs.  On a hit the outputs and the depfile are copied out of the cache instead of
+// running the commands.
+//
+// The cache can be shared between branches and output directories.  The output directory passed
+// with --out-dir is replaced by a placeholder in the manifest and the input paths that are hashed,
+// and in the output paths, the depfile and the depfile inputs that are stored in an entry, and the
+// placeholder is replaced by the output directory of the build that restores the entry.  The
+// sandbox paths are never part of the key, the manifest refers to them with placeholders.  The
+// input_hash field of the manifest, which is a hash of the paths of the inputs, is not hashed,
+// the paths and contents of the inputs are.  Outputs whose contents contain the path of the output
+// directory can't be shared between output directories.
+//
+// The cache is only as good as the declared inputs.  Files that are only listed in the depfile of
+// a command are recorded in the entry with their hashes and checked on every lookup, but files that
+// the command reads without declaring them are not, use --isolate to find those.
+
+// cacheVersion is part of every key, it must be changed when the layout of the cache or the
+// meaning of the keys changes.
+const cacheVersion = "2"
+
+// outDirPlaceholder replaces the output directory in cache keys and entries.
+const outDirPlaceholder = "__SBOX_OUT_DIR__"
+
+const (
+	cacheEntryFile   = "entry.json"
+	cacheOutputsDir  = "outputs"
+	cacheDepfileFile = "depfile"
+)
+
+// cacheEntry describes the outputs of a manifest stored in the cache.
+type cacheEntry struct {
+	// Outputs are the paths relative to the $PWD when sbox was started of the output files, with
+	// the output directory replaced by outDirPlaceholder.  The contents of Outputs[i] are stored in
+	// the file named i in the outputs directory of the entry.
+	Outputs []string
+
+	// DepfileInputs maps the inputs listed in the output depfile, with the output directory
+	// replaced by outDirPlaceholder, to the hashes of their contents when the entry was stored.
+	DepfileInputs map[string]string
+}
+
+// cacheKey returns the key of the cache entry for the manifest, or an error if any of the declared
+// inputs can't be hashed, in which case the manifest must not be cached.  outDir is the output
+// directory of the build, which is not part of the key.
+func cacheKey(manifest *sbox_proto.Manifest, outDir string) (string, error) {
+	keyManifest := proto.Clone(manifest).(*sbox_proto.Manifest)
+	for _, command := range keyManifest.Commands {
+		command.InputHash = nil
+	}
+	manifestData := normalizeOutDir(proto.MarshalTextString(keyManifest), outDir)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "sbox cache version %s\n", cacheVersion)
+	fmt.Fprintf(h, "manifest %d\n", len(manifestData))
+	io.WriteString(h, manifestData)
+
+	inputs, err := declaredInputs(manifest)
+	if err != nil {
+		return "", err
+	}
+	for _, input := range inputs {
+		fileHash, err := hashFile(input)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "input %s %s\n", normalizeOutDir(input, outDir), fileHash)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// declaredInputs returns the sorted list of files that the commands in the manifest declare as
+// inputs.
+func declaredInputs(manifest *sbox_proto.Manifest) ([]string, error) {
+	inputSet := make(map[string]bool)
+	for _, command := range manifest.Commands {
+		for _, copyPair := range command.CopyBefore {
+			inputSet[copyPair.GetFrom()] = true
+		}
+		for _, rspFile := range command.RspFiles {
+			inputSet[rspFile.GetFile()] = true
+			files, err := readRspFile(rspFile.GetFile())
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range files {
+				inputSet[file] = true
+			}
+		}
+		for _, input := range command.IsolatedInputs {
+			inputSet[input] = true
+		}
+	}
+
+	inputs := make([]string, 0, len(inputSet))
+	for input := range inputSet {
+		inputs = append(inputs, input)
+	}
+	sort.Strings(inputs)
+	return inputs, nil
+}
+
+// manifestOutputs returns the paths relative to the $PWD when sbox was started of the files that
+// the manifest copies out of the sandbox.
+func manifestOutputs(manifest *sbox_proto.Manifest) []string {
+	var outputs []string
+	for _, command := range manifest.Commands {
+		for _, copyPair := range command.CopyAfter {
+			outputs = append(outputs, copyPair.GetTo())
+		}
+	}
+	return outputs
+}
+
+// cacheEntryDir returns the directory that holds the cache entry for key.
+func cacheEntryDir(cacheDir, key string) string {
+	return filepath.Join(cacheDir, key[:2], key)
+}
+
+// normalizeOutDir replaces the output directory in the paths in s with outDirPlaceholder.  Only
+// occurrences that start a path, possibly after a short flag like -I, and end at a path separator
+// are replaced, so an output directory "out" doesn't replace anything in "src/layout/out.txt".
+func normalizeOutDir(s, outDir string) string {
+	outDir = filepath.Clean(outDir)
+	if outDir == "" || outDir == "." {
+		return s
+	}
+
+	isPathChar := func(c byte) bool {
+		return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.IndexByte("_.+@~-/", c) != -1
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, outDir)
+		if i == -1 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(outDir)
+		startsPath := i == 0 || !isPathChar(s[i-1]) ||
+			i >= 2 && s[i-2] == '-' && s[i-1] != '/' && (i == 2 || !isPathChar(s[i-3]))
+		endsPath := end == len(s) || s[end] == '/' || !isPathChar(s[end])
+		if startsPath && endsPath {
+			b.WriteString(s[:i])
+			b.WriteString(outDirPlaceholder)
+		} else {
+			b.WriteString(s[:end])
+		}
+		s = s[end:]
+	}
+}
+
+// expandOutDir replaces outDirPlaceholder in s with the output directory.
+func expandOutDir(s, outDir string) string {
+	if outDir == "" {
+		outDir = "."
+	}
+	return strings.ReplaceAll(s, outDirPlaceholder, filepath.Clean(outDir))
+}
+
+// restoreFromCache copies the outputs and the depfile of the manifest out of the cache entry for
+// key.  It returns false if there is no entry for key, or if any of the inputs listed in the
+// depfile of the entry have changed.
+func restoreFromCache(cacheDir, key string, manifest *sbox_proto.Manifest, outDir string) (bool, error) {
+	entryDir := cacheEntryDir(cacheDir, key)
+
+	data, err := ioutil.ReadFile(filepath.Join(entryDir, cacheEntryFile))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return false, fmt.Errorf("failed to parse cache entry %q: %w", entryDir, err)
+	}
+
+	for input, wantHash := range entry.DepfileInputs {
+		if fileHash, err := hashFile(expandOutDir(input, outDir)); err != nil || fileHash != wantHash {
+			return false, nil
+		}
+	}
+
+	for i, output := range entry.Outputs {
+		from := filepath.Join(entryDir, cacheOutputsDir, strconv.Itoa(i))
+		output = expandOutDir(output, outDir)
+		if err := copyOneFile(from, output, false, false); os.IsNotExist(err) {
+			// The entry was replaced by another sbox process.
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("error copying %q to %q: %w", from, output, err)
+		}
+	}
+
+	if outputDepFile := manifest.GetOutputDepfile(); outputDepFile != "" {
+		from := filepath.Join(entryDir, cacheDepfileFile)
+		data, err := ioutil.ReadFile(from)
+		if os.IsNotExist(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if err := os.MkdirAll(filepath.Dir(outputDepFile), 0777); err != nil {
+			return false, err
+		}
+		if err := ioutil.WriteFile(outputDepFile, []byte(expandOutDir(string(data), outDir)), 0666); err != nil {
+			return false, fmt.Errorf("error copying %q to %q: %w", from, outputDepFile, err)
+		}
+	}
+
+	return true, nil
+}
+
+// storeInCache copies the outputs and the depfile of the manifest into a new cache entry for key,
+// replacing any stale entry.  The entry is written to a temporary directory and then renamed into
+// place, so concurrent sbox processes never see partial entries.
+func storeInCache(cacheDir, key string, manifest *sbox_proto.Manifest, outDir string) (err error) {
+	entryDir := cacheEntryDir(cacheDir, key)
+
+	outputs := manifestOutputs(manifest)
+	var entry cacheEntry
+	for _, output := range outputs {
+		entry.Outputs = append(entry.Outputs, normalizeOutDir(output, outDir))
+	}
+
+	var depfileData []byte
+	if outputDepFile := manifest.GetOutputDepfile(); outputDepFile != "" {
+		depfileData, err = ioutil.ReadFile(outputDepFile)
+		if err != nil {
+			return err
+		}
+		deps, err := makedeps.Parse(outputDepFile, bytes.NewBuffer(depfileData))
+		if err != nil {
+			return err
+		}
+		entry.DepfileInputs = make(map[string]string)
+		for _, input := range deps.Inputs {
+			fileHash, err := hashFile(input)
+			if err != nil {
+				return err
+			}
+			entry.DepfileInputs[normalizeOutDir(input, outDir)] = fileHash
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(entryDir), 0777); err != nil {
+		return err
+	}
+	tempEntryDir, err := ioutil.TempDir(filepath.Dir(entryDir), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(tempEntryDir)
+		}
+	}()
+
+	for i, output := range outputs {
+		to := filepath.Join(tempEntryDir, cacheOutputsDir, strconv.Itoa(i))
+		if err := copyOneFile(output, to, false, false); err != nil {
+			return err
+		}
+	}
+
+	if depfileData != nil {
+		depfile := normalizeOutDir(string(depfileData), outDir)
+		if err := ioutil.WriteFile(filepath.Join(tempEntryDir, cacheDepfileFile), []byte(depfile), 0666); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(tempEntryDir, cacheEntryFile), data, 0666); err != nil {
+		return err
+	}
+
+	// An existing entry for the key is stale, one of the inputs in its depfile changed.  Move it out
+	// of the way before replacing it.
+	staleEntryDir := tempEntryDir + ".stale"
+	if err := os.Rename(entryDir, staleEntryDir); err == nil {
+		defer os.RemoveAll(staleEntryDir)
+	}
+
+	if err := os.Rename(tempEntryDir, entryDir); err != nil {
+		if _, statErr := os.Stat(entryDir); statErr == nil {
+			// Another sbox process stored an entry for the key first.
+			os.RemoveAll(tempEntryDir)
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// hashFile returns the hex encoded sha256 hash of the contents of a file.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %q: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readRspFile returns the list of files in an rsp file.
+func readRspFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return response.ReadRspFile(f)
+}
@@ -0,0 +1,237 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"android/soong/cmd/sbox/sbox_proto"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func writeTestFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(contents), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// testCacheManifest returns a manifest like the ones RuleBuilder writes for a rule in outDir that
+// reads src/in.txt and the generated outDir/gen/in.txt.
+func testCacheManifest(outDir string) *sbox_proto.Manifest {
+	return &sbox_proto.Manifest{
+		Commands: []*sbox_proto.Command{
+			{
+				Command: proto.String("cat src/in.txt " + outDir + "/gen/in.txt > __SBOX_SANDBOX_DIR__/out/out.txt"),
+				CopyAfter: []*sbox_proto.Copy{
+					{
+						From: proto.String("out/out.txt"),
+						To:   proto.String(outDir + "/gen/rule/out.txt"),
+					},
+				},
+				IsolatedInputs: []string{"src/in.txt", outDir + "/gen/in.txt"},
+				InputHash:      proto.String("hash of " + outDir),
+			},
+		},
+		OutputDepfile: proto.String(outDir + "/gen/rule/out.d"),
+	}
+}
+
+func TestNormalizeOutDir(t *testing.T) {
+	testCases := []struct {
+		name   string
+		s      string
+		outDir string
+		want   string
+	}{
+		{"path", "out/soong/gen/foo", "out/soong", "__SBOX_OUT_DIR__/gen/foo"},
+		{"out dir", "out/soong", "out/soong", "__SBOX_OUT_DIR__"},
+		{"quoted", `from: "out/soong"`, "out/soong", `from: "__SBOX_OUT_DIR__"`},
+		{"several", "a out/soong/a out/soong/b", "out/soong", "a __SBOX_OUT_DIR__/a __SBOX_OUT_DIR__/b"},
+		{"prefix of a directory", "out/soong2/gen/foo", "out/soong", "out/soong2/gen/foo"},
+		{"inside a path", "src/out/soong/foo", "out/soong", "src/out/soong/foo"},
+		{"suffix of a directory", "layout/soong/foo", "out/soong", "layout/soong/foo"},
+		{"short flag", "-Iout/soong/gen -o out/soong/a.o", "out/soong", "-I__SBOX_OUT_DIR__/gen -o __SBOX_OUT_DIR__/a.o"},
+		{"long flag", "--gen=out/soong/gen", "out/soong", "--gen=__SBOX_OUT_DIR__/gen"},
+		{"absolute", "-I/tmp/out/soong/gen", "/tmp/out/soong", "-I__SBOX_OUT_DIR__/gen"},
+		{"no out dir", "out/soong/gen/foo", "", "out/soong/gen/foo"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := normalizeOutDir(tc.s, tc.outDir)
+			if got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+			if tc.outDir != "" {
+				if expanded := expandOutDir(got, tc.outDir); expanded != tc.s {
+					t.Errorf("want expanded %q, got %q", tc.s, expanded)
+				}
+			}
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	chdirToTestTree(t, nil)
+	writeTestFile(t, "src/in.txt", "source")
+	writeTestFile(t, "out/soong/gen/in.txt", "generated")
+	writeTestFile(t, "out2/soong/gen/in.txt", "generated")
+
+	key := func(manifest *sbox_proto.Manifest, outDir string) string {
+		t.Helper()
+		key, err := cacheKey(manifest, outDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+
+	base := key(testCacheManifest("out/soong"), "out/soong")
+
+	if got := key(testCacheManifest("out/soong"), "out/soong"); got != base {
+		t.Errorf("key of the same manifest changed from %q to %q", base, got)
+	}
+
+	if got := key(testCacheManifest("out2/soong"), "out2/soong"); got != base {
+		t.Errorf("key in another output directory changed from %q to %q", base, got)
+	}
+
+	changedCommand := testCacheManifest("out/soong")
+	changedCommand.Commands[0].Command = proto.String("cat src/in.txt > __SBOX_SANDBOX_DIR__/out/out.txt")
+	if got := key(changedCommand, "out/soong"); got == base {
+		t.Errorf("key didn't change when the command changed")
+	}
+
+	changedOutput := testCacheManifest("out/soong")
+	changedOutput.Commands[0].CopyAfter[0].To = proto.String("out/soong/gen/rule/other.txt")
+	if got := key(changedOutput, "out/soong"); got == base {
+		t.Errorf("key didn't change when the output changed")
+	}
+
+	writeTestFile(t, "out2/soong/gen/in.txt", "generated differently")
+	if got := key(testCacheManifest("out2/soong"), "out2/soong"); got == base {
+		t.Errorf("key didn't change when the contents of an input changed")
+	}
+
+	writeTestFile(t, "src/in.txt", "changed source")
+	if got := key(testCacheManifest("out/soong"), "out/soong"); got == base {
+		t.Errorf("key didn't change when the contents of a source input changed")
+	}
+
+	os.Remove("src/in.txt")
+	if _, err := cacheKey(testCacheManifest("out/soong"), "out/soong"); err == nil {
+		t.Errorf("expected an error for a missing input")
+	}
+}
+
+func TestCacheStoreAndRestore(t *testing.T) {
+	top := chdirToTestTree(t, nil)
+	cacheDir := filepath.Join(top, "cache")
+
+	writeTestFile(t, "src/in.txt", "source")
+	writeTestFile(t, "src/dep.h", "header")
+	for _, outDir := range []string{"out/soong", "out2/soong"} {
+		writeTestFile(t, outDir+"/gen/in.txt", "generated")
+		writeTestFile(t, outDir+"/gen/dep.h", "generated header")
+	}
+
+	// Run the rule in out/soong and store its outputs.
+	writeTestFile(t, "out/soong/gen/rule/out.txt", "output")
+	writeTestFile(t, "out/soong/gen/rule/out.d",
+		"out/soong/gen/rule/out.txt: src/dep.h out/soong/gen/dep.h\n")
+
+	manifest := testCacheManifest("out/soong")
+	key, err := cacheKey(manifest, "out/soong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storeInCache(cacheDir, key, manifest, "out/soong"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore the outputs of the same rule in out2/soong.
+	manifest2 := testCacheManifest("out2/soong")
+	key2, err := cacheKey(manifest2, "out2/soong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hit, err := restoreFromCache(cacheDir, key2, manifest2, "out2/soong")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hit {
+		t.Fatal("expected a cache hit in another output directory")
+	}
+	if got := readTestFile(t, "out2/soong/gen/rule/out.txt"); got != "output" {
+		t.Errorf("want restored output %q, got %q", "output", got)
+	}
+	wantDepfile := "out2/soong/gen/rule/out.txt: src/dep.h out2/soong/gen/dep.h\n"
+	if got := readTestFile(t, "out2/soong/gen/rule/out.d"); got != wantDepfile {
+		t.Errorf("want restored depfile %q, got %q", wantDepfile, got)
+	}
+
+	// A changed input that is only listed in the depfile makes the entry stale.
+	writeTestFile(t, "out2/soong/gen/dep.h", "changed generated header")
+	if hit, err := restoreFromCache(cacheDir, key2, manifest2, "out2/soong"); err != nil {
+		t.Fatal(err)
+	} else if hit {
+		t.Error("expected a miss after a depfile input in the output directory changed")
+	}
+	// The entry is still valid in the original output directory.
+	if hit, err := restoreFromCache(cacheDir, key, manifest, "out/soong"); err != nil {
+		t.Fatal(err)
+	} else if !hit {
+		t.Error("expected a hit in the original output directory")
+	}
+
+	writeTestFile(t, "src/dep.h", "changed header")
+	if hit, err := restoreFromCache(cacheDir, key, manifest, "out/soong"); err != nil {
+		t.Fatal(err)
+	} else if hit {
+		t.Error("expected a miss after a source depfile input changed")
+	}
+
+	// Storing the outputs again replaces the stale entry.
+	if err := storeInCache(cacheDir, key, manifest, "out/soong"); err != nil {
+		t.Fatal(err)
+	}
+	if hit, err := restoreFromCache(cacheDir, key, manifest, "out/soong"); err != nil {
+		t.Fatal(err)
+	} else if !hit {
+		t.Error("expected a hit after replacing the stale entry")
+	}
+
+	if hit, err := restoreFromCache(cacheDir, "0123456789abcdef", manifest, "out/soong"); err != nil {
+		t.Fatal(err)
+	} else if hit {
+		t.Error("expected a miss for a missing entry")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// With --isolate sbox runs each command in new user and mount namespaces whose root directory only
+// contains the system directories, the temporary sandbox directory and the isolated_inputs from the
+// manifest.  Everything else in the source and output directories is hidden, so commands that read
+// files that aren't declared as inputs fail locally instead of only when they run remotely.
+//
+// Setting up the mounts has to happen in a process that is already in the new namespaces, so sbox
+// re-executes itself with isolatedChildArg and the path to a JSON isolateConfig.  The child sets up
+// the mounts, changes its root directory and then replaces itself with the command.
+
+const isolatedChildArg = "--isolated-child"
+
+// maxDeniedPaths is the maximum number of denied paths listed after a failed command.
+const maxDeniedPaths = 20
+
+// isolateConfig is passed from sbox to the child process it runs in the new namespaces.
+type isolateConfig struct {
+	// Root is the empty directory that becomes the root directory of the command.  The working
+	// directory of the command is the working directory of the child process.
+	Root string
+
+	// ReadOnly are the absolute paths of files and directories that are visible to the command
+	// at the same path, read-only.
+	ReadOnly []string
+
+	// Writable are the absolute paths of directories that are visible to the command at the same
+	// path, writable.
+	Writable []string
+
+	// Command is the bash command line to run.
+	Command string
+}
+
+// pathInOutputRe matches things that look like relative or absolute paths in the output of a
+// command.
+var pathInOutputRe = regexp.MustCompile(`[A-Za-z0-9_.+@~-]*(?:/[A-Za-z0-9_.+@~-]+)+`)
+
+// deniedPaths returns the paths mentioned in the output of a failed command that exist, but were
+// hidden from the command because they are neither isolated inputs nor in the temporary sandbox
+// directory.  sbox can't observe the files the command tried to open, but compilers and most other
+// tools print the names of the files they failed to read.  Relative paths in the output are relative
+// to tempDir if chdir is true.
+func deniedPaths(output []byte, tempDir string, chdir bool, isolatedInputs []string) []string {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	if filepath.IsAbs(tempDir) {
+		if rel, err := filepath.Rel(pwd, tempDir); err == nil {
+			tempDir = rel
+		}
+	}
+
+	visible := make(map[string]bool)
+	for _, input := range isolatedInputs {
+		visible[filepath.Clean(input)] = true
+	}
+
+	seen := make(map[string]bool)
+	var denied []string
+	for _, match := range pathInOutputRe.FindAllString(string(output), -1) {
+		path := filepath.Clean(strings.TrimRight(match, ".:"))
+		if filepath.IsAbs(path) {
+			// Absolute paths outside the source tree are system paths that are visible.
+			rel, err := filepath.Rel(pwd, path)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+				continue
+			}
+			path = rel
+		} else if chdir {
+			path = filepath.Join(tempDir, path)
+		}
+
+		if seen[path] || visible[path] || path == "." || strings.HasPrefix(path, "../") {
+			continue
+		}
+		seen[path] = true
+
+		if rel, err := filepath.Rel(tempDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+
+		// Directories that contain isolated inputs are partially visible, only list them if none
+		// of their contents were visible.
+		if hasVisibleChild(path, visible) {
+			continue
+		}
+
+		denied = append(denied, path)
+	}
+
+	sort.Strings(denied)
+	if len(denied) > maxDeniedPaths {
+		denied = append(denied[:maxDeniedPaths], "...")
+	}
+	return denied
+}
+
+// hasVisibleChild returns true if any of the visible paths is inside dir.
+func hasVisibleChild(dir string, visible map[string]bool) bool {
+	prefix := dir + "/"
+	for path := range visible {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
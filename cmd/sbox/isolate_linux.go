@@ -0,0 +1,259 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// systemDirs are made visible read-only to isolated commands so that bash and the host tools work.
+var systemDirs = []string{"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/libx32", "/etc", "/sys"}
+
+// Flags returned by statfs that have to be preserved when remounting a bind mount read-only in a
+// user namespace.  The kernel refuses to clear them, as that would give the namespace more access
+// than the original mount.
+var lockedMountFlags = []struct {
+	statfs uintptr
+	mount  uintptr
+}{
+	{0x2, syscall.MS_NOSUID},       // ST_NOSUID
+	{0x4, syscall.MS_NODEV},        // ST_NODEV
+	{0x8, syscall.MS_NOEXEC},       // ST_NOEXEC
+	{0x400, syscall.MS_NOATIME},    // ST_NOATIME
+	{0x800, syscall.MS_NODIRATIME}, // ST_NODIRATIME
+	{0x1000, syscall.MS_RELATIME},  // ST_RELATIME
+}
+
+// isolatedRoot returns the directory used as the root directory of the isolated command.
+func isolatedRoot(tempDir string) string {
+	return tempDir + ".root"
+}
+
+// isolatedConfigFile returns the file used to pass the isolateConfig to the child process.
+func isolatedConfigFile(tempDir string) string {
+	return tempDir + ".isolate.json"
+}
+
+// isolatedCommand returns a command that runs rawCommand in new user and mount namespaces that
+// only contain the system directories, tempDir and isolatedInputs.  The returned command's Dir
+// must be set before it is started.
+func isolatedCommand(rawCommand, tempDir string, isolatedInputs []string) (*exec.Cmd, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sbox executable for --isolate: %w", err)
+	}
+
+	absTempDir, err := filepath.Abs(tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	config := isolateConfig{
+		Root:     isolatedRoot(absTempDir),
+		Writable: []string{absTempDir},
+		Command:  rawCommand,
+	}
+	for _, input := range isolatedInputs {
+		absInput, err := filepath.Abs(input)
+		if err != nil {
+			return nil, err
+		}
+		config.ReadOnly = append(config.ReadOnly, absInput)
+	}
+
+	if err := os.MkdirAll(config.Root, 0777); err != nil {
+		return nil, fmt.Errorf("failed to create %q: %w", config.Root, err)
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(isolatedConfigFile(tempDir), data, 0666); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(self, isolatedChildArg, isolatedConfigFile(absTempDir))
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS,
+		// Unprivileged processes can only map their own uid and gid.  Map them to root in the
+		// namespace so that the child has the capabilities it needs to set up the mounts.
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+	}
+	return cmd, nil
+}
+
+// cleanupIsolatedCommand removes the files created by isolatedCommand.  The mounts disappear with
+// the namespaces when the command exits, so the root directory is empty again.
+func cleanupIsolatedCommand(tempDir string) {
+	os.Remove(isolatedRoot(tempDir))
+	os.Remove(isolatedConfigFile(tempDir))
+}
+
+// runIsolatedChild runs in the new namespaces, sets up the root directory described by the
+// isolateConfig passed on the command line and replaces itself with the command.
+func runIsolatedChild() {
+	if err := isolatedChild(); err != nil {
+		fmt.Fprintln(os.Stderr, "sbox --isolate:", err)
+		os.Exit(1)
+	}
+}
+
+func isolatedChild() error {
+	if len(os.Args) != 3 {
+		return fmt.Errorf("expected %s <config>", isolatedChildArg)
+	}
+
+	data, err := ioutil.ReadFile(os.Args[2])
+	if err != nil {
+		return err
+	}
+	var config isolateConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse %q: %w", os.Args[2], err)
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	// Don't propagate any of the mounts back to the parent namespace.
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+
+	root := config.Root
+	if err := syscall.Mount("tmpfs", root, "tmpfs", 0, ""); err != nil {
+		return fmt.Errorf("failed to mount tmpfs on %q: %w", root, err)
+	}
+
+	for _, systemDir := range systemDirs {
+		if err := exposePath(root, systemDir, true, true); err != nil {
+			return err
+		}
+	}
+	if err := exposePath(root, "/dev", false, false); err != nil {
+		return err
+	}
+	if err := exposePath(root, "/proc", false, false); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(root, "tmp"), 01777); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", filepath.Join(root, "tmp"), "tmpfs", 0, ""); err != nil {
+		return fmt.Errorf("failed to mount tmpfs on /tmp: %w", err)
+	}
+
+	for _, path := range config.ReadOnly {
+		if err := exposePath(root, path, true, false); err != nil {
+			return err
+		}
+	}
+	for _, path := range config.Writable {
+		if err := exposePath(root, path, false, false); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, dir), 0777); err != nil {
+		return err
+	}
+
+	if err := syscall.Chroot(root); err != nil {
+		return fmt.Errorf("failed to change root directory: %w", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("failed to change directory to %q: %w", dir, err)
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(bash, []string{"bash", "-c", config.Command}, os.Environ())
+}
+
+// exposePath makes path visible at the same path inside root by bind mounting it.  If optional is
+// true and path doesn't exist it is skipped.  Symlinks, for example /bin on systems where it links to
+// /usr/bin, are recreated inside root instead of bind mounted.
+func exposePath(root, path string, readOnly, optional bool) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) && optional {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	target := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(target), 0777); err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		dest, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(dest, target); err != nil && !os.IsExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	// Bind mounts need an existing file or directory to mount over.
+	if info.IsDir() {
+		err = os.MkdirAll(target, 0777)
+	} else {
+		var f *os.File
+		f, err = os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0666)
+		if err == nil {
+			err = f.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Mount(path, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to bind mount %q: %w", path, err)
+	}
+
+	if readOnly {
+		var statfs syscall.Statfs_t
+		if err := syscall.Statfs(path, &statfs); err != nil {
+			return err
+		}
+		flags := uintptr(syscall.MS_REMOUNT | syscall.MS_BIND | syscall.MS_RDONLY)
+		for _, f := range lockedMountFlags {
+			if uintptr(statfs.Flags)&f.statfs != 0 {
+				flags |= f.mount
+			}
+		}
+		if err := syscall.Mount("", target, "", flags, ""); err != nil {
+			return fmt.Errorf("failed to make %q read-only: %w", path, err)
+		}
+	}
+
+	return nil
+}
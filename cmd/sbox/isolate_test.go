@@ -0,0 +1,189 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chdirToTestTree changes the working directory to a new temporary directory that contains the
+// given files and returns its absolute path.  The working directory is restored when the test ends.
+func chdirToTestTree(t *testing.T, files []string) string {
+	t.Helper()
+
+	top, err := ioutil.TempDir("", "sbox_isolate_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	top, err = filepath.EvalSymlinks(top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(pwd)
+		os.RemoveAll(top)
+	})
+
+	for _, file := range files {
+		path := filepath.Join(top, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chdir(top); err != nil {
+		t.Fatal(err)
+	}
+	return top
+}
+
+func TestDeniedPaths(t *testing.T) {
+	top := chdirToTestTree(t, []string{
+		"src/foo.h",
+		"src/bar.h",
+		"include/visible.h",
+		"include/hidden.h",
+		"out/sbox/tmp/gen.h",
+	})
+
+	isolatedInputs := []string{"src/bar.h", "include/visible.h"}
+
+	testCases := []struct {
+		name    string
+		output  string
+		tempDir string
+		chdir   bool
+		want    []string
+	}{
+		{
+			name:    "relative path",
+			output:  "src/foo.h:12:10: error: some error",
+			tempDir: "out/sbox/tmp",
+			want:    []string{"src/foo.h"},
+		},
+		{
+			name:    "trailing period",
+			output:  "error: could not read src/foo.h.",
+			tempDir: "out/sbox/tmp",
+			want:    []string{"src/foo.h"},
+		},
+		{
+			name:    "isolated input",
+			output:  "src/bar.h: error",
+			tempDir: "out/sbox/tmp",
+			want:    nil,
+		},
+		{
+			name:    "file in temp dir",
+			output:  "out/sbox/tmp/gen.h: error",
+			tempDir: "out/sbox/tmp",
+			want:    nil,
+		},
+		{
+			name:    "missing file",
+			output:  "src/missing.h: No such file or directory",
+			tempDir: "out/sbox/tmp",
+			want:    nil,
+		},
+		{
+			name:    "absolute path in the tree",
+			output:  top + "/src/foo.h: error",
+			tempDir: "out/sbox/tmp",
+			want:    []string{"src/foo.h"},
+		},
+		{
+			name:    "absolute path outside the tree",
+			output:  "/usr/include/stdio.h: error",
+			tempDir: "out/sbox/tmp",
+			want:    nil,
+		},
+		{
+			name:    "absolute temp dir",
+			output:  "out/sbox/tmp/gen.h src/foo.h",
+			tempDir: top + "/out/sbox/tmp",
+			want:    []string{"src/foo.h"},
+		},
+		{
+			name:    "chdir",
+			output:  "../../../src/foo.h ../../../src/bar.h gen.h",
+			tempDir: "out/sbox/tmp",
+			chdir:   true,
+			want:    []string{"src/foo.h"},
+		},
+		{
+			name:    "chdir and absolute temp dir",
+			output:  "../../../src/foo.h",
+			tempDir: top + "/out/sbox/tmp",
+			chdir:   true,
+			want:    []string{"src/foo.h"},
+		},
+		{
+			name:    "outside the tree with chdir",
+			output:  "../../../../foo.h",
+			tempDir: "out/sbox/tmp",
+			chdir:   true,
+			want:    nil,
+		},
+		{
+			name:    "directory with a visible child",
+			output:  "include include/hidden.h src",
+			tempDir: "out/sbox/tmp",
+			want:    []string{"include/hidden.h"},
+		},
+		{
+			name:    "duplicates",
+			output:  "src/foo.h src/foo.h ./src/foo.h",
+			tempDir: "out/sbox/tmp",
+			want:    []string{"src/foo.h"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := deniedPaths([]byte(tc.output), tc.tempDir, tc.chdir, isolatedInputs)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("incorrect denied paths:\nwant: %q\n got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDeniedPathsLimit(t *testing.T) {
+	var files, want []string
+	for i := 0; i < maxDeniedPaths+5; i++ {
+		files = append(files, fmt.Sprintf("src/%02d.h", i))
+	}
+	chdirToTestTree(t, files)
+
+	want = append(want, files[:maxDeniedPaths]...)
+	want = append(want, "...")
+
+	got := deniedPaths([]byte(strings.Join(files, "\n")), "out/sbox/tmp", false, nil)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect denied paths:\nwant: %q\n got: %q", want, got)
+	}
+}
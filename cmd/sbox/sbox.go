@@ -41,6 +41,9 @@ var (
 	sandboxesRoot string
 	manifestFile  string
 	keepOutDir    bool
+	isolate       bool
+	cacheDir      string
+	outDir        string
 )
 
 const (
@@ -55,6 +58,14 @@ func init() {
 		"textproto manifest describing the sandboxed command(s)")
 	flag.BoolVar(&keepOutDir, "keep-out-dir", false,
 		"whether to keep the sandbox directory when done")
+	flag.BoolVar(&isolate, "isolate", false,
+		"run the commands in user and mount namespaces that only contain the sandbox directory, "+
+			"the isolated_inputs from the manifest and the system directories")
+	flag.StringVar(&cacheDir, "cache-dir", "",
+		"directory of a local cache of outputs keyed by the manifest and the hashes of its inputs")
+	flag.StringVar(&outDir, "out-dir", "",
+		"build output directory, which is not part of the keys of the cache so that entries are "+
+			"shared between output directories")
 }
 
 func usageViolation(violation string) {
@@ -71,6 +82,11 @@ func usageViolation(violation string) {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == isolatedChildArg {
+		// sbox re-executes itself in new namespaces to set up the mounts for --isolate.
+		runIsolatedChild()
+	}
+
 	flag.Usage = func() {
 		usageViolation("")
 	}
@@ -118,6 +134,19 @@ func run() error {
 		return fmt.Errorf("at least one commands entry is required in %q", manifestFile)
 	}
 
+	// Look for the outputs in the cache before setting up the sandbox.  Manifests whose inputs
+	// can't be hashed are never cached.
+	var key string
+	if cacheDir != "" {
+		if key, err = cacheKey(manifest, outDir); err != nil {
+			key = ""
+		} else if hit, err := restoreFromCache(cacheDir, key, manifest, outDir); err != nil {
+			return err
+		} else if hit {
+			return nil
+		}
+	}
+
 	// setup sandbox directory
 	err = os.MkdirAll(sandboxesRoot, 0777)
 	if err != nil {
@@ -191,6 +220,12 @@ func run() error {
 		}
 	}
 
+	if key != "" {
+		// Failing to store the outputs in the cache doesn't fail the command, the next lookup
+		// will just miss.
+		storeInCache(cacheDir, key, manifest, outDir)
+	}
+
 	return nil
 }
 
@@ -255,7 +290,16 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 		return "", err
 	}
 
-	cmd := exec.Command("bash", "-c", rawCommand)
+	var cmd *exec.Cmd
+	if isolate {
+		cmd, err = isolatedCommand(rawCommand, tempDir, command.IsolatedInputs)
+		if err != nil {
+			return "", err
+		}
+		defer cleanupIsolatedCommand(tempDir)
+	} else {
+		cmd = exec.Command("bash", "-c", rawCommand)
+	}
 	buf := &bytes.Buffer{}
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = buf
@@ -298,6 +342,14 @@ func runCommand(command *sbox_proto.Command, tempDir string) (depFile string, er
 	os.Stdout.Write(buf.Bytes())
 
 	if err != nil {
+		if isolate {
+			// Files that are not visible in the namespaces are the most likely cause of failures
+			// that only happen with --isolate, list the ones the command's output mentioned.
+			if denied := deniedPaths(buf.Bytes(), tempDir, command.GetChdir(), command.IsolatedInputs); len(denied) > 0 {
+				fmt.Fprintf(os.Stderr, "The command may have failed because it accessed files that are not "+
+					"inputs of the sandboxed command:\n  %s\n", strings.Join(denied, "\n  "))
+			}
+		}
 		return "", err
 	}
 
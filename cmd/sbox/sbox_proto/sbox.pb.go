@@ -89,10 +89,16 @@ type Command struct {
 	InputHash *string `protobuf:"bytes,5,opt,name=input_hash,json=inputHash" json:"input_hash,omitempty"`
 	// A list of files that will be copied before the sandboxed command, and whose contents should be
 	// copied as if they were listed in copy_before.
-	RspFiles             []*RspFile `protobuf:"bytes,6,rep,name=rsp_files,json=rspFiles" json:"rsp_files,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
-	XXX_unrecognized     []byte     `json:"-"`
-	XXX_sizecache        int32      `json:"-"`
+	RspFiles []*RspFile `protobuf:"bytes,6,rep,name=rsp_files,json=rspFiles" json:"rsp_files,omitempty"`
+	// A list of paths relative to the $PWD when sbox was started that the command reads directly
+	// instead of through copies in the temporary sandbox directory, for example tools and inputs that
+	// are not copied into the sandbox.  When sbox is run with --isolate these are the only files
+	// outside the temporary sandbox directory and the system directories that the command can see.
+	// When sbox is run with --cache-dir their contents are part of the cache key.
+	IsolatedInputs       []string `protobuf:"bytes,7,rep,name=isolated_inputs,json=isolatedInputs" json:"isolated_inputs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Command) Reset()         { *m = Command{} }
@@ -162,6 +168,13 @@ func (m *Command) GetRspFiles() []*RspFile {
 	return nil
 }
 
+func (m *Command) GetIsolatedInputs() []string {
+	if m != nil {
+		return m.IsolatedInputs
+	}
+	return nil
+}
+
 // Copy describes a from-to pair of files to copy.  The paths may be relative, the root that they
 // are relative to is specific to the context the Copy is used in and will be different for
 // from and to.
@@ -332,27 +345,28 @@ func init() {
 }
 
 var fileDescriptor_9d0425bf0de86ed1 = []byte{
-	// 342 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0x5f, 0x4b, 0xc3, 0x30,
-	0x14, 0xc5, 0x69, 0xd7, 0xb9, 0xf6, 0xee, 0x0f, 0x18, 0x7c, 0xc8, 0x8b, 0x32, 0x0a, 0xc2, 0xa6,
-	0x30, 0xd0, 0x07, 0xdf, 0x9d, 0x22, 0x22, 0x0c, 0x24, 0xe0, 0x8b, 0x08, 0x25, 0xeb, 0x52, 0x5b,
-	0x58, 0x9b, 0x90, 0x64, 0xb0, 0x7d, 0x57, 0x3f, 0x8c, 0xe4, 0xa6, 0xd3, 0x82, 0x2f, 0xbe, 0xdd,
-	0x7b, 0x0e, 0xf7, 0xdc, 0x5f, 0xc2, 0x05, 0x30, 0x6b, 0xb9, 0x5f, 0x28, 0x2d, 0xad, 0x24, 0x91,
-	0xab, 0xd3, 0x0f, 0x88, 0x57, 0xbc, 0xa9, 0x0a, 0x61, 0x2c, 0x99, 0x43, 0x9c, 0xcb, 0xba, 0xe6,
-	0xcd, 0xc6, 0xd0, 0x60, 0xda, 0x9b, 0x0d, 0x6f, 0xc7, 0x0b, 0x1c, 0x78, 0xf0, 0x2a, 0xfb, 0xb1,
-	0xc9, 0x25, 0x4c, 0xe4, 0xce, 0xaa, 0x9d, 0xcd, 0x36, 0x42, 0x15, 0xd5, 0x56, 0xd0, 0x70, 0x1a,
-	0xcc, 0x12, 0x36, 0xf6, 0xea, 0xa3, 0x17, 0xd3, 0xaf, 0x00, 0x06, 0xed, 0x30, 0xb9, 0x86, 0x61,
-	0x2e, 0xd5, 0x21, 0x5b, 0x8b, 0x42, 0x6a, 0xd1, 0x2e, 0x80, 0xe3, 0x02, 0x75, 0x60, 0xe0, 0xec,
-	0x25, 0xba, 0xe4, 0x0c, 0xfa, 0x79, 0xb9, 0xa9, 0x34, 0xc6, 0xc6, 0xcc, 0x37, 0x84, 0xc2, 0xa0,
-	0x25, 0xa0, 0xbd, 0x69, 0x38, 0x4b, 0xd8, 0xb1, 0x25, 0x73, 0xc0, 0xe9, 0x8c, 0x17, 0x56, 0x68,
-	0x1a, 0xfd, 0xc9, 0x4e, 0x9c, 0x7b, 0xef, 0x4c, 0x72, 0x0e, 0x50, 0x35, 0x8e, 0xbc, 0xe4, 0xa6,
-	0xa4, 0x7d, 0xc4, 0x4e, 0x50, 0x79, 0xe6, 0xa6, 0x24, 0x57, 0x90, 0x68, 0xa3, 0x32, 0x87, 0x6f,
-	0xe8, 0x49, 0xf7, 0x17, 0x98, 0x51, 0x4f, 0xd5, 0x56, 0xb0, 0x58, 0xfb, 0xc2, 0xa4, 0x2f, 0x10,
-	0xb9, 0x74, 0x42, 0x20, 0x2a, 0xb4, 0xac, 0x69, 0x80, 0x50, 0x58, 0x93, 0x09, 0x84, 0x56, 0xd2,
-	0x10, 0x95, 0xd0, 0x4a, 0x72, 0x01, 0x20, 0xf6, 0x22, 0xdf, 0x59, 0xbe, 0xde, 0x0a, 0xda, 0xc3,
-	0x67, 0x75, 0x94, 0xf4, 0x0d, 0x06, 0xed, 0x02, 0x8c, 0x73, 0x5f, 0x7a, 0x8c, 0x73, 0xda, 0x1d,
-	0x8c, 0x15, 0xb7, 0x65, 0x56, 0x73, 0xa5, 0xaa, 0xe6, 0xd3, 0xd0, 0x10, 0xd1, 0x4e, 0x3d, 0xda,
-	0x2b, 0xb7, 0xe5, 0xca, 0x3b, 0x6c, 0xa4, 0x7e, 0x1b, 0x93, 0xde, 0xc0, 0xb0, 0x63, 0xfe, 0x87,
-	0x74, 0x39, 0x7a, 0xc7, 0x33, 0xc9, 0xf0, 0x4c, 0xbe, 0x03, 0x00, 0x00, 0xff, 0xff, 0x83, 0x82,
-	0xb0, 0xc3, 0x33, 0x02, 0x00, 0x00,
+	// 365 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0x4f, 0x6b, 0xe3, 0x30,
+	0x10, 0xc5, 0x89, 0xed, 0xac, 0xed, 0xc9, 0x9f, 0x65, 0xc5, 0x1e, 0x74, 0xd9, 0xc5, 0x18, 0x96,
+	0x75, 0x76, 0x21, 0xd0, 0x1e, 0x7a, 0x6f, 0x5a, 0x4a, 0x5b, 0x08, 0x14, 0x41, 0x2f, 0xa5, 0x60,
+	0x14, 0x5b, 0xae, 0x0d, 0xb6, 0x25, 0x2c, 0x05, 0x92, 0x0f, 0xd0, 0xef, 0x5d, 0x34, 0xb6, 0xdb,
+	0x40, 0x2f, 0xbd, 0xcd, 0xfc, 0x1e, 0xf3, 0xf4, 0x46, 0x0c, 0x80, 0xde, 0xc9, 0xc3, 0x5a, 0x75,
+	0xd2, 0x48, 0xe2, 0xd9, 0x3a, 0x7e, 0x86, 0x60, 0xcb, 0xdb, 0xaa, 0x10, 0xda, 0x90, 0x15, 0x04,
+	0x99, 0x6c, 0x1a, 0xde, 0xe6, 0x9a, 0x4e, 0x22, 0x37, 0x99, 0x9d, 0x2f, 0xd6, 0x38, 0x70, 0xd5,
+	0x53, 0xf6, 0x2e, 0x93, 0x3f, 0xb0, 0x94, 0x7b, 0xa3, 0xf6, 0x26, 0xcd, 0x85, 0x2a, 0xaa, 0x5a,
+	0x50, 0x27, 0x9a, 0x24, 0x21, 0x5b, 0xf4, 0xf4, 0xba, 0x87, 0xf1, 0xab, 0x03, 0xfe, 0x30, 0x4c,
+	0xfe, 0xc3, 0x2c, 0x93, 0xea, 0x98, 0xee, 0x44, 0x21, 0x3b, 0x31, 0x3c, 0x00, 0xe3, 0x03, 0xea,
+	0xc8, 0xc0, 0xca, 0x1b, 0x54, 0xc9, 0x4f, 0x98, 0x66, 0x65, 0x5e, 0x75, 0x68, 0x1b, 0xb0, 0xbe,
+	0x21, 0x14, 0xfc, 0x21, 0x01, 0x75, 0x23, 0x27, 0x09, 0xd9, 0xd8, 0x92, 0x15, 0xe0, 0x74, 0xca,
+	0x0b, 0x23, 0x3a, 0xea, 0x7d, 0xf2, 0x0e, 0xad, 0x7a, 0x69, 0x45, 0xf2, 0x0b, 0xa0, 0x6a, 0x6d,
+	0xf2, 0x92, 0xeb, 0x92, 0x4e, 0x31, 0x76, 0x88, 0xe4, 0x96, 0xeb, 0x92, 0xfc, 0x83, 0xb0, 0xd3,
+	0x2a, 0xb5, 0xf1, 0x35, 0xfd, 0x76, 0xfa, 0x0b, 0x4c, 0xab, 0x9b, 0xaa, 0x16, 0x2c, 0xe8, 0xfa,
+	0x42, 0x93, 0xbf, 0xf0, 0xbd, 0xd2, 0xb2, 0xe6, 0x46, 0xe4, 0x29, 0x3a, 0x68, 0xea, 0x47, 0x6e,
+	0x12, 0xb2, 0xe5, 0x88, 0xef, 0x90, 0xc6, 0xf7, 0xe0, 0xd9, 0x18, 0x84, 0x80, 0x57, 0x74, 0xb2,
+	0xa1, 0x13, 0x4c, 0x8f, 0x35, 0x59, 0x82, 0x63, 0x24, 0x75, 0x90, 0x38, 0x46, 0x92, 0xdf, 0x00,
+	0xe2, 0x20, 0xb2, 0xbd, 0xe1, 0xbb, 0x5a, 0x50, 0x17, 0xf7, 0x3f, 0x21, 0xf1, 0x23, 0xf8, 0x43,
+	0x12, 0xb4, 0xb3, 0x7f, 0x3f, 0xda, 0x59, 0x76, 0x01, 0x0b, 0xc5, 0x4d, 0x99, 0x36, 0x5c, 0xa9,
+	0xaa, 0x7d, 0xd1, 0xd4, 0xc1, 0x1d, 0x7e, 0xf4, 0x3b, 0x3c, 0x70, 0x53, 0x6e, 0x7b, 0x85, 0xcd,
+	0xd5, 0x47, 0xa3, 0xe3, 0x33, 0x98, 0x9d, 0x88, 0x5f, 0x49, 0xba, 0x99, 0x3f, 0xe1, 0x3d, 0xa5,
+	0x78, 0x4f, 0x6f, 0x03, 0x00, 0x28, 0xc9, 0xa2, 0x80, 0x5c, 0x02, 0x00, 0x00,
 }
@@ -174,14 +174,38 @@ func writeJsonModuleGraph(configuration android.Config, ctx *android.Context, pa
 	writeFakeNinjaFile(extraNinjaDeps, configuration.BuildDir())
 }
 
+func writeVisibilityExplanation(configuration android.Config, extraNinjaDeps []string) {
+	explanation, err := android.ExplainVisibility(configuration)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Print(explanation)
+	writeFakeNinjaFile(extraNinjaDeps, configuration.BuildDir())
+}
+
+func writeDependencyPaths(configuration android.Config, ctx *android.Context, extraNinjaDeps []string) {
+	paths, err := android.QueryDependencyPaths(configuration, ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Print(paths)
+	writeFakeNinjaFile(extraNinjaDeps, configuration.BuildDir())
+}
+
 func doChosenActivity(configuration android.Config, extraNinjaDeps []string) string {
 	bazelConversionRequested := bp2buildMarker != ""
 	mixedModeBuild := configuration.BazelContext.BazelEnabled()
 	generateQueryView := bazelQueryViewDir != ""
 	jsonModuleFile := configuration.Getenv("SOONG_DUMP_JSON_MODULE_GRAPH")
+	explainVisibility := configuration.ExplainVisibilityQuery() != ""
+	queryDependencyPaths := configuration.DependencyPathsQuery() != ""
 
 	blueprintArgs := bootstrap.CmdlineArgs
-	prepareBuildActions := !generateQueryView && jsonModuleFile == ""
+	prepareBuildActions := !generateQueryView && jsonModuleFile == "" && !explainVisibility && !queryDependencyPaths
 	if bazelConversionRequested {
 		// Run the alternate pipeline of bp2build mutators and singleton to convert
 		// Blueprint to BUILD files before everything else.
@@ -217,6 +241,16 @@ func doChosenActivity(configuration android.Config, extraNinjaDeps []string) str
 		return bootstrap.CmdlineArgs.OutFile // TODO: This is a lie
 	}
 
+	if explainVisibility {
+		writeVisibilityExplanation(configuration, extraNinjaDeps)
+		return bootstrap.CmdlineArgs.OutFile // TODO: This is a lie
+	}
+
+	if queryDependencyPaths {
+		writeDependencyPaths(configuration, ctx, extraNinjaDeps)
+		return bootstrap.CmdlineArgs.OutFile // TODO: This is a lie
+	}
+
 	writeMetrics(configuration)
 	return bootstrap.CmdlineArgs.OutFile
 }
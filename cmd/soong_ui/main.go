@@ -197,8 +197,18 @@ func main() {
 	stat.AddOutput(status.NewVerboseLog(log, filepath.Join(logsDir, c.logsPrefix+"verbose.log")))
 	stat.AddOutput(status.NewErrorLog(log, filepath.Join(logsDir, c.logsPrefix+"error.log")))
 	stat.AddOutput(status.NewProtoErrorLog(log, buildErrorFile))
-	stat.AddOutput(status.NewCriticalPath(log))
+	stat.AddOutput(status.NewCriticalPath(log, filepath.Join(logsDir, c.logsPrefix+"critical_path.trace")))
 	stat.AddOutput(status.NewBuildProgressLog(log, filepath.Join(logsDir, c.logsPrefix+"build_progress.pb")))
+	stat.AddOutput(status.NewJSONLogFile(log, filepath.Join(logsDir, c.logsPrefix+"build_status.jsonl")))
+	if fd, ok := os.LookupEnv("SOONG_UI_JSON_STATUS_FD"); ok {
+		// Stream the status events to a file descriptor inherited from the caller, for example
+		// the write end of a pipe created by an IDE.
+		if n, err := strconv.Atoi(fd); err == nil {
+			stat.AddOutput(status.NewJSONLog(log, os.NewFile(uintptr(n), "json_status")))
+		} else {
+			log.Printf("Invalid SOONG_UI_JSON_STATUS_FD %q: %v", fd, err)
+		}
+	}
 
 	buildCtx.Verbosef("Detected %.3v GB total RAM", float32(config.TotalRAM())/(1024*1024*1024))
 	buildCtx.Verbosef("Parallelism (local/remote/highmem): %v/%v/%v",
@@ -514,7 +524,9 @@ func runMake(ctx build.Context, config build.Config, _ []string, logsDir string)
 	if config.Checkbuild() {
 		toBuild |= build.RunBuildTests
 	}
+	incremental := build.IsIncrementalBuild(config)
 	build.Build(ctx, config, toBuild)
+	build.CheckBuildTimeRegressions(ctx, config, incremental)
 }
 
 // getCommand finds the appropriate command based on args[1] flag. args[0]
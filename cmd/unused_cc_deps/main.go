@@ -0,0 +1,384 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// unused_cc_deps finds the shared_libs, static_libs and header_libs of a variant of a cc module
+// that contributed nothing to its build.  A dependency contributed headers if the compiler
+// depfiles of the variant list a header in its exported include directories or one of its
+// generated headers, a static library contributed objects if the link map of the variant lists a
+// member of the archive, and a shared library contributed symbols if it defines one of the
+// undefined dynamic symbols of the output.
+//
+// With -merge it merges the results of all variants of all modules into the report that
+// `bpfix -remove_unused_cc_deps` reads.  A dependency is only reported as unused if no variant of
+// the module used it.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/makedeps"
+)
+
+var (
+	outFile = flag.String("o", "", "output file")
+	llvmNm  = flag.String("llvm_nm", "llvm-nm", "path to llvm-nm")
+	merge   = flag.Bool("merge", false, "merge the results of the variants into the unused dependency report")
+)
+
+// Input is the evidence of a variant of a module, written by Soong.
+type Input struct {
+	Module    string
+	Blueprint string
+
+	// The compiler depfiles of the sources of the variant.
+	Depfiles []string
+
+	// The link map and the output of the variant, if it is linked.
+	LinkMap string
+	Output  string
+
+	Deps []Dep
+}
+
+// Dep is a dependency of a variant listed in shared_libs, static_libs or header_libs.
+type Dep struct {
+	Name     string
+	Property string
+
+	IncludeDirs      []string
+	GeneratedHeaders []string
+
+	StaticLibrary string
+	SharedLibrary string
+}
+
+// Result lists the dependencies that a variant used and did not use by property.  The
+// dependencies it has no evidence for are in neither.
+type Result struct {
+	Module    string
+	Blueprint string
+	Used      map[string][]string `json:",omitempty"`
+	Unused    map[string][]string `json:",omitempty"`
+}
+
+// Report is an entry of the merged report of unused dependencies.
+type Report struct {
+	Module    string
+	Blueprint string
+	Unused    map[string][]string
+}
+
+// symbolsFunc returns the dynamic symbols that a file defines, or that are undefined in it.
+type symbolsFunc func(file string, undefined bool) (map[string]bool, error)
+
+func nmSymbols(file string, undefined bool) (map[string]bool, error) {
+	args := []string{"-D", "--format=just-symbols"}
+	if undefined {
+		args = append(args, "--undefined-only")
+	} else {
+		args = append(args, "--defined-only")
+	}
+	cmd := exec.Command(*llvmNm, append(args, file)...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %s", *llvmNm, file, err)
+	}
+
+	symbols := make(map[string]bool)
+	for _, s := range strings.Fields(string(out)) {
+		// Strip the symbol version, e.g. "malloc@LIBC".
+		if i := strings.Index(s, "@"); i > 0 {
+			s = s[:i]
+		}
+		symbols[s] = true
+	}
+	return symbols, nil
+}
+
+// evidence is the parsed evidence of a variant.
+type evidence struct {
+	// The headers that the sources included, nil if the variant compiled no sources.
+	headers []string
+
+	// The link map, nil if the variant was not linked.
+	linkMap []byte
+
+	// The undefined dynamic symbols of the output, nil if the variant was not linked.
+	undefined map[string]bool
+}
+
+func readEvidence(in Input, symbols symbolsFunc) (*evidence, error) {
+	e := &evidence{}
+	for _, depfile := range in.Depfiles {
+		data, err := ioutil.ReadFile(depfile)
+		if err != nil {
+			return nil, err
+		}
+		deps, err := makedeps.Parse(depfile, bytes.NewBuffer(data))
+		if err != nil {
+			return nil, err
+		}
+		if e.headers == nil {
+			e.headers = []string{}
+		}
+		for _, header := range deps.Inputs {
+			e.headers = append(e.headers, filepath.Clean(header))
+		}
+	}
+
+	if in.LinkMap != "" {
+		data, err := ioutil.ReadFile(in.LinkMap)
+		if err != nil {
+			return nil, err
+		}
+		e.linkMap = data
+		e.undefined, err = symbols(in.Output, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// usedHeaders returns whether one of the headers is in the include directories of the dependency
+// or one of its generated headers, and whether there is evidence.
+func (e *evidence) usedHeaders(dep Dep) (used, known bool) {
+	if e.headers == nil {
+		return false, false
+	}
+	for _, header := range e.headers {
+		for _, dir := range dep.IncludeDirs {
+			if dir = filepath.Clean(dir); dir == "." || strings.HasPrefix(header, dir+"/") {
+				return true, true
+			}
+		}
+		for _, generated := range dep.GeneratedHeaders {
+			if header == filepath.Clean(generated) {
+				return true, true
+			}
+		}
+	}
+	return false, true
+}
+
+// linked returns whether the linker used the library of the dependency, and whether there is
+// evidence.
+func (e *evidence) linked(dep Dep, symbols symbolsFunc) (used, known bool, err error) {
+	if e.linkMap == nil {
+		return false, false, nil
+	}
+	if dep.StaticLibrary != "" {
+		// lld lists the archive members it linked as "<archive>(<member>)".
+		return bytes.Contains(e.linkMap, []byte(dep.StaticLibrary+"(")), true, nil
+	}
+	if dep.SharedLibrary != "" {
+		defined, err := symbols(dep.SharedLibrary, false)
+		if err != nil {
+			return false, false, err
+		}
+		for s := range e.undefined {
+			if defined[s] {
+				return true, true, nil
+			}
+		}
+		return false, true, nil
+	}
+	return false, false, nil
+}
+
+// analyze returns the dependencies of the variant that were used and that were not used.
+func analyze(in Input, symbols symbolsFunc) (Result, error) {
+	result := Result{
+		Module:    in.Module,
+		Blueprint: in.Blueprint,
+		Used:      make(map[string][]string),
+		Unused:    make(map[string][]string),
+	}
+
+	e, err := readEvidence(in, symbols)
+	if err != nil {
+		return result, err
+	}
+
+	for _, dep := range in.Deps {
+		usedHeaders, knownHeaders := e.usedHeaders(dep)
+		linked, knownLink, err := e.linked(dep, symbols)
+		if err != nil {
+			return result, err
+		}
+
+		isLibrary := dep.StaticLibrary != "" || dep.SharedLibrary != ""
+		switch {
+		case usedHeaders || linked:
+			result.Used[dep.Property] = append(result.Used[dep.Property], dep.Name)
+		case knownHeaders && (knownLink || !isLibrary):
+			result.Unused[dep.Property] = append(result.Unused[dep.Property], dep.Name)
+		}
+	}
+	return result, nil
+}
+
+// mergeResults returns the dependencies of each module that some variant did not use and no
+// variant used, sorted by Android.bp file and module.
+func mergeResults(results []Result) []Report {
+	type key struct{ module, blueprint string }
+	used := make(map[key]map[string]bool)
+	unused := make(map[key]map[string]map[string]bool)
+
+	for _, r := range results {
+		k := key{r.Module, r.Blueprint}
+		if used[k] == nil {
+			used[k] = make(map[string]bool)
+			unused[k] = make(map[string]map[string]bool)
+		}
+		for property, names := range r.Used {
+			for _, name := range names {
+				used[k][property+"\x00"+name] = true
+			}
+		}
+		for property, names := range r.Unused {
+			if unused[k][property] == nil {
+				unused[k][property] = make(map[string]bool)
+			}
+			for _, name := range names {
+				unused[k][property][name] = true
+			}
+		}
+	}
+
+	var reports []Report
+	for k, properties := range unused {
+		report := Report{Module: k.module, Blueprint: k.blueprint, Unused: make(map[string][]string)}
+		for property, names := range properties {
+			for name := range names {
+				if !used[k][property+"\x00"+name] {
+					report.Unused[property] = append(report.Unused[property], name)
+				}
+			}
+			sort.Strings(report.Unused[property])
+		}
+		if len(report.Unused) > 0 {
+			reports = append(reports, report)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Blueprint != reports[j].Blueprint {
+			return reports[i].Blueprint < reports[j].Blueprint
+		}
+		return reports[i].Module < reports[j].Module
+	})
+	return reports
+}
+
+// expandArgs replaces the arguments starting with "@" with the whitespace separated contents of
+// the response file they name.
+func expandArgs(args []string) ([]string, error) {
+	var ret []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "@") {
+			ret = append(ret, arg)
+			continue
+		}
+		f, err := os.Open(strings.TrimPrefix(arg, "@"))
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Split(bufio.ScanWords)
+		for scanner.Scan() {
+			ret = append(ret, scanner.Text())
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func readJSON(file string, v interface{}) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %s", file, err)
+	}
+	return nil
+}
+
+func run(args []string) (interface{}, error) {
+	if *merge {
+		results := make([]Result, len(args))
+		for i, arg := range args {
+			if err := readJSON(arg, &results[i]); err != nil {
+				return nil, err
+			}
+		}
+		reports := mergeResults(results)
+		if reports == nil {
+			reports = []Report{}
+		}
+		return reports, nil
+	}
+
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected one input file, got %d", len(args))
+	}
+	var in Input
+	if err := readJSON(args[0], &in); err != nil {
+		return nil, err
+	}
+	return analyze(in, nmSymbols)
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [-llvm_nm <llvm-nm>] -o <output> <input.json>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -merge -o <output> <result.json>|@<rspfile>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *outFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	args, err := expandArgs(flag.Args())
+	if err == nil {
+		var out interface{}
+		if out, err = run(args); err == nil {
+			var data []byte
+			if data, err = json.MarshalIndent(out, "", "  "); err == nil {
+				err = ioutil.WriteFile(*outFile, append(data, '\n'), 0666)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unused_cc_deps:", err)
+		os.Exit(1)
+	}
+}
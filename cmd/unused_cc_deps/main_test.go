@@ -0,0 +1,140 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	dir, err := ioutil.TempDir("", "unused_cc_deps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	depfile := write("foo.o.d", "obj/foo.o: foo.cpp \\\n  external/bar/include/bar.h \\\n  out/gen/baz.h\n")
+	linkMap := write("link.map",
+		"             VMA              LMA     Size Align Out     In      Symbol\n"+
+			"            1000             1000       10     4         out/libstatic.a(static.o):(.text)\n")
+
+	symbols := func(file string, undefined bool) (map[string]bool, error) {
+		return map[string]map[string]bool{
+			"out/foo":          {"used_shared": true, "malloc": true},
+			"out/libshared.so": {"used_shared": true},
+			"out/libunused.so": {"unused_shared": true},
+		}[file], nil
+	}
+
+	in := Input{
+		Module:    "foo",
+		Blueprint: "Android.bp",
+		Depfiles:  []string{depfile},
+		LinkMap:   linkMap,
+		Output:    "out/foo",
+		Deps: []Dep{
+			{Name: "libbar_headers", Property: "header_libs", IncludeDirs: []string{"external/bar/include"}},
+			{Name: "libbaz_headers", Property: "header_libs", GeneratedHeaders: []string{"out/gen/baz.h"}},
+			{Name: "libunused_headers", Property: "header_libs", IncludeDirs: []string{"external/unused/include"}},
+			{Name: "libstatic", Property: "static_libs", StaticLibrary: "out/libstatic.a"},
+			{Name: "libunusedstatic", Property: "static_libs", StaticLibrary: "out/libunusedstatic.a"},
+			{Name: "libshared", Property: "shared_libs", SharedLibrary: "out/libshared.so"},
+			{Name: "libunused", Property: "shared_libs", SharedLibrary: "out/libunused.so",
+				IncludeDirs: []string{"external/libunused/include"}},
+		},
+	}
+
+	got, err := analyze(in, symbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Result{
+		Module:    "foo",
+		Blueprint: "Android.bp",
+		Used: map[string][]string{
+			"header_libs": {"libbar_headers", "libbaz_headers"},
+			"static_libs": {"libstatic"},
+			"shared_libs": {"libshared"},
+		},
+		Unused: map[string][]string{
+			"header_libs": {"libunused_headers"},
+			"static_libs": {"libunusedstatic"},
+			"shared_libs": {"libunused"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %#v\ngot  %#v", want, got)
+	}
+
+	// Without a link there is no evidence for the libraries.
+	in.LinkMap = ""
+	got, err = analyze(in, symbols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantUnused := map[string][]string{"header_libs": {"libunused_headers"}}
+	if !reflect.DeepEqual(got.Unused, wantUnused) {
+		t.Errorf("want unused %v, got %v", wantUnused, got.Unused)
+	}
+}
+
+func TestMergeResults(t *testing.T) {
+	results := []Result{
+		{
+			Module:    "foo",
+			Blueprint: "b/Android.bp",
+			Unused:    map[string][]string{"shared_libs": {"libb", "liba"}, "header_libs": {"libc"}},
+		},
+		{
+			Module:    "foo",
+			Blueprint: "b/Android.bp",
+			Used:      map[string][]string{"shared_libs": {"libb"}},
+			Unused:    map[string][]string{"shared_libs": {"liba"}},
+		},
+		{
+			Module:    "bar",
+			Blueprint: "a/Android.bp",
+			Used:      map[string][]string{"static_libs": {"libd"}},
+		},
+		{
+			Module:    "bar",
+			Blueprint: "a/Android.bp",
+			Unused:    map[string][]string{"static_libs": {"libd", "libe"}},
+		},
+	}
+
+	want := []Report{
+		{Module: "bar", Blueprint: "a/Android.bp", Unused: map[string][]string{"static_libs": {"libe"}}},
+		{Module: "foo", Blueprint: "b/Android.bp", Unused: map[string][]string{
+			"header_libs": {"libc"},
+			"shared_libs": {"liba"},
+		}},
+	}
+	if got := mergeResults(results); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %#v\ngot  %#v", want, got)
+	}
+}
@@ -15,12 +15,14 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 	"time"
@@ -29,6 +31,7 @@ import (
 
 	"android/soong/jar"
 	"android/soong/third_party/zip"
+	soongzip "android/soong/zip"
 )
 
 var (
@@ -38,6 +41,10 @@ var (
 	sortJava  = flag.Bool("j", false, "sort using jar ordering within each glob (META-INF/MANIFEST.MF first)")
 	setTime   = flag.Bool("t", false, "set timestamps to 2009-01-01 00:00:00")
 
+	compLevel       = flag.Int("L", -1, "recompress entries at this deflate compression level (0-9), -1 copies entries with their existing compression")
+	storeCompressed = flag.Bool("store-compressed", false, "when recompressing, store entries with extensions of already compressed formats (.png, .jar, .so, .apk, ...) and deflate the rest")
+	parallelJobs    = flag.Int("parallel", runtime.NumCPU(), "number of parallel threads to use when recompressing")
+
 	staticTime = time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC)
 
 	excludes   multiFlag
@@ -63,7 +70,8 @@ func main() {
 		fmt.Fprintln(os.Stderr, "<glob> uses the rules at https://godoc.org/github.com/google/blueprint/pathtools/#Match")
 		fmt.Fprintln(os.Stderr, "")
 		fmt.Fprintln(os.Stderr, "Files will be copied with their existing compression from the input zipfile to")
-		fmt.Fprintln(os.Stderr, "the output zipfile, in the order of filespec arguments.")
+		fmt.Fprintln(os.Stderr, "the output zipfile, in the order of filespec arguments, unless -L is used to")
+		fmt.Fprintln(os.Stderr, "recompress them in parallel.")
 		fmt.Fprintln(os.Stderr, "")
 		fmt.Fprintln(os.Stderr, "If no filepsec is provided all files and directories are copied.")
 	}
@@ -97,8 +105,19 @@ func main() {
 		}
 	}()
 
+	var recompress *soongzip.RecompressArgs
+	if *compLevel >= 0 {
+		recompress = &soongzip.RecompressArgs{
+			CompressionLevel: *compLevel,
+			StoreCompressed:  *storeCompressed,
+			NumParallelJobs:  *parallelJobs,
+		}
+	} else if *storeCompressed {
+		log.Fatal(errors.New("must specify -L when specifying -store-compressed"))
+	}
+
 	if err := zip2zip(&reader.Reader, writer, *sortGlobs, *sortJava, *setTime,
-		flag.Args(), excludes, includes, uncompress); err != nil {
+		flag.Args(), excludes, includes, uncompress, recompress); err != nil {
 
 		log.Fatal(err)
 	}
@@ -111,7 +130,8 @@ type pair struct {
 }
 
 func zip2zip(reader *zip.Reader, writer *zip.Writer, sortOutput, sortJava, setTime bool,
-	args []string, excludes, includes multiFlag, uncompresses []string) error {
+	args []string, excludes, includes multiFlag, uncompresses []string,
+	recompress *soongzip.RecompressArgs) error {
 
 	matches := []pair{}
 
@@ -213,6 +233,26 @@ func zip2zip(reader *zip.Reader, writer *zip.Writer, sortOutput, sortJava, setTi
 		matchesAfterExcludes = append(matchesAfterExcludes, match)
 	}
 
+	if recompress != nil {
+		return soongzip.Recompress(writer, *recompress, func(r *soongzip.Recompressor) error {
+			for _, match := range matchesAfterExcludes {
+				if setTime {
+					match.File.SetModTime(staticTime)
+				}
+				var err error
+				if match.uncompress {
+					err = r.Convert(match.File, match.newName, zip.Store)
+				} else {
+					err = r.Recompress(match.File, match.newName)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
 	for _, match := range matchesAfterExcludes {
 		if setTime {
 			match.File.SetModTime(staticTime)
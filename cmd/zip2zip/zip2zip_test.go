@@ -17,10 +17,12 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"reflect"
 	"testing"
 
 	"android/soong/third_party/zip"
+	soongzip "android/soong/zip"
 )
 
 var testCases = []struct {
@@ -438,7 +440,7 @@ func TestZip2Zip(t *testing.T) {
 
 			outputWriter := zip.NewWriter(outputBuf)
 			err = zip2zip(inputReader, outputWriter, testCase.sortGlobs, testCase.sortJava, false,
-				testCase.args, testCase.excludes, testCase.includes, testCase.uncompresses)
+				testCase.args, testCase.excludes, testCase.includes, testCase.uncompresses, nil)
 			if errorString(testCase.err) != errorString(err) {
 				t.Fatalf("Unexpected error:\n got: %q\nwant: %q", errorString(err), errorString(testCase.err))
 			}
@@ -471,6 +473,114 @@ func TestZip2Zip(t *testing.T) {
 	}
 }
 
+func TestZip2ZipRecompress(t *testing.T) {
+	contents := bytes.Repeat([]byte("compressible "), 100)
+
+	inputBuf := &bytes.Buffer{}
+	inputWriter := zip.NewWriter(inputBuf)
+	for _, file := range []struct {
+		name   string
+		method uint16
+	}{
+		{"a/a.txt", zip.Store},
+		{"a/b.png", zip.Deflate},
+		{"a/c.so", zip.Store},
+		{"a/d.txt", zip.Deflate},
+	} {
+		w, err := inputWriter.CreateHeader(&zip.FileHeader{Name: file.name, Method: file.method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(contents)
+	}
+	inputWriter.Close()
+	inputBytes := inputBuf.Bytes()
+
+	testCases := []struct {
+		name         string
+		recompress   soongzip.RecompressArgs
+		uncompresses []string
+		storedFiles  []string
+	}{
+		{
+			name:        "deflate",
+			recompress:  soongzip.RecompressArgs{CompressionLevel: 9},
+			storedFiles: nil,
+		},
+		{
+			name:        "store",
+			recompress:  soongzip.RecompressArgs{CompressionLevel: 0},
+			storedFiles: []string{"a/a.txt", "a/b.png", "a/c.so", "a/d.txt"},
+		},
+		{
+			name:        "store compressed",
+			recompress:  soongzip.RecompressArgs{CompressionLevel: 5, StoreCompressed: true},
+			storedFiles: []string{"a/b.png", "a/c.so"},
+		},
+		{
+			name:         "uncompress",
+			recompress:   soongzip.RecompressArgs{CompressionLevel: 5},
+			uncompresses: []string{"a/d.txt"},
+			storedFiles:  []string{"a/d.txt"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			inputReader, err := zip.NewReader(bytes.NewReader(inputBytes), int64(len(inputBytes)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			outputBuf := &bytes.Buffer{}
+			outputWriter := zip.NewWriter(outputBuf)
+			testCase.recompress.NumParallelJobs = 2
+			err = zip2zip(inputReader, outputWriter, false, false, false, nil, nil, nil,
+				testCase.uncompresses, &testCase.recompress)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outputWriter.Close()
+
+			outputBytes := outputBuf.Bytes()
+			outputReader, err := zip.NewReader(bytes.NewReader(outputBytes), int64(len(outputBytes)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var outputFiles []string
+			var storedFiles []string
+			for _, file := range outputReader.File {
+				outputFiles = append(outputFiles, file.Name)
+				if file.Method == zip.Store {
+					storedFiles = append(storedFiles, file.Name)
+				}
+
+				r, err := file.Open()
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := ioutil.ReadAll(r)
+				r.Close()
+				if err != nil {
+					t.Fatalf("%s: %s", file.Name, err)
+				}
+				if !bytes.Equal(got, contents) {
+					t.Errorf("%s: incorrect contents", file.Name)
+				}
+			}
+
+			wantOutputFiles := []string{"a/a.txt", "a/b.png", "a/c.so", "a/d.txt"}
+			if !reflect.DeepEqual(wantOutputFiles, outputFiles) {
+				t.Errorf("Output file list does not match:\nwant: %v\n got: %v", wantOutputFiles, outputFiles)
+			}
+			if !reflect.DeepEqual(testCase.storedFiles, storedFiles) {
+				t.Errorf("Stored file list does not match:\nwant: %v\n got: %v", testCase.storedFiles, storedFiles)
+			}
+		})
+	}
+}
+
 func TestConstantPartOfPattern(t *testing.T) {
 	testCases := []struct{ in, out string }{
 		{
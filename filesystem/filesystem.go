@@ -82,6 +82,10 @@ type filesystemProperties struct {
 
 	// Symbolic links to be created under root with "ln -sf <target> <name>".
 	Symlinks []symlinkDefinition
+
+	// When set to true, the notices for the packaged files are generated from their license
+	// modules and included in the image as etc/NOTICE.xml.gz under base_dir. Default is false.
+	Include_license_notices *bool
 }
 
 // android_filesystem packages a set of modules and their transitive dependencies into a filesystem
@@ -202,6 +206,9 @@ func (f *filesystem) buildRootZip(ctx android.ModuleContext) android.OutputPath
 			}
 		}
 	}
+	if proptools.Bool(f.properties.Include_license_notices) {
+		extraFiles = append(extraFiles, f.buildLicenseNotices(ctx, rootForExtraFiles))
+	}
 
 	// Zip them all
 	zipOut := android.PathForModuleGen(ctx, "root.zip").OutputPath
@@ -224,6 +231,20 @@ func (f *filesystem) buildRootZip(ctx android.ModuleContext) android.OutputPath
 	return zipOut
 }
 
+// Generates the notices for the packaged files and places them under root at the location where
+// they are installed.
+func (f *filesystem) buildLicenseNotices(ctx android.ModuleContext, root android.OutputPath) android.OutputPath {
+	depsBase := proptools.StringDefault(f.properties.Base_dir, ".")
+	notices := android.BuildLicenseNoticesForPackagingSpecs(ctx, f.GatherPackagingSpecs(ctx), depsBase)
+	output := root.Join(ctx, depsBase, "etc", "NOTICE.xml.gz")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:   android.Cp,
+		Input:  notices.XmlGz,
+		Output: output,
+	})
+	return output
+}
+
 func (f *filesystem) buildImageUsingBuildImage(ctx android.ModuleContext) android.OutputPath {
 	depsZipFile := android.PathForModuleOut(ctx, "deps.zip").OutputPath
 	f.CopyDepsToZip(ctx, depsZipFile)
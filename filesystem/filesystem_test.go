@@ -74,3 +74,60 @@ func TestFileSystemFillsLinkerConfigWithStubLibs(t *testing.T) {
 	android.AssertStringDoesNotContain(t, "linker.config.pb should not have libbar",
 		output.RuleParams.Command, "libbar.so")
 }
+
+func TestFileSystemLicenseNotices(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		fixture,
+		android.PrepareForTestWithLicenses,
+		android.FixtureAddTextFile("external/foo/LICENSE", "MIT License"),
+	).RunTestWithBp(t, `
+		android_filesystem {
+			name: "myfilesystem",
+			base_dir: "system",
+			deps: ["libfoo"],
+			include_license_notices: true,
+		}
+
+		license_kind {
+			name: "mit_kind",
+			conditions: ["notice"],
+		}
+
+		license {
+			name: "libfoo_license",
+			license_kinds: ["mit_kind"],
+			copyright_notice: "Copyright (C) Foo",
+			license_text: ["external/foo/LICENSE"],
+		}
+
+		cc_library {
+			name: "libfoo",
+			licenses: ["libfoo_license"],
+		}
+	`)
+
+	module := result.ModuleForTests("myfilesystem", "android_common")
+
+	notice := module.Output("system/etc/NOTICE.xml.gz")
+	android.AssertPathRelativeToTopEquals(t, "notice input",
+		"out/soong/.intermediates/myfilesystem/android_common/license_notices/NOTICE.xml.gz", notice.Input)
+
+	content := android.ContentFromFileRuleForTests(t, module.Output(
+		"out/soong/.intermediates/myfilesystem/android_common/license_notices/license_notices_input.json"))
+	android.AssertStringDoesContain(t, "license notices input", content, `"Path": "/system/lib64/libfoo.so",
+      "Licenses": [
+        "libfoo_license"
+      ]`)
+	android.AssertStringDoesContain(t, "license notices input", content, `"Name": "libfoo_license",
+      "CopyrightNotice": "Copyright (C) Foo",
+      "TextFiles": [
+        "external/foo/LICENSE"
+      ]`)
+
+	rule := module.Rule("license_notices")
+	android.AssertPathsRelativeToTopEquals(t, "license notices implicits",
+		[]string{
+			"out/soong/.intermediates/myfilesystem/android_common/license_notices/license_notices_input.json",
+			"external/foo/LICENSE",
+		}, rule.Implicits)
+}
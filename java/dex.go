@@ -132,6 +132,7 @@ var r8, r8RE = pctx.MultiCommandRemoteStaticRules("r8",
 			"${config.SoongZipCmd}",
 			"${config.MergeZipsCmd}",
 		},
+		Pool: android.HighmemR8Pool,
 	}, map[string]*remoteexec.REParams{
 		"$r8Template": &remoteexec.REParams{
 			Labels:          map[string]string{"type": "compile", "compiler": "r8"},
@@ -453,7 +453,7 @@ func (d *Droidstubs) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 	if BoolDefault(d.properties.High_mem, false) {
 		// This metalava run uses lots of memory, restrict the number of metalava jobs that can run in parallel.
-		rule.HighMem()
+		rule.HighMemPool(android.HighmemMetalavaPool)
 	}
 
 	generateStubs := BoolDefault(d.properties.Generate_stubs, true)
@@ -205,6 +205,14 @@ type dependencyTag struct {
 	name string
 }
 
+// PropagatesLicenseConditions returns true for static libraries, whose classes are included in the
+// module that depends on them.
+func (d dependencyTag) PropagatesLicenseConditions() bool {
+	return d == staticLibTag
+}
+
+var _ android.LicenseConditionsPropagatingDependencyTag = dependencyTag{}
+
 // installDependencyTag is a dependency tag that is annotated to cause the installed files of the
 // dependency to be installed when the parent module is installed.
 type installDependencyTag struct {
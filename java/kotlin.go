@@ -54,6 +54,7 @@ var kotlinc = pctx.AndroidRemoteStaticRule("kotlinc", android.RemoteRuleSupports
 		},
 		Rspfile:        "$out.rsp",
 		RspfileContent: `$in`,
+		Pool:           android.HighmemKotlinPool,
 	},
 	"kotlincFlags", "classpath", "srcJars", "commonSrcFilesArg", "srcJarDir", "classesDir",
 	"kotlinJvmTarget", "kotlinBuildFile", "emptyDir", "name")
@@ -148,6 +149,7 @@ var kapt = pctx.AndroidRemoteStaticRule("kapt", android.RemoteRuleSupports{Goma:
 		},
 		Rspfile:        "$out.rsp",
 		RspfileContent: `$in`,
+		Pool:           android.HighmemKotlinPool,
 	},
 	"kotlincFlags", "encodedJavacFlags", "kaptProcessorPath", "kaptProcessor",
 	"classpath", "srcJars", "commonSrcFilesArg", "srcJarDir", "kaptDir", "kotlinJvmTarget",
@@ -812,6 +812,14 @@ func (d dependencyTag) InstallDepNeeded() bool {
 
 var _ android.InstallNeededDependencyTag = dependencyTag{}
 
+// PropagatesLicenseConditions returns true for rlibs, which are linked into the module that
+// depends on them.
+func (d dependencyTag) PropagatesLicenseConditions() bool {
+	return d == rlibDepTag
+}
+
+var _ android.LicenseConditionsPropagatingDependencyTag = dependencyTag{}
+
 var (
 	customBindgenDepTag = dependencyTag{name: "customBindgenTag"}
 	rlibDepTag          = dependencyTag{name: "rlibTag", library: true}
@@ -29,6 +29,9 @@ type SanitizeProperties struct {
 		Hwaddress *bool `android:"arch_variant"`
 		Fuzzer    *bool `android:"arch_variant"`
 		Never     *bool `android:"arch_variant"`
+
+		// MemorySanitizer is not supported for rust modules, setting it is an error.
+		Memory *bool `android:"arch_variant"`
 	}
 	SanitizerEnabled bool `blueprint:"mutated"`
 	SanitizeDep      bool `blueprint:"mutated"`
@@ -96,6 +99,12 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 	if ctx.Os() == android.Android && Bool(s.Hwaddress) {
 		sanitize.Properties.SanitizerEnabled = true
 	}
+
+	// MSan reports false positives for memory initialized by uninstrumented code, and the
+	// prebuilt rust standard library is not built with MSan.
+	if Bool(s.Memory) {
+		ctx.PropertyErrorf("sanitize.memory", "MemorySanitizer is not supported for rust modules because the prebuilt rust standard library is not instrumented")
+	}
 }
 
 type sanitize struct {
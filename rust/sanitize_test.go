@@ -0,0 +1,32 @@
+// Copyright 2021 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"testing"
+)
+
+// Test that MemorySanitizer is rejected, the prebuilt rust standard library is not instrumented.
+func TestMsanUnsupported(t *testing.T) {
+	testRustError(t, `sanitize.memory: MemorySanitizer is not supported for rust modules`, `
+		rust_binary_host {
+			name: "foo",
+			srcs: ["foo.rs"],
+			sanitize: {
+				memory: true,
+			},
+		}
+	`)
+}
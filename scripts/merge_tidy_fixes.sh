@@ -0,0 +1,49 @@
+#!/bin/bash -eu
+
+# Copyright 2021 Google Inc. All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Script to merge the fix-its that clang-tidy exported with -export-fixes for
+# several sources into one replacement file for clang-apply-replacements
+# Empty inputs are sources without fix-its.
+# Arguments:
+#  output: the merged replacement file
+#  fixes...: the exported fix-its
+
+if [ $# -lt 1 ]; then
+    echo "usage: $0 <output> [fixes...]" >&2
+    exit 1
+fi
+
+output="$1"
+shift
+
+# Concatenate the Diagnostics lists of the inputs, which end at the end of
+# their YAML document.
+awk '
+    FNR == 1 { diagnostics = 0 }
+    /^Diagnostics:/ { diagnostics = 1; next }
+    /^(\.\.\.|---)$/ { diagnostics = 0; next }
+    diagnostics { merged = merged $0 "\n" }
+    END {
+        print "---"
+        print "MainSourceFile:  \"\""
+        if (merged == "") {
+            print "Diagnostics:     []"
+        } else {
+            print "Diagnostics:"
+            printf "%s", merged
+        }
+        print "..."
+    }' /dev/null "$@" > "${output}"
@@ -0,0 +1,49 @@
+#!/bin/bash -eu
+
+# Copyright 2021 Google Inc. All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Script to check the clang-tidy findings of a module against its tidy_baseline
+# The findings are the warnings in the clang-tidy output without their line and
+# column numbers, so that the baseline does not change whenever unrelated code
+# moves:
+#   path/to/foo.cpp: warning: message [check-name]
+# Fails if there are findings that are not in the baseline.
+# Arguments:
+#  baseline: the checked-in baseline of the module
+#  findings: output file for the sorted findings of the module
+#  tidy outputs...: the clang-tidy output for the sources of the module
+
+if [ $# -lt 2 ]; then
+    echo "usage: $0 <baseline> <findings> [tidy outputs...]" >&2
+    exit 1
+fi
+
+baseline="$1"
+findings="$2"
+shift 2
+
+export LC_ALL=C
+
+cat /dev/null "$@" | \
+    sed -n -E 's/^([^ :]+):[0-9]+:[0-9]+: warning: (.*)$/\1: warning: \2/p' | \
+    sort -u > "${findings}"
+
+new_findings=$(grep -v -e '^#' -e '^$' "${baseline}" | sort -u | comm -13 - "${findings}")
+if [ -n "${new_findings}" ]; then
+    echo "error: clang-tidy findings that are not in ${baseline}:" >&2
+    echo "${new_findings}" >&2
+    echo "Fix them, or add them to ${baseline}. All findings of the module are in ${findings}." >&2
+    exit 1
+fi
@@ -60,12 +60,21 @@ builddir = {{.OutDir}}
 {{end -}}
 pool highmem_pool
  depth = {{.HighmemParallel}}
+{{range .HighmemPools}}pool {{.Name}}
+ depth = {{.Depth}}
+{{end -}}
 {{if .HasKatiSuffix}}subninja {{.KatiBuildNinjaFile}}
 subninja {{.KatiPackageNinjaFile}}
 {{end -}}
 subninja {{.SoongNinjaFile}}
 `))
 
+// combinedBuildNinjaParams are the values used by combinedBuildNinjaTemplate.
+type combinedBuildNinjaParams struct {
+	Config
+	HighmemPools []highmemPoolParams
+}
+
 func createCombinedBuildNinjaFile(ctx Context, config Config) {
 	// If we're in SkipKati mode, skip creating this file if it already exists
 	if config.SkipKati() {
@@ -80,7 +89,12 @@ func createCombinedBuildNinjaFile(ctx Context, config Config) {
 	}
 	defer file.Close()
 
-	if err := combinedBuildNinjaTemplate.Execute(file, config); err != nil {
+	params := combinedBuildNinjaParams{
+		Config:       config,
+		HighmemPools: highmemPoolDepths(ctx, config),
+	}
+
+	if err := combinedBuildNinjaTemplate.Execute(file, params); err != nil {
 		ctx.Fatalln("Failed to write combined ninja file:", err)
 	}
 }
@@ -0,0 +1,54 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"android/soong/ui/metrics"
+)
+
+const buildHistoryFilename = ".build_history.pb"
+
+// IsIncrementalBuild returns true if ninja has already run in the out directory.  It must be
+// called before the build starts.
+func IsIncrementalBuild(config Config) bool {
+	_, err := os.Stat(filepath.Join(config.OutDir(), ".ninja_log"))
+	return err == nil
+}
+
+// CheckBuildTimeRegressions adds the metrics of the build that just finished to the build history
+// in the out directory, and warns about any phase of the build that took far longer than in
+// earlier comparable builds.
+func CheckBuildTimeRegressions(ctx Context, config Config, incremental bool) {
+	if ctx.Metrics == nil {
+		return
+	}
+
+	history := metrics.LoadHistory(filepath.Join(config.OutDir(), buildHistoryFilename))
+	build := ctx.Metrics.HistoryEntry(incremental)
+
+	for _, r := range history.Regressions(build) {
+		ctx.Printf("Warning: %s took %s, more than %.0fx the median of %s over the last %d comparable builds.",
+			r.Phase, r.Duration.Round(time.Second), metrics.RegressionFactor, r.Median.Round(time.Second), r.Samples)
+	}
+
+	history.Add(build)
+	if err := history.Save(); err != nil {
+		ctx.Verboseln("Failed to save build history:", err)
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+
+	"android/soong/ui/status"
+)
+
+// The highmem pools are ninja pools for rules that are known to need a lot of
+// RAM, like the linker, R8, metalava and the Kotlin compiler. Soong puts the
+// rules in the pools (see android/defs.go), and soong_ui records the peak
+// memory used by the actions in each pool as reported by ninja, and sizes the
+// pools in the next build so that the actions in a pool fit in the RAM of the
+// machine. Until the actions of the linker, R8 and the Kotlin compiler have been
+// seen to use a lot of RAM, their pools are as deep as the build is parallel,
+// so they don't restrict anything.
+
+const (
+	highmemPeakMemoryFilename = ".highmem_peak_memory.json"
+
+	// maxPeakMemorySamples is the number of builds whose peak memory use is
+	// remembered for each pool. The largest of them is used to size the pool.
+	maxPeakMemorySamples = 5
+
+	// highmemRAMFraction is the fraction of the total RAM that the actions in
+	// each highmem pool may use at the same time.
+	highmemRAMFraction = 0.5
+)
+
+// highmemPool is a ninja pool for rules that need a lot of RAM.
+type highmemPool struct {
+	name string
+
+	// command matches the command lines of the actions in the pool.
+	command *regexp.Regexp
+
+	// unrestricted is true if the pool is as deep as the build is parallel
+	// until the peak memory use of its actions is known, instead of as deep
+	// as highmem_pool.
+	unrestricted bool
+}
+
+var highmemPools = []highmemPool{
+	// The ld rule in cc/builder.go.
+	{"highmem_link_pool", regexp.MustCompile(`/clang\+\+ .*@\S+\.rsp\b`), true},
+	// The r8 rule in java/dex.go.
+	{"highmem_r8_pool", regexp.MustCompile(`/r8-compat-proguard `), true},
+	// The metalava rules in java/droidstubs.go, which run in sbox. Only the
+	// droidstubs modules with high_mem: true run in the pool.
+	{"highmem_metalava_pool", regexp.MustCompile(`/metalava\.sbox\.textproto\b`), false},
+	// The kotlinc and kapt rules in java/kotlin.go.
+	{"highmem_kotlin_pool", regexp.MustCompile(`/kotlinc `), true},
+}
+
+// highmemPoolParams is the name and depth of a highmem pool, as written to the
+// combined ninja file.
+type highmemPoolParams struct {
+	Name  string
+	Depth int
+}
+
+// highmemPeakMemory maps the name of each highmem pool to the peak memory use
+// in kB of the actions in the pool in the most recent builds, oldest first.
+type highmemPeakMemory map[string][]uint64
+
+func loadHighmemPeakMemory(config Config) highmemPeakMemory {
+	peaks := make(highmemPeakMemory)
+	if data, err := ioutil.ReadFile(filepath.Join(config.OutDir(), highmemPeakMemoryFilename)); err == nil {
+		if err := json.Unmarshal(data, &peaks); err != nil {
+			return make(highmemPeakMemory)
+		}
+	}
+	return peaks
+}
+
+func (p highmemPeakMemory) save(config Config) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(config.OutDir(), highmemPeakMemoryFilename), data, 0666)
+}
+
+// add records the peak memory use of the actions in each pool in a build.
+func (p highmemPeakMemory) add(peaks map[string]uint64) {
+	for pool, peak := range peaks {
+		samples := append(p[pool], peak)
+		if extra := len(samples) - maxPeakMemorySamples; extra > 0 {
+			samples = samples[extra:]
+		}
+		p[pool] = samples
+	}
+}
+
+// peak returns the largest peak memory use in kB of the actions in pool in
+// the recent builds, or 0 if none of them ran.
+func (p highmemPeakMemory) peak(pool string) uint64 {
+	var ret uint64
+	for _, peak := range p[pool] {
+		if peak > ret {
+			ret = peak
+		}
+	}
+	return ret
+}
+
+// highmemPoolDepths returns the depth of each highmem pool. Pools are sized
+// from the peak memory use of their actions in recent builds, unless
+// NINJA_HIGHMEM_NUM_JOBS is set or the actions may run remotely. The other
+// pools are as deep as the build is parallel if they are unrestricted, and as
+// deep as highmem_pool if they aren't or NINJA_HIGHMEM_NUM_JOBS is set.
+func highmemPoolDepths(ctx Context, config Config) []highmemPoolParams {
+	_, override := config.Environment().GetInt("NINJA_HIGHMEM_NUM_JOBS")
+	learn := !override && !config.UseRemoteBuild() && config.TotalRAM() != 0
+
+	peaks := loadHighmemPeakMemory(config)
+
+	var ret []highmemPoolParams
+	for _, pool := range highmemPools {
+		depth := config.HighmemParallel()
+		if pool.unrestricted && !override {
+			depth = config.Parallel()
+		}
+		if peak := peaks.peak(pool.name); learn && peak != 0 {
+			depth = highmemPoolDepth(config.TotalRAM(), config.Parallel(), peak)
+			ctx.Verbosef("Depth of %s is %d from peak memory use of %dMB", pool.name, depth, peak/1024)
+		}
+		ret = append(ret, highmemPoolParams{Name: pool.name, Depth: depth})
+	}
+	return ret
+}
+
+// highmemPoolDepth returns the number of actions that each used peakKB of RAM
+// that can run at the same time, between 1 and parallel.
+func highmemPoolDepth(totalRAM uint64, parallel int, peakKB uint64) int {
+	depth := int(float64(totalRAM) * highmemRAMFraction / float64(peakKB*1024))
+	if depth > parallel {
+		depth = parallel
+	}
+	if depth < 1 {
+		depth = 1
+	}
+	return depth
+}
+
+// newHighmemPeakMemory returns a status output that records the peak memory
+// use of the actions in each highmem pool.
+func newHighmemPeakMemory(ctx Context) *status.PeakMemory {
+	var classes []status.PeakMemoryClass
+	for _, pool := range highmemPools {
+		classes = append(classes, status.PeakMemoryClass{Name: pool.name, Command: pool.command})
+	}
+	peakMemory := status.NewPeakMemory(classes)
+	ctx.Status.AddOutput(peakMemory)
+	return peakMemory
+}
+
+// saveHighmemPeakMemory adds the peak memory use of the actions in each
+// highmem pool in this build to the ones from recent builds.
+func saveHighmemPeakMemory(ctx Context, config Config, peakMemory *status.PeakMemory) {
+	peaks := loadHighmemPeakMemory(config)
+	peaks.add(peakMemory.Peaks())
+	if err := peaks.save(config); err != nil {
+		ctx.Verboseln("Failed to save highmem peak memory:", err)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHighmemPoolDepth(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+	const gbInKB = 1024 * 1024
+
+	testCases := []struct {
+		name     string
+		totalRAM uint64
+		parallel int
+		peakKB   uint64
+		want     int
+	}{
+		{"64GB workstation, 4GB links", 64 * gb, 72, 4 * gbInKB, 8},
+		{"256GB server, 4GB links", 256 * gb, 72, 4 * gbInKB, 32},
+		{"limited by parallelism", 256 * gb, 16, 1 * gbInKB, 16},
+		{"at least one", 16 * gb, 72, 20 * gbInKB, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := highmemPoolDepth(tc.totalRAM, tc.parallel, tc.peakKB); got != tc.want {
+				t.Errorf("want %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestHighmemPeakMemory(t *testing.T) {
+	peaks := make(highmemPeakMemory)
+	for i := uint64(1); i <= maxPeakMemorySamples+2; i++ {
+		// The peak memory use of the link pool decreases over time, r8 only runs in the first build.
+		build := map[string]uint64{"highmem_link_pool": 100 - i}
+		if i == 1 {
+			build["highmem_r8_pool"] = 50
+		}
+		peaks.add(build)
+	}
+
+	want := highmemPeakMemory{
+		"highmem_link_pool": {97, 96, 95, 94, 93},
+		"highmem_r8_pool":   {50},
+	}
+	if !reflect.DeepEqual(peaks, want) {
+		t.Errorf("incorrect samples:\nwant: %v\n got: %v", want, peaks)
+	}
+
+	if got := peaks.peak("highmem_link_pool"); got != 97 {
+		t.Errorf("want link peak 97, got %d", got)
+	}
+	if got := peaks.peak("highmem_kotlin_pool"); got != 0 {
+		t.Errorf("want kotlin peak 0, got %d", got)
+	}
+}
@@ -34,6 +34,22 @@ func runNinjaForBuild(ctx Context, config Config) {
 	ctx.BeginTrace(metrics.PrimaryNinja, "ninja")
 	defer ctx.EndTrace()
 
+	// If SOONG_UI_EXPLAIN_REBUILDS is set, run ninja with -d explain and
+	// summarize why the first actions of the build were dirty.
+	explainer := newRebuildExplainer(ctx, config)
+	if explainer != nil {
+		defer func() {
+			if summary := explainer.Summary(); summary != "" {
+				ctx.Print(summary)
+			}
+		}()
+	}
+
+	// Record the peak memory used by the rules in the highmem pools to size
+	// the pools in the next build.
+	peakMemory := newHighmemPeakMemory(ctx)
+	defer saveHighmemPeakMemory(ctx, config, peakMemory)
+
 	// Sets up the FIFO status updater that reads the Ninja protobuf output, and
 	// translates it to the soong_ui status output, displaying real-time
 	// progress of the build.
@@ -49,6 +65,10 @@ func runNinjaForBuild(ctx Context, config Config) {
 		"--frontend_file", fifo,
 	}
 
+	if explainer != nil {
+		args = append(args, "-d", "explain")
+	}
+
 	args = append(args, config.NinjaArgs()...)
 
 	var parallel int
@@ -206,6 +226,40 @@ func runNinjaForBuild(ctx Context, config Config) {
 	cmd.RunAndStreamOrFatal()
 }
 
+// defaultExplainRebuilds is the number of actions explained when
+// SOONG_UI_EXPLAIN_REBUILDS is set to true instead of a number.
+const defaultExplainRebuilds = 100
+
+// newRebuildExplainer returns a status output that explains why the first
+// actions of the build were rebuilt if SOONG_UI_EXPLAIN_REBUILDS is set, or nil
+// otherwise. It loads the .ninja_log and .ninja_deps files, so it must be
+// called before ninja starts.
+func newRebuildExplainer(ctx Context, config Config) *status.RebuildExplainer {
+	value, ok := config.Environment().Get("SOONG_UI_EXPLAIN_REBUILDS")
+	if !ok || value == "" || value == "false" {
+		return nil
+	}
+
+	maxActions := defaultExplainRebuilds
+	if value != "true" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			ctx.Fatalf("Invalid SOONG_UI_EXPLAIN_REBUILDS %q, expected a positive number of actions", value)
+		}
+		maxActions = n
+	}
+
+	explainer := status.NewRebuildExplainer(maxActions)
+	if err := explainer.LoadNinjaLog(filepath.Join(config.OutDir(), ".ninja_log")); err != nil && !os.IsNotExist(err) {
+		ctx.Verbosef("Failed to load .ninja_log: %v", err)
+	}
+	if err := explainer.LoadNinjaDeps(filepath.Join(config.OutDir(), ".ninja_deps")); err != nil && !os.IsNotExist(err) {
+		ctx.Verbosef("Failed to load .ninja_deps: %v", err)
+	}
+	ctx.Status.AddOutput(explainer)
+	return explainer
+}
+
 // A simple struct for checking if Ninja gets stuck, using timestamps.
 type ninjaStucknessChecker struct {
 	logPath     string
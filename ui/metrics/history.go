@@ -0,0 +1,228 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+// The build history is a small rolling record of the time spent in each phase
+// of the most recent builds in an out directory. At the end of a successful
+// build, soong_ui adds the build to the history and compares the time spent in
+// each phase against the median of earlier comparable builds, so that
+// developers find out about build time regressions as soon as they happen
+// instead of from the dashboards.
+
+import (
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"android/soong/ui/metrics/metrics_proto"
+)
+
+const (
+	// MaxHistoryBuilds is the number of builds kept in the build history.
+	MaxHistoryBuilds = 20
+
+	// MinRegressionSamples is the minimum number of earlier comparable builds
+	// needed before a phase is checked for regressions.
+	MinRegressionSamples = 3
+
+	// RegressionFactor is how many times longer than the median a phase has
+	// to take to be reported as a regression.
+	RegressionFactor = 2.0
+
+	// MinRegression is the minimum difference from the median for a phase to
+	// be reported as a regression, to avoid noise from short phases.
+	MinRegression = 30 * time.Second
+)
+
+// History is a rolling history of the metrics of recent builds.
+type History struct {
+	filename string
+	history  soong_metrics_proto.BuildHistory
+}
+
+// LoadHistory reads the build history from filename. A missing or unreadable
+// history file results in an empty history, as the history is only advisory.
+func LoadHistory(filename string) *History {
+	h := &History{filename: filename}
+
+	if data, err := ioutil.ReadFile(filename); err == nil {
+		if err := proto.Unmarshal(data, &h.history); err != nil {
+			h.history.Reset()
+		}
+	}
+
+	return h
+}
+
+// Builds returns the builds in the history, oldest first.
+func (h *History) Builds() []*soong_metrics_proto.BuildHistoryEntry {
+	return h.history.Builds
+}
+
+// Query returns the builds in the history for which filter returns true,
+// oldest first.
+func (h *History) Query(filter func(*soong_metrics_proto.BuildHistoryEntry) bool) []*soong_metrics_proto.BuildHistoryEntry {
+	var ret []*soong_metrics_proto.BuildHistoryEntry
+	for _, build := range h.history.Builds {
+		if filter(build) {
+			ret = append(ret, build)
+		}
+	}
+	return ret
+}
+
+// Comparable returns the earlier builds in the history that built the same
+// targets for the same product and variant as build, and were incremental if
+// build was incremental.
+func (h *History) Comparable(build *soong_metrics_proto.BuildHistoryEntry) []*soong_metrics_proto.BuildHistoryEntry {
+	return h.Query(func(b *soong_metrics_proto.BuildHistoryEntry) bool {
+		return b != build &&
+			b.GetTargetProduct() == build.GetTargetProduct() &&
+			b.GetTargetBuildVariant() == build.GetTargetBuildVariant() &&
+			b.GetBuildCommand() == build.GetBuildCommand() &&
+			b.GetIncremental() == build.GetIncremental()
+	})
+}
+
+// Add appends build to the history, dropping the oldest builds if there are
+// more than MaxHistoryBuilds.
+func (h *History) Add(build *soong_metrics_proto.BuildHistoryEntry) {
+	h.history.Builds = append(h.history.Builds, build)
+	if extra := len(h.history.Builds) - MaxHistoryBuilds; extra > 0 {
+		h.history.Builds = h.history.Builds[extra:]
+	}
+}
+
+// Save writes the history back to the file it was loaded from.
+func (h *History) Save() error {
+	return save(&h.history, h.filename)
+}
+
+// PhaseDuration returns the total time spent in the phase with the given
+// description in build, and false if the phase didn't run.
+func PhaseDuration(build *soong_metrics_proto.BuildHistoryEntry, phase string) (time.Duration, bool) {
+	var total time.Duration
+	found := false
+	for _, perf := range build.Phases {
+		if perf.GetDesc() == phase {
+			total += time.Duration(perf.GetRealTime())
+			found = true
+		}
+	}
+	return total, found
+}
+
+// MedianPhaseDuration returns the median time spent in the phase across
+// builds, and the number of builds in which the phase ran.
+func MedianPhaseDuration(builds []*soong_metrics_proto.BuildHistoryEntry, phase string) (time.Duration, int) {
+	var durations []time.Duration
+	for _, build := range builds {
+		if d, ok := PhaseDuration(build, phase); ok {
+			durations = append(durations, d)
+		}
+	}
+
+	if len(durations) == 0 {
+		return 0, 0
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	mid := len(durations) / 2
+	if len(durations)%2 == 0 {
+		return (durations[mid-1] + durations[mid]) / 2, len(durations)
+	}
+	return durations[mid], len(durations)
+}
+
+// A Regression is a phase of a build that took far longer than the median of
+// earlier comparable builds.
+type Regression struct {
+	Phase    string
+	Duration time.Duration
+	Median   time.Duration
+	Samples  int
+}
+
+// Regressions returns the phases of build that took more than
+// RegressionFactor times and at least MinRegression longer than the median of
+// the comparable builds in the history. Only incremental builds are checked,
+// the duration of full builds depends too much on the state of the machine.
+func (h *History) Regressions(build *soong_metrics_proto.BuildHistoryEntry) []Regression {
+	if !build.GetIncremental() {
+		return nil
+	}
+
+	comparable := h.Comparable(build)
+
+	var regressions []Regression
+	seen := make(map[string]bool)
+	for _, perf := range build.Phases {
+		phase := perf.GetDesc()
+		if seen[phase] {
+			continue
+		}
+		seen[phase] = true
+
+		duration, _ := PhaseDuration(build, phase)
+		median, samples := MedianPhaseDuration(comparable, phase)
+		if samples < MinRegressionSamples {
+			continue
+		}
+
+		if float64(duration) > float64(median)*RegressionFactor && duration-median >= MinRegression {
+			regressions = append(regressions, Regression{
+				Phase:    phase,
+				Duration: duration,
+				Median:   median,
+				Samples:  samples,
+			})
+		}
+	}
+
+	return regressions
+}
+
+// HistoryEntry returns the summary of the metrics collected so far that is
+// stored in the build history.
+func (m *Metrics) HistoryEntry(incremental bool) *soong_metrics_proto.BuildHistoryEntry {
+	entry := &soong_metrics_proto.BuildHistoryEntry{
+		BuildDateTimestamp: m.metrics.BuildDateTimestamp,
+		TargetProduct:      m.metrics.TargetProduct,
+		TargetBuildVariant: m.metrics.TargetBuildVariant,
+		BuildCommand:       m.metrics.BuildCommand,
+		Incremental:        proto.Bool(incremental),
+	}
+
+	var runs []*soong_metrics_proto.PerfInfo
+	runs = append(runs, m.metrics.SoongRuns...)
+	runs = append(runs, m.metrics.KatiRuns...)
+	runs = append(runs, m.metrics.NinjaRuns...)
+	runs = append(runs, m.metrics.BazelRuns...)
+	if m.metrics.Total != nil {
+		runs = append(runs, m.metrics.Total)
+	}
+
+	for _, perf := range runs {
+		entry.Phases = append(entry.Phases, &soong_metrics_proto.PerfInfo{
+			Desc:     perf.Desc,
+			Name:     perf.Name,
+			RealTime: perf.RealTime,
+		})
+	}
+
+	return entry
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"android/soong/ui/metrics/metrics_proto"
+)
+
+func historyBuild(product string, incremental bool, phases map[string]time.Duration) *soong_metrics_proto.BuildHistoryEntry {
+	build := &soong_metrics_proto.BuildHistoryEntry{
+		TargetProduct: proto.String(product),
+		BuildCommand:  proto.String("m droid"),
+		Incremental:   proto.Bool(incremental),
+	}
+	for desc, d := range phases {
+		build.Phases = append(build.Phases, &soong_metrics_proto.PerfInfo{
+			Desc:     proto.String(desc),
+			RealTime: proto.Uint64(uint64(d)),
+		})
+	}
+	return build
+}
+
+func TestHistoryRegressions(t *testing.T) {
+	h := &History{}
+	for _, soong := range []time.Duration{50 * time.Second, 60 * time.Second, 70 * time.Second} {
+		h.Add(historyBuild("aosp_arm", true, map[string]time.Duration{
+			"soong": soong,
+			"ninja": 10 * time.Second,
+		}))
+	}
+	// Builds of other products or full builds are not comparable.
+	h.Add(historyBuild("aosp_x86", true, map[string]time.Duration{"soong": time.Hour}))
+	h.Add(historyBuild("aosp_arm", false, map[string]time.Duration{"soong": time.Hour}))
+
+	testCases := []struct {
+		name   string
+		build  *soong_metrics_proto.BuildHistoryEntry
+		phases []string
+	}{
+		{
+			name: "no regression",
+			build: historyBuild("aosp_arm", true, map[string]time.Duration{
+				"soong": 65 * time.Second,
+				"ninja": 12 * time.Second,
+			}),
+		},
+		{
+			name: "soong regression",
+			build: historyBuild("aosp_arm", true, map[string]time.Duration{
+				"soong": 150 * time.Second,
+				"ninja": 10 * time.Second,
+			}),
+			phases: []string{"soong"},
+		},
+		{
+			name: "small absolute regression",
+			build: historyBuild("aosp_arm", true, map[string]time.Duration{
+				"ninja": 30 * time.Second,
+			}),
+		},
+		{
+			name: "too few samples",
+			build: historyBuild("aosp_x86", true, map[string]time.Duration{
+				"soong": 3 * time.Hour,
+			}),
+		},
+		{
+			name: "full build",
+			build: historyBuild("aosp_arm", false, map[string]time.Duration{
+				"soong": 3 * time.Hour,
+			}),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var phases []string
+			for _, r := range h.Regressions(tc.build) {
+				phases = append(phases, r.Phase)
+				if r.Median != time.Minute || r.Samples != 3 {
+					t.Errorf("want median 1m0s over 3 samples, got %s over %d", r.Median, r.Samples)
+				}
+			}
+			if !reflect.DeepEqual(phases, tc.phases) {
+				t.Errorf("want regressions in %q, got %q", tc.phases, phases)
+			}
+		})
+	}
+}
+
+func TestHistorySaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "history.pb")
+
+	h := LoadHistory(filename)
+	if len(h.Builds()) != 0 {
+		t.Fatalf("want empty history, got %d builds", len(h.Builds()))
+	}
+
+	for i := 0; i < MaxHistoryBuilds+5; i++ {
+		h.Add(historyBuild("aosp_arm", true, map[string]time.Duration{"ninja": time.Duration(i)}))
+	}
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	h = LoadHistory(filename)
+	builds := h.Builds()
+	if len(builds) != MaxHistoryBuilds {
+		t.Fatalf("want %d builds, got %d", MaxHistoryBuilds, len(builds))
+	}
+	if d, _ := PhaseDuration(builds[0], "ninja"); d != 5 {
+		t.Errorf("want the oldest builds to be dropped, got oldest ninja duration %d", d)
+	}
+}
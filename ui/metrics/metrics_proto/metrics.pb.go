@@ -970,6 +970,132 @@ func (m *SoongBuildMetrics) GetMaxHeapSize() uint64 {
 	return 0
 }
 
+type BuildHistory struct {
+	// The most recent builds in an out directory, oldest first.
+	Builds               []*BuildHistoryEntry `protobuf:"bytes,1,rep,name=builds" json:"builds,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *BuildHistory) Reset()         { *m = BuildHistory{} }
+func (m *BuildHistory) String() string { return proto.CompactTextString(m) }
+func (*BuildHistory) ProtoMessage()    {}
+func (*BuildHistory) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6039342a2ba47b72, []int{9}
+}
+
+func (m *BuildHistory) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BuildHistory.Unmarshal(m, b)
+}
+func (m *BuildHistory) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BuildHistory.Marshal(b, m, deterministic)
+}
+func (m *BuildHistory) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BuildHistory.Merge(m, src)
+}
+func (m *BuildHistory) XXX_Size() int {
+	return xxx_messageInfo_BuildHistory.Size(m)
+}
+func (m *BuildHistory) XXX_DiscardUnknown() {
+	xxx_messageInfo_BuildHistory.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BuildHistory proto.InternalMessageInfo
+
+func (m *BuildHistory) GetBuilds() []*BuildHistoryEntry {
+	if m != nil {
+		return m.Builds
+	}
+	return nil
+}
+
+type BuildHistoryEntry struct {
+	// The build date and time in seconds since the epoch.
+	BuildDateTimestamp *int64 `protobuf:"varint,1,opt,name=build_date_timestamp,json=buildDateTimestamp" json:"build_date_timestamp,omitempty"`
+	// The target product information, eg. aosp_arm.
+	TargetProduct *string `protobuf:"bytes,2,opt,name=target_product,json=targetProduct" json:"target_product,omitempty"`
+	// The target build variant information, eg. eng.
+	TargetBuildVariant *MetricsBase_BuildVariant `protobuf:"varint,3,opt,name=target_build_variant,json=targetBuildVariant,enum=soong_build_metrics.MetricsBase_BuildVariant" json:"target_build_variant,omitempty"`
+	// The build command that the user entered to the build system.
+	BuildCommand *string `protobuf:"bytes,4,opt,name=build_command,json=buildCommand" json:"build_command,omitempty"`
+	// Whether the out directory contained a previous build when the build started.
+	Incremental *bool `protobuf:"varint,5,opt,name=incremental" json:"incremental,omitempty"`
+	// The time spent in each phase of the build, eg. soong, kati build, ninja
+	// and total.  Only the desc, name and real_time fields are set.
+	Phases               []*PerfInfo `protobuf:"bytes,6,rep,name=phases" json:"phases,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *BuildHistoryEntry) Reset()         { *m = BuildHistoryEntry{} }
+func (m *BuildHistoryEntry) String() string { return proto.CompactTextString(m) }
+func (*BuildHistoryEntry) ProtoMessage()    {}
+func (*BuildHistoryEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6039342a2ba47b72, []int{10}
+}
+
+func (m *BuildHistoryEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BuildHistoryEntry.Unmarshal(m, b)
+}
+func (m *BuildHistoryEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BuildHistoryEntry.Marshal(b, m, deterministic)
+}
+func (m *BuildHistoryEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BuildHistoryEntry.Merge(m, src)
+}
+func (m *BuildHistoryEntry) XXX_Size() int {
+	return xxx_messageInfo_BuildHistoryEntry.Size(m)
+}
+func (m *BuildHistoryEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_BuildHistoryEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BuildHistoryEntry proto.InternalMessageInfo
+
+func (m *BuildHistoryEntry) GetBuildDateTimestamp() int64 {
+	if m != nil && m.BuildDateTimestamp != nil {
+		return *m.BuildDateTimestamp
+	}
+	return 0
+}
+
+func (m *BuildHistoryEntry) GetTargetProduct() string {
+	if m != nil && m.TargetProduct != nil {
+		return *m.TargetProduct
+	}
+	return ""
+}
+
+func (m *BuildHistoryEntry) GetTargetBuildVariant() MetricsBase_BuildVariant {
+	if m != nil && m.TargetBuildVariant != nil {
+		return *m.TargetBuildVariant
+	}
+	return MetricsBase_USER
+}
+
+func (m *BuildHistoryEntry) GetBuildCommand() string {
+	if m != nil && m.BuildCommand != nil {
+		return *m.BuildCommand
+	}
+	return ""
+}
+
+func (m *BuildHistoryEntry) GetIncremental() bool {
+	if m != nil && m.Incremental != nil {
+		return *m.Incremental
+	}
+	return false
+}
+
+func (m *BuildHistoryEntry) GetPhases() []*PerfInfo {
+	if m != nil {
+		return m.Phases
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("soong_build_metrics.MetricsBase_BuildVariant", MetricsBase_BuildVariant_name, MetricsBase_BuildVariant_value)
 	proto.RegisterEnum("soong_build_metrics.MetricsBase_Arch", MetricsBase_Arch_name, MetricsBase_Arch_value)
@@ -983,6 +1109,8 @@ func init() {
 	proto.RegisterType((*CriticalUserJourneyMetrics)(nil), "soong_build_metrics.CriticalUserJourneyMetrics")
 	proto.RegisterType((*CriticalUserJourneysMetrics)(nil), "soong_build_metrics.CriticalUserJourneysMetrics")
 	proto.RegisterType((*SoongBuildMetrics)(nil), "soong_build_metrics.SoongBuildMetrics")
+	proto.RegisterType((*BuildHistory)(nil), "soong_build_metrics.BuildHistory")
+	proto.RegisterType((*BuildHistoryEntry)(nil), "soong_build_metrics.BuildHistoryEntry")
 }
 
 func init() {
@@ -990,92 +1118,98 @@ func init() {
 }
 
 var fileDescriptor_6039342a2ba47b72 = []byte{
-	// 1380 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x57, 0xef, 0x52, 0x1b, 0x37,
-	0x10, 0x8f, 0xc1, 0x60, 0x7b, 0xfd, 0x07, 0x23, 0xa0, 0x5c, 0x48, 0xd2, 0x52, 0xb7, 0x49, 0x99,
-	0x4e, 0x43, 0x32, 0x34, 0xc3, 0x64, 0x98, 0x4c, 0xa7, 0xe0, 0xd0, 0x34, 0x65, 0xc0, 0x8c, 0x08,
-	0x69, 0xda, 0x7e, 0x50, 0xe5, 0xb3, 0x0c, 0x97, 0xdc, 0x9d, 0x6e, 0x24, 0x1d, 0xc5, 0x79, 0xb3,
-	0x7e, 0xee, 0x4b, 0xf4, 0x05, 0xfa, 0x04, 0x7d, 0x81, 0x8e, 0x56, 0x77, 0xe6, 0x20, 0x6e, 0xc2,
-	0xe4, 0xdb, 0xe9, 0xb7, 0xbf, 0xdf, 0x6a, 0xb5, 0xd2, 0xee, 0xda, 0xd0, 0x8c, 0x84, 0x51, 0x81,
-	0xaf, 0xd7, 0x13, 0x25, 0x8d, 0x24, 0x0b, 0x5a, 0xca, 0xf8, 0x84, 0xf5, 0xd3, 0x20, 0x1c, 0xb0,
-	0xcc, 0xd4, 0xf9, 0xbb, 0x01, 0xf5, 0x7d, 0xf7, 0xbd, 0xc3, 0xb5, 0x20, 0x0f, 0x61, 0xd1, 0x11,
-	0x06, 0xdc, 0x08, 0x66, 0x82, 0x48, 0x68, 0xc3, 0xa3, 0xc4, 0x2b, 0xad, 0x96, 0xd6, 0xa6, 0x29,
-	0x41, 0xdb, 0x53, 0x6e, 0xc4, 0x8b, 0xdc, 0x42, 0x6e, 0x42, 0xd5, 0x29, 0x82, 0x81, 0x37, 0xb5,
-	0x5a, 0x5a, 0xab, 0xd1, 0x0a, 0xae, 0x9f, 0x0f, 0xc8, 0x16, 0xdc, 0x4c, 0x42, 0x6e, 0x86, 0x52,
-	0x45, 0xec, 0x4c, 0x28, 0x1d, 0xc8, 0x98, 0xf9, 0x72, 0x20, 0x62, 0x1e, 0x09, 0x6f, 0x1a, 0xb9,
-	0xcb, 0x39, 0xe1, 0xa5, 0xb3, 0x77, 0x33, 0x33, 0xb9, 0x0b, 0x2d, 0xc3, 0xd5, 0x89, 0x30, 0x2c,
-	0x51, 0x72, 0x90, 0xfa, 0xc6, 0x2b, 0xa3, 0xa0, 0xe9, 0xd0, 0x43, 0x07, 0x92, 0x01, 0x2c, 0x66,
-	0x34, 0x17, 0xc4, 0x19, 0x57, 0x01, 0x8f, 0x8d, 0x37, 0xb3, 0x5a, 0x5a, 0x6b, 0x6d, 0xdc, 0x5f,
-	0x9f, 0x70, 0xe6, 0xf5, 0xc2, 0x79, 0xd7, 0x77, 0xac, 0xe5, 0xa5, 0x13, 0x6d, 0x4d, 0xef, 0x1e,
-	0x3c, 0xa3, 0xc4, 0xf9, 0x2b, 0x1a, 0x48, 0x0f, 0xea, 0xd9, 0x2e, 0x5c, 0xf9, 0xa7, 0xde, 0x2c,
-	0x3a, 0xbf, 0xfb, 0x41, 0xe7, 0xdb, 0xca, 0x3f, 0xdd, 0xaa, 0x1c, 0x1f, 0xec, 0x1d, 0xf4, 0x7e,
-	0x3e, 0xa0, 0xe0, 0x5c, 0x58, 0x90, 0xac, 0xc3, 0x42, 0xc1, 0xe1, 0x38, 0xea, 0x0a, 0x1e, 0x71,
-	0xfe, 0x82, 0x98, 0x07, 0xf0, 0x0d, 0x64, 0x61, 0x31, 0x3f, 0x49, 0xc7, 0xf4, 0x2a, 0xd2, 0xdb,
-	0xce, 0xd2, 0x4d, 0xd2, 0x9c, 0xbd, 0x07, 0xb5, 0x53, 0xa9, 0xb3, 0x60, 0x6b, 0x1f, 0x15, 0x6c,
-	0xd5, 0x3a, 0xc0, 0x50, 0x29, 0x34, 0xd1, 0xd9, 0x46, 0x3c, 0x70, 0x0e, 0xe1, 0xa3, 0x1c, 0xd6,
-	0xad, 0x93, 0x8d, 0x78, 0x80, 0x3e, 0x97, 0xa1, 0x82, 0x3e, 0xa5, 0xf6, 0xea, 0x78, 0x86, 0x59,
-	0xbb, 0xec, 0x69, 0xd2, 0xc9, 0x36, 0x93, 0x9a, 0x89, 0x73, 0xa3, 0xb8, 0xd7, 0x40, 0x73, 0xdd,
-	0x99, 0x77, 0x2d, 0x34, 0xe6, 0xf8, 0x4a, 0x6a, 0x6d, 0x5d, 0x34, 0x2f, 0x38, 0x5d, 0x8b, 0xf5,
-	0x34, 0xb9, 0x07, 0x73, 0x05, 0x0e, 0x86, 0xdd, 0x72, 0xcf, 0x67, 0xcc, 0xc2, 0x40, 0xee, 0xc3,
-	0x42, 0x81, 0x37, 0x3e, 0xe2, 0x9c, 0x4b, 0xec, 0x98, 0x5b, 0x88, 0x5b, 0xa6, 0x86, 0x0d, 0x02,
-	0xe5, 0xb5, 0x5d, 0xdc, 0x32, 0x35, 0x4f, 0x03, 0x45, 0xbe, 0x83, 0xba, 0x16, 0x26, 0x4d, 0x98,
-	0x91, 0x32, 0xd4, 0xde, 0xfc, 0xea, 0xf4, 0x5a, 0x7d, 0xe3, 0xce, 0xc4, 0x14, 0x1d, 0x0a, 0x35,
-	0x7c, 0x1e, 0x0f, 0x25, 0x05, 0x54, 0xbc, 0xb0, 0x02, 0xb2, 0x05, 0xb5, 0x37, 0xdc, 0x04, 0x4c,
-	0xa5, 0xb1, 0xf6, 0xc8, 0x75, 0xd4, 0x55, 0xcb, 0xa7, 0x69, 0xac, 0xc9, 0x13, 0x00, 0xc7, 0x44,
-	0xf1, 0xc2, 0x75, 0xc4, 0x35, 0xb4, 0xe6, 0xea, 0x38, 0x88, 0x5f, 0x73, 0xa7, 0x5e, 0xbc, 0x96,
-	0x1a, 0x05, 0xa8, 0xfe, 0x16, 0x66, 0x8c, 0x34, 0x3c, 0xf4, 0x96, 0x56, 0x4b, 0x1f, 0x16, 0x3a,
-	0x2e, 0x79, 0x09, 0x93, 0x5a, 0x91, 0xf7, 0x09, 0xba, 0xb8, 0x37, 0xd1, 0xc5, 0x91, 0xc5, 0xb0,
-	0x24, 0xb3, 0x17, 0x46, 0xe7, 0xf5, 0x55, 0x88, 0x74, 0xa1, 0xe1, 0x54, 0xbe, 0x8c, 0x87, 0xc1,
-	0x89, 0xb7, 0x8c, 0x0e, 0x57, 0x27, 0x3a, 0x44, 0x61, 0x17, 0x79, 0xb4, 0xde, 0xbf, 0x58, 0x90,
-	0x15, 0xc0, 0xa7, 0x8f, 0x2d, 0xca, 0xc3, 0x3b, 0x1e, 0xaf, 0xc9, 0x2f, 0xb0, 0xa8, 0x47, 0xda,
-	0x88, 0x88, 0x29, 0xa1, 0x65, 0xaa, 0x7c, 0xc1, 0x82, 0x78, 0x28, 0xbd, 0x9b, 0xb8, 0xd1, 0x57,
-	0x93, 0x23, 0x47, 0x01, 0xcd, 0xf8, 0x98, 0x06, 0xa2, 0xdf, 0xc1, 0xc8, 0x17, 0xd0, 0xcc, 0x63,
-	0x8f, 0x22, 0x1e, 0x0f, 0xbc, 0x15, 0xdc, 0xbb, 0x91, 0x85, 0x86, 0x98, 0xbd, 0xab, 0x3e, 0x7f,
-	0x2b, 0x42, 0x77, 0x57, 0xb7, 0xae, 0x75, 0x57, 0x28, 0xb0, 0x77, 0xd5, 0x79, 0x08, 0x8d, 0x4b,
-	0x4d, 0xad, 0x0a, 0xe5, 0xe3, 0xa3, 0x5d, 0xda, 0xbe, 0x41, 0x9a, 0x50, 0xb3, 0x5f, 0x4f, 0x77,
-	0x77, 0x8e, 0x9f, 0xb5, 0x4b, 0xa4, 0x02, 0xb6, 0x11, 0xb6, 0xa7, 0x3a, 0x4f, 0xa0, 0x8c, 0xcf,
-	0xbe, 0x0e, 0x79, 0x19, 0xb7, 0x6f, 0x58, 0xeb, 0x36, 0xdd, 0x6f, 0x97, 0x48, 0x0d, 0x66, 0xb6,
-	0xe9, 0xfe, 0xe6, 0xa3, 0xf6, 0x94, 0xc5, 0x5e, 0x3d, 0xde, 0x6c, 0x4f, 0x13, 0x80, 0xd9, 0x57,
-	0x8f, 0x37, 0xd9, 0xe6, 0xa3, 0x76, 0xb9, 0x73, 0x02, 0xf5, 0x42, 0x96, 0xed, 0x9c, 0x48, 0xb5,
-	0x60, 0x27, 0x32, 0xe2, 0x38, 0x4d, 0xaa, 0xb4, 0x92, 0x6a, 0xf1, 0x4c, 0x46, 0xdc, 0x96, 0x95,
-	0x35, 0xa9, 0xbe, 0xc0, 0x09, 0x52, 0xa5, 0xb3, 0xa9, 0x16, 0xb4, 0x2f, 0xc8, 0x97, 0xd0, 0x1a,
-	0x4a, 0x9b, 0xe6, 0xb1, 0x72, 0x1a, 0xed, 0x0d, 0x44, 0x8f, 0x9d, 0xbc, 0x23, 0x81, 0xbc, 0x9b,
-	0x65, 0xb2, 0x01, 0x4b, 0xf8, 0xdc, 0x58, 0x72, 0x3a, 0xd2, 0x81, 0xcf, 0x43, 0x16, 0x89, 0x48,
-	0xaa, 0x11, 0x6e, 0x5e, 0xa6, 0x0b, 0x68, 0x3c, 0xcc, 0x6c, 0xfb, 0x68, 0xb2, 0x43, 0x87, 0x9f,
-	0xf1, 0x20, 0xe4, 0xfd, 0x50, 0xd8, 0x4e, 0xab, 0x31, 0x9e, 0x19, 0xda, 0x1c, 0xa3, 0xdd, 0x24,
-	0xd5, 0x9d, 0x7f, 0x4b, 0x50, 0xcd, 0x33, 0x4c, 0x08, 0x94, 0x07, 0x42, 0xfb, 0xe8, 0xb6, 0x46,
-	0xf1, 0xdb, 0x62, 0xf8, 0x80, 0xdc, 0x3c, 0xc4, 0x6f, 0x72, 0x07, 0x40, 0x1b, 0xae, 0x0c, 0x0e,
-	0x55, 0x3c, 0x47, 0x99, 0xd6, 0x10, 0xb1, 0xb3, 0x94, 0xdc, 0x82, 0x9a, 0x12, 0x3c, 0x74, 0xd6,
-	0x32, 0x5a, 0xab, 0x16, 0x40, 0xe3, 0xe7, 0x00, 0x2e, 0x78, 0x9b, 0x08, 0x9c, 0x6d, 0xe5, 0x9d,
-	0x29, 0xaf, 0x44, 0x6b, 0x0e, 0x3d, 0xd6, 0x82, 0xfc, 0x0e, 0xcb, 0x89, 0x92, 0xbe, 0xd0, 0x5a,
-	0xe8, 0x2b, 0xcf, 0x73, 0x16, 0x1f, 0xca, 0xda, 0xe4, 0x87, 0xe2, 0x34, 0x97, 0xde, 0xe7, 0xd2,
-	0xd8, 0x51, 0x11, 0xee, 0xfc, 0x39, 0x0d, 0x0b, 0x13, 0xe8, 0xe3, 0xc3, 0x96, 0x0a, 0x87, 0x5d,
-	0x83, 0x76, 0xaa, 0x85, 0xc2, 0xd3, 0xb0, 0x28, 0xb0, 0xed, 0x15, 0x93, 0x51, 0xa6, 0x2d, 0x8b,
-	0xdb, 0x43, 0xed, 0x23, 0x6a, 0x27, 0x5b, 0x56, 0x53, 0x45, 0xae, 0x4b, 0x4f, 0xdb, 0x59, 0x0a,
-	0xec, 0xdb, 0x00, 0x11, 0x3f, 0x67, 0x4a, 0x6b, 0xf6, 0xa6, 0x9f, 0xa7, 0x29, 0xe2, 0xe7, 0x54,
-	0xeb, 0xbd, 0x3e, 0xf9, 0x1a, 0xe6, 0xa3, 0x20, 0x96, 0x8a, 0x25, 0xfc, 0x44, 0xb0, 0x21, 0x4f,
-	0x43, 0xa3, 0x5d, 0xb6, 0xe8, 0x1c, 0x1a, 0x0e, 0xf9, 0x89, 0xf8, 0x01, 0x61, 0xe4, 0xf2, 0xd7,
-	0x57, 0xb8, 0xb3, 0x19, 0xd7, 0x1a, 0x0a, 0xdc, 0x4f, 0xa1, 0x1e, 0x48, 0x16, 0xc4, 0x49, 0x6a,
-	0xec, 0xb6, 0x15, 0x77, 0x77, 0x81, 0x7c, 0x6e, 0x91, 0xbd, 0x3e, 0x59, 0x85, 0x46, 0x20, 0x99,
-	0x4c, 0x4d, 0x46, 0xa8, 0x22, 0x01, 0x02, 0xd9, 0x43, 0x68, 0xaf, 0x4f, 0x9e, 0xc0, 0xca, 0x99,
-	0x0c, 0xd3, 0xd8, 0x70, 0x35, 0xb2, 0xed, 0xc9, 0x88, 0x73, 0xc3, 0xf4, 0x1f, 0x81, 0xf1, 0x4f,
-	0x85, 0xc6, 0x11, 0x5d, 0xa6, 0xde, 0x98, 0xd1, 0x75, 0x84, 0xa3, 0xcc, 0x4e, 0xbe, 0x87, 0xdb,
-	0x41, 0xfc, 0x1e, 0x3d, 0xa0, 0x7e, 0xa5, 0xc0, 0xb9, 0xe2, 0xa1, 0xf3, 0x4f, 0x09, 0x5a, 0xfb,
-	0x72, 0x90, 0x86, 0xe2, 0xc5, 0x28, 0x71, 0xd7, 0xf6, 0x5b, 0xde, 0x2d, 0x5d, 0x92, 0xf1, 0xfa,
-	0x5a, 0x1b, 0x0f, 0x26, 0x8f, 0xf5, 0x4b, 0x52, 0xd7, 0x3c, 0x5d, 0xc9, 0x15, 0x06, 0x7c, 0xff,
-	0x02, 0x25, 0x9f, 0x41, 0x3d, 0x42, 0x0d, 0x33, 0xa3, 0x24, 0xaf, 0x03, 0x88, 0xc6, 0x6e, 0x6c,
-	0x65, 0xc7, 0x69, 0xc4, 0xe4, 0x90, 0x39, 0xd0, 0x5d, 0x79, 0x93, 0x36, 0xe2, 0x34, 0xea, 0x0d,
-	0xdd, 0x7e, 0xba, 0xf3, 0x20, 0x6b, 0x21, 0x99, 0xd7, 0x4b, 0x7d, 0xa8, 0x06, 0x33, 0x47, 0xbd,
-	0xde, 0x81, 0x6d, 0x58, 0x55, 0x28, 0xef, 0x6f, 0xef, 0xed, 0xb6, 0xa7, 0x3a, 0x21, 0xac, 0x74,
-	0x55, 0x60, 0x6c, 0x49, 0x1f, 0x6b, 0xa1, 0x7e, 0x92, 0xa9, 0x8a, 0xc5, 0x28, 0x1f, 0x10, 0x93,
-	0x5e, 0xea, 0x16, 0x54, 0xf2, 0x01, 0x34, 0xf5, 0x9e, 0x79, 0x51, 0xf8, 0x61, 0x43, 0x73, 0x41,
-	0xa7, 0x0f, 0xb7, 0x26, 0xec, 0xa6, 0x2f, 0xe6, 0x51, 0xd9, 0x4f, 0x5f, 0x6b, 0xaf, 0x84, 0xf5,
-	0x37, 0x39, 0xb3, 0xff, 0x1f, 0x2d, 0x45, 0x71, 0xe7, 0xaf, 0x12, 0xcc, 0xbf, 0x33, 0xfd, 0x88,
-	0x07, 0x95, 0x3c, 0x6f, 0x25, 0xcc, 0x5b, 0xbe, 0xb4, 0xf3, 0x2b, 0xfb, 0x79, 0xe8, 0x0e, 0xd4,
-	0xa4, 0xe3, 0xb5, 0x7d, 0xf3, 0xae, 0x25, 0xf2, 0x30, 0x94, 0x3e, 0xf3, 0x65, 0x1a, 0x9b, 0xac,
-	0xd4, 0xe6, 0xd0, 0xb0, 0x6d, 0xf1, 0xae, 0x85, 0x6d, 0x05, 0x17, 0xb9, 0x3a, 0x78, 0x9b, 0xb7,
-	0xa5, 0xd6, 0x05, 0xf5, 0x28, 0x78, 0x2b, 0xec, 0xef, 0x31, 0x5b, 0x93, 0xa7, 0x82, 0x27, 0x8e,
-	0xe6, 0x2a, 0xae, 0x1e, 0xf1, 0xf3, 0x1f, 0x05, 0x4f, 0x2c, 0x67, 0x67, 0xe9, 0xd7, 0x6c, 0xe4,
-	0x67, 0xe7, 0x66, 0xf8, 0x97, 0xe4, 0xbf, 0x00, 0x00, 0x00, 0xff, 0xff, 0xcb, 0xfb, 0x8e, 0xf5,
-	0xa2, 0x0c, 0x00, 0x00,
+	// 1475 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x57, 0xdd, 0x52, 0x1b, 0xc7,
+	0x12, 0xb6, 0x7e, 0xd0, 0x4f, 0xeb, 0x07, 0x31, 0xc0, 0x61, 0x8d, 0xed, 0x73, 0x74, 0x74, 0x8e,
+	0x1d, 0x2a, 0x15, 0x63, 0x17, 0x71, 0x28, 0x17, 0xe5, 0x72, 0x05, 0x64, 0x62, 0x3b, 0x14, 0x88,
+	0x5a, 0x8c, 0xe3, 0x24, 0x17, 0x93, 0xd1, 0x6a, 0x04, 0x8b, 0xb5, 0x3b, 0x5b, 0x33, 0xb3, 0x04,
+	0xf9, 0xcd, 0x72, 0x9d, 0xcb, 0xbc, 0x40, 0x5e, 0x20, 0x4f, 0x90, 0x17, 0x48, 0x4d, 0xcf, 0xae,
+	0x10, 0x66, 0xb1, 0x29, 0xee, 0x76, 0xbf, 0xfe, 0xbe, 0x9e, 0x9e, 0x9e, 0xe9, 0xee, 0x5d, 0x68,
+	0x04, 0x5c, 0x4b, 0xdf, 0x53, 0xab, 0x91, 0x14, 0x5a, 0x90, 0x79, 0x25, 0x44, 0x78, 0x44, 0xfb,
+	0xb1, 0x3f, 0x1a, 0xd0, 0xc4, 0xd4, 0xf9, 0xb3, 0x0e, 0xb5, 0x5d, 0xfb, 0xbc, 0xc5, 0x14, 0x27,
+	0x8f, 0x61, 0xc1, 0x12, 0x06, 0x4c, 0x73, 0xaa, 0xfd, 0x80, 0x2b, 0xcd, 0x82, 0xc8, 0xc9, 0xb5,
+	0x73, 0x2b, 0x05, 0x97, 0xa0, 0xed, 0x05, 0xd3, 0xfc, 0x4d, 0x6a, 0x21, 0xb7, 0xa1, 0x62, 0x15,
+	0xfe, 0xc0, 0xc9, 0xb7, 0x73, 0x2b, 0x55, 0xb7, 0x8c, 0xef, 0xaf, 0x07, 0x64, 0x03, 0x6e, 0x47,
+	0x23, 0xa6, 0x87, 0x42, 0x06, 0xf4, 0x94, 0x4b, 0xe5, 0x8b, 0x90, 0x7a, 0x62, 0xc0, 0x43, 0x16,
+	0x70, 0xa7, 0x80, 0xdc, 0xa5, 0x94, 0xf0, 0xd6, 0xda, 0xbb, 0x89, 0x99, 0xdc, 0x87, 0xa6, 0x66,
+	0xf2, 0x88, 0x6b, 0x1a, 0x49, 0x31, 0x88, 0x3d, 0xed, 0x14, 0x51, 0xd0, 0xb0, 0xe8, 0xbe, 0x05,
+	0xc9, 0x00, 0x16, 0x12, 0x9a, 0x0d, 0xe2, 0x94, 0x49, 0x9f, 0x85, 0xda, 0x99, 0x69, 0xe7, 0x56,
+	0x9a, 0x6b, 0x0f, 0x57, 0x33, 0xf6, 0xbc, 0x3a, 0xb5, 0xdf, 0xd5, 0x2d, 0x63, 0x79, 0x6b, 0x45,
+	0x1b, 0x85, 0xed, 0xbd, 0x97, 0x2e, 0xb1, 0xfe, 0xa6, 0x0d, 0xa4, 0x07, 0xb5, 0x64, 0x15, 0x26,
+	0xbd, 0x63, 0xa7, 0x84, 0xce, 0xef, 0x7f, 0xd6, 0xf9, 0xa6, 0xf4, 0x8e, 0x37, 0xca, 0x87, 0x7b,
+	0x3b, 0x7b, 0xbd, 0x1f, 0xf6, 0x5c, 0xb0, 0x2e, 0x0c, 0x48, 0x56, 0x61, 0x7e, 0xca, 0xe1, 0x24,
+	0xea, 0x32, 0x6e, 0x71, 0xee, 0x9c, 0x98, 0x06, 0xf0, 0x15, 0x24, 0x61, 0x51, 0x2f, 0x8a, 0x27,
+	0xf4, 0x0a, 0xd2, 0x5b, 0xd6, 0xd2, 0x8d, 0xe2, 0x94, 0xbd, 0x03, 0xd5, 0x63, 0xa1, 0x92, 0x60,
+	0xab, 0x37, 0x0a, 0xb6, 0x62, 0x1c, 0x60, 0xa8, 0x2e, 0x34, 0xd0, 0xd9, 0x5a, 0x38, 0xb0, 0x0e,
+	0xe1, 0x46, 0x0e, 0x6b, 0xc6, 0xc9, 0x5a, 0x38, 0x40, 0x9f, 0x4b, 0x50, 0x46, 0x9f, 0x42, 0x39,
+	0x35, 0xdc, 0x43, 0xc9, 0xbc, 0xf6, 0x14, 0xe9, 0x24, 0x8b, 0x09, 0x45, 0xf9, 0x99, 0x96, 0xcc,
+	0xa9, 0xa3, 0xb9, 0x66, 0xcd, 0xdb, 0x06, 0x9a, 0x70, 0x3c, 0x29, 0x94, 0x32, 0x2e, 0x1a, 0xe7,
+	0x9c, 0xae, 0xc1, 0x7a, 0x8a, 0x3c, 0x80, 0xd9, 0x29, 0x0e, 0x86, 0xdd, 0xb4, 0xd7, 0x67, 0xc2,
+	0xc2, 0x40, 0x1e, 0xc2, 0xfc, 0x14, 0x6f, 0xb2, 0xc5, 0x59, 0x9b, 0xd8, 0x09, 0x77, 0x2a, 0x6e,
+	0x11, 0x6b, 0x3a, 0xf0, 0xa5, 0xd3, 0xb2, 0x71, 0x8b, 0x58, 0xbf, 0xf0, 0x25, 0x79, 0x0e, 0x35,
+	0xc5, 0x75, 0x1c, 0x51, 0x2d, 0xc4, 0x48, 0x39, 0x73, 0xed, 0xc2, 0x4a, 0x6d, 0xed, 0x5e, 0x66,
+	0x8a, 0xf6, 0xb9, 0x1c, 0xbe, 0x0e, 0x87, 0xc2, 0x05, 0x54, 0xbc, 0x31, 0x02, 0xb2, 0x01, 0xd5,
+	0xf7, 0x4c, 0xfb, 0x54, 0xc6, 0xa1, 0x72, 0xc8, 0x75, 0xd4, 0x15, 0xc3, 0x77, 0xe3, 0x50, 0x91,
+	0x67, 0x00, 0x96, 0x89, 0xe2, 0xf9, 0xeb, 0x88, 0xab, 0x68, 0x4d, 0xd5, 0xa1, 0x1f, 0x9e, 0x30,
+	0xab, 0x5e, 0xb8, 0x96, 0x1a, 0x05, 0xa8, 0xfe, 0x1a, 0x66, 0xb4, 0xd0, 0x6c, 0xe4, 0x2c, 0xb6,
+	0x73, 0x9f, 0x17, 0x5a, 0x2e, 0x79, 0x0b, 0x59, 0xad, 0xc8, 0xf9, 0x17, 0xba, 0x78, 0x90, 0xe9,
+	0xe2, 0xc0, 0x60, 0x58, 0x92, 0xc9, 0x0d, 0x73, 0xe7, 0xd4, 0xc7, 0x10, 0xe9, 0x42, 0xdd, 0xaa,
+	0x3c, 0x11, 0x0e, 0xfd, 0x23, 0x67, 0x09, 0x1d, 0xb6, 0x33, 0x1d, 0xa2, 0xb0, 0x8b, 0x3c, 0xb7,
+	0xd6, 0x3f, 0x7f, 0x21, 0xcb, 0x80, 0x57, 0x1f, 0x5b, 0x94, 0x83, 0x67, 0x3c, 0x79, 0x27, 0x3f,
+	0xc2, 0x82, 0x1a, 0x2b, 0xcd, 0x03, 0x2a, 0xb9, 0x12, 0xb1, 0xf4, 0x38, 0xf5, 0xc3, 0xa1, 0x70,
+	0x6e, 0xe3, 0x42, 0x5f, 0x64, 0x47, 0x8e, 0x02, 0x37, 0xe1, 0x63, 0x1a, 0x88, 0xba, 0x84, 0x91,
+	0xff, 0x41, 0x23, 0x8d, 0x3d, 0x08, 0x58, 0x38, 0x70, 0x96, 0x71, 0xed, 0x7a, 0x12, 0x1a, 0x62,
+	0xe6, 0xac, 0xfa, 0xec, 0x03, 0x1f, 0xd9, 0xb3, 0xba, 0x73, 0xad, 0xb3, 0x42, 0x81, 0x39, 0xab,
+	0xce, 0x63, 0xa8, 0x5f, 0x68, 0x6a, 0x15, 0x28, 0x1e, 0x1e, 0x6c, 0xbb, 0xad, 0x5b, 0xa4, 0x01,
+	0x55, 0xf3, 0xf4, 0x62, 0x7b, 0xeb, 0xf0, 0x65, 0x2b, 0x47, 0xca, 0x60, 0x1a, 0x61, 0x2b, 0xdf,
+	0x79, 0x06, 0x45, 0xbc, 0xf6, 0x35, 0x48, 0xcb, 0xb8, 0x75, 0xcb, 0x58, 0x37, 0xdd, 0xdd, 0x56,
+	0x8e, 0x54, 0x61, 0x66, 0xd3, 0xdd, 0x5d, 0x7f, 0xd2, 0xca, 0x1b, 0xec, 0xdd, 0xd3, 0xf5, 0x56,
+	0x81, 0x00, 0x94, 0xde, 0x3d, 0x5d, 0xa7, 0xeb, 0x4f, 0x5a, 0xc5, 0xce, 0x11, 0xd4, 0xa6, 0xb2,
+	0x6c, 0xe6, 0x44, 0xac, 0x38, 0x3d, 0x12, 0x01, 0xc3, 0x69, 0x52, 0x71, 0xcb, 0xb1, 0xe2, 0x2f,
+	0x45, 0xc0, 0x4c, 0x59, 0x19, 0x93, 0xec, 0x73, 0x9c, 0x20, 0x15, 0xb7, 0x14, 0x2b, 0xee, 0xf6,
+	0x39, 0xf9, 0x3f, 0x34, 0x87, 0xc2, 0xa4, 0x79, 0xa2, 0x2c, 0xa0, 0xbd, 0x8e, 0xe8, 0xa1, 0x95,
+	0x77, 0x04, 0x90, 0xcb, 0x59, 0x26, 0x6b, 0xb0, 0x88, 0xd7, 0x8d, 0x46, 0xc7, 0x63, 0xe5, 0x7b,
+	0x6c, 0x44, 0x03, 0x1e, 0x08, 0x39, 0xc6, 0xc5, 0x8b, 0xee, 0x3c, 0x1a, 0xf7, 0x13, 0xdb, 0x2e,
+	0x9a, 0xcc, 0xd0, 0x61, 0xa7, 0xcc, 0x1f, 0xb1, 0xfe, 0x88, 0x9b, 0x4e, 0xab, 0x30, 0x9e, 0x19,
+	0xb7, 0x31, 0x41, 0xbb, 0x51, 0xac, 0x3a, 0x7f, 0xe7, 0xa0, 0x92, 0x66, 0x98, 0x10, 0x28, 0x0e,
+	0xb8, 0xf2, 0xd0, 0x6d, 0xd5, 0xc5, 0x67, 0x83, 0xe1, 0x05, 0xb2, 0xf3, 0x10, 0x9f, 0xc9, 0x3d,
+	0x00, 0xa5, 0x99, 0xd4, 0x38, 0x54, 0x71, 0x1f, 0x45, 0xb7, 0x8a, 0x88, 0x99, 0xa5, 0xe4, 0x0e,
+	0x54, 0x25, 0x67, 0x23, 0x6b, 0x2d, 0xa2, 0xb5, 0x62, 0x00, 0x34, 0xfe, 0x17, 0xc0, 0x06, 0x6f,
+	0x12, 0x81, 0xb3, 0xad, 0xb8, 0x95, 0x77, 0x72, 0x6e, 0xd5, 0xa2, 0x87, 0x8a, 0x93, 0x5f, 0x60,
+	0x29, 0x92, 0xc2, 0xe3, 0x4a, 0x71, 0xf5, 0xd1, 0xf5, 0x2c, 0xe1, 0x45, 0x59, 0xc9, 0xbe, 0x28,
+	0x56, 0x73, 0xe1, 0x7e, 0x2e, 0x4e, 0x1c, 0x4d, 0xc3, 0x9d, 0xdf, 0x0a, 0x30, 0x9f, 0x41, 0x9f,
+	0x6c, 0x36, 0x37, 0xb5, 0xd9, 0x15, 0x68, 0xc5, 0x8a, 0x4b, 0xdc, 0x0d, 0x0d, 0x7c, 0xd3, 0x5e,
+	0x31, 0x19, 0x45, 0xb7, 0x69, 0x70, 0xb3, 0xa9, 0x5d, 0x44, 0xcd, 0x64, 0x4b, 0x6a, 0x6a, 0x9a,
+	0x6b, 0xd3, 0xd3, 0xb2, 0x96, 0x29, 0xf6, 0x5d, 0x80, 0x80, 0x9d, 0x51, 0xa9, 0x14, 0x7d, 0xdf,
+	0x4f, 0xd3, 0x14, 0xb0, 0x33, 0x57, 0xa9, 0x9d, 0x3e, 0xf9, 0x12, 0xe6, 0x02, 0x3f, 0x14, 0x92,
+	0x46, 0xec, 0x88, 0xd3, 0x21, 0x8b, 0x47, 0x5a, 0xd9, 0x6c, 0xb9, 0xb3, 0x68, 0xd8, 0x67, 0x47,
+	0xfc, 0x3b, 0x84, 0x91, 0xcb, 0x4e, 0x3e, 0xe2, 0x96, 0x12, 0x2e, 0x3b, 0xb9, 0xc0, 0xfd, 0x37,
+	0xd4, 0x7c, 0x41, 0xfd, 0x30, 0x8a, 0xb5, 0x59, 0xb6, 0x6c, 0xcf, 0xce, 0x17, 0xaf, 0x0d, 0xb2,
+	0xd3, 0x27, 0x6d, 0xa8, 0xfb, 0x82, 0x8a, 0x58, 0x27, 0x84, 0x0a, 0x12, 0xc0, 0x17, 0x3d, 0x84,
+	0x76, 0xfa, 0xe4, 0x19, 0x2c, 0x9f, 0x8a, 0x51, 0x1c, 0x6a, 0x26, 0xc7, 0xa6, 0x3d, 0x69, 0x7e,
+	0xa6, 0xa9, 0xfa, 0xd5, 0xd7, 0xde, 0x31, 0x57, 0x38, 0xa2, 0x8b, 0xae, 0x33, 0x61, 0x74, 0x2d,
+	0xe1, 0x20, 0xb1, 0x93, 0x6f, 0xe1, 0xae, 0x1f, 0x7e, 0x42, 0x0f, 0xa8, 0x5f, 0xf6, 0xc3, 0xab,
+	0x3c, 0x74, 0xfe, 0xca, 0x41, 0x73, 0x57, 0x0c, 0xe2, 0x11, 0x7f, 0x33, 0x8e, 0xec, 0xb1, 0xfd,
+	0x9c, 0x76, 0x4b, 0x9b, 0x64, 0x3c, 0xbe, 0xe6, 0xda, 0xa3, 0xec, 0xb1, 0x7e, 0x41, 0x6a, 0x9b,
+	0xa7, 0x2d, 0xb9, 0xa9, 0x01, 0xdf, 0x3f, 0x47, 0xc9, 0x7f, 0xa0, 0x16, 0xa0, 0x86, 0xea, 0x71,
+	0x94, 0xd6, 0x01, 0x04, 0x13, 0x37, 0xa6, 0xb2, 0xc3, 0x38, 0xa0, 0x62, 0x48, 0x2d, 0x68, 0x8f,
+	0xbc, 0xe1, 0xd6, 0xc3, 0x38, 0xe8, 0x0d, 0xed, 0x7a, 0xaa, 0xf3, 0x28, 0x69, 0x21, 0x89, 0xd7,
+	0x0b, 0x7d, 0xa8, 0x0a, 0x33, 0x07, 0xbd, 0xde, 0x9e, 0x69, 0x58, 0x15, 0x28, 0xee, 0x6e, 0xee,
+	0x6c, 0xb7, 0xf2, 0x9d, 0x11, 0x2c, 0x77, 0xa5, 0xaf, 0x4d, 0x49, 0x1f, 0x2a, 0x2e, 0xbf, 0x17,
+	0xb1, 0x0c, 0xf9, 0x38, 0x1d, 0x10, 0x59, 0x37, 0x75, 0x03, 0xca, 0xe9, 0x00, 0xca, 0x7f, 0x62,
+	0x5e, 0x4c, 0x7d, 0xd8, 0xb8, 0xa9, 0xa0, 0xd3, 0x87, 0x3b, 0x19, 0xab, 0xa9, 0xf3, 0x79, 0x54,
+	0xf4, 0xe2, 0x13, 0xe5, 0xe4, 0xb0, 0xfe, 0xb2, 0x33, 0x7b, 0x75, 0xb4, 0x2e, 0x8a, 0x3b, 0xbf,
+	0xe7, 0x60, 0xee, 0xd2, 0xf4, 0x23, 0x0e, 0x94, 0xd3, 0xbc, 0xe5, 0x30, 0x6f, 0xe9, 0xab, 0x99,
+	0x5f, 0xc9, 0xe7, 0xa1, 0xdd, 0x50, 0xc3, 0x9d, 0xbc, 0x9b, 0x3b, 0x6f, 0x5b, 0x22, 0x1b, 0x8d,
+	0x84, 0x47, 0x3d, 0x11, 0x87, 0x3a, 0x29, 0xb5, 0x59, 0x34, 0x6c, 0x1a, 0xbc, 0x6b, 0x60, 0x53,
+	0xc1, 0xd3, 0x5c, 0xe5, 0x7f, 0x48, 0xdb, 0x52, 0xf3, 0x9c, 0x7a, 0xe0, 0x7f, 0xe0, 0xe6, 0x7b,
+	0xcc, 0xd4, 0xe4, 0x31, 0x67, 0x91, 0xa5, 0xd9, 0x8a, 0xab, 0x05, 0xec, 0xec, 0x15, 0x67, 0x91,
+	0xe1, 0x74, 0xf6, 0x92, 0xd9, 0xf3, 0xca, 0x57, 0xda, 0x34, 0xda, 0xe7, 0x50, 0xc2, 0x3c, 0xa4,
+	0xc9, 0x79, 0x70, 0xf5, 0x90, 0x4e, 0x24, 0xdb, 0xa1, 0x96, 0x63, 0x37, 0x51, 0x75, 0xfe, 0xc8,
+	0xc3, 0xdc, 0x25, 0xeb, 0x0d, 0x7e, 0x5e, 0x2e, 0xff, 0x65, 0xe4, 0xb3, 0xfe, 0x32, 0xe8, 0x15,
+	0x7f, 0x19, 0x85, 0x1b, 0xfc, 0x65, 0x64, 0xfe, 0x60, 0x5c, 0x1a, 0xff, 0xc5, 0x8c, 0xf1, 0xdf,
+	0x86, 0x9a, 0x1f, 0x7a, 0x92, 0x07, 0x3c, 0x34, 0x9f, 0x5c, 0x33, 0x38, 0x0a, 0xa7, 0x21, 0xf2,
+	0x0d, 0x94, 0xa2, 0x63, 0xa6, 0xb8, 0x72, 0x4a, 0xd7, 0xf9, 0x38, 0x48, 0xc8, 0x5b, 0x8b, 0x3f,
+	0x25, 0x1f, 0x64, 0x09, 0x83, 0xe2, 0x0f, 0xe3, 0x3f, 0x03, 0x00, 0xf5, 0xbc, 0xee, 0xaa, 0x40,
+	0x0e, 0x00, 0x00,
 }
@@ -20,15 +20,82 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+// The kind of failure of a build action, as determined by matching the
+// action's output against known error patterns.
+type ErrorCategory int32
+
+const (
+	// The failure did not match any known pattern.
+	ErrorCategory_UNCLASSIFIED ErrorCategory = 0
+	// A C or C++ compiler error.
+	ErrorCategory_CC_COMPILE_ERROR ErrorCategory = 1
+	// A linker error caused by an undefined symbol.
+	ErrorCategory_LINKER_UNDEFINED_SYMBOL ErrorCategory = 2
+	// A javac error.
+	ErrorCategory_JAVAC_ERROR ErrorCategory = 3
+	// An error reported by kati while reading makefiles.
+	ErrorCategory_KATI_ERROR ErrorCategory = 4
+	// An sbox sandboxed command didn't create all of its declared outputs.
+	ErrorCategory_SANDBOX_MISSING_OUTPUT ErrorCategory = 5
+	// The command was killed by a signal, usually by the out of memory killer.
+	ErrorCategory_KILLED_BY_SIGNAL ErrorCategory = 6
+)
+
+var ErrorCategory_name = map[int32]string{
+	0: "UNCLASSIFIED",
+	1: "CC_COMPILE_ERROR",
+	2: "LINKER_UNDEFINED_SYMBOL",
+	3: "JAVAC_ERROR",
+	4: "KATI_ERROR",
+	5: "SANDBOX_MISSING_OUTPUT",
+	6: "KILLED_BY_SIGNAL",
+}
+
+var ErrorCategory_value = map[string]int32{
+	"UNCLASSIFIED":            0,
+	"CC_COMPILE_ERROR":        1,
+	"LINKER_UNDEFINED_SYMBOL": 2,
+	"JAVAC_ERROR":             3,
+	"KATI_ERROR":              4,
+	"SANDBOX_MISSING_OUTPUT":  5,
+	"KILLED_BY_SIGNAL":        6,
+}
+
+func (x ErrorCategory) Enum() *ErrorCategory {
+	p := new(ErrorCategory)
+	*p = x
+	return p
+}
+
+func (x ErrorCategory) String() string {
+	return proto.EnumName(ErrorCategory_name, int32(x))
+}
+
+func (x *ErrorCategory) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(ErrorCategory_value, data, "ErrorCategory")
+	if err != nil {
+		return err
+	}
+	*x = ErrorCategory(value)
+	return nil
+}
+
+func (ErrorCategory) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_a2e15b05802a5501, []int{0}
+}
+
 type BuildError struct {
 	// List of error messages of the overall build. The error messages
 	// are not associated with a build action.
 	ErrorMessages []string `protobuf:"bytes,1,rep,name=error_messages,json=errorMessages" json:"error_messages,omitempty"`
 	// List of build action errors.
-	ActionErrors         []*BuildActionError `protobuf:"bytes,2,rep,name=action_errors,json=actionErrors" json:"action_errors,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
-	XXX_unrecognized     []byte              `json:"-"`
-	XXX_sizecache        int32               `json:"-"`
+	ActionErrors []*BuildActionError `protobuf:"bytes,2,rep,name=action_errors,json=actionErrors" json:"action_errors,omitempty"`
+	// Summary of the build action errors, grouped by category and primary
+	// location, ordered by decreasing number of errors.
+	ErrorSummaries       []*ErrorSummary `protobuf:"bytes,3,rep,name=error_summaries,json=errorSummaries" json:"error_summaries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
 }
 
 func (m *BuildError) Reset()         { *m = BuildError{} }
@@ -70,6 +137,142 @@ func (m *BuildError) GetActionErrors() []*BuildActionError {
 	return nil
 }
 
+func (m *BuildError) GetErrorSummaries() []*ErrorSummary {
+	if m != nil {
+		return m.ErrorSummaries
+	}
+	return nil
+}
+
+// A source location extracted from the output of a failed build action.
+type ErrorLocation struct {
+	// The path of the file.
+	File *string `protobuf:"bytes,1,opt,name=file" json:"file,omitempty"`
+	// The line number, or 0 if unknown.
+	Line *uint32 `protobuf:"varint,2,opt,name=line" json:"line,omitempty"`
+	// The column number, or 0 if unknown.
+	Column               *uint32  `protobuf:"varint,3,opt,name=column" json:"column,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ErrorLocation) Reset()         { *m = ErrorLocation{} }
+func (m *ErrorLocation) String() string { return proto.CompactTextString(m) }
+func (*ErrorLocation) ProtoMessage()    {}
+func (*ErrorLocation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a2e15b05802a5501, []int{1}
+}
+
+func (m *ErrorLocation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ErrorLocation.Unmarshal(m, b)
+}
+func (m *ErrorLocation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ErrorLocation.Marshal(b, m, deterministic)
+}
+func (m *ErrorLocation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ErrorLocation.Merge(m, src)
+}
+func (m *ErrorLocation) XXX_Size() int {
+	return xxx_messageInfo_ErrorLocation.Size(m)
+}
+func (m *ErrorLocation) XXX_DiscardUnknown() {
+	xxx_messageInfo_ErrorLocation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ErrorLocation proto.InternalMessageInfo
+
+func (m *ErrorLocation) GetFile() string {
+	if m != nil && m.File != nil {
+		return *m.File
+	}
+	return ""
+}
+
+func (m *ErrorLocation) GetLine() uint32 {
+	if m != nil && m.Line != nil {
+		return *m.Line
+	}
+	return 0
+}
+
+func (m *ErrorLocation) GetColumn() uint32 {
+	if m != nil && m.Column != nil {
+		return *m.Column
+	}
+	return 0
+}
+
+// A group of build action errors that have the same category and primary
+// location.
+type ErrorSummary struct {
+	// The category of the errors.
+	Category *ErrorCategory `protobuf:"varint,1,opt,name=category,enum=soong_build_error.ErrorCategory" json:"category,omitempty"`
+	// The first location reported by each of the errors, if any.
+	Location *ErrorLocation `protobuf:"bytes,2,opt,name=location" json:"location,omitempty"`
+	// The number of build actions that failed with this category and location.
+	Count *uint32 `protobuf:"varint,3,opt,name=count" json:"count,omitempty"`
+	// A human readable description of the group, eg.
+	// "37 C++ compile errors in foo.h:12".
+	Message              *string  `protobuf:"bytes,4,opt,name=message" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ErrorSummary) Reset()         { *m = ErrorSummary{} }
+func (m *ErrorSummary) String() string { return proto.CompactTextString(m) }
+func (*ErrorSummary) ProtoMessage()    {}
+func (*ErrorSummary) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a2e15b05802a5501, []int{2}
+}
+
+func (m *ErrorSummary) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ErrorSummary.Unmarshal(m, b)
+}
+func (m *ErrorSummary) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ErrorSummary.Marshal(b, m, deterministic)
+}
+func (m *ErrorSummary) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ErrorSummary.Merge(m, src)
+}
+func (m *ErrorSummary) XXX_Size() int {
+	return xxx_messageInfo_ErrorSummary.Size(m)
+}
+func (m *ErrorSummary) XXX_DiscardUnknown() {
+	xxx_messageInfo_ErrorSummary.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ErrorSummary proto.InternalMessageInfo
+
+func (m *ErrorSummary) GetCategory() ErrorCategory {
+	if m != nil && m.Category != nil {
+		return *m.Category
+	}
+	return ErrorCategory_UNCLASSIFIED
+}
+
+func (m *ErrorSummary) GetLocation() *ErrorLocation {
+	if m != nil {
+		return m.Location
+	}
+	return nil
+}
+
+func (m *ErrorSummary) GetCount() uint32 {
+	if m != nil && m.Count != nil {
+		return *m.Count
+	}
+	return 0
+}
+
+func (m *ErrorSummary) GetMessage() string {
+	if m != nil && m.Message != nil {
+		return *m.Message
+	}
+	return ""
+}
+
 // Build is composed of a list of build action. There can be a set of build
 // actions that can failed.
 type BuildActionError struct {
@@ -82,17 +285,21 @@ type BuildActionError struct {
 	// List of artifacts (i.e. files) that was produced by the command.
 	Artifacts []string `protobuf:"bytes,4,rep,name=artifacts" json:"artifacts,omitempty"`
 	// The error string produced by the build action.
-	Error                *string  `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Error *string `protobuf:"bytes,5,opt,name=error" json:"error,omitempty"`
+	// The category of the failure.
+	Category *ErrorCategory `protobuf:"varint,6,opt,name=category,enum=soong_build_error.ErrorCategory" json:"category,omitempty"`
+	// The source locations reported in the output of the build action.
+	Locations            []*ErrorLocation `protobuf:"bytes,7,rep,name=locations" json:"locations,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
 
 func (m *BuildActionError) Reset()         { *m = BuildActionError{} }
 func (m *BuildActionError) String() string { return proto.CompactTextString(m) }
 func (*BuildActionError) ProtoMessage()    {}
 func (*BuildActionError) Descriptor() ([]byte, []int) {
-	return fileDescriptor_a2e15b05802a5501, []int{1}
+	return fileDescriptor_a2e15b05802a5501, []int{3}
 }
 
 func (m *BuildActionError) XXX_Unmarshal(b []byte) error {
@@ -148,28 +355,63 @@ func (m *BuildActionError) GetError() string {
 	return ""
 }
 
+func (m *BuildActionError) GetCategory() ErrorCategory {
+	if m != nil && m.Category != nil {
+		return *m.Category
+	}
+	return ErrorCategory_UNCLASSIFIED
+}
+
+func (m *BuildActionError) GetLocations() []*ErrorLocation {
+	if m != nil {
+		return m.Locations
+	}
+	return nil
+}
+
 func init() {
+	proto.RegisterEnum("soong_build_error.ErrorCategory", ErrorCategory_name, ErrorCategory_value)
 	proto.RegisterType((*BuildError)(nil), "soong_build_error.BuildError")
+	proto.RegisterType((*ErrorLocation)(nil), "soong_build_error.ErrorLocation")
+	proto.RegisterType((*ErrorSummary)(nil), "soong_build_error.ErrorSummary")
 	proto.RegisterType((*BuildActionError)(nil), "soong_build_error.BuildActionError")
 }
 
 func init() { proto.RegisterFile("build_error.proto", fileDescriptor_a2e15b05802a5501) }
 
 var fileDescriptor_a2e15b05802a5501 = []byte{
-	// 229 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0x90, 0xc1, 0x4a, 0xc3, 0x40,
-	0x10, 0x86, 0x49, 0x63, 0x95, 0x4c, 0xad, 0xd8, 0x41, 0x74, 0x04, 0x0f, 0xa1, 0x22, 0xe4, 0x94,
-	0x83, 0x6f, 0x60, 0x41, 0xf0, 0xe2, 0x25, 0x47, 0x2f, 0x61, 0xdd, 0xac, 0x65, 0xc1, 0x64, 0xc2,
-	0xce, 0xe6, 0xe8, 0x8b, 0xf8, 0xb4, 0x92, 0x69, 0xa5, 0xa5, 0x39, 0x7e, 0xdf, 0x3f, 0xfb, 0xef,
-	0xce, 0xc2, 0xea, 0x73, 0xf0, 0xdf, 0x4d, 0xed, 0x42, 0xe0, 0x50, 0xf6, 0x81, 0x23, 0xe3, 0x4a,
-	0x98, 0xbb, 0x6d, 0x7d, 0x14, 0xac, 0x7f, 0x00, 0x36, 0x23, 0xbe, 0x8e, 0x84, 0x4f, 0x70, 0xa5,
-	0xba, 0x6e, 0x9d, 0x88, 0xd9, 0x3a, 0xa1, 0x24, 0x4f, 0x8b, 0xac, 0x5a, 0xaa, 0x7d, 0xdf, 0x4b,
-	0x7c, 0x83, 0xa5, 0xb1, 0xd1, 0x73, 0xb7, 0x2b, 0x11, 0x9a, 0xe5, 0x69, 0xb1, 0x78, 0x7e, 0x2c,
-	0x27, 0xfd, 0xa5, 0x96, 0xbf, 0xe8, 0xb0, 0x5e, 0x51, 0x5d, 0x9a, 0x03, 0xc8, 0xfa, 0x37, 0x81,
-	0xeb, 0xd3, 0x11, 0xcc, 0x61, 0xd1, 0x38, 0xb1, 0xc1, 0xf7, 0xa3, 0xa3, 0x24, 0x4f, 0x8a, 0xac,
-	0x3a, 0x56, 0x48, 0x70, 0x61, 0xb9, 0x6d, 0x4d, 0xd7, 0xd0, 0x4c, 0xd3, 0x7f, 0xc4, 0x5b, 0x38,
-	0xe7, 0x21, 0xf6, 0x43, 0xa4, 0x54, 0x83, 0x3d, 0xe1, 0x03, 0x64, 0x26, 0x44, 0xff, 0x65, 0x6c,
-	0x14, 0x3a, 0xd3, 0xa5, 0x0e, 0x02, 0x6f, 0x60, 0xae, 0xcf, 0xa5, 0xb9, 0x1e, 0xda, 0xc1, 0xe6,
-	0xfe, 0xe3, 0x6e, 0xb2, 0x50, 0xad, 0x3f, 0xf9, 0x17, 0x00, 0x00, 0xff, 0xff, 0xb6, 0x18, 0x9e,
-	0x17, 0x5d, 0x01, 0x00, 0x00,
+	// 519 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x53, 0xcd, 0x6e, 0x9b, 0x4c,
+	0x14, 0xfd, 0xf0, 0x5f, 0x3e, 0xae, 0x7f, 0x42, 0x46, 0x51, 0x32, 0xfd, 0x91, 0x8a, 0x5c, 0x55,
+	0xb2, 0xba, 0xf0, 0x22, 0xeb, 0xaa, 0x12, 0xc6, 0x24, 0xa5, 0xc6, 0x10, 0x0d, 0x76, 0xd5, 0x74,
+	0x33, 0xa2, 0x98, 0x58, 0x48, 0x86, 0xb1, 0x18, 0x58, 0xe4, 0x4d, 0xfa, 0x06, 0x7d, 0x87, 0xbe,
+	0x40, 0x5f, 0xab, 0xe2, 0x82, 0x63, 0xa7, 0x69, 0x54, 0x75, 0x37, 0xe7, 0xce, 0xbd, 0xe7, 0x9c,
+	0x7b, 0x18, 0xe0, 0xe4, 0x6b, 0x11, 0x6f, 0x56, 0x3c, 0xca, 0x32, 0x91, 0x8d, 0xb7, 0x99, 0xc8,
+	0x05, 0x39, 0x91, 0x42, 0xa4, 0x6b, 0x7e, 0x70, 0x31, 0xfc, 0xa9, 0x00, 0x4c, 0x4a, 0x6c, 0x95,
+	0x90, 0xbc, 0x81, 0x01, 0xd6, 0x79, 0x12, 0x49, 0x19, 0xac, 0x23, 0x49, 0x15, 0xbd, 0x39, 0x52,
+	0x59, 0x1f, 0xab, 0xf3, 0xba, 0x48, 0x3e, 0x40, 0x3f, 0x08, 0xf3, 0x58, 0xa4, 0x15, 0x8b, 0xa4,
+	0x0d, 0xbd, 0x39, 0xea, 0x5e, 0xbc, 0x1e, 0x3f, 0x12, 0x18, 0x23, 0xb9, 0x81, 0xcd, 0x28, 0xc1,
+	0x7a, 0xc1, 0x1e, 0x94, 0x4c, 0xc7, 0x95, 0xa0, 0x2c, 0x92, 0x24, 0xc8, 0xe2, 0x48, 0xd2, 0x26,
+	0x72, 0xbd, 0xfa, 0x03, 0x17, 0xce, 0xf8, 0xd8, 0x78, 0xc7, 0x06, 0xd1, 0x1e, 0xc5, 0x91, 0x1c,
+	0x7a, 0xd0, 0xc7, 0x7b, 0x47, 0x84, 0x41, 0x29, 0x40, 0x08, 0xb4, 0x6e, 0xe3, 0x4d, 0x44, 0x15,
+	0x5d, 0x19, 0xa9, 0x0c, 0xcf, 0x65, 0x6d, 0x13, 0xa7, 0x11, 0x6d, 0xe8, 0xca, 0xa8, 0xcf, 0xf0,
+	0x4c, 0xce, 0xa0, 0x13, 0x8a, 0x4d, 0x91, 0xa4, 0xb4, 0x89, 0xd5, 0x1a, 0x0d, 0x7f, 0x28, 0xd0,
+	0x3b, 0x54, 0x24, 0xef, 0xe0, 0xff, 0x30, 0xc8, 0xa3, 0xb5, 0xc8, 0xee, 0x90, 0x74, 0x70, 0xa1,
+	0x3f, 0x65, 0xd2, 0xac, 0xfb, 0xd8, 0xfd, 0x44, 0x39, 0xbd, 0xa9, 0xad, 0xa1, 0x7c, 0xf7, 0xe9,
+	0xe9, 0xdd, 0x0a, 0xec, 0x7e, 0x82, 0x9c, 0x42, 0x3b, 0x14, 0x45, 0x9a, 0xd7, 0x1e, 0x2b, 0x40,
+	0x28, 0x1c, 0xd5, 0x1f, 0x8a, 0xb6, 0x70, 0xcb, 0x1d, 0x1c, 0x7e, 0x6b, 0x80, 0xf6, 0x7b, 0xf4,
+	0x44, 0x87, 0xee, 0x2a, 0x92, 0x61, 0x16, 0x6f, 0xd1, 0x45, 0x15, 0xcc, 0x61, 0xa9, 0x24, 0x0c,
+	0x45, 0x92, 0x04, 0xe9, 0x0a, 0x3d, 0xaa, 0x6c, 0x07, 0xcb, 0x94, 0x44, 0x91, 0x6f, 0x8b, 0xca,
+	0x81, 0xca, 0x6a, 0x44, 0x5e, 0x82, 0x1a, 0x64, 0x79, 0x7c, 0x1b, 0x84, 0xb9, 0xa4, 0x2d, 0x7c,
+	0x2c, 0xfb, 0x42, 0x69, 0x1b, 0xf7, 0xa2, 0x6d, 0x1c, 0xaa, 0xc0, 0x83, 0x20, 0x3b, 0xff, 0x1c,
+	0xe4, 0x7b, 0x50, 0x77, 0xb1, 0x48, 0x7a, 0x84, 0x8f, 0xe5, 0xef, 0x49, 0xee, 0x47, 0xde, 0x7e,
+	0x57, 0xa0, 0xff, 0x80, 0x9b, 0x68, 0xd0, 0x5b, 0xba, 0xa6, 0x63, 0xf8, 0xbe, 0x7d, 0x69, 0x5b,
+	0x53, 0xed, 0x3f, 0x72, 0x0a, 0x9a, 0x69, 0x72, 0xd3, 0x9b, 0x5f, 0xdb, 0x8e, 0xc5, 0x2d, 0xc6,
+	0x3c, 0xa6, 0x29, 0xe4, 0x05, 0x9c, 0x3b, 0xb6, 0x3b, 0xb3, 0x18, 0x5f, 0xba, 0x53, 0xeb, 0xd2,
+	0x76, 0xad, 0x29, 0xf7, 0x6f, 0xe6, 0x13, 0xcf, 0xd1, 0x1a, 0xe4, 0x18, 0xba, 0x1f, 0x8d, 0x4f,
+	0x86, 0x59, 0x77, 0x37, 0xc9, 0x00, 0x60, 0x66, 0x2c, 0xec, 0x1a, 0xb7, 0xc8, 0x73, 0x38, 0xf3,
+	0x0d, 0x77, 0x3a, 0xf1, 0x3e, 0xf3, 0xb9, 0xed, 0xfb, 0xb6, 0x7b, 0xc5, 0xbd, 0xe5, 0xe2, 0x7a,
+	0xb9, 0xd0, 0xda, 0xa5, 0xde, 0xcc, 0x76, 0x1c, 0x6b, 0xca, 0x27, 0x37, 0xdc, 0xb7, 0xaf, 0x5c,
+	0xc3, 0xd1, 0x3a, 0x93, 0x67, 0x5f, 0xce, 0x1f, 0xed, 0xc5, 0xf1, 0x57, 0xfe, 0x35, 0x00, 0xbd,
+	0x80, 0xe7, 0x0a, 0xde, 0x03, 0x00, 0x00,
 }
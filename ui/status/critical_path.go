@@ -15,17 +15,26 @@
 package status
 
 import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
 	"time"
 
 	"android/soong/ui/logger"
 )
 
-func NewCriticalPath(log logger.Logger) StatusOutput {
+// NewCriticalPath returns a StatusOutput that computes the critical path of the build and logs it to
+// the verbose log when flushed.  If traceFile is not empty, a trace of every finished action is also
+// written to it in the Chrome trace event format, with flow arrows between dependent actions and the
+// critical path highlighted, so that it can be loaded in chrome://tracing or ui.perfetto.dev.
+func NewCriticalPath(log logger.Logger, traceFile string) StatusOutput {
 	return &criticalPath{
-		log:     log,
-		running: make(map[*Action]time.Time),
-		nodes:   make(map[string]*node),
-		clock:   osClock{},
+		log:       log,
+		running:   make(map[*Action]time.Time),
+		nodes:     make(map[string]*node),
+		clock:     osClock{},
+		traceFile: traceFile,
 	}
 }
 
@@ -35,6 +44,12 @@ type criticalPath struct {
 	nodes   map[string]*node
 	running map[*Action]time.Time
 
+	// finished contains every node in the order that its action finished, including nodes whose
+	// outputs were later overwritten in nodes by another action.
+	finished []*node
+
+	traceFile string
+
 	start, end time.Time
 
 	clock clock
@@ -55,6 +70,11 @@ type node struct {
 	cumulativeDuration time.Duration
 	duration           time.Duration
 	input              *node
+
+	// start and end are the times the action started and finished.
+	start, end time.Time
+	// inputs are the nodes that produced any of the inputs of the action, including input.
+	inputs []*node
 }
 
 func (cp *criticalPath) StartAction(action *Action, counts Counts) {
@@ -71,11 +91,17 @@ func (cp *criticalPath) FinishAction(result ActionResult, counts Counts) {
 
 		// Determine the input to this edge with the longest cumulative duration
 		var criticalPathInput *node
+		var inputs []*node
+		seen := make(map[*node]bool)
 		for _, input := range result.Action.Inputs {
 			if x := cp.nodes[input]; x != nil {
 				if criticalPathInput == nil || x.cumulativeDuration > criticalPathInput.cumulativeDuration {
 					criticalPathInput = x
 				}
+				if !seen[x] {
+					seen[x] = true
+					inputs = append(inputs, x)
+				}
 			}
 		}
 
@@ -92,11 +118,15 @@ func (cp *criticalPath) FinishAction(result ActionResult, counts Counts) {
 			cumulativeDuration: cumulativeDuration,
 			duration:           duration,
 			input:              criticalPathInput,
+			start:              start,
+			end:                end,
+			inputs:             inputs,
 		}
 
 		for _, output := range result.Action.Outputs {
 			cp.nodes[output] = node
 		}
+		cp.finished = append(cp.finished, node)
 
 		cp.end = end
 	}
@@ -126,6 +156,12 @@ func (cp *criticalPath) Flush() {
 				seconds/60, seconds%60, criticalPath[i].action.Description)
 		}
 	}
+
+	if cp.traceFile != "" {
+		if err := cp.writeTraceFile(criticalPath); err != nil {
+			cp.log.Println("Failed to write critical path trace:", err)
+		}
+	}
 }
 
 func (cp *criticalPath) Message(level MsgLevel, msg string) {}
@@ -152,3 +188,155 @@ func (cp *criticalPath) criticalPath() []*node {
 
 	return criticalPath
 }
+
+// traceEvent is a single event in the Chrome trace event format:
+// https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU/edit
+type traceEvent struct {
+	Name         string      `json:"name,omitempty"`
+	Category     string      `json:"cat,omitempty"`
+	Phase        string      `json:"ph"`
+	Time         int64       `json:"ts"`
+	Dur          int64       `json:"dur,omitempty"`
+	Pid          int         `json:"pid"`
+	Tid          int         `json:"tid"`
+	ID           int         `json:"id,omitempty"`
+	BindingPoint string      `json:"bp,omitempty"`
+	Color        string      `json:"cname,omitempty"`
+	Args         interface{} `json:"args,omitempty"`
+}
+
+type traceActionArgs struct {
+	Outputs      []string `json:"outputs,omitempty"`
+	CriticalPath bool     `json:"critical_path,omitempty"`
+}
+
+type traceNameArgs struct {
+	Name string `json:"name"`
+}
+
+// criticalPathColor is the reserved trace viewer color name used to highlight actions and
+// dependency edges on the critical path.
+const criticalPathColor = "terrible"
+
+func (cp *criticalPath) writeTraceFile(criticalPath []*node) error {
+	f, err := logger.CreateFileWithRotation(cp.traceFile, 5)
+	if err != nil {
+		return err
+	}
+
+	if err := cp.writeTrace(f, criticalPath); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeTrace writes every finished action as a complete event, laid out on as few threads as
+// possible without overlapping, followed by flow events from each action to the actions that
+// consumed its outputs.  Actions and edges on the critical path are highlighted.
+func (cp *criticalPath) writeTrace(w io.Writer, criticalPath []*node) error {
+	onCriticalPath := make(map[*node]bool)
+	for _, n := range criticalPath {
+		onCriticalPath[n] = true
+	}
+
+	nodes := append([]*node(nil), cp.finished...)
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].start.Before(nodes[j].start) })
+
+	// Assign each action to the first thread that is idle when it starts.
+	tids := make(map[*node]int)
+	var threadEnds []time.Time
+	for _, n := range nodes {
+		tid := -1
+		for i, end := range threadEnds {
+			if !end.After(n.start) {
+				tid = i
+				break
+			}
+		}
+		if tid == -1 {
+			tid = len(threadEnds)
+			threadEnds = append(threadEnds, time.Time{})
+		}
+		threadEnds[tid] = n.end
+		tids[n] = tid
+	}
+
+	micros := func(t time.Time) int64 {
+		return t.Sub(cp.start).Microseconds()
+	}
+
+	events := []traceEvent{}
+	for tid := range threadEnds {
+		events = append(events, traceEvent{
+			Name:  "thread_name",
+			Phase: "M",
+			Tid:   tid,
+			Args:  traceNameArgs{Name: "action"},
+		})
+	}
+
+	for _, n := range nodes {
+		event := traceEvent{
+			Name:     n.action.Description,
+			Category: "action",
+			Phase:    "X",
+			Time:     micros(n.start),
+			Dur:      n.duration.Microseconds(),
+			Tid:      tids[n],
+			Args: traceActionArgs{
+				Outputs:      n.action.Outputs,
+				CriticalPath: onCriticalPath[n],
+			},
+		}
+		if onCriticalPath[n] {
+			event.Color = criticalPathColor
+		}
+		events = append(events, event)
+	}
+
+	flowID := 0
+	for _, n := range nodes {
+		for _, input := range n.inputs {
+			flowID++
+			color := ""
+			if onCriticalPath[n] && n.input == input {
+				color = criticalPathColor
+			}
+
+			// Flow events bind to the enclosing slice, so start the arrow just inside the
+			// end of the input's slice.
+			flowStart := micros(input.end) - 1
+			if min := micros(input.start); flowStart < min {
+				flowStart = min
+			}
+
+			events = append(events,
+				traceEvent{
+					Name:     "dependency",
+					Category: "dependency",
+					Phase:    "s",
+					Time:     flowStart,
+					Tid:      tids[input],
+					ID:       flowID,
+					Color:    color,
+				},
+				traceEvent{
+					Name:         "dependency",
+					Category:     "dependency",
+					Phase:        "f",
+					Time:         micros(n.start),
+					Tid:          tids[n],
+					ID:           flowID,
+					BindingPoint: "e",
+					Color:        color,
+				})
+		}
+	}
+
+	buf := bufio.NewWriter(w)
+	if err := json.NewEncoder(buf).Encode(events); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
@@ -15,6 +15,8 @@
 package status
 
 import (
+	"bytes"
+	"encoding/json"
 	"reflect"
 	"testing"
 	"time"
@@ -137,7 +139,7 @@ func TestCriticalPath(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cp := &testCriticalPath{
-				criticalPath: NewCriticalPath(nil).(*criticalPath),
+				criticalPath: NewCriticalPath(nil, "").(*criticalPath),
 				actions:      make(map[int]*Action),
 			}
 
@@ -164,3 +166,85 @@ func TestCriticalPath(t *testing.T) {
 		})
 	}
 }
+
+func TestCriticalPathTrace(t *testing.T) {
+	cp := &testCriticalPath{
+		criticalPath: NewCriticalPath(nil, "").(*criticalPath),
+		actions:      make(map[int]*Action),
+	}
+
+	//  a
+	//  |\
+	//  b c
+	//  |/
+	//  d
+	cp.start(0, 0, []string{"a"}, nil)
+	cp.finish(0, 1000)
+	cp.start(1, 1000, []string{"b"}, []string{"a"})
+	cp.start(2, 1000, []string{"c"}, []string{"a"})
+	cp.finish(1, 2000)
+	cp.finish(2, 3000)
+	cp.start(3, 3000, []string{"d"}, []string{"b", "c"})
+	cp.finish(3, 4000)
+
+	buf := &bytes.Buffer{}
+	if err := cp.writeTrace(buf, cp.criticalPath.criticalPath()); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []traceEvent
+	if err := json.Unmarshal(buf.Bytes(), &events); err != nil {
+		t.Fatalf("failed to parse trace: %s\n%s", err, buf.String())
+	}
+
+	type slice struct {
+		name     string
+		ts, dur  int64
+		tid      int
+		critical bool
+	}
+	type flow struct {
+		phase    string
+		ts       int64
+		tid      int
+		critical bool
+	}
+
+	var slices []slice
+	var flows []flow
+	threads := 0
+	for _, e := range events {
+		switch e.Phase {
+		case "M":
+			threads++
+		case "X":
+			slices = append(slices, slice{e.Name, e.Time, e.Dur, e.Tid, e.Color == criticalPathColor})
+		case "s", "f":
+			flows = append(flows, flow{e.Phase, e.Time, e.Tid, e.Color == criticalPathColor})
+		}
+	}
+
+	// The test clock is in nanoseconds and the trace is in microseconds.
+	wantSlices := []slice{
+		{"a", 0, 1, 0, true},
+		{"b", 1, 1, 0, false},
+		{"c", 1, 2, 1, true},
+		{"d", 3, 1, 0, true},
+	}
+	wantFlows := []flow{
+		{"s", 0, 0, false}, {"f", 1, 0, false},
+		{"s", 0, 0, true}, {"f", 1, 1, true},
+		{"s", 1, 0, false}, {"f", 3, 0, false},
+		{"s", 2, 1, true}, {"f", 3, 0, true},
+	}
+
+	if threads != 2 {
+		t.Errorf("want 2 threads, got %d", threads)
+	}
+	if !reflect.DeepEqual(slices, wantSlices) {
+		t.Errorf("incorrect slices:\nwant: %v\n got: %v", wantSlices, slices)
+	}
+	if !reflect.DeepEqual(flows, wantFlows) {
+		t.Errorf("incorrect flows:\nwant: %v\n got: %v", wantFlows, flows)
+	}
+}
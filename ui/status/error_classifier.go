@@ -0,0 +1,272 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+
+	"android/soong/ui/status/build_error_proto"
+)
+
+// maxErrorLocations is the maximum number of locations recorded for a single failed action.
+const maxErrorLocations = 20
+
+var ansiEscapeRe = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+
+var (
+	exitCodeRe = regexp.MustCompile(`exited with code: (\d+)`)
+	killedRe   = regexp.MustCompile(`(?m)^(Killed|Killed by signal.*|.*java\.lang\.OutOfMemoryError.*)$`)
+
+	sandboxMissingOutputRe  = regexp.MustCompile(`(?m)^mismatch between declared and actual outputs$`)
+	sandboxMissingOutputLoc = regexp.MustCompile(`(?m)^  (\S+): (?:does not exist|not a file)$`)
+
+	katiErrorLoc = regexp.MustCompile(`(?m)^(\S+\.mk):(\d+): (?:\S+ )?error:`)
+
+	linkerUndefinedSymbolRe = regexp.MustCompile(`(?m)(: error: undefined symbol: |: undefined reference to |^Undefined symbols for architecture )`)
+	linkerReferencedByLoc   = regexp.MustCompile(`(?m)^>>> referenced by (?:\S+ \((\S+?):(\d+)\)|(\S+?):(\d+))$`)
+	linkerUndefinedRefLoc   = regexp.MustCompile(`(?m)^(\S+?):(\d+): undefined reference to `)
+
+	javacErrorLoc = regexp.MustCompile(`(?m)^(\S+\.java):(\d+): error:`)
+
+	ccErrorLoc = regexp.MustCompile(`(?m)^(\S+\.(?:c|cc|cpp|cxx|c\+\+|C|h|hh|hpp|hxx|inc|def|ipp|tcc|m|mm|S|s)):(\d+):(\d+): (?:fatal )?error:`)
+)
+
+// errorClassifier matches the result of a failed action against a known failure pattern.
+type errorClassifier struct {
+	category soong_build_error_proto.ErrorCategory
+
+	// match returns true if the result matches the pattern.
+	match func(result ActionResult, output string) bool
+
+	// locations, if set, is a regexp that matches the locations of the errors in the output.  The
+	// first non-empty submatch is the file, and the next ones, if present, are the line and column.
+	locations *regexp.Regexp
+}
+
+// errorClassifiers are tried in order, so more specific patterns must come before more generic
+// ones.
+var errorClassifiers = []errorClassifier{
+	{
+		category: soong_build_error_proto.ErrorCategory_KILLED_BY_SIGNAL,
+		match: func(result ActionResult, output string) bool {
+			if matches := exitCodeRe.FindStringSubmatch(result.Error.Error()); matches != nil {
+				// Shells report commands that were killed by a signal as exiting with 128 + the
+				// signal number.
+				if code, err := strconv.Atoi(matches[1]); err == nil && code > 128 {
+					return true
+				}
+			}
+			return killedRe.MatchString(output)
+		},
+	},
+	{
+		category: soong_build_error_proto.ErrorCategory_SANDBOX_MISSING_OUTPUT,
+		match: func(result ActionResult, output string) bool {
+			return sandboxMissingOutputRe.MatchString(output)
+		},
+		locations: sandboxMissingOutputLoc,
+	},
+	{
+		category:  soong_build_error_proto.ErrorCategory_KATI_ERROR,
+		locations: katiErrorLoc,
+	},
+	{
+		category: soong_build_error_proto.ErrorCategory_LINKER_UNDEFINED_SYMBOL,
+		match: func(result ActionResult, output string) bool {
+			return linkerUndefinedSymbolRe.MatchString(output)
+		},
+		locations: linkerReferencedByLoc,
+	},
+	{
+		category:  soong_build_error_proto.ErrorCategory_JAVAC_ERROR,
+		locations: javacErrorLoc,
+	},
+	{
+		category:  soong_build_error_proto.ErrorCategory_CC_COMPILE_ERROR,
+		locations: ccErrorLoc,
+	},
+}
+
+// errorClassification is the category of a failed action and the source locations that were
+// extracted from its output.
+type errorClassification struct {
+	category  soong_build_error_proto.ErrorCategory
+	locations []*soong_build_error_proto.ErrorLocation
+}
+
+// classifyError determines the category of a failed action from its error and output.  Classifiers
+// without a match function match if their locations regexp matches the output.
+func classifyError(result ActionResult) errorClassification {
+	output := ansiEscapeRe.ReplaceAllString(result.Output, "")
+
+	for _, c := range errorClassifiers {
+		var matches [][]string
+		if c.locations != nil {
+			matches = c.locations.FindAllStringSubmatch(output, -1)
+		}
+
+		if c.match != nil {
+			if !c.match(result, output) {
+				continue
+			}
+		} else if len(matches) == 0 {
+			continue
+		}
+
+		locations := errorLocations(matches)
+		if c.category == soong_build_error_proto.ErrorCategory_LINKER_UNDEFINED_SYMBOL && len(locations) == 0 {
+			// GNU ld reports the location on the same line as the error.
+			locations = errorLocations(linkerUndefinedRefLoc.FindAllStringSubmatch(output, -1))
+		}
+
+		return errorClassification{
+			category:  c.category,
+			locations: locations,
+		}
+	}
+
+	return errorClassification{category: soong_build_error_proto.ErrorCategory_UNCLASSIFIED}
+}
+
+// errorLocations converts regexp submatches into a deduplicated list of locations.
+func errorLocations(matches [][]string) []*soong_build_error_proto.ErrorLocation {
+	var locations []*soong_build_error_proto.ErrorLocation
+	seen := make(map[string]bool)
+
+	for _, match := range matches {
+		// Skip the full match and any empty alternatives before the file.
+		groups := match[1:]
+		for len(groups) > 0 && groups[0] == "" {
+			groups = groups[1:]
+		}
+		if len(groups) == 0 {
+			continue
+		}
+
+		location := &soong_build_error_proto.ErrorLocation{
+			File: proto.String(groups[0]),
+		}
+		if len(groups) > 1 {
+			if line, err := strconv.ParseUint(groups[1], 10, 32); err == nil {
+				location.Line = proto.Uint32(uint32(line))
+			}
+		}
+		if len(groups) > 2 {
+			if column, err := strconv.ParseUint(groups[2], 10, 32); err == nil {
+				location.Column = proto.Uint32(uint32(column))
+			}
+		}
+
+		key := formatErrorLocation(location)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		locations = append(locations, location)
+		if len(locations) == maxErrorLocations {
+			break
+		}
+	}
+
+	return locations
+}
+
+// formatErrorLocation returns the location as file:line, or file if the line is unknown.
+func formatErrorLocation(location *soong_build_error_proto.ErrorLocation) string {
+	if location.GetLine() == 0 {
+		return location.GetFile()
+	}
+	return fmt.Sprintf("%s:%d", location.GetFile(), location.GetLine())
+}
+
+// errorCategoryNames are the singular and plural descriptions of each category, used in the
+// summary.
+var errorCategoryNames = map[soong_build_error_proto.ErrorCategory][2]string{
+	soong_build_error_proto.ErrorCategory_UNCLASSIFIED:            {"unclassified failure", "unclassified failures"},
+	soong_build_error_proto.ErrorCategory_CC_COMPILE_ERROR:        {"C/C++ compile error", "C/C++ compile errors"},
+	soong_build_error_proto.ErrorCategory_LINKER_UNDEFINED_SYMBOL: {"undefined symbol link error", "undefined symbol link errors"},
+	soong_build_error_proto.ErrorCategory_JAVAC_ERROR:             {"javac error", "javac errors"},
+	soong_build_error_proto.ErrorCategory_KATI_ERROR:              {"kati error", "kati errors"},
+	soong_build_error_proto.ErrorCategory_SANDBOX_MISSING_OUTPUT:  {"sandboxed action with missing outputs", "sandboxed actions with missing outputs"},
+	soong_build_error_proto.ErrorCategory_KILLED_BY_SIGNAL:        {"action killed by a signal (possibly out of memory)", "actions killed by a signal (possibly out of memory)"},
+}
+
+// summarizeErrors groups failed actions by category and first location, ordered by decreasing
+// count.
+func summarizeErrors(actionErrors []*soong_build_error_proto.BuildActionError) []*soong_build_error_proto.ErrorSummary {
+	var summaries []*soong_build_error_proto.ErrorSummary
+	groups := make(map[string]*soong_build_error_proto.ErrorSummary)
+
+	for _, actionError := range actionErrors {
+		var location *soong_build_error_proto.ErrorLocation
+		if len(actionError.Locations) > 0 {
+			location = actionError.Locations[0]
+		}
+
+		key := actionError.GetCategory().String()
+		if location != nil {
+			key += " " + formatErrorLocation(location)
+		}
+
+		summary := groups[key]
+		if summary == nil {
+			summary = &soong_build_error_proto.ErrorSummary{
+				Category: actionError.Category,
+				Location: location,
+				Count:    proto.Uint32(0),
+			}
+			groups[key] = summary
+			summaries = append(summaries, summary)
+		}
+		*summary.Count++
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].GetCount() > summaries[j].GetCount()
+	})
+
+	for _, summary := range summaries {
+		names := errorCategoryNames[summary.GetCategory()]
+		name := names[0]
+		if summary.GetCount() > 1 {
+			name = names[1]
+		}
+
+		message := fmt.Sprintf("%d %s", summary.GetCount(), name)
+		if summary.Location != nil {
+			message += " in " + formatErrorLocation(summary.Location)
+		}
+		summary.Message = proto.String(message)
+	}
+
+	return summaries
+}
+
+// formatErrorSummaries returns the lines printed at the end of a build with multiple failures.
+func formatErrorSummaries(summaries []*soong_build_error_proto.ErrorSummary) string {
+	sb := &strings.Builder{}
+	fmt.Fprintln(sb, "Build failure summary:")
+	for _, summary := range summaries {
+		fmt.Fprintf(sb, "  %s\n", summary.GetMessage())
+	}
+	return sb.String()
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"android/soong/ui/status/build_error_proto"
+)
+
+func TestClassifyError(t *testing.T) {
+	testCases := []struct {
+		name          string
+		err           string
+		output        string
+		wantCategory  soong_build_error_proto.ErrorCategory
+		wantLocations []string
+	}{
+		{
+			name:         "unclassified",
+			err:          "exited with code: 1",
+			output:       "something went wrong",
+			wantCategory: soong_build_error_proto.ErrorCategory_UNCLASSIFIED,
+		},
+		{
+			name: "clang",
+			err:  "exited with code: 1",
+			output: "In file included from external/foo/foo.cpp:1:\n" +
+				"\x1b[1mexternal/foo/foo.h:12:3: \x1b[0m\x1b[0;1;31merror: \x1b[0m\x1b[1munknown type name 'bar'\x1b[0m\n" +
+				"external/foo/foo.cpp:20:1: error: expected ';'\n" +
+				"external/foo/foo.h:12:3: error: unknown type name 'bar'\n" +
+				"2 errors generated.\n",
+			wantCategory:  soong_build_error_proto.ErrorCategory_CC_COMPILE_ERROR,
+			wantLocations: []string{"external/foo/foo.h:12", "external/foo/foo.cpp:20"},
+		},
+		{
+			name: "lld",
+			err:  "exited with code: 1",
+			output: "ld.lld: error: undefined symbol: foo()\n" +
+				">>> referenced by bar.cpp:34 (external/bar/bar.cpp:34)\n" +
+				">>>               out/obj/bar.o:(main)\n",
+			wantCategory:  soong_build_error_proto.ErrorCategory_LINKER_UNDEFINED_SYMBOL,
+			wantLocations: []string{"external/bar/bar.cpp:34"},
+		},
+		{
+			name:          "gnu ld",
+			err:           "exited with code: 1",
+			output:        "bar.cpp:34: undefined reference to `foo()'\n",
+			wantCategory:  soong_build_error_proto.ErrorCategory_LINKER_UNDEFINED_SYMBOL,
+			wantLocations: []string{"bar.cpp:34"},
+		},
+		{
+			name: "javac",
+			err:  "exited with code: 1",
+			output: "frameworks/base/Foo.java:42: error: cannot find symbol\n" +
+				"        Bar bar;\n" +
+				"1 error\n",
+			wantCategory:  soong_build_error_proto.ErrorCategory_JAVAC_ERROR,
+			wantLocations: []string{"frameworks/base/Foo.java:42"},
+		},
+		{
+			name:          "kati",
+			err:           "makefile error",
+			output:        "device/foo/BoardConfig.mk:5: error: FOO is not set.\n",
+			wantCategory:  soong_build_error_proto.ErrorCategory_KATI_ERROR,
+			wantLocations: []string{"device/foo/BoardConfig.mk:5"},
+		},
+		{
+			name: "sbox",
+			err:  "exited with code: 1",
+			output: "mismatch between declared and actual outputs\n" +
+				"in sbox command(gen foo)\n\n" +
+				"in sandbox out/soong/.temp/sbox123,\n" +
+				"failed to create 1 files:\n" +
+				"  out/soong/.temp/sbox123/out/foo.h: does not exist\n" +
+				"created 0 files.",
+			wantCategory:  soong_build_error_proto.ErrorCategory_SANDBOX_MISSING_OUTPUT,
+			wantLocations: []string{"out/soong/.temp/sbox123/out/foo.h"},
+		},
+		{
+			name:         "signal exit code",
+			err:          "exited with code: 137",
+			output:       "external/foo/foo.cpp:20:1: error: expected ';'\n",
+			wantCategory: soong_build_error_proto.ErrorCategory_KILLED_BY_SIGNAL,
+		},
+		{
+			name:         "killed",
+			err:          "exited with code: 1",
+			output:       "Killed\n",
+			wantCategory: soong_build_error_proto.ErrorCategory_KILLED_BY_SIGNAL,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := classifyError(ActionResult{
+				Action: &Action{Description: tc.name},
+				Output: tc.output,
+				Error:  errors.New(tc.err),
+			})
+
+			if got.category != tc.wantCategory {
+				t.Errorf("want category %s, got %s", tc.wantCategory, got.category)
+			}
+
+			var gotLocations []string
+			for _, location := range got.locations {
+				gotLocations = append(gotLocations, formatErrorLocation(location))
+			}
+			if !reflect.DeepEqual(gotLocations, tc.wantLocations) {
+				t.Errorf("incorrect locations:\nwant: %q\n got: %q", tc.wantLocations, gotLocations)
+			}
+		})
+	}
+}
+
+func TestSummarizeErrors(t *testing.T) {
+	actionError := func(category soong_build_error_proto.ErrorCategory, locations ...string) *soong_build_error_proto.BuildActionError {
+		ret := &soong_build_error_proto.BuildActionError{
+			Category: category.Enum(),
+		}
+		for _, location := range locations {
+			ret.Locations = append(ret.Locations, &soong_build_error_proto.ErrorLocation{
+				File: proto.String(location),
+				Line: proto.Uint32(12),
+			})
+		}
+		return ret
+	}
+
+	summaries := summarizeErrors([]*soong_build_error_proto.BuildActionError{
+		actionError(soong_build_error_proto.ErrorCategory_JAVAC_ERROR, "Foo.java"),
+		actionError(soong_build_error_proto.ErrorCategory_CC_COMPILE_ERROR, "foo.h", "a.cpp"),
+		actionError(soong_build_error_proto.ErrorCategory_CC_COMPILE_ERROR, "foo.h", "b.cpp"),
+		actionError(soong_build_error_proto.ErrorCategory_UNCLASSIFIED),
+		actionError(soong_build_error_proto.ErrorCategory_CC_COMPILE_ERROR, "foo.h"),
+	})
+
+	var got []string
+	for _, summary := range summaries {
+		got = append(got, summary.GetMessage())
+	}
+
+	want := []string{
+		"3 C/C++ compile errors in foo.h:12",
+		"1 javac error in Foo.java:12",
+		"1 unclassified failure",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect summary:\nwant: %q\n got: %q", want, got)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"encoding/json"
+	"io"
+
+	"android/soong/ui/logger"
+)
+
+// jsonLog is a StatusOutput that writes a stream of JSON objects, one per line, describing every
+// action and message.  It is intended for tools that want to follow the progress of a build
+// without parsing the terminal output.  The format is:
+//
+//	{"type":"start","time_ms":...,"action":{...},"counts":{...}}
+//	{"type":"finish","time_ms":...,"action":{...},"result":{...},"counts":{...}}
+//	{"type":"message","time_ms":...,"level":"error","message":"..."}
+//	{"type":"output","time_ms":...,"message":"..."}
+//
+// Fields are only ever added to this format, never removed or renamed.
+type jsonLog struct {
+	log logger.Logger
+
+	w   io.WriteCloser
+	enc *json.Encoder
+
+	clock clock
+}
+
+// NewJSONLog returns a StatusOutput that writes a JSON object to w for each action start, action
+// finish and message.  Each event is written with a single call to w.Write so that readers of a
+// pipe see complete lines as soon as they happen.  w is closed when the StatusOutput is flushed.
+func NewJSONLog(log logger.Logger, w io.WriteCloser) StatusOutput {
+	return &jsonLog{
+		log:   log,
+		w:     w,
+		enc:   json.NewEncoder(w),
+		clock: osClock{},
+	}
+}
+
+// NewJSONLogFile returns a StatusOutput like NewJSONLog that writes to filename, rotating any
+// existing files.
+func NewJSONLogFile(log logger.Logger, filename string) StatusOutput {
+	f, err := logger.CreateFileWithRotation(filename, 5)
+	if err != nil {
+		log.Println("Failed to create json log file:", err)
+		return nil
+	}
+
+	return NewJSONLog(log, f)
+}
+
+type jsonLogEvent struct {
+	Type    string `json:"type"`
+	TimeMs  int64  `json:"time_ms"`
+	Level   string `json:"level,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	Action *jsonLogAction `json:"action,omitempty"`
+	Result *jsonLogResult `json:"result,omitempty"`
+	Counts *jsonLogCounts `json:"counts,omitempty"`
+}
+
+type jsonLogAction struct {
+	Description string   `json:"description,omitempty"`
+	Command     string   `json:"command,omitempty"`
+	Outputs     []string `json:"outputs,omitempty"`
+	Inputs      []string `json:"inputs,omitempty"`
+}
+
+type jsonLogResult struct {
+	Success bool          `json:"success"`
+	Error   string        `json:"error,omitempty"`
+	Output  string        `json:"output,omitempty"`
+	Stats   *jsonLogStats `json:"stats"`
+}
+
+type jsonLogStats struct {
+	UserTimeMs                 uint32 `json:"user_time_ms"`
+	SystemTimeMs               uint32 `json:"system_time_ms"`
+	MaxRssKB                   uint64 `json:"max_rss_kb"`
+	MinorPageFaults            uint64 `json:"minor_page_faults"`
+	MajorPageFaults            uint64 `json:"major_page_faults"`
+	IOInputKB                  uint64 `json:"io_input_kb"`
+	IOOutputKB                 uint64 `json:"io_output_kb"`
+	VoluntaryContextSwitches   uint64 `json:"voluntary_context_switches"`
+	InvoluntaryContextSwitches uint64 `json:"involuntary_context_switches"`
+}
+
+type jsonLogCounts struct {
+	TotalActions    int `json:"total_actions"`
+	RunningActions  int `json:"running_actions"`
+	StartedActions  int `json:"started_actions"`
+	FinishedActions int `json:"finished_actions"`
+}
+
+func newJSONLogAction(action *Action) *jsonLogAction {
+	return &jsonLogAction{
+		Description: action.Description,
+		Command:     action.Command,
+		Outputs:     action.Outputs,
+		Inputs:      action.Inputs,
+	}
+}
+
+func newJSONLogCounts(counts Counts) *jsonLogCounts {
+	return &jsonLogCounts{
+		TotalActions:    counts.TotalActions,
+		RunningActions:  counts.RunningActions,
+		StartedActions:  counts.StartedActions,
+		FinishedActions: counts.FinishedActions,
+	}
+}
+
+func jsonLogLevel(level MsgLevel) string {
+	switch level {
+	case VerboseLvl:
+		return "verbose"
+	case StatusLvl:
+		return "status"
+	case PrintLvl:
+		return "print"
+	case ErrorLvl:
+		return "error"
+	default:
+		panic("Unknown message level")
+	}
+}
+
+func (j *jsonLog) write(event *jsonLogEvent) {
+	event.TimeMs = j.clock.Now().UnixNano() / 1e6
+	if err := j.enc.Encode(event); err != nil {
+		j.log.Println("Failed to write json log event:", err)
+	}
+}
+
+func (j *jsonLog) StartAction(action *Action, counts Counts) {
+	j.write(&jsonLogEvent{
+		Type:   "start",
+		Action: newJSONLogAction(action),
+		Counts: newJSONLogCounts(counts),
+	})
+}
+
+func (j *jsonLog) FinishAction(result ActionResult, counts Counts) {
+	stats := result.Stats
+	r := &jsonLogResult{
+		Success: result.Error == nil,
+		Output:  result.Output,
+		Stats: &jsonLogStats{
+			UserTimeMs:                 stats.UserTime,
+			SystemTimeMs:               stats.SystemTime,
+			MaxRssKB:                   stats.MaxRssKB,
+			MinorPageFaults:            stats.MinorPageFaults,
+			MajorPageFaults:            stats.MajorPageFaults,
+			IOInputKB:                  stats.IOInputKB,
+			IOOutputKB:                 stats.IOOutputKB,
+			VoluntaryContextSwitches:   stats.VoluntaryContextSwitches,
+			InvoluntaryContextSwitches: stats.InvoluntaryContextSwitches,
+		},
+	}
+	if result.Error != nil {
+		r.Error = result.Error.Error()
+	}
+
+	j.write(&jsonLogEvent{
+		Type:   "finish",
+		Action: newJSONLogAction(result.Action),
+		Result: r,
+		Counts: newJSONLogCounts(counts),
+	})
+}
+
+func (j *jsonLog) Message(level MsgLevel, message string) {
+	j.write(&jsonLogEvent{
+		Type:    "message",
+		Level:   jsonLogLevel(level),
+		Message: message,
+	})
+}
+
+func (j *jsonLog) Flush() {
+	j.w.Close()
+}
+
+func (j *jsonLog) Write(p []byte) (int, error) {
+	j.write(&jsonLogEvent{
+		Type:    "output",
+		Message: string(p),
+	})
+	return len(p), nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nopWriteCloser struct{ *bytes.Buffer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestJSONLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	j := NewJSONLog(nil, nopWriteCloser{buf}).(*jsonLog)
+	j.clock = testClock(time.Unix(1, 0))
+
+	s := &Status{}
+	s.AddOutput(j)
+
+	tool := s.StartTool()
+	tool.SetTotalActions(2)
+
+	action1 := &Action{Description: "action1", Outputs: []string{"out1"}, Inputs: []string{"in1"}}
+	action2 := &Action{Description: "action2", Command: "false"}
+
+	tool.StartAction(action1)
+	tool.FinishAction(ActionResult{
+		Action: action1,
+		Stats:  ActionResultStats{UserTime: 10, MaxRssKB: 1024},
+	})
+	tool.StartAction(action2)
+	tool.FinishAction(ActionResult{
+		Action: action2,
+		Output: "failed",
+		Error:  fmt.Errorf("exited with code: 1"),
+	})
+	tool.Error("error message")
+	tool.Finish()
+	s.Finish()
+
+	want := []string{
+		`{"type":"start","time_ms":1000,"action":{"description":"action1","outputs":["out1"],"inputs":["in1"]},"counts":{"total_actions":2,"running_actions":1,"started_actions":1,"finished_actions":0}}`,
+		`{"type":"finish","time_ms":1000,"action":{"description":"action1","outputs":["out1"],"inputs":["in1"]},"result":{"success":true,"stats":{"user_time_ms":10,"system_time_ms":0,"max_rss_kb":1024,"minor_page_faults":0,"major_page_faults":0,"io_input_kb":0,"io_output_kb":0,"voluntary_context_switches":0,"involuntary_context_switches":0}},"counts":{"total_actions":2,"running_actions":0,"started_actions":1,"finished_actions":1}}`,
+		`{"type":"start","time_ms":1000,"action":{"description":"action2","command":"false"},"counts":{"total_actions":2,"running_actions":1,"started_actions":2,"finished_actions":1}}`,
+		`{"type":"finish","time_ms":1000,"action":{"description":"action2","command":"false"},"result":{"success":false,"error":"exited with code: 1","output":"failed","stats":{"user_time_ms":0,"system_time_ms":0,"max_rss_kb":0,"minor_page_faults":0,"major_page_faults":0,"io_input_kb":0,"io_output_kb":0,"voluntary_context_switches":0,"involuntary_context_switches":0}},"counts":{"total_actions":2,"running_actions":0,"started_actions":2,"finished_actions":2}}`,
+		`{"type":"message","time_ms":1000,"level":"error","message":"error message"}`,
+	}
+
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("want %d lines, got %d:\n%s", len(want), len(got), buf.String())
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("incorrect line %d:\nwant: %s\n got: %s", i, want[i], got[i])
+		}
+	}
+}
@@ -171,13 +171,18 @@ func (e *errorProtoLog) FinishAction(result ActionResult, counts Counts) {
 		return
 	}
 
+	classification := classifyError(result)
+
 	e.errorProto.ActionErrors = append(e.errorProto.ActionErrors, &soong_build_error_proto.BuildActionError{
 		Description: proto.String(result.Description),
 		Command:     proto.String(result.Command),
 		Output:      proto.String(result.Output),
 		Artifacts:   result.Outputs,
 		Error:       proto.String(result.Error.Error()),
+		Category:    classification.category.Enum(),
+		Locations:   classification.locations,
 	})
+	e.errorProto.ErrorSummaries = summarizeErrors(e.errorProto.ActionErrors)
 
 	err := writeToFile(&e.errorProto, e.filename)
 	if err != nil {
@@ -186,7 +191,10 @@ func (e *errorProtoLog) FinishAction(result ActionResult, counts Counts) {
 }
 
 func (e *errorProtoLog) Flush() {
-	//Not required.
+	// A single failure is already easy to find in the output, only summarize multiple failures.
+	if len(e.errorProto.ActionErrors) > 1 {
+		e.log.Print(formatErrorSummaries(e.errorProto.ErrorSummaries))
+	}
 }
 
 func (e *errorProtoLog) Message(level MsgLevel, message string) {
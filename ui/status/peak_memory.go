@@ -0,0 +1,72 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import "regexp"
+
+// PeakMemoryClass is a class of actions whose peak memory use is recorded, identified by a regular
+// expression that matches their command lines.
+type PeakMemoryClass struct {
+	Name    string
+	Command *regexp.Regexp
+}
+
+// PeakMemory is a StatusOutput that records the largest max RSS reported for the actions in each
+// class.  Actions are assigned to the first class whose regular expression matches their command.
+type PeakMemory struct {
+	classes []PeakMemoryClass
+	peaks   map[string]uint64
+}
+
+// NewPeakMemory returns a PeakMemory that records the peak memory use of the given classes of
+// actions.
+func NewPeakMemory(classes []PeakMemoryClass) *PeakMemory {
+	return &PeakMemory{
+		classes: classes,
+		peaks:   make(map[string]uint64),
+	}
+}
+
+// Peaks returns the largest max RSS in kB of the actions in each class that ran, indexed by class
+// name.  Classes with no actions that reported their max RSS are not included.
+func (p *PeakMemory) Peaks() map[string]uint64 {
+	return p.peaks
+}
+
+func (p *PeakMemory) StartAction(action *Action, counts Counts) {}
+
+func (p *PeakMemory) FinishAction(result ActionResult, counts Counts) {
+	rss := result.Stats.MaxRssKB
+	if rss == 0 {
+		return
+	}
+
+	for _, class := range p.classes {
+		if class.Command.MatchString(result.Action.Command) {
+			if rss > p.peaks[class.Name] {
+				p.peaks[class.Name] = rss
+			}
+			return
+		}
+	}
+}
+
+func (p *PeakMemory) Message(level MsgLevel, msg string) {}
+
+func (p *PeakMemory) Flush() {}
+
+func (p *PeakMemory) Write(b []byte) (int, error) {
+	return len(b), nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestPeakMemory(t *testing.T) {
+	p := NewPeakMemory([]PeakMemoryClass{
+		{Name: "link", Command: regexp.MustCompile(`/clang\+\+ .*@\S+\.rsp`)},
+		{Name: "r8", Command: regexp.MustCompile(`/r8-compat-proguard `)},
+		{Name: "any clang", Command: regexp.MustCompile(`/clang`)},
+		{Name: "kotlinc", Command: regexp.MustCompile(`/kotlinc `)},
+	})
+
+	finish := func(command string, rss uint64) {
+		action := &Action{Command: command}
+		p.StartAction(action, Counts{})
+		p.FinishAction(ActionResult{
+			Action: action,
+			Stats:  ActionResultStats{MaxRssKB: rss},
+		}, Counts{})
+	}
+
+	finish("bin/clang++ crtbegin.o @out/libfoo.so.rsp -o out/libfoo.so", 1000)
+	finish("bin/clang++ crtbegin.o @out/libbar.so.rsp -o out/libbar.so", 3000)
+	finish("bin/clang++ crtbegin.o @out/libbaz.so.rsp -o out/libbaz.so", 2000)
+	finish("bin/clang++ -c foo.cpp -o out/foo.o", 500)
+	finish("bin/r8-compat-proguard -injars foo.jar", 0)
+	finish("bin/javac Foo.java", 4000)
+
+	want := map[string]uint64{
+		"link":      3000,
+		"any clang": 500,
+	}
+	if got := p.Peaks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect peaks:\nwant: %v\n got: %v", want, got)
+	}
+}
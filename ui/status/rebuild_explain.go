@@ -0,0 +1,499 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RebuildCauseKind is the reason ninja decided that an output was dirty.
+type RebuildCauseKind int
+
+const (
+	// RebuildInputChanged means an input was newer than the output.
+	RebuildInputChanged RebuildCauseKind = iota
+	// RebuildRestat means an input was newer than the output after the output was restat'ed.
+	RebuildRestat
+	// RebuildCommandChanged means the command line differs from the one in .ninja_log.
+	RebuildCommandChanged
+	// RebuildNewAction means the output was never built before in this out directory.
+	RebuildNewAction
+	// RebuildOutputMissing means the output was built before but no longer exists.
+	RebuildOutputMissing
+	// RebuildDepsMissing means the dependencies discovered from the depfile are missing.
+	RebuildDepsMissing
+	// RebuildInputMissing means a source input doesn't exist.
+	RebuildInputMissing
+	// RebuildUnexplained means ninja considered the output dirty without explaining why.
+	RebuildUnexplained
+)
+
+func (k RebuildCauseKind) String() string {
+	switch k {
+	case RebuildInputChanged:
+		return "input changed"
+	case RebuildRestat:
+		return "input newer than restat output"
+	case RebuildCommandChanged:
+		return "command line changed"
+	case RebuildNewAction:
+		return "new action"
+	case RebuildOutputMissing:
+		return "output missing"
+	case RebuildDepsMissing:
+		return "depfile deps missing"
+	case RebuildInputMissing:
+		return "input missing"
+	case RebuildUnexplained:
+		return "unexplained"
+	default:
+		panic(fmt.Errorf("unknown RebuildCauseKind %d", int(k)))
+	}
+}
+
+// RebuildCause is a root cause of actions being rebuilt, along with the number of actions it
+// invalidated.
+type RebuildCause struct {
+	Kind RebuildCauseKind
+
+	// Path is the changed or missing input for RebuildInputChanged, RebuildRestat and
+	// RebuildInputMissing, and the output for the other kinds.
+	Path string
+
+	// Actions is the number of explained actions that were rebuilt because of this cause.
+	Actions int
+}
+
+func (c RebuildCause) String() string {
+	return c.Kind.String() + ": " + c.Path
+}
+
+// maxRebuildCauses is the maximum number of root causes printed in the summary.
+const maxRebuildCauses = 20
+
+// Patterns for the messages printed by ninja -d explain, without the "ninja explain: " prefix.
+var (
+	explainOutputMissingRe = regexp.MustCompile(`^output (.+?) (?:of phony edge with no inputs )?doesn't exist$`)
+	explainOlderRe         = regexp.MustCompile(`^(restat of )?output (.+?) older than most recent input (.+) \(-?\d+ vs -?\d+\)$`)
+	explainRecordedMtimeRe = regexp.MustCompile(`^recorded mtime of (.+?) older than most recent input (.+) \(-?\d+ vs -?\d+\)$`)
+	explainCommandRe       = regexp.MustCompile(`^command line changed for (.+)$`)
+	explainNotInLogRe      = regexp.MustCompile(`^command line not found in log for (.+)$`)
+	explainDepsMissingRe   = regexp.MustCompile(`^deps for '(.+)' are missing$`)
+	explainInputMissingRe  = regexp.MustCompile(`^(.+) has no in-edge and is missing$`)
+	explainDirtyRe         = regexp.MustCompile(`^(.+) is dirty$`)
+)
+
+// RebuildExplainer is a StatusOutput that collects the messages printed by ninja -d explain and the
+// actions that ninja ran, and attributes each of the first actions of the build to the root causes
+// that made it dirty.  Combined with the .ninja_log and .ninja_deps files from before the build it
+// can tell apart outputs that were deleted from actions that are new, and follow dependencies that
+// were discovered from depfiles.
+type RebuildExplainer struct {
+	maxActions int
+
+	// explained are the first maxActions actions started by ninja.
+	explained []*Action
+	// totalActions is the number of actions started by ninja.
+	totalActions int
+	// producers maps every output of a started action to the action.
+	producers map[string]*Action
+
+	// causes maps an output to the reasons ninja printed for it being dirty.
+	causes map[string][]RebuildCause
+	// dirty is the set of inputs that ninja reported as dirty.
+	dirty map[string]bool
+
+	// ninjaLog is the set of outputs in .ninja_log before the build.
+	ninjaLog map[string]bool
+	// ninjaDeps maps outputs to the dependencies ninja discovered from their depfiles.
+	ninjaDeps map[string][]string
+}
+
+// NewRebuildExplainer returns a RebuildExplainer that explains the first maxActions actions run by
+// ninja.  Ninja must be run with -d explain.
+func NewRebuildExplainer(maxActions int) *RebuildExplainer {
+	return &RebuildExplainer{
+		maxActions: maxActions,
+		producers:  make(map[string]*Action),
+		causes:     make(map[string][]RebuildCause),
+		dirty:      make(map[string]bool),
+	}
+}
+
+// LoadNinjaLog reads the outputs that were built before from .ninja_log.  It must be called before
+// ninja starts, as ninja appends to the log as it runs actions.
+func (e *RebuildExplainer) LoadNinjaLog(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entries, err := readNinjaLog(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	e.ninjaLog = make(map[string]bool)
+	for _, entry := range entries {
+		e.ninjaLog[entry.output] = true
+	}
+	return nil
+}
+
+// LoadNinjaDeps reads the dependencies discovered from depfiles from .ninja_deps.  It must be called
+// before ninja starts, as ninja rewrites the deps log when it starts.
+func (e *RebuildExplainer) LoadNinjaDeps(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	deps, err := readNinjaDeps(bufio.NewReader(f))
+	if err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	e.ninjaDeps = deps
+	return nil
+}
+
+func (e *RebuildExplainer) StartAction(action *Action, counts Counts) {
+	e.totalActions++
+	if len(e.explained) < e.maxActions {
+		e.explained = append(e.explained, action)
+	}
+	for _, output := range action.Outputs {
+		e.producers[output] = action
+	}
+}
+
+func (e *RebuildExplainer) FinishAction(result ActionResult, counts Counts) {}
+
+func (e *RebuildExplainer) Message(level MsgLevel, msg string) {
+	msg = strings.TrimPrefix(msg, "ninja: ")
+	msg = strings.TrimPrefix(msg, "ninja ")
+	if !strings.HasPrefix(msg, "explain: ") {
+		return
+	}
+	e.addExplanation(strings.TrimSuffix(strings.TrimPrefix(msg, "explain: "), "\n"))
+}
+
+func (e *RebuildExplainer) Flush() {}
+
+func (e *RebuildExplainer) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// addExplanation records the reason given by a single ninja explain message.
+func (e *RebuildExplainer) addExplanation(msg string) {
+	add := func(output string, kind RebuildCauseKind, path string) {
+		e.causes[output] = append(e.causes[output], RebuildCause{Kind: kind, Path: path})
+	}
+
+	if m := explainOutputMissingRe.FindStringSubmatch(msg); m != nil {
+		add(m[1], RebuildOutputMissing, m[1])
+	} else if m := explainOlderRe.FindStringSubmatch(msg); m != nil {
+		if m[1] != "" {
+			add(m[2], RebuildRestat, m[3])
+		} else {
+			add(m[2], RebuildInputChanged, m[3])
+		}
+	} else if m := explainRecordedMtimeRe.FindStringSubmatch(msg); m != nil {
+		add(m[1], RebuildInputChanged, m[2])
+	} else if m := explainCommandRe.FindStringSubmatch(msg); m != nil {
+		add(m[1], RebuildCommandChanged, m[1])
+	} else if m := explainNotInLogRe.FindStringSubmatch(msg); m != nil {
+		add(m[1], RebuildNewAction, m[1])
+	} else if m := explainDepsMissingRe.FindStringSubmatch(msg); m != nil {
+		add(m[1], RebuildDepsMissing, m[1])
+	} else if m := explainInputMissingRe.FindStringSubmatch(msg); m != nil {
+		add(m[1], RebuildInputMissing, m[1])
+	} else if m := explainDirtyRe.FindStringSubmatch(msg); m != nil {
+		e.dirty[m[1]] = true
+	}
+}
+
+// Explain returns the root causes of the explained actions being rebuilt, ordered by decreasing
+// number of actions invalidated.  An action that was invalidated by multiple root causes is counted
+// for each of them.
+func (e *RebuildExplainer) Explain() []RebuildCause {
+	memo := make(map[string][]RebuildCause)
+
+	counts := make(map[RebuildCause]int)
+	for _, action := range e.explained {
+		seen := make(map[RebuildCause]bool)
+		for _, output := range action.Outputs {
+			for _, cause := range e.rootCauses(output, memo, make(map[string]bool)) {
+				if !seen[cause] {
+					seen[cause] = true
+					counts[cause]++
+				}
+			}
+		}
+	}
+
+	causes := make([]RebuildCause, 0, len(counts))
+	for cause, count := range counts {
+		cause.Actions = count
+		causes = append(causes, cause)
+	}
+	sort.Slice(causes, func(i, j int) bool {
+		if causes[i].Actions != causes[j].Actions {
+			return causes[i].Actions > causes[j].Actions
+		}
+		if causes[i].Kind != causes[j].Kind {
+			return causes[i].Kind < causes[j].Kind
+		}
+		return causes[i].Path < causes[j].Path
+	})
+	return causes
+}
+
+// rootCauses returns the reasons output was dirty, following dirty inputs back to the outputs that
+// ninja gave a reason for.
+func (e *RebuildExplainer) rootCauses(output string, memo map[string][]RebuildCause, visiting map[string]bool) []RebuildCause {
+	if causes, ok := memo[output]; ok {
+		return causes
+	}
+	if visiting[output] {
+		return nil
+	}
+	visiting[output] = true
+
+	var causes []RebuildCause
+	if direct := e.causes[output]; len(direct) > 0 {
+		for _, cause := range direct {
+			if cause.Kind == RebuildOutputMissing && e.ninjaLog != nil && !e.ninjaLog[output] {
+				// An output that is missing and was never built is a new action rather than a
+				// deleted output.
+				cause.Kind = RebuildNewAction
+			}
+			causes = append(causes, cause)
+		}
+	} else {
+		// Ninja only explains why the outputs of an action are dirty if none of its inputs are
+		// dirty, so look for the dirty inputs.
+		var inputs []string
+		if action := e.producers[output]; action != nil {
+			inputs = append(inputs, action.Inputs...)
+		}
+		inputs = append(inputs, e.ninjaDeps[output]...)
+
+		seen := make(map[RebuildCause]bool)
+		for _, input := range inputs {
+			if input == output || !(e.dirty[input] || e.producers[input] != nil || len(e.causes[input]) > 0) {
+				continue
+			}
+			for _, cause := range e.rootCauses(input, memo, visiting) {
+				if !seen[cause] {
+					seen[cause] = true
+					causes = append(causes, cause)
+				}
+			}
+		}
+
+		if len(causes) == 0 {
+			causes = []RebuildCause{{Kind: RebuildUnexplained, Path: output}}
+		}
+	}
+
+	memo[output] = causes
+	return causes
+}
+
+// Summary returns the root causes of the explained actions being rebuilt formatted for printing at
+// the end of the build, or an empty string if ninja didn't run any actions.
+func (e *RebuildExplainer) Summary() string {
+	if len(e.explained) == 0 {
+		return ""
+	}
+
+	causes := e.Explain()
+
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "Explaining why ninja ran the first %d of %d actions:\n", len(e.explained), e.totalActions)
+
+	kindCounts := make(map[RebuildCauseKind]int)
+	for _, cause := range causes {
+		kindCounts[cause.Kind] += cause.Actions
+	}
+	var kinds []RebuildCauseKind
+	for kind := range kindCounts {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		if kindCounts[kinds[i]] != kindCounts[kinds[j]] {
+			return kindCounts[kinds[i]] > kindCounts[kinds[j]]
+		}
+		return kinds[i] < kinds[j]
+	})
+	fmt.Fprintln(sb, "  Actions by cause:")
+	for _, kind := range kinds {
+		fmt.Fprintf(sb, "    %6d %s\n", kindCounts[kind], kind)
+	}
+
+	fmt.Fprintln(sb, "  Root causes:")
+	for i, cause := range causes {
+		if i == maxRebuildCauses {
+			fmt.Fprintf(sb, "    ... and %d more\n", len(causes)-maxRebuildCauses)
+			break
+		}
+		fmt.Fprintf(sb, "    %6d %s\n", cause.Actions, cause)
+	}
+
+	return sb.String()
+}
+
+// ninjaLogEntry is a single line of .ninja_log.
+type ninjaLogEntry struct {
+	start, end int
+	mtime      int64
+	output     string
+	cmdHash    uint64
+}
+
+// readNinjaLog parses a version 5 or 6 .ninja_log file.  Later entries for an output replace earlier
+// ones, so the returned entries are in the order the outputs first appeared.
+func readNinjaLog(r io.Reader) ([]ninjaLogEntry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("missing header")
+	}
+	var version int
+	if _, err := fmt.Sscanf(scanner.Text(), "# ninja log v%d", &version); err != nil {
+		return nil, fmt.Errorf("invalid header %q", scanner.Text())
+	}
+	if version < 5 || version > 6 {
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+
+	var entries []ninjaLogEntry
+	index := make(map[string]int)
+	for line := 2; scanner.Scan(); line++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("line %d: expected 5 fields, found %d", line, len(fields))
+		}
+
+		var entry ninjaLogEntry
+		var err error
+		if entry.start, err = strconv.Atoi(fields[0]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid start time: %w", line, err)
+		}
+		if entry.end, err = strconv.Atoi(fields[1]); err != nil {
+			return nil, fmt.Errorf("line %d: invalid end time: %w", line, err)
+		}
+		if entry.mtime, err = strconv.ParseInt(fields[2], 10, 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid mtime: %w", line, err)
+		}
+		entry.output = fields[3]
+		if entry.cmdHash, err = strconv.ParseUint(fields[4], 16, 64); err != nil {
+			return nil, fmt.Errorf("line %d: invalid command hash: %w", line, err)
+		}
+
+		if i, ok := index[entry.output]; ok {
+			entries[i] = entry
+		} else {
+			index[entry.output] = len(entries)
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+const ninjaDepsSignature = "# ninjadeps\n"
+
+// readNinjaDeps parses a version 4 .ninja_deps file and returns the dependencies of each output.
+// The last record for an output replaces earlier ones.
+func readNinjaDeps(r io.Reader) (map[string][]string, error) {
+	header := make([]byte, len(ninjaDepsSignature)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if string(header[:len(ninjaDepsSignature)]) != ninjaDepsSignature {
+		return nil, fmt.Errorf("invalid header")
+	}
+	if version := binary.LittleEndian.Uint32(header[len(ninjaDepsSignature):]); version != 4 {
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+
+	var paths []string
+	deps := make(map[string][]string)
+	var sizeBuf [4]byte
+	for {
+		// Ninja may have been killed while writing a record, ignore a truncated record at the end
+		// of the file like ninja does.
+		if _, err := io.ReadFull(r, sizeBuf[:]); err != nil {
+			break
+		}
+		size := binary.LittleEndian.Uint32(sizeBuf[:])
+		isDeps := size&0x80000000 != 0
+		size &= 0x7fffffff
+
+		record := make([]byte, size)
+		if _, err := io.ReadFull(r, record); err != nil {
+			break
+		}
+
+		if isDeps {
+			// A deps record is the id of the output, the mtime of the output, and the ids of the
+			// inputs.
+			if size < 12 || size%4 != 0 {
+				return nil, fmt.Errorf("invalid deps record size %d", size)
+			}
+			out := binary.LittleEndian.Uint32(record)
+			if int(out) >= len(paths) {
+				return nil, fmt.Errorf("deps record for unknown path id %d", out)
+			}
+			var inputs []string
+			for i := 12; i < len(record); i += 4 {
+				in := binary.LittleEndian.Uint32(record[i:])
+				if int(in) >= len(paths) {
+					return nil, fmt.Errorf("deps record with unknown path id %d", in)
+				}
+				inputs = append(inputs, paths[in])
+			}
+			deps[paths[out]] = inputs
+		} else {
+			// A path record is the path padded to a multiple of 4 bytes with NULs, followed by
+			// the one's complement of its id as a checksum.
+			if size < 4 {
+				return nil, fmt.Errorf("invalid path record size %d", size)
+			}
+			checksum := binary.LittleEndian.Uint32(record[size-4:])
+			if ^checksum != uint32(len(paths)) {
+				return nil, fmt.Errorf("invalid checksum for path record %d", len(paths))
+			}
+			paths = append(paths, string(bytes.TrimRight(record[:size-4], "\x00")))
+		}
+	}
+
+	return deps, nil
+}
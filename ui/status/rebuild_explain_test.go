@@ -0,0 +1,169 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRebuildExplainer(t *testing.T) {
+	e := NewRebuildExplainer(5)
+	e.ninjaLog = map[string]bool{
+		"out/deleted.o": true,
+	}
+	e.ninjaDeps = map[string][]string{
+		"out/b.o": {"b.cpp", "gen/version.h"},
+	}
+
+	for _, msg := range []string{
+		"ninja: explain: output gen/version.h older than most recent input build_id.txt (100 vs 200)",
+		"ninja: explain: gen/version.h is dirty",
+		"ninja: explain: gen/version.h is dirty",
+		"ninja: explain: output out/deleted.o doesn't exist",
+		"ninja: explain: output out/new.o doesn't exist",
+		"ninja: explain: command line changed for out/flags.o",
+		"ninja: explain: out/a.o is dirty",
+		"ninja: some other message",
+	} {
+		e.Message(VerboseLvl, msg)
+	}
+
+	for _, action := range []*Action{
+		{Outputs: []string{"gen/version.h"}, Inputs: []string{"build_id.txt"}},
+		{Outputs: []string{"out/a.o"}, Inputs: []string{"a.cpp", "gen/version.h"}},
+		// out/b.o only depends on gen/version.h through its depfile.
+		{Outputs: []string{"out/b.o"}, Inputs: []string{"b.cpp"}},
+		{Outputs: []string{"out/lib.so"}, Inputs: []string{"out/a.o", "out/flags.o"}},
+		{Outputs: []string{"out/deleted.o"}},
+		{Outputs: []string{"out/new.o"}},
+		{Outputs: []string{"out/flags.o"}},
+	} {
+		e.StartAction(action, Counts{})
+	}
+
+	want := []RebuildCause{
+		{Kind: RebuildInputChanged, Path: "build_id.txt", Actions: 4},
+		{Kind: RebuildCommandChanged, Path: "out/flags.o", Actions: 1},
+		{Kind: RebuildOutputMissing, Path: "out/deleted.o", Actions: 1},
+	}
+	if got := e.Explain(); !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect causes:\nwant: %v\n got: %v", want, got)
+	}
+
+	summary := e.Summary()
+	for _, line := range []string{
+		"Explaining why ninja ran the first 5 of 7 actions:",
+		"         4 input changed: build_id.txt",
+		"         1 output missing: out/deleted.o",
+	} {
+		if !strings.Contains(summary, line+"\n") {
+			t.Errorf("summary missing line %q:\n%s", line, summary)
+		}
+	}
+}
+
+func TestRebuildExplainerNewAction(t *testing.T) {
+	e := NewRebuildExplainer(10)
+	e.ninjaLog = map[string]bool{}
+
+	e.Message(VerboseLvl, "ninja: ninja explain: output out/new.o doesn't exist")
+	e.Message(VerboseLvl, "ninja: explain: command line not found in log for out/other.o")
+	e.Message(VerboseLvl, "ninja: explain: restat of output out/restat.h older than most recent input gen.py (1 vs 2)")
+	e.StartAction(&Action{Outputs: []string{"out/new.o"}}, Counts{})
+	e.StartAction(&Action{Outputs: []string{"out/other.o"}}, Counts{})
+	e.StartAction(&Action{Outputs: []string{"out/restat.h"}}, Counts{})
+
+	want := []RebuildCause{
+		{Kind: RebuildRestat, Path: "gen.py", Actions: 1},
+		{Kind: RebuildNewAction, Path: "out/new.o", Actions: 1},
+		{Kind: RebuildNewAction, Path: "out/other.o", Actions: 1},
+	}
+	if got := e.Explain(); !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect causes:\nwant: %v\n got: %v", want, got)
+	}
+}
+
+func TestReadNinjaLog(t *testing.T) {
+	log := "# ninja log v5\n" +
+		"0\t10\t100\tout/a.o\t1a2b\n" +
+		"0\t20\t200\tout/b.o\tffff\n" +
+		"30\t40\t300\tout/a.o\t3c4d\n"
+
+	got, err := readNinjaLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ninjaLogEntry{
+		{start: 30, end: 40, mtime: 300, output: "out/a.o", cmdHash: 0x3c4d},
+		{start: 0, end: 20, mtime: 200, output: "out/b.o", cmdHash: 0xffff},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect entries:\nwant: %+v\n got: %+v", want, got)
+	}
+
+	if _, err := readNinjaLog(strings.NewReader("# ninja log v4\n")); err == nil {
+		t.Errorf("expected error for unsupported version")
+	}
+}
+
+func TestReadNinjaDeps(t *testing.T) {
+	buf := &bytes.Buffer{}
+	write := func(v uint32) { binary.Write(buf, binary.LittleEndian, v) }
+
+	buf.WriteString(ninjaDepsSignature)
+	write(4)
+
+	paths := 0
+	writePath := func(path string) {
+		padded := path + strings.Repeat("\x00", (4-len(path)%4)%4)
+		write(uint32(len(padded) + 4))
+		buf.WriteString(padded)
+		write(^uint32(paths))
+		paths++
+	}
+	writeDeps := func(out uint32, ins ...uint32) {
+		write(uint32(12+4*len(ins)) | 0x80000000)
+		write(out)
+		binary.Write(buf, binary.LittleEndian, int64(1234))
+		for _, in := range ins {
+			write(in)
+		}
+	}
+
+	writePath("out/a.o")
+	writePath("a.cpp")
+	writePath("a.h")
+	writeDeps(0, 1)
+	writeDeps(0, 1, 2)
+	// A truncated record at the end is ignored.
+	write(100)
+
+	got, err := readNinjaDeps(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"out/a.o": {"a.cpp", "a.h"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect deps:\nwant: %q\n got: %q", want, got)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zip
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"android/soong/third_party/zip"
+)
+
+// compressedExtensions are the extensions of files whose contents are usually already compressed,
+// or that are stored uncompressed so that they can be mapped directly from the zip file.
+var compressedExtensions = map[string]bool{
+	".7z":   true,
+	".apex": true,
+	".apk":  true,
+	".br":   true,
+	".bz2":  true,
+	".gif":  true,
+	".gz":   true,
+	".jar":  true,
+	".jpeg": true,
+	".jpg":  true,
+	".lz4":  true,
+	".mp3":  true,
+	".mp4":  true,
+	".ogg":  true,
+	".png":  true,
+	".so":   true,
+	".webp": true,
+	".xz":   true,
+	".zip":  true,
+}
+
+// IsCompressedName returns true if the extension of name is one of a file whose contents are
+// usually already compressed, or one that is usually stored uncompressed in zip files.
+func IsCompressedName(name string) bool {
+	return compressedExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+type RecompressArgs struct {
+	// CompressionLevel is the deflate level to compress entries with, 0 stores all entries.
+	CompressionLevel int
+
+	// StoreCompressed stores the entries for which IsCompressedName returns true instead of
+	// deflating them.
+	StoreCompressed bool
+
+	NumParallelJobs int
+}
+
+// A Recompressor adds entries from other zip files to a zip file, recompressing them in parallel
+// with the same rate limited pipeline that is used by Zip.
+type Recompressor struct {
+	z               *ZipWriter
+	storeCompressed bool
+}
+
+// Recompress runs addEntries in a separate goroutine, and writes the entries that it adds to the
+// Recompressor to zipw in the order they are added.  The zip files that the entries are read from
+// must stay open until Recompress returns.
+func Recompress(zipw *zip.Writer, args RecompressArgs, addEntries func(r *Recompressor) error) error {
+	r := &Recompressor{
+		z: &ZipWriter{
+			compLevel: args.CompressionLevel,
+		},
+		storeCompressed: args.StoreCompressed,
+	}
+
+	return r.z.pipeline(zipw, args.NumParallelJobs, func() error {
+		return addEntries(r)
+	})
+}
+
+// Recompress adds file to the zip file as dest, stored or deflated according to the
+// RecompressArgs.
+func (r *Recompressor) Recompress(file *zip.File, dest string) error {
+	method := zip.Deflate
+	if r.z.compLevel == 0 || (r.storeCompressed && IsCompressedName(dest)) {
+		method = zip.Store
+	}
+	return r.Convert(file, dest, method)
+}
+
+// Convert adds file to the zip file as dest, using method to compress it.  Directories are copied
+// verbatim.  If method is zip.Deflate but deflating the file doesn't make it smaller it is stored
+// instead.
+func (r *Recompressor) Convert(file *zip.File, dest string, method uint16) error {
+	if file.FileInfo().IsDir() {
+		return r.Copy(file, dest)
+	}
+
+	fh := file.FileHeader
+	fh.Name = dest
+	fh.Method = method
+	fh.CompressedSize = 0
+	fh.CompressedSize64 = 0
+
+	return r.z.writeFileContents(&fh, &zipFileReader{file: file})
+}
+
+// Copy adds file to the zip file as dest with its existing compression.
+func (r *Recompressor) Copy(file *zip.File, dest string) error {
+	return r.Write(func(zw *zip.Writer) error {
+		return zw.CopyFrom(file, dest)
+	})
+}
+
+// Write adds an entry to the zip file that is written by calling write with the zip writer.
+func (r *Recompressor) Write(write func(zw *zip.Writer) error) error {
+	ze := make(chan *zipEntry, 1)
+	ze <- &zipEntry{
+		write: write,
+	}
+	close(ze)
+	r.z.writeOps <- ze
+	return nil
+}
+
+// zipFileReader is a pathtools.ReaderAtSeekerCloser for the uncompressed contents of an entry in a
+// zip file.  The contents are read into memory the first time they are needed, after the memory
+// for them has been requested from the MemoryRateLimiter.
+type zipFileReader struct {
+	file *zip.File
+
+	once   sync.Once
+	reader *bytes.Reader
+	err    error
+}
+
+func (r *zipFileReader) load() error {
+	r.once.Do(func() {
+		var rc io.ReadCloser
+		rc, r.err = r.file.Open()
+		if r.err != nil {
+			return
+		}
+		defer rc.Close()
+
+		var buf []byte
+		buf, r.err = ioutil.ReadAll(rc)
+		r.reader = bytes.NewReader(buf)
+	})
+	return r.err
+}
+
+func (r *zipFileReader) Read(p []byte) (int, error) {
+	if err := r.load(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+func (r *zipFileReader) ReadAt(p []byte, off int64) (int, error) {
+	if err := r.load(); err != nil {
+		return 0, err
+	}
+	return r.reader.ReadAt(p, off)
+}
+
+func (r *zipFileReader) Seek(offset int64, whence int) (int64, error) {
+	if err := r.load(); err != nil {
+		return 0, err
+	}
+	return r.reader.Seek(offset, whence)
+}
+
+func (r *zipFileReader) Close() error {
+	r.reader = nil
+	return nil
+}
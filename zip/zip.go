@@ -229,6 +229,10 @@ type zipEntry struct {
 	// List of delayed io.Reader
 	futureReaders chan chan io.Reader
 
+	// If set, the entry is written by calling write with the zip writer instead of from fh and
+	// futureReaders, for example to copy it verbatim from another zip file.
+	write func(zw *zip.Writer) error
+
 	// Only used for passing into the MemoryRateLimiter to ensure we
 	// release as much memory as much as we request
 	allocatedSize int64
@@ -436,6 +440,44 @@ func jarSort(mappings []pathMapping) {
 func (z *ZipWriter) write(f io.Writer, pathMappings []pathMapping, manifest string, emulateJar, srcJar bool,
 	parallelJobs int) error {
 
+	if manifest != "" && !emulateJar {
+		return errors.New("must specify --jar when specifying a manifest via -m")
+	}
+
+	if emulateJar {
+		// manifest may be empty, in which case addManifest will fill in a default
+		pathMappings = append(pathMappings, pathMapping{jar.ManifestFile, manifest, zip.Deflate})
+
+		jarSort(pathMappings)
+	}
+
+	zipw := zip.NewWriter(f)
+
+	err := z.pipeline(zipw, parallelJobs, func() error {
+		for _, ele := range pathMappings {
+			var err error
+			if emulateJar && ele.dest == jar.ManifestFile {
+				err = z.addManifest(ele.dest, ele.src, ele.zipMethod)
+			} else {
+				err = z.addFile(ele.dest, ele.src, ele.zipMethod, emulateJar, srcJar)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	zipw.Close()
+	return nil
+}
+
+// pipeline runs addEntries in a separate goroutine, and writes the entries it adds to zipw in the
+// order they were added while they are compressed in parallel.
+func (z *ZipWriter) pipeline(zipw *zip.Writer, parallelJobs int, addEntries func() error) error {
 	z.errors = make(chan error)
 	defer close(z.errors)
 
@@ -459,36 +501,14 @@ func (z *ZipWriter) write(f io.Writer, pathMappings []pathMapping, manifest stri
 		z.memoryRateLimiter.Stop()
 	}()
 
-	if manifest != "" && !emulateJar {
-		return errors.New("must specify --jar when specifying a manifest via -m")
-	}
-
-	if emulateJar {
-		// manifest may be empty, in which case addManifest will fill in a default
-		pathMappings = append(pathMappings, pathMapping{jar.ManifestFile, manifest, zip.Deflate})
-
-		jarSort(pathMappings)
-	}
-
 	go func() {
-		var err error
 		defer close(z.writeOps)
 
-		for _, ele := range pathMappings {
-			if emulateJar && ele.dest == jar.ManifestFile {
-				err = z.addManifest(ele.dest, ele.src, ele.zipMethod)
-			} else {
-				err = z.addFile(ele.dest, ele.src, ele.zipMethod, emulateJar, srcJar)
-			}
-			if err != nil {
-				z.errors <- err
-				return
-			}
+		if err := addEntries(); err != nil {
+			z.errors <- err
 		}
 	}()
 
-	zipw := zip.NewWriter(f)
-
 	var currentWriteOpChan chan *zipEntry
 	var currentWriter io.WriteCloser
 	var currentReaders chan chan io.Reader
@@ -521,6 +541,13 @@ func (z *ZipWriter) write(f io.Writer, pathMappings []pathMapping, manifest stri
 		case op := <-writeOpChan:
 			currentWriteOpChan = nil
 
+			if op.write != nil {
+				if err := op.write(zipw); err != nil {
+					return err
+				}
+				break
+			}
+
 			var err error
 			if op.fh.Method == zip.Deflate {
 				currentWriter, err = zipw.CreateCompressedHeader(op.fh)
@@ -571,7 +598,6 @@ func (z *ZipWriter) write(f io.Writer, pathMappings []pathMapping, manifest stri
 	case err := <-z.errors:
 		return err
 	default:
-		zipw.Close()
 		return nil
 	}
 }
@@ -709,7 +735,10 @@ func (z *ZipWriter) addManifest(dest string, src string, _ uint16) error {
 
 func (z *ZipWriter) writeFileContents(header *zip.FileHeader, r pathtools.ReaderAtSeekerCloser) (err error) {
 
-	header.SetModTime(z.time)
+	// Entries recompressed from other zip files keep their original timestamps.
+	if !z.time.IsZero() {
+		header.SetModTime(z.time)
+	}
 
 	compressChan := make(chan *zipEntry, 1)
 	z.writeOps <- compressChan